@@ -13,6 +13,10 @@ const (
 	AuthenticationMethodOK        AuthenticationMethod = 0
 	AuthenticationMethodPlaintext AuthenticationMethod = 3
 	AuthenticationMethodMD5       AuthenticationMethod = 5
+	// SASL authentication methods, used for SCRAM-SHA-256
+	AuthenticationMethodSASL         AuthenticationMethod = 10
+	AuthenticationMethodSASLContinue AuthenticationMethod = 11
+	AuthenticationMethodSASLFinal    AuthenticationMethod = 12
 )
 
 func (a AuthenticationMethod) String() string {
@@ -23,6 +27,12 @@ func (a AuthenticationMethod) String() string {
 		return "Plaintext"
 	case AuthenticationMethodMD5:
 		return "MD5"
+	case AuthenticationMethodSASL:
+		return "SASL"
+	case AuthenticationMethodSASLContinue:
+		return "SASLContinue"
+	case AuthenticationMethodSASLFinal:
+		return "SASLFinal"
 	}
 
 	return "Unknown"
@@ -30,9 +40,14 @@ func (a AuthenticationMethod) String() string {
 
 // AuthenticationRequest is a server response either asking the client to authenticate or
 // used to indicate that authentication was successful
+//
+// Salt is only used by the MD5 method, Mechanisms by the SASL method and Data by the SASLContinue
+// and SASLFinal methods
 type AuthenticationRequest struct {
-	Method AuthenticationMethod
-	Salt   []byte
+	Method     AuthenticationMethod
+	Salt       []byte
+	Mechanisms [][]byte
+	Data       []byte
 }
 
 func (a *AuthenticationRequest) server() {}
@@ -57,23 +72,38 @@ func ParseAuthenticationRequest(r io.Reader) (*AuthenticationRequest, error) {
 	if err != nil {
 		return nil, err
 	}
-	m := AuthenticationMethod(i)
-	if m != AuthenticationMethodOK && m != AuthenticationMethodPlaintext && m != AuthenticationMethodMD5 {
-		return nil, fmt.Errorf("received unknown authentication request method number %d", m)
-	}
-
 	a := &AuthenticationRequest{
-		Method: m,
+		Method: AuthenticationMethod(i),
 	}
 
-	if a.Method == AuthenticationMethodMD5 {
-		a.Salt, err = buf.ReadString(false)
+	switch a.Method {
+	case AuthenticationMethodOK, AuthenticationMethodPlaintext:
+	case AuthenticationMethodMD5:
+		// DEV: Read the salt as exactly 4 bytes, the salt may contain null bytes
+		a.Salt = make([]byte, 4)
+		_, err = buf.Read(a.Salt)
 		if err != nil {
-			return nil, err
-		}
-		if len(a.Salt) != 4 {
 			return nil, fmt.Errorf("expected salt of length 4")
 		}
+	case AuthenticationMethodSASL:
+		// ([string - mechanism] \0)+ \0
+		for {
+			mechanism, err := buf.ReadString(stripNull)
+			if err != nil {
+				return nil, err
+			}
+			if len(mechanism) == 0 {
+				break
+			}
+			a.Mechanisms = append(a.Mechanisms, mechanism)
+		}
+	case AuthenticationMethodSASLContinue, AuthenticationMethodSASLFinal:
+		a.Data, err = buf.ReadRemaining()
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("received unknown authentication request method number %d", a.Method)
 	}
 
 	return a, nil
@@ -84,8 +114,16 @@ func (a *AuthenticationRequest) Encode() []byte {
 	// 'R' [int32 - length] [int32 - method] [other - optional]
 	w := newWriteBuffer()
 	w.WriteInt(int(a.Method))
-	if a.Method == AuthenticationMethodMD5 {
+	switch a.Method {
+	case AuthenticationMethodMD5:
 		w.WriteString(a.Salt, false)
+	case AuthenticationMethodSASL:
+		for _, m := range a.Mechanisms {
+			w.WriteString(m, true)
+		}
+		w.WriteByte('\x00')
+	case AuthenticationMethodSASLContinue, AuthenticationMethodSASLFinal:
+		w.WriteBytes(a.Data)
 	}
 	w.Wrap('R')
 	return w.Bytes()
@@ -98,16 +136,34 @@ func (a *AuthenticationRequest) Encode() []byte {
 //     "Payload": map[string]interface{}{
 //       "Method": <AuthenticationRequest.Method>,
 //       "Salt": <AuthenticationRequest.Salt>,
+//       "Mechanisms": <AuthenticationRequest.Mechanisms>,
+//       "Data": <AuthenticationRequest.Data>,
 //     },
 //   }
 func (a *AuthenticationRequest) AsMap() map[string]interface{} {
+	mechanisms := make([]string, len(a.Mechanisms))
+	for i, m := range a.Mechanisms {
+		mechanisms[i] = string(m)
+	}
 	return map[string]interface{}{
 		"Type": "AuthenticationRequest",
 		"Payload": map[string]interface{}{
-			"Method": int(a.Method),
-			"Salt":   a.Salt,
+			"Method":     int(a.Method),
+			"Salt":       redactBytes(a, "Salt", a.Salt),
+			"Mechanisms": mechanisms,
+			"Data":       redactBytes(a, "Data", a.Data),
 		},
 	}
 }
 
 func (a *AuthenticationRequest) String() string { return messageToString(a) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (a *AuthenticationRequest) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("AuthenticationRequest", a)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (a *AuthenticationRequest) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "AuthenticationRequest", a)
+}
@@ -71,3 +71,13 @@ func (b *BackendKeyData) AsMap() map[string]interface{} {
 }
 
 func (b *BackendKeyData) String() string { return messageToString(b) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (b *BackendKeyData) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("BackendKeyData", b)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (b *BackendKeyData) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "BackendKeyData", b)
+}
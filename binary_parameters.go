@@ -5,6 +5,10 @@ import (
 )
 
 // BinaryParameters represents a client message for sending binary parameters to the server
+//
+// Deprecated: BinaryParameters is not a message of the protocol, the parameters of a prepared
+// statement are sent with a Bind, which ParseClientMessage returns for 'B' messages. Bind.Parameters
+// holds the values BinaryParameters.Fields did.
 type BinaryParameters struct {
 	Fields [][]byte
 }
@@ -12,6 +16,8 @@ type BinaryParameters struct {
 func (p *BinaryParameters) client() {}
 
 // ParseBinaryParameters will attempt to read an BinaryParameter message from the io.Reader
+//
+// Deprecated: Use ParseBind.
 func ParseBinaryParameters(r io.Reader) (*BinaryParameters, error) {
 	b := newReadBuffer(r)
 
@@ -27,7 +33,7 @@ func ParseBinaryParameters(r io.Reader) (*BinaryParameters, error) {
 	}
 
 	// Field count - int16
-	c, err := b.ReadInt16()
+	c, err := b.ReadCount(4)
 	if err != nil {
 		return nil, err
 	}
@@ -37,23 +43,13 @@ func ParseBinaryParameters(r io.Reader) (*BinaryParameters, error) {
 	}
 
 	for i := 0; i < c; i++ {
-		// [int32 - length] [string - data]
-		l, err := b.ReadInt()
+		// [int32 - length] [string - data], a length of -1 is NULL
+		p.Fields[i], err = b.ReadValue()
 		if err == io.EOF {
 			break
 		} else if err != nil {
 			return nil, err
 		}
-
-		if l == -1 {
-			p.Fields[i] = nil
-		} else {
-			p.Fields[i] = make([]byte, l)
-			_, err = b.Read(p.Fields[i])
-			if err != nil {
-				return nil, err
-			}
-		}
 	}
 
 	return p, nil
@@ -64,6 +60,11 @@ func (p *BinaryParameters) Encode() []byte {
 	b := newWriteBuffer()
 	b.WriteInt16(len(p.Fields))
 	for _, f := range p.Fields {
+		// NULL values are encoded with a length of -1
+		if f == nil {
+			b.WriteInt(-1)
+			continue
+		}
 		b.WriteInt(len(f))
 		b.WriteBytes(f)
 	}
@@ -93,3 +94,13 @@ func (p *BinaryParameters) AsMap() map[string]interface{} {
 }
 
 func (p *BinaryParameters) String() string { return messageToString(p) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (p *BinaryParameters) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("BinaryParameters", p)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (p *BinaryParameters) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "BinaryParameters", p)
+}
@@ -0,0 +1,161 @@
+package pgproto
+
+import (
+	"io"
+)
+
+// Bind represents a client request to bind parameters to a prepared statement, creating a portal
+type Bind struct {
+	Portal           []byte
+	Statement        []byte
+	ParameterFormats []Format
+	Parameters       [][]byte
+	ResultFormats    []Format
+}
+
+func (b *Bind) client() {}
+
+// ParseBind will attempt to read a Bind message from the io.Reader
+func ParseBind(r io.Reader) (*Bind, error) {
+	buf := newReadBuffer(r)
+
+	// 'B' [int32 - length] [string - portal] \0 [string - statement] \0
+	//   [int16 - format count] [int16 - format] ...
+	//   [int16 - parameter count] ([int32 - length] [bytes - value]) ...
+	//   [int16 - result format count] [int16 - format] ...
+	err := buf.ReadTag('B')
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err = buf.ReadLength()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bind{}
+
+	b.Portal, err = buf.ReadString(true)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Statement, err = buf.ReadString(true)
+	if err != nil {
+		return nil, err
+	}
+
+	b.ParameterFormats, err = readFormats(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := buf.ReadCount(4)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Parameters = make([][]byte, count)
+	for i := 0; i < count; i++ {
+		// [int32 - length] [bytes - value], a length of -1 is NULL
+		b.Parameters[i], err = buf.ReadValue()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b.ResultFormats, err = readFormats(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Encode will return the byte representation of this message
+func (b *Bind) Encode() []byte {
+	w := newWriteBuffer()
+	w.WriteString(b.Portal, true)
+	w.WriteString(b.Statement, true)
+	writeFormats(w, b.ParameterFormats)
+	w.WriteInt16(len(b.Parameters))
+	for _, p := range b.Parameters {
+		if p == nil {
+			w.WriteInt(-1)
+			continue
+		}
+		w.WriteInt(len(p))
+		w.WriteBytes(p)
+	}
+	writeFormats(w, b.ResultFormats)
+	w.Wrap('B')
+	return w.Bytes()
+}
+
+// AsMap method returns a common map representation of this message:
+//
+//   map[string]interface{}{
+//     "Type": "Bind",
+//     "Payload": map[string]interface{}{
+//       "Portal": <Bind.Portal>,
+//       "Statement": <Bind.Statement>,
+//       "ParameterFormats": <Bind.ParameterFormats>,
+//       "Parameters": <Bind.Parameters>,
+//       "ResultFormats": <Bind.ResultFormats>,
+//     },
+//   }
+func (b *Bind) AsMap() map[string]interface{} {
+	params := make([]interface{}, len(b.Parameters))
+	for i, p := range b.Parameters {
+		if p != nil {
+			params[i] = string(p)
+		}
+	}
+	return map[string]interface{}{
+		"Type": "Bind",
+		"Payload": map[string]interface{}{
+			"Portal":           string(b.Portal),
+			"Statement":        string(b.Statement),
+			"ParameterFormats": b.ParameterFormats,
+			"Parameters":       params,
+			"ResultFormats":    b.ResultFormats,
+		},
+	}
+}
+
+func (b *Bind) String() string { return messageToString(b) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (b *Bind) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("Bind", b)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (b *Bind) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "Bind", b)
+}
+
+func readFormats(buf *readBuffer) ([]Format, error) {
+	// [int16 - count] [int16 - format] ...
+	count, err := buf.ReadCount(2)
+	if err != nil {
+		return nil, err
+	}
+
+	formats := make([]Format, count)
+	for i := 0; i < count; i++ {
+		f, err := buf.ReadInt16()
+		if err != nil {
+			return nil, err
+		}
+		formats[i] = Format(f)
+	}
+	return formats, nil
+}
+
+func writeFormats(w *writeBuffer, formats []Format) {
+	w.WriteInt16(len(formats))
+	for _, f := range formats {
+		w.WriteInt16(int(f))
+	}
+}
@@ -57,3 +57,13 @@ func (b *BindComplete) AsMap() map[string]interface{} {
 }
 
 func (b *BindComplete) String() string { return messageToString(b) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (b *BindComplete) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("BindComplete", b)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (b *BindComplete) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "BindComplete", b)
+}
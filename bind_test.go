@@ -0,0 +1,128 @@
+package pgproto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+// 'B' [int32 - length] [string - portal] \0 [string - statement] \0 [int16 - format count] [int16 - format] ...
+//   [int16 - parameter count] ([int32 - length] [bytes - value]) ... [int16 - result format count] [int16 - format] ...
+var rawBindMessage = []byte{
+	// Tag
+	'B',
+	// Length
+	'\x00', '\x00', '\x00', '\x20',
+	// Portal "" \0
+	'\x00',
+	// Statement "stmt" \0
+	's', 't', 'm', 't', '\x00',
+	// Parameter format count
+	'\x00', '\x01',
+	// Binary
+	'\x00', '\x01',
+	// Parameter count
+	'\x00', '\x02',
+	// Length
+	'\x00', '\x00', '\x00', '\x04',
+	// Value
+	'\x00', '\x00', '\x00', '\x2a',
+	// NULL
+	'\xff', '\xff', '\xff', '\xff',
+	// Result format count
+	'\x00', '\x01',
+	// Text
+	'\x00', '\x00',
+}
+
+type BindTestSuite struct {
+	suite.Suite
+}
+
+func TestBindTestSuite(t *testing.T) {
+	suite.Run(t, new(BindTestSuite))
+}
+
+func (s *BindTestSuite) Test_ParseBind() {
+	b, err := pgproto.ParseBind(bytes.NewReader(rawBindMessage))
+	s.Nil(err)
+	s.NotNil(b)
+	s.Equal([]byte{}, b.Portal)
+	s.Equal([]byte("stmt"), b.Statement)
+	s.Equal([]pgproto.Format{pgproto.FormatBinary}, b.ParameterFormats)
+	s.Equal([][]byte{{'\x00', '\x00', '\x00', '\x2a'}, nil}, b.Parameters)
+	s.Equal([]pgproto.Format{pgproto.FormatText}, b.ResultFormats)
+	s.Equal(rawBindMessage, b.Encode())
+}
+
+func (s *BindTestSuite) Test_ParseBind_Empty() {
+	b, err := pgproto.ParseBind(bytes.NewReader([]byte{}))
+	s.NotNil(err)
+	s.Nil(b)
+}
+
+func (s *BindTestSuite) Test_ParseBind_Malformed() {
+	// patch returns a copy of rawBindMessage with the bytes at offset replaced by b
+	patch := func(offset int, b ...byte) []byte {
+		raw := append([]byte{}, rawBindMessage...)
+		copy(raw[offset:], b)
+		return raw
+	}
+
+	for name, raw := range map[string][]byte{
+		"empty body":                   {'B', '\x00', '\x00', '\x00', '\x04'},
+		"negative parameter formats":   patch(11, '\xff', '\xfe'),
+		"too many parameter formats":   patch(11, '\x7f', '\xff'),
+		"negative parameter count":     patch(15, '\xff', '\xff'),
+		"too many parameters":          patch(15, '\x10', '\x00'),
+		"negative parameter length":    patch(17, '\xff', '\xff', '\xff', '\xf0'),
+		"too long parameter":           patch(17, '\x7f', '\xff', '\xff', '\xff'),
+		"negative result formats":      patch(29, '\x80', '\x00'),
+		"too many result formats":      patch(29, '\x00', '\x02'),
+		"message length below 4":       patch(1, '\x00', '\x00', '\x00', '\x02'),
+		"message length past the data": patch(1, '\x00', '\x00', '\x01', '\x00'),
+	} {
+		s.NotPanics(func() {
+			b, err := pgproto.ParseBind(bytes.NewReader(raw))
+			s.NotNil(err, name)
+			s.Nil(b, name)
+		}, name)
+		s.NotPanics(func() {
+			m, err := pgproto.ParseClientMessage(bytes.NewReader(raw))
+			s.NotNil(err, name)
+			s.Nil(m, name)
+		}, name)
+	}
+}
+
+func (s *BindTestSuite) Test_BindEncode() {
+	b := &pgproto.Bind{
+		Statement:        []byte("stmt"),
+		ParameterFormats: []pgproto.Format{pgproto.FormatBinary},
+		Parameters:       [][]byte{{'\x00', '\x00', '\x00', '\x2a'}, nil},
+		ResultFormats:    []pgproto.Format{pgproto.FormatText},
+	}
+	s.Equal(rawBindMessage, b.Encode())
+}
+
+func (s *BindTestSuite) Test_Bind_ParseClientMessage() {
+	m, err := pgproto.ParseClientMessage(bytes.NewReader(rawBindMessage))
+	s.Nil(err)
+	b, ok := m.(*pgproto.Bind)
+	s.True(ok)
+	s.Equal([]byte("stmt"), b.Statement)
+	s.Equal(rawBindMessage, m.Encode())
+}
+
+func BenchmarkParseBind(b *testing.B) {
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			_, err := pgproto.ParseBind(bytes.NewReader(rawBindMessage))
+			if err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
@@ -21,6 +21,14 @@ const (
 	dontWriteNull           = false
 )
 
+const (
+	// maxMessageLength is the largest message length accepted, the limit PostgreSQL itself applies
+	maxMessageLength = 1<<30 - 1
+
+	// maxStartupLength is the largest startup message length PostgreSQL accepts
+	maxStartupLength = 10000
+)
+
 type readBuffer struct {
 	io.Reader
 	oneByte   [1]byte
@@ -40,6 +48,12 @@ func newReadBuffer(r io.Reader) *readBuffer {
 	return buf
 }
 
+// Read will fill p entirely from the underlying reader, network connections
+// are allowed to return short reads so we must keep reading until we have everything
+func (b *readBuffer) Read(p []byte) (int, error) {
+	return io.ReadFull(b.Reader, p)
+}
+
 func (b *readBuffer) ReadInt() (int, error) {
 	n, err := b.Read(b.fourBytes[:])
 	if err != nil {
@@ -69,14 +83,18 @@ func (b *readBuffer) ReadLength() (*readBuffer, error) {
 	if err != nil {
 		return nil, err
 	}
-	if l <= 0 {
+	if l < 4 || l > maxMessageLength {
 		return nil, fmt.Errorf("unable to parse length from message")
 	}
+	if rem := b.Len(); rem >= 0 && l-4 > rem {
+		return nil, fmt.Errorf("message length %d exceeds the %d bytes left", l, rem+4)
+	}
 
 	// Length needs to account for the 4 bytes of the length value that have already been parsed
+	// DEV: An empty body still returns a buffer, reads from it return io.EOF
 	l = l - 4
 	if l == 0 {
-		return nil, nil
+		return newReadBuffer(bytes.NewReader(nil)), nil
 	}
 
 	buf := make([]byte, l)
@@ -92,6 +110,68 @@ func (b *readBuffer) ReadLength() (*readBuffer, error) {
 	return newReadBuffer(bytes.NewReader(buf)), nil
 }
 
+// Len returns the number of bytes left to read, or -1 when the underlying reader can not tell
+func (b *readBuffer) Len() int {
+	if r, ok := b.Reader.(interface{ Len() int }); ok {
+		return r.Len()
+	}
+	return -1
+}
+
+// ReadCount will read an int16 count of items which take at least size bytes each, failing when
+// the count is negative or more items than the bytes left could hold
+func (b *readBuffer) ReadCount(size int) (int, error) {
+	c, err := b.ReadInt16()
+	if err != nil {
+		return 0, err
+	}
+	return c, b.checkCount(c, size)
+}
+
+// ReadCount32 will read an int32 count of items which take at least size bytes each, see ReadCount
+func (b *readBuffer) ReadCount32(size int) (int, error) {
+	c, err := b.ReadInt()
+	if err != nil {
+		return 0, err
+	}
+	return c, b.checkCount(c, size)
+}
+
+func (b *readBuffer) checkCount(c int, size int) error {
+	if c < 0 {
+		return fmt.Errorf("invalid count %d in message", c)
+	}
+	if rem := b.Len(); rem >= 0 && c*size > rem {
+		return fmt.Errorf("count %d exceeds the %d bytes left in message", c, rem)
+	}
+	return nil
+}
+
+// ReadValue will read an [int32 - length] [bytes - value] pair, a length of -1 is NULL and returned
+// as a nil value, any other negative length or one past the bytes left fails
+func (b *readBuffer) ReadValue() ([]byte, error) {
+	l, err := b.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+	if l == -1 {
+		return nil, nil
+	}
+	if l < -1 {
+		return nil, fmt.Errorf("invalid value length %d in message", l)
+	}
+	if rem := b.Len(); rem >= 0 && l > rem {
+		return nil, fmt.Errorf("value length %d exceeds the %d bytes left in message", l, rem)
+	}
+
+	v := make([]byte, l)
+	_, err = b.Read(v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
 func (b *readBuffer) ReadByte() (c byte, err error) {
 	l, err := b.Read(b.oneByte[:])
 	if l == 1 {
@@ -132,6 +212,11 @@ func (b *readBuffer) ReadString(trimNull nullStrip) ([]byte, error) {
 	return str, nil
 }
 
+// ReadRemaining will read everything left in the underlying reader
+func (b *readBuffer) ReadRemaining() ([]byte, error) {
+	return io.ReadAll(b.Reader)
+}
+
 func (b *readBuffer) ReadTag(t byte) error {
 	tag, err := b.ReadByte()
 	if err != nil {
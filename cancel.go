@@ -0,0 +1,98 @@
+package pgproto
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	cancelRequestCode = 80877102
+)
+
+// CancelRequest is sent by the client on a new connection, instead of a StartupMessage,
+// to cancel the query running on the backend identified by the BackendKeyData it received
+type CancelRequest struct {
+	PID int
+	Key int
+}
+
+func (c *CancelRequest) client() {}
+
+// ParseCancelRequest will attempt to read a CancelRequest message from the io.Reader
+func ParseCancelRequest(r io.Reader) (*CancelRequest, error) {
+	b := newReadBuffer(r)
+
+	// [int32 - length] [int32 - cancel request code] [int32 - pid] [int32 - key]
+	l, err := b.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+	if l != 16 {
+		return nil, fmt.Errorf("expected message length of 16")
+	}
+
+	code, err := b.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+	if code != cancelRequestCode {
+		return nil, fmt.Errorf("invalid cancel request code %d", code)
+	}
+
+	pid, err := b.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := b.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+
+	return &CancelRequest{
+		PID: pid,
+		Key: key,
+	}, nil
+}
+
+// Encode will return the byte representation of this message
+func (c *CancelRequest) Encode() []byte {
+	// [int32 - length] [int32 - cancel request code] [int32 - pid] [int32 - key]
+	w := newWriteBuffer()
+	w.WriteInt(cancelRequestCode)
+	w.WriteInt(c.PID)
+	w.WriteInt(c.Key)
+	w.PrependLength()
+	return w.Bytes()
+}
+
+// AsMap method returns a common map representation of this message:
+//
+//   map[string]interface{}{
+//     "Type": "CancelRequest",
+//     "Payload": map[string]interface{}{
+//       "PID": <CancelRequest.PID>,
+//       "Key": <CancelRequest.Key>,
+//     },
+//   }
+func (c *CancelRequest) AsMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Type": "CancelRequest",
+		"Payload": map[string]interface{}{
+			"PID": c.PID,
+			"Key": c.Key,
+		},
+	}
+}
+
+func (c *CancelRequest) String() string { return messageToString(c) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (c *CancelRequest) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("CancelRequest", c)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (c *CancelRequest) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "CancelRequest", c)
+}
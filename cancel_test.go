@@ -0,0 +1,54 @@
+package pgproto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+// [int32 - length] [int32 - cancel request code] [int32 - pid] [int32 - key]
+var rawCancelRequestMessage = []byte{
+	// Length
+	'\x00', '\x00', '\x00', '\x10',
+	// Cancel request code
+	'\x04', '\xd2', '\x16', '\x2e',
+	// PID
+	'\x00', '\x00', '\x04', '\xd2',
+	// Key
+	'\x00', '\x00', '\x16', '\x2e',
+}
+
+type CancelRequestTestSuite struct {
+	suite.Suite
+}
+
+func TestCancelRequestTestSuite(t *testing.T) {
+	suite.Run(t, new(CancelRequestTestSuite))
+}
+
+func (s *CancelRequestTestSuite) Test_ParseCancelRequest() {
+	c, err := pgproto.ParseCancelRequest(bytes.NewReader(rawCancelRequestMessage))
+	s.Nil(err)
+	s.NotNil(c)
+	s.Equal(1234, c.PID)
+	s.Equal(5678, c.Key)
+	s.Equal(rawCancelRequestMessage, c.Encode())
+}
+
+func (s *CancelRequestTestSuite) Test_ParseCancelRequest_Empty() {
+	c, err := pgproto.ParseCancelRequest(bytes.NewReader([]byte{}))
+	s.NotNil(err)
+	s.Nil(c)
+}
+
+func (s *CancelRequestTestSuite) Test_CancelRequest_ParseClientMessage() {
+	m, err := pgproto.ParseClientMessage(bytes.NewReader(rawCancelRequestMessage))
+	s.Nil(err)
+	c, ok := m.(*pgproto.CancelRequest)
+	s.True(ok)
+	s.Equal(1234, c.PID)
+	s.Equal(5678, c.Key)
+	s.Equal(rawCancelRequestMessage, m.Encode())
+}
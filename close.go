@@ -1,6 +1,8 @@
 package pgproto
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 )
 
@@ -22,16 +24,25 @@ func ParseClose(r io.Reader) (*Close, error) {
 		return nil, err
 	}
 
+	b, err = b.ReadLength()
+	if err != nil {
+		return nil, err
+	}
+
 	c := &Close{}
 	t, err := b.ReadByte()
 	if err != nil {
 		return nil, err
 	}
 	c.ObjectType = ObjectType(t)
-	c.Name, err = b.ReadString(stripNull)
+	name, err := b.ReadString(dontStripNull)
 	if err != nil {
 		return nil, err
 	}
+	if !bytes.HasSuffix(name, []byte{'\x00'}) {
+		return nil, fmt.Errorf("missing null terminator in close message")
+	}
+	c.Name = name[:len(name)-1]
 
 	return c, nil
 }
@@ -58,10 +69,20 @@ func (c *Close) AsMap() map[string]interface{} {
 	return map[string]interface{}{
 		"Type": "Close",
 		"Payload": map[string]interface{}{
-			"ObjectType": c.ObjectType,
-			"Name":       c.Name,
+			"ObjectType": byte(c.ObjectType),
+			"Name":       string(c.Name),
 		},
 	}
 }
 
 func (c *Close) String() string { return messageToString(c) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (c *Close) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("Close", c)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (c *Close) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "Close", c)
+}
@@ -56,3 +56,13 @@ func (c *CloseComplete) AsMap() map[string]interface{} {
 }
 
 func (c *CloseComplete) String() string { return messageToString(c) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (c *CloseComplete) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("CloseComplete", c)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (c *CloseComplete) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "CloseComplete", c)
+}
@@ -0,0 +1,76 @@
+package pgproto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+// 'C' [int32 - length] [byte - object type] [string - name] \0
+var rawCloseMessage = []byte{
+	// Tag
+	'C',
+	// Length
+	'\x00', '\x00', '\x00', '\x0a',
+	// Prepared statement
+	'S',
+	// Name "stmt" \0
+	's', 't', 'm', 't', '\x00',
+}
+
+type CloseTestSuite struct {
+	suite.Suite
+}
+
+func TestCloseTestSuite(t *testing.T) {
+	suite.Run(t, new(CloseTestSuite))
+}
+
+func (s *CloseTestSuite) Test_ParseClose() {
+	c, err := pgproto.ParseClose(bytes.NewReader(rawCloseMessage))
+	s.Nil(err)
+	s.NotNil(c)
+	s.Equal(pgproto.ObjectTypePreparedStatement, c.ObjectType)
+	s.Equal([]byte("stmt"), c.Name)
+	s.Equal(rawCloseMessage, c.Encode())
+
+	m, err := pgproto.ParseClientMessage(bytes.NewReader(rawCloseMessage))
+	s.Nil(err)
+	s.Equal(c, m)
+}
+
+func (s *CloseTestSuite) Test_ParseClose_Empty() {
+	c, err := pgproto.ParseClose(bytes.NewReader([]byte{}))
+	s.NotNil(err)
+	s.Nil(c)
+}
+
+func (s *CloseTestSuite) Test_ParseClose_Malformed() {
+	for name, raw := range map[string][]byte{
+		"empty body":             {'C', '\x00', '\x00', '\x00', '\x04'},
+		"missing name":           {'C', '\x00', '\x00', '\x00', '\x05', 'S'},
+		"missing terminator":     {'C', '\x00', '\x00', '\x00', '\x07', 'S', 'a', 'b'},
+		"message length below 4": {'C', '\x00', '\x00', '\x00', '\x03'},
+	} {
+		s.NotPanics(func() {
+			c, err := pgproto.ParseClose(bytes.NewReader(raw))
+			s.NotNil(err, name)
+			s.Nil(c, name)
+		}, name)
+		s.NotPanics(func() {
+			m, err := pgproto.ParseClientMessage(bytes.NewReader(raw))
+			s.NotNil(err, name)
+			s.Nil(m, name)
+		}, name)
+	}
+}
+
+func (s *CloseTestSuite) Test_CloseEncode() {
+	c := &pgproto.Close{
+		ObjectType: pgproto.ObjectTypePreparedStatement,
+		Name:       []byte("stmt"),
+	}
+	s.Equal(rawCloseMessage, c.Encode())
+}
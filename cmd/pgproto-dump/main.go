@@ -0,0 +1,221 @@
+/*
+Command pgproto-dump is a PostgreSQL proxy printing every message relayed in both directions.
+
+Clients connect to -listen and are relayed to -upstream using the proxy package. Every message is
+printed with the time it was relayed, the session it belongs to and an arrow showing its direction,
+"->" for messages sent by the client and "<-" for messages sent by the server.
+
+	pgproto-dump -listen 127.0.0.1:6432 -upstream 127.0.0.1:5432 -types SimpleQuery,Error
+	psql -h 127.0.0.1 -p 6432
+
+	15:04:05.123456 #1 -> SimpleQuery<Query="SELECT 1">
+
+With -json every message is printed as a JSON object on its own line instead, ready to be filtered with jq.
+The "type" and "payload" keys follow the schema of pgproto.DecodeJSONMessage, which can decode the lines back into messages:
+
+	{"time":"2024-01-02T15:04:05.123456Z","session":1,"direction":"Frontend","type":"SimpleQuery","payload":{"Query":"SELECT 1"}}
+
+With -hex the raw frame of every message is printed as a hex dump, or added to the JSON object as
+"raw". Messages are dumped as relayed, once every interceptor has run. Messages pgproto does not
+parse, e.g. FunctionCall, are printed as their tag and length with the type Unknown.
+
+Passwords, SASL exchanges and authentication salts are redacted from the output, along with the raw
+frame of the messages holding them. Use -secrets to print them, e.g. to debug authentication.
+*/
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/proxy"
+)
+
+// timeFormat is the time of day with a fixed number of fractional digits, so messages line up
+const timeFormat = "15:04:05.000000"
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:6432", "address to accept clients on")
+	upstream := flag.String("upstream", "127.0.0.1:5432", "address of the PostgreSQL server to relay to")
+	types := flag.String("types", "", "comma separated message types to print, e.g. SimpleQuery,Error, defaults to all")
+	exclude := flag.String("exclude", "", "comma separated message types not to print, e.g. DataRow")
+	hexDump := flag.Bool("hex", false, "print the raw frame of every message")
+	jsonLines := flag.Bool("json", false, "print every message as a JSON object on its own line")
+	secrets := flag.Bool("secrets", false, "print passwords and authentication exchanges instead of redacting them")
+	flag.Parse()
+
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *secrets {
+		pgproto.Redaction = pgproto.RedactNothing
+	}
+
+	d := &dumper{
+		out:     os.Stdout,
+		types:   typeSet(*types),
+		exclude: typeSet(*exclude),
+		hex:     *hexDump,
+		json:    *jsonLines,
+	}
+	p := &proxy.Proxy{
+		Upstream:     *upstream,
+		Interceptors: []proxy.Interceptor{d},
+		ErrorLog:     log.New(os.Stderr, "", log.LstdFlags),
+	}
+	log.Printf("relaying %s to %s", *listen, *upstream)
+	log.Fatal(p.ListenAndServe(*listen))
+}
+
+// typeSet returns the set of message types in the comma separated list s, or nil when s is empty
+func typeSet(s string) map[string]bool {
+	if s == "" {
+		return nil
+	}
+	set := make(map[string]bool)
+	for _, t := range strings.Split(s, ",") {
+		set[strings.TrimSpace(t)] = true
+	}
+	return set
+}
+
+// dumper is a proxy.Interceptor printing the messages it sees
+type dumper struct {
+	types   map[string]bool
+	exclude map[string]bool
+	hex     bool
+	json    bool
+
+	// mu serializes the output of concurrent sessions
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (d *dumper) ClientMessage(s *proxy.Session, m pgproto.ClientMessage) (pgproto.ClientMessage, error) {
+	return m, nil
+}
+
+func (d *dumper) ServerMessage(s *proxy.Session, m pgproto.ServerMessage) (pgproto.ServerMessage, error) {
+	return m, nil
+}
+
+// ClientFrame dumps every message sent to the server, as a proxy.FrameObserver
+func (d *dumper) ClientFrame(s *proxy.Session, frame []byte, m pgproto.ClientMessage) error {
+	d.dump(s, "Frontend", frame, m)
+	return nil
+}
+
+// ServerFrame dumps every message sent to the client, as a proxy.FrameObserver
+func (d *dumper) ServerFrame(s *proxy.Session, frame []byte, m pgproto.ServerMessage) error {
+	d.dump(s, "Backend", frame, m)
+	return nil
+}
+
+func (d *dumper) dump(s *proxy.Session, direction string, frame []byte, m pgproto.Message) {
+	out, err := d.format(time.Now(), s.ID, direction, frame, m)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	if out == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.out.Write(out)
+}
+
+// format returns the output for the message m, relayed as frame at now in the direction of the
+// given session, or nil when it is filtered out by -types or -exclude. m is nil for the messages
+// pgproto does not parse, which are printed as their tag and length, of type Unknown.
+func (d *dumper) format(now time.Time, session uint64, direction string, frame []byte, m pgproto.Message) ([]byte, error) {
+	typ := "Unknown"
+	if m != nil {
+		typ, _ = m.AsMap()["Type"].(string)
+	}
+	if (d.types != nil && !d.types[typ]) || d.exclude[typ] {
+		return nil, nil
+	}
+	dumpRaw := d.hex && (m == nil || !hasSecrets(m))
+
+	if d.json {
+		line := jsonLine{
+			Time:      now.UTC(),
+			Session:   session,
+			Direction: direction,
+			Type:      typ,
+		}
+		if dumpRaw {
+			line.Raw = hex.EncodeToString(frame)
+		}
+		var err error
+		if m == nil {
+			line.Payload, err = json.Marshal(unknownMessage{Tag: string(frame[:1]), Length: len(frame) - 1})
+		} else {
+			var encoded []byte
+			encoded, err = json.Marshal(m)
+			if err == nil {
+				err = json.Unmarshal(encoded, &line)
+			}
+		}
+		var out []byte
+		if err == nil {
+			out, err = json.Marshal(line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode %s: %v", typ, err)
+		}
+		return append(out, '\n'), nil
+	}
+
+	arrow := "->"
+	if direction == "Backend" {
+		arrow = "<-"
+	}
+	var text string
+	if m == nil {
+		text = fmt.Sprintf("Unknown<Tag='%c', Length=%d>", frame[0], len(frame)-1)
+	} else {
+		text = m.String()
+	}
+	out := []byte(fmt.Sprintf("%s #%d %s %s\n", now.Format(timeFormat), session, arrow, text))
+	if dumpRaw {
+		out = append(out, hex.Dump(frame)...)
+	}
+	return out, nil
+}
+
+// unredacted formats messages with every field, whatever the redaction policy
+var unredacted = &pgproto.Formatter{Redact: pgproto.RedactNothing}
+
+// hasSecrets returns whether fields of m are redacted, its raw frame must not be dumped then
+func hasSecrets(m pgproto.Message) bool {
+	return m.String() != unredacted.Format(m)
+}
+
+// unknownMessage is the payload printed with -json for the messages pgproto does not parse
+type unknownMessage struct {
+	Tag    string
+	Length int
+}
+
+// jsonLine is a message printed with -json, its type and payload are the message's JSON encoding
+type jsonLine struct {
+	Time      time.Time       `json:"time"`
+	Session   uint64          `json:"session"`
+	Direction string          `json:"direction"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	Raw       string          `json:"raw,omitempty"`
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+type DumpTestSuite struct {
+	suite.Suite
+}
+
+func TestDumpTestSuite(t *testing.T) {
+	suite.Run(t, new(DumpTestSuite))
+}
+
+var (
+	now      = time.Date(2024, 1, 2, 15, 4, 5, 123456789, time.UTC)
+	query    = &pgproto.SimpleQuery{Query: []byte("SELECT 1")}
+	password = &pgproto.PasswordMessage{Password: []byte("secret")}
+)
+
+func (s *DumpTestSuite) format(d *dumper, direction string, m pgproto.Message) string {
+	return s.formatFrame(d, direction, m.Encode(), m)
+}
+
+func (s *DumpTestSuite) formatFrame(d *dumper, direction string, frame []byte, m pgproto.Message) string {
+	out, err := d.format(now, 1, direction, frame, m)
+	s.Require().Nil(err)
+	return string(out)
+}
+
+func (s *DumpTestSuite) Test_Text() {
+	d := &dumper{}
+	s.Equal("15:04:05.123456 #1 -> SimpleQuery<Query=\"SELECT 1\">\n", s.format(d, "Frontend", query))
+	s.Equal("15:04:05.123456 #1 <- ReadyForQuery<Status=Idle>\n", s.format(d, "Backend", &pgproto.ReadyForQuery{Status: pgproto.READY_IDLE}))
+}
+
+func (s *DumpTestSuite) Test_Types() {
+	d := &dumper{types: typeSet("SimpleQuery, Error")}
+	s.NotEmpty(s.format(d, "Frontend", query))
+	s.NotEmpty(s.format(d, "Backend", &pgproto.Error{}))
+	s.Empty(s.format(d, "Frontend", &pgproto.Sync{}))
+}
+
+func (s *DumpTestSuite) Test_Exclude() {
+	d := &dumper{exclude: typeSet("DataRow")}
+	s.NotEmpty(s.format(d, "Frontend", query))
+	s.Empty(s.format(d, "Backend", &pgproto.DataRow{}))
+
+	// Excluded types are filtered out of the listed ones
+	d.types = typeSet("SimpleQuery,DataRow")
+	s.NotEmpty(s.format(d, "Frontend", query))
+	s.Empty(s.format(d, "Backend", &pgproto.DataRow{}))
+}
+
+func (s *DumpTestSuite) Test_Hex() {
+	d := &dumper{hex: true}
+	s.Equal("15:04:05.123456 #1 -> SimpleQuery<Query=\"SELECT 1\">\n"+hex.Dump(query.Encode()), s.format(d, "Frontend", query))
+}
+
+func (s *DumpTestSuite) Test_JSON() {
+	d := &dumper{json: true}
+	s.Equal(
+		`{"time":"2024-01-02T15:04:05.123456789Z","session":1,"direction":"Frontend","type":"SimpleQuery","payload":{"Query":"SELECT 1"}}`+"\n",
+		s.format(d, "Frontend", query),
+	)
+
+	d.hex = true
+	s.Equal(
+		`{"time":"2024-01-02T15:04:05.123456789Z","session":1,"direction":"Frontend","type":"SimpleQuery","payload":{"Query":"SELECT 1"},"raw":"`+hex.EncodeToString(query.Encode())+`"}`+"\n",
+		s.format(d, "Frontend", query),
+	)
+}
+
+func (s *DumpTestSuite) Test_Secrets() {
+	// The password and the raw frame holding it are redacted by default
+	d := &dumper{hex: true}
+	s.Equal("15:04:05.123456 #1 -> PasswordMessage<Password=<redacted>>\n", s.format(d, "Frontend", password))
+	d.json = true
+	s.Equal(
+		`{"time":"2024-01-02T15:04:05.123456789Z","session":1,"direction":"Frontend","type":"PasswordMessage","payload":{"Password":{"redacted":true}}}`+"\n",
+		s.format(d, "Frontend", password),
+	)
+
+	// -secrets
+	pgproto.Redaction = pgproto.RedactNothing
+	defer func() { pgproto.Redaction = pgproto.RedactSecrets }()
+
+	d.json = false
+	s.Equal("15:04:05.123456 #1 -> PasswordMessage<Password=\"secret\">\n"+hex.Dump(password.Encode()), s.format(d, "Frontend", password))
+	d.json = true
+	s.Equal(
+		`{"time":"2024-01-02T15:04:05.123456789Z","session":1,"direction":"Frontend","type":"PasswordMessage","payload":{"Password":"secret"},"raw":"`+hex.EncodeToString(password.Encode())+`"}`+"\n",
+		s.format(d, "Frontend", password),
+	)
+}
+
+func (s *DumpTestSuite) Test_RawFrame() {
+	// The unknown 'X' field of the notice is dropped when parsed, the frame is dumped as relayed
+	frame := append([]byte{'N', '\x00', '\x00', '\x00', '\x14'}, "SNOTICE\x00Xextra\x00\x00"...)
+	notice, err := pgproto.ParseServerMessage(bytes.NewReader(frame))
+	s.Require().Nil(err)
+	s.Require().NotEqual(frame, notice.Encode())
+
+	d := &dumper{hex: true}
+	s.Equal("15:04:05.123456 #1 <- "+notice.String()+"\n"+hex.Dump(frame), s.formatFrame(d, "Backend", frame, notice))
+}
+
+func (s *DumpTestSuite) Test_Unknown() {
+	functionCall := []byte{'F', '\x00', '\x00', '\x00', '\x0e', '\x00', '\x00', '\x04', '\xd2', '\x00', '\x00', '\x00', '\x00', '\x00', '\x00'}
+
+	d := &dumper{hex: true}
+	s.Equal("15:04:05.123456 #1 -> Unknown<Tag='F', Length=14>\n"+hex.Dump(functionCall), s.formatFrame(d, "Frontend", functionCall, nil))
+	d.json = true
+	s.Equal(
+		`{"time":"2024-01-02T15:04:05.123456789Z","session":1,"direction":"Frontend","type":"Unknown","payload":{"Tag":"F","Length":14},"raw":"`+hex.EncodeToString(functionCall)+`"}`+"\n",
+		s.formatFrame(d, "Frontend", functionCall, nil),
+	)
+
+	d = &dumper{types: typeSet("SimpleQuery")}
+	s.Empty(s.formatFrame(d, "Frontend", functionCall, nil))
+	d = &dumper{exclude: typeSet("Unknown")}
+	s.Empty(s.formatFrame(d, "Frontend", functionCall, nil))
+}
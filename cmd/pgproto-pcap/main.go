@@ -0,0 +1,116 @@
+/*
+Command pgproto-pcap prints the PostgreSQL messages found in a pcap or pcapng capture.
+
+Every message is printed on its own line with its capture time, the connection it was sent on and
+an arrow showing its direction, "->" for messages sent by the client and "<-" for messages sent by
+the server. Connections which can not be decoded further, e.g. once encrypted, are reported on stderr.
+
+	tcpdump -i any -w session.pcap port 5432
+	pgproto-pcap -ports 5432,6432 session.pcap
+
+	2024-01-02T15:04:05.123456Z #1 10.0.0.1:51234-10.0.0.2:5432 -> SimpleQuery<Query="SELECT 1">
+
+Passwords, SASL exchanges and authentication salts are redacted, use -secrets to print them.
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pcap"
+	"github.com/c653labs/pgproto/record"
+)
+
+// timeFormat is RFC 3339 with a fixed number of fractional digits, so messages line up
+const timeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+func main() {
+	ports := flag.String("ports", strconv.Itoa(pcap.DefaultPort), "comma separated server ports to decode")
+	conn := flag.Uint64("conn", 0, "only print the messages of this connection")
+	secrets := flag.Bool("secrets", false, "print passwords and authentication exchanges instead of redacting them")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <capture>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *secrets {
+		pgproto.Redaction = pgproto.RedactNothing
+	}
+	portList, err := parsePorts(*ports)
+	if err != nil {
+		fmt.Fprintln(flag.CommandLine.Output(), err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	d, err := pcap.NewDecoder(f, portList...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for {
+		m, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+
+		var perr *pcap.ParseError
+		var serr *pcap.StreamError
+		switch {
+		case errors.As(err, &perr):
+			if *conn == 0 || perr.Message.Conn.ID == *conn {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			continue
+		case errors.As(err, &serr):
+			if *conn == 0 || serr.Conn.ID == *conn {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			continue
+		case err != nil:
+			log.Fatal(err)
+		}
+
+		if *conn == 0 || m.Conn.ID == *conn {
+			fmt.Printf("%s %s %s %s\n", m.Time.Format(timeFormat), m.Conn, arrow(m.Direction), m.Message)
+		}
+	}
+}
+
+func parsePorts(s string) ([]uint16, error) {
+	ports := make([]uint16, 0)
+	for _, p := range strings.Split(s, ",") {
+		port, err := strconv.ParseUint(strings.TrimSpace(p), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", p)
+		}
+		ports = append(ports, uint16(port))
+	}
+	return ports, nil
+}
+
+func arrow(d record.Direction) string {
+	if d == record.Frontend {
+		return "->"
+	}
+	return "<-"
+}
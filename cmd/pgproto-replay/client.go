@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/internal/pgconn"
+	"github.com/c653labs/pgproto/record"
+)
+
+// client replays the client side of the session against the server at addr
+func (r *replayer) client(addr string, password string) error {
+	startup, next, err := r.startup()
+	if err != nil {
+		return err
+	}
+
+	config := &pgconn.Config{
+		Address:  addr,
+		Password: password,
+		Options:  make(map[string]string),
+	}
+	for k, v := range startup.Options {
+		switch k {
+		case "user":
+			config.User = string(v)
+		case "database":
+			config.Database = string(v)
+		default:
+			config.Options[k] = string(v)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	conn, err := pgconn.Connect(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	r.logf("connected to %s as %s", addr, config.User)
+
+	// Skip the recorded authentication, Connect has already authenticated and waited for the server
+	for next < len(r.records) {
+		rec := r.records[next]
+		next++
+		if _, ok := rec.Message.(*pgproto.ReadyForQuery); ok {
+			break
+		}
+	}
+
+	// Count the ReadyForQuery messages sent by the server
+	p := newProgress()
+	go func() {
+		count := 0
+		for {
+			msg, err := conn.Receive()
+			var unknown *pgproto.UnknownMessageError
+			if errors.As(err, &unknown) {
+				r.logf("<- unparsed message '%c'", unknown.Tag)
+				continue
+			}
+			if err != nil {
+				p.done(err)
+				return
+			}
+			r.logf("<- %s", msg)
+			if _, ok := msg.(*pgproto.ReadyForQuery); ok {
+				count++
+				p.add(count)
+			}
+		}
+	}()
+
+	ready := 0
+	for _, rec := range r.records[next:] {
+		if rec.Direction == record.Backend {
+			if _, ok := rec.Message.(*pgproto.ReadyForQuery); ok {
+				ready++
+			}
+			continue
+		}
+
+		err = p.wait(ready)
+		if err != nil {
+			return err
+		}
+		r.pace(rec)
+
+		r.logf("-> %s", describe(rec))
+		_, err = conn.NetConn().Write(rec.Raw)
+		if err != nil {
+			return err
+		}
+		if _, ok := rec.Message.(*pgproto.Termination); ok {
+			return nil
+		}
+	}
+
+	// Wait for the responses to the last messages before terminating
+	return p.wait(ready)
+}
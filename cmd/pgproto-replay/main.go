@@ -0,0 +1,203 @@
+/*
+Command pgproto-replay replays one session of a recording made with the record package.
+
+In client mode the client side of the session is sent to a server. The recorded StartupMessage is used
+to connect, the server's authentication requests are answered with -password instead of the recorded
+responses, and every following client message is sent once the server has answered as many queries as
+it had when the message was recorded.
+
+In server mode a single client is accepted and the server side of the session is sent to it, each
+message once the client has sent as many messages as it had when the message was recorded.
+
+Messages pgproto can not parse, e.g. FunctionCall, are replayed as recorded like any other message.
+
+Messages are sent with the delays between them in the recording divided by -speed, a speed of 0 sends
+them as fast as possible.
+
+	pgproto-replay -mode client -addr 127.0.0.1:5432 -password secret session.rec
+	pgproto-replay -mode server -listen 127.0.0.1:5433 -speed 10 session.rec
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/record"
+)
+
+func main() {
+	mode := flag.String("mode", "client", "side of the session to replay, \"client\" or \"server\"")
+	addr := flag.String("addr", "127.0.0.1:5432", "server to connect to in client mode")
+	listen := flag.String("listen", "127.0.0.1:5432", "address to accept a client on in server mode")
+	session := flag.Uint64("session", 0, "session to replay, defaults to the first session in the recording")
+	speed := flag.Float64("speed", 1, "timing multiplier, 0 sends messages without delay")
+	password := flag.String("password", "", "password used to authenticate in client mode")
+	verbose := flag.Bool("v", false, "print every message sent and received")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <recording>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || *speed < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	records, err := load(flag.Arg(0), *session)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r := &replayer{
+		records: records,
+		speed:   *speed,
+		verbose: *verbose,
+	}
+	switch *mode {
+	case "client":
+		err = r.client(*addr, *password)
+	case "server":
+		err = r.server(*listen)
+	default:
+		err = fmt.Errorf("unknown mode %q", *mode)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// load returns the records of session from the recording at path, or of the first session when it is 0
+func load(path string, session uint64) ([]*record.Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := record.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*record.Record, 0)
+	for {
+		rec, err := r.Next()
+		var perr *record.ParseError
+		if errors.As(err, &perr) {
+			// The raw message is replayed as recorded
+			err = nil
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if session == 0 {
+			session = rec.Session
+		}
+		if rec.Session == session {
+			records = append(records, rec)
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no records found for session %d", session)
+	}
+	return records, nil
+}
+
+type replayer struct {
+	records []*record.Record
+	speed   float64
+	verbose bool
+
+	// prev is the recorded time of the previous message sent, sent is when it was sent
+	prev time.Time
+	sent time.Time
+}
+
+// pace waits until rec is due according to the recorded delay since the previous message sent
+func (r *replayer) pace(rec *record.Record) {
+	if r.speed > 0 && !r.prev.IsZero() {
+		delay := time.Duration(float64(rec.Time.Sub(r.prev)) / r.speed)
+		time.Sleep(time.Until(r.sent.Add(delay)))
+	}
+	r.prev = rec.Time
+	r.sent = time.Now()
+}
+
+// describe returns the message of rec to log, messages which could not be parsed by their tag
+func describe(rec *record.Record) string {
+	if rec.Message == nil {
+		return fmt.Sprintf("unparsed message of %d bytes starting with '%c'", len(rec.Raw), rec.Raw[0])
+	}
+	return rec.Message.String()
+}
+
+func (r *replayer) logf(format string, args ...interface{}) {
+	if r.verbose {
+		log.Printf(format, args...)
+	}
+}
+
+// startup returns the recorded StartupMessage along with the index of the record following it
+func (r *replayer) startup() (*pgproto.StartupMessage, int, error) {
+	for i, rec := range r.records {
+		if startup, ok := rec.Message.(*pgproto.StartupMessage); ok && !startup.SSLRequest && !startup.GSSEncRequest {
+			return startup, i + 1, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("recording does not contain a startup message")
+}
+
+// progress counts the messages received from the peer, so sending can wait for the peer to catch up
+type progress struct {
+	counts chan int
+	count  int
+	err    error
+}
+
+func newProgress() *progress {
+	return &progress{counts: make(chan int, 1)}
+}
+
+// add is called by the receiving goroutine for every message counted
+func (p *progress) add(count int) {
+	// Only the latest count matters, replace any count not yet seen by wait
+	select {
+	case <-p.counts:
+	default:
+	}
+	p.counts <- count
+}
+
+// done is called by the receiving goroutine once the peer has stopped sending
+func (p *progress) done(err error) {
+	p.err = err
+	close(p.counts)
+}
+
+// wait blocks until at least n messages have been counted, returning an error when the peer stopped first
+func (p *progress) wait(n int) error {
+	for p.count < n {
+		count, ok := <-p.counts
+		if !ok {
+			if p.err == nil || p.err == io.EOF {
+				return fmt.Errorf("peer closed the connection")
+			}
+			return p.err
+		}
+		p.count = count
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/record"
+)
+
+// server accepts a single client on addr and replays the server side of the session to it
+func (r *replayer) server(addr string) error {
+	_, next, err := r.startup()
+	if err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	r.logf("listening on %s", l.Addr())
+	conn, err := l.Accept()
+	l.Close()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn, msg, err := pgproto.AcceptSSL(conn, nil)
+	if err != nil {
+		return err
+	}
+	r.logf("<- %s", msg)
+	if _, ok := msg.(*pgproto.StartupMessage); !ok {
+		return nil
+	}
+
+	// The authentication request the client is answering next, used to parse its response
+	var mu sync.Mutex
+	var auth *pgproto.AuthenticationRequest
+
+	// Count the messages sent by the client after its StartupMessage
+	p := newProgress()
+	go func() {
+		br := bufio.NewReader(conn)
+		count := 0
+		for {
+			var msg pgproto.ClientMessage
+			tag, err := br.Peek(1)
+			if err == nil && tag[0] == 'p' {
+				mu.Lock()
+				req := auth
+				mu.Unlock()
+				msg, err = pgproto.ParseAuthenticationResponse(br, req)
+			} else if err == nil {
+				msg, err = pgproto.ParseClientMessage(br)
+			}
+			var unknown *pgproto.UnknownMessageError
+			if errors.As(err, &unknown) {
+				r.logf("<- unparsed message '%c'", unknown.Tag)
+				count++
+				p.add(count)
+				continue
+			}
+			if err != nil {
+				p.done(err)
+				return
+			}
+
+			r.logf("<- %s", msg)
+			count++
+			p.add(count)
+			if _, ok := msg.(*pgproto.Termination); ok {
+				p.done(nil)
+				return
+			}
+		}
+	}()
+
+	sent := 0
+	for _, rec := range r.records[next:] {
+		if rec.Direction == record.Frontend {
+			sent++
+			if _, ok := rec.Message.(*pgproto.Termination); ok {
+				break
+			}
+			continue
+		}
+
+		err = p.wait(sent)
+		if err != nil {
+			return err
+		}
+		r.pace(rec)
+
+		if req, ok := rec.Message.(*pgproto.AuthenticationRequest); ok {
+			mu.Lock()
+			auth = req
+			mu.Unlock()
+		}
+
+		r.logf("-> %s", describe(rec))
+		_, err = conn.Write(rec.Raw)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
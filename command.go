@@ -54,10 +54,20 @@ func (c *CommandCompletion) Encode() []byte {
 func (c *CommandCompletion) AsMap() map[string]interface{} {
 	return map[string]interface{}{
 		"Type": "CommandCompletion",
-		"Payload": map[string]string{
+		"Payload": map[string]interface{}{
 			"Tag": string(c.Tag),
 		},
 	}
 }
 
 func (c *CommandCompletion) String() string { return messageToString(c) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (c *CommandCompletion) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("CommandCompletion", c)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (c *CommandCompletion) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "CommandCompletion", c)
+}
@@ -27,7 +27,7 @@ func ParseCopyBothResponse(r io.Reader) (*CopyBothResponse, error) {
 
 	format, err := buf.ReadByte()
 
-	count, err := buf.ReadInt16()
+	count, err := buf.ReadCount(2)
 	if err != nil {
 		return nil, err
 	}
@@ -71,3 +71,13 @@ func (c *CopyBothResponse) AsMap() map[string]interface{} {
 }
 
 func (c *CopyBothResponse) String() string { return messageToString(c) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (c *CopyBothResponse) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("CopyBothResponse", c)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (c *CopyBothResponse) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "CopyBothResponse", c)
+}
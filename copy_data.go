@@ -9,6 +9,7 @@ type CopyData struct {
 	Data []byte
 }
 
+func (c *CopyData) client() {}
 func (c *CopyData) server() {}
 
 func ParseCopyData(r io.Reader) (*CopyData, error) {
@@ -25,6 +26,15 @@ func ParseCopyData(r io.Reader) (*CopyData, error) {
 		return nil, err
 	}
 
+	// DEV: Subtract 4 to account for the length of the int32 we just read
+	l = l - 4
+	if l < 0 || l > maxMessageLength-4 {
+		return nil, fmt.Errorf("unable to parse length from message")
+	}
+	if rem := b.Len(); rem >= 0 && l > rem {
+		return nil, fmt.Errorf("message length %d exceeds the %d bytes left", l+4, rem+4)
+	}
+
 	c := &CopyData{
 		Data: make([]byte, l),
 	}
@@ -55,3 +65,13 @@ func (c *CopyData) AsMap() map[string]interface{} {
 }
 
 func (c *CopyData) String() string { return messageToString(c) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (c *CopyData) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("CopyData", c)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (c *CopyData) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "CopyData", c)
+}
@@ -0,0 +1,67 @@
+package pgproto
+
+import (
+	"fmt"
+	"io"
+)
+
+// CopyDone is sent by either the client or the server to indicate the end of a COPY data stream
+type CopyDone struct{}
+
+func (c *CopyDone) client() {}
+func (c *CopyDone) server() {}
+
+// ParseCopyDone will attempt to read a CopyDone message from the io.Reader
+func ParseCopyDone(r io.Reader) (*CopyDone, error) {
+	b := newReadBuffer(r)
+
+	// 'c' [int32 - length]
+	err := b.ReadTag('c')
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := b.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+
+	if l != 4 {
+		return nil, fmt.Errorf("expected message length of 4")
+	}
+
+	return &CopyDone{}, nil
+}
+
+// Encode will return the byte representation of this message
+func (c *CopyDone) Encode() []byte {
+	// 'c' [int32 - length]
+	b := newWriteBuffer()
+	b.Wrap('c')
+	return b.Bytes()
+}
+
+// AsMap method returns a common map representation of this message:
+//
+//   map[string]interface{}{
+//     "Type": "CopyDone",
+//     "Payload": nil,
+//   }
+func (c *CopyDone) AsMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Type":    "CopyDone",
+		"Payload": nil,
+	}
+}
+
+func (c *CopyDone) String() string { return messageToString(c) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (c *CopyDone) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("CopyDone", c)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (c *CopyDone) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "CopyDone", c)
+}
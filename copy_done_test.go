@@ -0,0 +1,87 @@
+package pgproto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+type CopyDoneTestSuite struct {
+	suite.Suite
+}
+
+func TestCopyDoneTestSuite(t *testing.T) {
+	suite.Run(t, new(CopyDoneTestSuite))
+}
+
+func (s *CopyDoneTestSuite) Test_ParseCopyDone() {
+	raw := []byte{
+		// Tag
+		'c',
+		// Length
+		'\x00', '\x00', '\x00', '\x04',
+	}
+
+	done, err := pgproto.ParseCopyDone(bytes.NewReader(raw))
+	s.Nil(err)
+	s.NotNil(done)
+	s.Equal(raw, done.Encode())
+
+	// CopyDone is sent by both the client and the server
+	m, err := pgproto.ParseClientMessage(bytes.NewReader(raw))
+	s.Nil(err)
+	s.Equal(done, m)
+	sm, err := pgproto.ParseServerMessage(bytes.NewReader(raw))
+	s.Nil(err)
+	s.Equal(done, sm)
+}
+
+func (s *CopyDoneTestSuite) Test_ParseCopyDone_Empty() {
+	done, err := pgproto.ParseCopyDone(bytes.NewReader([]byte{}))
+	s.NotNil(err)
+	s.Nil(done)
+}
+
+func (s *CopyDoneTestSuite) Test_ParseCopyDone_Malformed() {
+	for _, raw := range [][]byte{
+		{'c', '\x00', '\x00', '\x00', '\x05', '\x00'},
+		{'c', '\xff', '\xff', '\xff', '\xff'},
+		{'c', '\x00', '\x00'},
+	} {
+		done, err := pgproto.ParseCopyDone(bytes.NewReader(raw))
+		s.NotNil(err, raw)
+		s.Nil(done, raw)
+	}
+}
+
+func (s *CopyDoneTestSuite) Test_CopyDoneEncode() {
+	expected := []byte{
+		// Tag
+		'c',
+		// Length
+		'\x00', '\x00', '\x00', '\x04',
+	}
+
+	done := &pgproto.CopyDone{}
+	s.Equal(expected, done.Encode())
+}
+
+func BenchmarkCopyDoneParse(b *testing.B) {
+	raw := []byte{
+		// Tag
+		'c',
+		// Length
+		'\x00', '\x00', '\x00', '\x04',
+	}
+
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			_, err := pgproto.ParseCopyDone(bytes.NewReader(raw))
+			if err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
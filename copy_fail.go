@@ -0,0 +1,81 @@
+package pgproto
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// CopyFail is sent by the client to abort a COPY FROM STDIN operation
+type CopyFail struct {
+	Message []byte
+}
+
+func (c *CopyFail) client() {}
+
+// ParseCopyFail will attempt to read a CopyFail message from the io.Reader
+func ParseCopyFail(r io.Reader) (*CopyFail, error) {
+	b := newReadBuffer(r)
+
+	// 'f' [int32 - length] [string - message] \0
+	err := b.ReadTag('f')
+	if err != nil {
+		return nil, err
+	}
+
+	b, err = b.ReadLength()
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := b.ReadString(dontStripNull)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasSuffix(msg, []byte{'\x00'}) {
+		return nil, fmt.Errorf("missing null terminator in copy fail message")
+	}
+
+	c := &CopyFail{
+		Message: msg[:len(msg)-1],
+	}
+
+	return c, nil
+}
+
+// Encode will return the byte representation of this message
+func (c *CopyFail) Encode() []byte {
+	b := newWriteBuffer()
+	b.WriteString(c.Message, writeNull)
+	b.Wrap('f')
+	return b.Bytes()
+}
+
+// AsMap method returns a common map representation of this message:
+//
+//   map[string]interface{}{
+//     "Type": "CopyFail",
+//     "Payload": map[string]interface{}{
+//       "Message": <CopyFail.Message>,
+//     },
+//   }
+func (c *CopyFail) AsMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Type": "CopyFail",
+		"Payload": map[string]interface{}{
+			"Message": string(c.Message),
+		},
+	}
+}
+
+func (c *CopyFail) String() string { return messageToString(c) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (c *CopyFail) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("CopyFail", c)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (c *CopyFail) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "CopyFail", c)
+}
@@ -0,0 +1,76 @@
+package pgproto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+// 'f' [int32 - length] [string - message] \0
+var rawCopyFailMessage = []byte{
+	// Tag
+	'f',
+	// Length
+	'\x00', '\x00', '\x00', '\x0c',
+	// Message "aborted" \0
+	'a', 'b', 'o', 'r', 't', 'e', 'd', '\x00',
+}
+
+type CopyFailTestSuite struct {
+	suite.Suite
+}
+
+func TestCopyFailTestSuite(t *testing.T) {
+	suite.Run(t, new(CopyFailTestSuite))
+}
+
+func (s *CopyFailTestSuite) Test_ParseCopyFail() {
+	c, err := pgproto.ParseCopyFail(bytes.NewReader(rawCopyFailMessage))
+	s.Nil(err)
+	s.NotNil(c)
+	s.Equal([]byte("aborted"), c.Message)
+	s.Equal(rawCopyFailMessage, c.Encode())
+
+	m, err := pgproto.ParseClientMessage(bytes.NewReader(rawCopyFailMessage))
+	s.Nil(err)
+	s.Equal(c, m)
+}
+
+func (s *CopyFailTestSuite) Test_ParseCopyFail_Empty() {
+	c, err := pgproto.ParseCopyFail(bytes.NewReader([]byte{}))
+	s.NotNil(err)
+	s.Nil(c)
+}
+
+func (s *CopyFailTestSuite) Test_ParseCopyFail_Malformed() {
+	for name, raw := range map[string][]byte{
+		"empty body":             {'f', '\x00', '\x00', '\x00', '\x04'},
+		"missing terminator":     {'f', '\x00', '\x00', '\x00', '\x06', 'a', 'b'},
+		"message length below 4": {'f', '\x00', '\x00', '\x00', '\x01'},
+		"negative length":        {'f', '\xff', '\xff', '\xff', '\xff'},
+	} {
+		s.NotPanics(func() {
+			c, err := pgproto.ParseCopyFail(bytes.NewReader(raw))
+			s.NotNil(err, name)
+			s.Nil(c, name)
+		}, name)
+	}
+}
+
+func (s *CopyFailTestSuite) Test_CopyFailEncode() {
+	c := &pgproto.CopyFail{Message: []byte("aborted")}
+	s.Equal(rawCopyFailMessage, c.Encode())
+}
+
+func BenchmarkCopyFailParse(b *testing.B) {
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			_, err := pgproto.ParseCopyFail(bytes.NewReader(rawCopyFailMessage))
+			if err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
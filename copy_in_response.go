@@ -27,7 +27,7 @@ func ParseCopyInResponse(r io.Reader) (*CopyInResponse, error) {
 
 	format, err := buf.ReadByte()
 
-	count, err := buf.ReadInt16()
+	count, err := buf.ReadCount(2)
 	if err != nil {
 		return nil, err
 	}
@@ -70,3 +70,13 @@ func (c *CopyInResponse) AsMap() map[string]interface{} {
 }
 
 func (c *CopyInResponse) String() string { return messageToString(c) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (c *CopyInResponse) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("CopyInResponse", c)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (c *CopyInResponse) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "CopyInResponse", c)
+}
@@ -27,7 +27,7 @@ func ParseCopyOutResponse(r io.Reader) (*CopyOutResponse, error) {
 
 	format, err := buf.ReadByte()
 
-	count, err := buf.ReadInt16()
+	count, err := buf.ReadCount(2)
 	if err != nil {
 		return nil, err
 	}
@@ -70,3 +70,13 @@ func (c *CopyOutResponse) AsMap() map[string]interface{} {
 }
 
 func (c *CopyOutResponse) String() string { return messageToString(c) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (c *CopyOutResponse) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("CopyOutResponse", c)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (c *CopyOutResponse) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "CopyOutResponse", c)
+}
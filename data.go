@@ -25,7 +25,7 @@ func ParseDataRow(r io.Reader) (*DataRow, error) {
 	}
 
 	// Field count - int16
-	c, err := b.ReadInt16()
+	c, err := b.ReadCount(4)
 	if err != nil {
 		return nil, err
 	}
@@ -35,23 +35,13 @@ func ParseDataRow(r io.Reader) (*DataRow, error) {
 	}
 
 	for i := 0; i < c; i++ {
-		// [int32 - length] [string - data]
-		l, err := b.ReadInt()
+		// [int32 - length] [string - data], a length of -1 is NULL
+		d.Fields[i], err = b.ReadValue()
 		if err == io.EOF {
 			break
 		} else if err != nil {
 			return nil, err
 		}
-
-		if l == -1 {
-			d.Fields[i] = nil
-		} else {
-			d.Fields[i] = make([]byte, l)
-			_, err = b.Read(d.Fields[i])
-			if err != nil {
-				return nil, err
-			}
-		}
 	}
 
 	return d, nil
@@ -61,6 +51,11 @@ func (d *DataRow) Encode() []byte {
 	b := newWriteBuffer()
 	b.WriteInt16(len(d.Fields))
 	for _, f := range d.Fields {
+		// NULL values are encoded with a length of -1
+		if f == nil {
+			b.WriteInt(-1)
+			continue
+		}
 		b.WriteInt(len(f))
 		b.WriteBytes(f)
 	}
@@ -82,3 +77,13 @@ func (d *DataRow) AsMap() map[string]interface{} {
 }
 
 func (d *DataRow) String() string { return messageToString(d) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (d *DataRow) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("DataRow", d)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (d *DataRow) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "DataRow", d)
+}
@@ -34,8 +34,7 @@ func ParseDescribe(r io.Reader) (*Describe, error) {
 	}
 
 	switch o := ObjectType(t); o {
-	case ObjectTypePreparedStatement:
-	case ObjectTypePortal:
+	case ObjectTypePreparedStatement, ObjectTypePortal:
 		d.ObjectType = o
 	default:
 		return nil, fmt.Errorf("unknown describe object type %#v", t)
@@ -69,3 +68,13 @@ func (d *Describe) AsMap() map[string]interface{} {
 }
 
 func (d *Describe) String() string { return messageToString(d) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (d *Describe) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("Describe", d)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (d *Describe) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "Describe", d)
+}
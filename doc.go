@@ -3,7 +3,25 @@ Package pgproto is a pure Go client and server protocol library for PostgreSQL.
 It provides the necessary structures and functions to parse and encode client or server PostgreSQL messages.
 
 The scope of pgproto is only for parsing/encoding messages and does not handle connections between
-PostgreSQL client and server.
+PostgreSQL client and server. Helpers for negotiating SSL on a connection are provided, the proxy
+package builds a protocol aware proxy on top of pgproto and the pooler package a session and
+transaction mode connection pooler. The record package records the traffic passing through a proxy,
+which the pgproto-replay command can replay against a server or client. The pcap package decodes
+PostgreSQL traffic from pcap and pcapng captures. The pgtype package converts the values of DataRow
+and Bind messages to and from Go values. The stdlib package implements a database/sql driver on top
+of pgproto and pgtype. The pgcopy package reads and writes the rows of COPY streams in the text and
+CSV formats as CopyData messages.
+
+Passwords, SASL exchanges and authentication salts are redacted from the String, AsMap and JSON
+output of messages by default, see Redaction.
+
+Upgrading
+
+ParseClientMessage returns a *Bind for 'B' messages and no longer accepts 't' messages, which it
+tried to parse as *BinaryParameters and *ParameterDescription and always failed to. Code switching on
+*BinaryParameters should switch on *Bind, whose Parameters hold the values Fields did, and parse
+ParameterDescription messages, which only servers send, with ParseServerMessage. BinaryParameters and
+ParseBinaryParameters are deprecated.
 
 Installation
 
@@ -48,3 +48,13 @@ func (e *EmptyQueryResponse) AsMap() map[string]interface{} {
 }
 
 func (e *EmptyQueryResponse) String() string { return messageToString(e) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (e *EmptyQueryResponse) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("EmptyQueryResponse", e)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (e *EmptyQueryResponse) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "EmptyQueryResponse", e)
+}
@@ -7,14 +7,24 @@ import (
 )
 
 type Error struct {
-	Severity []byte
-	Text     []byte
-	Code     []byte
-	Message  []byte
-	Position []byte
-	File     []byte
-	Line     []byte
-	Routine  []byte
+	Severity         []byte
+	Text             []byte
+	Code             []byte
+	Message          []byte
+	Detail           []byte
+	Hint             []byte
+	Position         []byte
+	InternalPosition []byte
+	InternalQuery    []byte
+	Where            []byte
+	Schema           []byte
+	Table            []byte
+	Column           []byte
+	DataType         []byte
+	Constraint       []byte
+	File             []byte
+	Line             []byte
+	Routine          []byte
 }
 
 func (e *Error) server() {}
@@ -44,7 +54,10 @@ func ParseError(r io.Reader) (*Error, error) {
 			return nil, err
 		}
 
-		// This message ends with a single null terminator
+		// This message ends with a single null terminator, an empty value means it is missing
+		if len(value) == 0 {
+			return nil, fmt.Errorf("missing null terminator in error message")
+		}
 		if bytes.Equal(value, []byte{'\x00'}) {
 			break
 		}
@@ -63,8 +76,28 @@ func ParseError(r io.Reader) (*Error, error) {
 			e.Code = value
 		case 'M':
 			e.Message = value
+		case 'D':
+			e.Detail = value
+		case 'H':
+			e.Hint = value
 		case 'P':
 			e.Position = value
+		case 'p':
+			e.InternalPosition = value
+		case 'q':
+			e.InternalQuery = value
+		case 'W':
+			e.Where = value
+		case 's':
+			e.Schema = value
+		case 't':
+			e.Table = value
+		case 'c':
+			e.Column = value
+		case 'd':
+			e.DataType = value
+		case 'n':
+			e.Constraint = value
 		case 'F':
 			e.File = value
 		case 'L':
@@ -84,6 +117,21 @@ func (e *Error) Encode() []byte {
 func (e *Error) AsMap() map[string]interface{} { return errorMap(e, "Error") }
 func (e *Error) String() string                { return messageToString(e) }
 
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("Error", e)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (e *Error) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "Error", e)
+}
+
+// Error allows an Error message to be used as a Go error
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s (SQLSTATE %s)", e.Severity, e.Message, e.Code)
+}
+
 func encodeError(e *Error, tag byte) []byte {
 	b := newWriteBuffer()
 
@@ -103,17 +151,33 @@ func encodeError(e *Error, tag byte) []byte {
 	b.WriteByte('M')
 	b.WriteString(e.Message, true)
 
-	// Position
-	b.WriteByte('P')
-	b.WriteString(e.Position, true)
-
-	// Line
-	b.WriteByte('L')
-	b.WriteString(e.Line, true)
-
-	// Routine
-	b.WriteByte('R')
-	b.WriteString(e.Routine, true)
+	// Optional fields are only written when present
+	optional := []struct {
+		code  byte
+		value []byte
+	}{
+		{'D', e.Detail},
+		{'H', e.Hint},
+		{'P', e.Position},
+		{'p', e.InternalPosition},
+		{'q', e.InternalQuery},
+		{'W', e.Where},
+		{'s', e.Schema},
+		{'t', e.Table},
+		{'c', e.Column},
+		{'d', e.DataType},
+		{'n', e.Constraint},
+		{'F', e.File},
+		{'L', e.Line},
+		{'R', e.Routine},
+	}
+	for _, f := range optional {
+		if len(f.value) == 0 {
+			continue
+		}
+		b.WriteByte(f.code)
+		b.WriteString(f.value, true)
+	}
 
 	// Finalize
 	b.WriteByte('\x00')
@@ -124,14 +188,25 @@ func encodeError(e *Error, tag byte) []byte {
 func errorMap(e *Error, name string) map[string]interface{} {
 	return map[string]interface{}{
 		"Type": name,
-		"Payload": map[string]string{
-			"Severity": string(e.Severity),
-			"Text":     string(e.Text),
-			"Code":     string(e.Code),
-			"Message":  string(e.Message),
-			"Position": string(e.Position),
-			"Line":     string(e.Line),
-			"Routine":  string(e.Routine),
+		"Payload": map[string]interface{}{
+			"Severity":         string(e.Severity),
+			"Text":             string(e.Text),
+			"Code":             string(e.Code),
+			"Message":          string(e.Message),
+			"Detail":           string(e.Detail),
+			"Hint":             string(e.Hint),
+			"Position":         string(e.Position),
+			"InternalPosition": string(e.InternalPosition),
+			"InternalQuery":    string(e.InternalQuery),
+			"Where":            string(e.Where),
+			"Schema":           string(e.Schema),
+			"Table":            string(e.Table),
+			"Column":           string(e.Column),
+			"DataType":         string(e.DataType),
+			"Constraint":       string(e.Constraint),
+			"File":             string(e.File),
+			"Line":             string(e.Line),
+			"Routine":          string(e.Routine),
 		},
 	}
 }
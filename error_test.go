@@ -0,0 +1,48 @@
+package pgproto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+type ErrorTestSuite struct {
+	suite.Suite
+}
+
+func TestErrorTestSuite(t *testing.T) {
+	suite.Run(t, new(ErrorTestSuite))
+}
+
+func (s *ErrorTestSuite) Test_ParseError() {
+	raw := []byte{
+		// Tag
+		'E',
+		// Length
+		'\x00', '\x00', '\x00', '\x0c',
+		// Severity "ERROR" \0
+		'S', 'E', 'R', 'R', 'O', 'R', '\x00',
+		// ending
+		'\x00',
+	}
+
+	e, err := pgproto.ParseError(bytes.NewReader(raw))
+	s.Nil(err)
+	s.NotNil(e)
+	s.Equal([]byte("ERROR"), e.Severity)
+}
+
+func (s *ErrorTestSuite) Test_ParseError_Malformed() {
+	for name, raw := range map[string][]byte{
+		"empty body":         {'E', '\x00', '\x00', '\x00', '\x04'},
+		"missing terminator": {'E', '\x00', '\x00', '\x00', '\x0b', 'S', 'E', 'R', 'R', 'O', 'R', '\x00'},
+	} {
+		s.NotPanics(func() {
+			e, err := pgproto.ParseError(bytes.NewReader(raw))
+			s.NotNil(err, name)
+			s.Nil(e, name)
+		}, name)
+	}
+}
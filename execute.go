@@ -60,3 +60,13 @@ func (e *Execute) AsMap() map[string]interface{} {
 }
 
 func (e *Execute) String() string { return messageToString(e) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (e *Execute) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("Execute", e)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (e *Execute) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "Execute", e)
+}
@@ -44,3 +44,13 @@ func (f *Flush) AsMap() map[string]interface{} {
 }
 
 func (f *Flush) String() string { return messageToString(f) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (f *Flush) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("Flush", f)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (f *Flush) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "Flush", f)
+}
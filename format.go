@@ -1,5 +1,7 @@
 package pgproto
 
+import "fmt"
+
 type Format uint8
 
 const (
@@ -16,3 +18,27 @@ func (f Format) String() string {
 	}
 	return "Unknown"
 }
+
+// MarshalText implements encoding.TextMarshaler, encoding the format as "text" or "binary"
+func (f Format) MarshalText() ([]byte, error) {
+	switch f {
+	case FormatText:
+		return []byte("text"), nil
+	case FormatBinary:
+		return []byte("binary"), nil
+	}
+	return nil, fmt.Errorf("unknown format %d", f)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, decoding "text" or "binary"
+func (f *Format) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "text":
+		*f = FormatText
+	case "binary":
+		*f = FormatBinary
+	default:
+		return fmt.Errorf("unknown format %q", text)
+	}
+	return nil
+}
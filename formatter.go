@@ -0,0 +1,222 @@
+package pgproto
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Formatter formats messages as readable strings with a stable field order
+//
+// Messages are formatted as their type followed by every field of the message struct in the order
+// they are declared:
+//
+//   SimpleQuery<Query="SELECT 1">
+//   Bind<Portal="", Statement="s1", ParameterFormats=[Text], Parameters=["1", nil], ResultFormats=[]>
+//
+// Byte fields are quoted strings with non printable bytes escaped, nil byte fields, e.g. NULL
+// values, are printed as nil and map entries are sorted by key.
+type Formatter struct {
+	// Redact is called for every field of a message and returns true when its value must not be
+	// printed, the value is replaced by <redacted>. The package Redaction policy is used when nil.
+	Redact RedactionPolicy
+
+	// Pretty formats RowDescription and DataRow messages over multiple lines, with a line per column
+	// starting with the column name
+	//
+	// The columns of a DataRow are named after the last RowDescription formatted, a Formatter should
+	// only be used for the messages of a single connection in this mode.
+	Pretty bool
+
+	mu      sync.Mutex
+	columns [][]byte
+}
+
+// DefaultFormatter is used by the String method of every message
+var DefaultFormatter = &Formatter{}
+
+// Format returns the string representation of m
+func (f *Formatter) Format(m Message) string {
+	v := reflect.ValueOf(m)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "<nil>"
+		}
+		v = v.Elem()
+	}
+	name := v.Type().Name()
+
+	if f.Pretty {
+		switch m := m.(type) {
+		case *RowDescription:
+			columns := make([][]byte, len(m.Fields))
+			for i, field := range m.Fields {
+				columns[i] = field.ColumnName
+			}
+			f.mu.Lock()
+			f.columns = columns
+			f.mu.Unlock()
+
+			if !f.redacted(m, "Fields") {
+				return f.prettyRowDescription(m)
+			}
+		case *DataRow:
+			if !f.redacted(m, "Fields") {
+				return f.prettyDataRow(m)
+			}
+		}
+	}
+
+	fields := make([]string, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i).Name
+		value := v.Field(i)
+
+		var str string
+		switch {
+		case !value.IsZero() && f.redacted(m, field):
+			str = redactedText
+		case value.Kind() == reflect.Map:
+			str = formatMap(value, func(key string) bool { return f.redacted(m, field+"."+key) })
+		default:
+			str = formatValue(value)
+		}
+		fields = append(fields, field+"="+str)
+	}
+	return name + "<" + strings.Join(fields, ", ") + ">"
+}
+
+func (f *Formatter) redacted(m Message, field string) bool {
+	if f.Redact == nil {
+		return redacted(m, field)
+	}
+	return f.Redact(m, field)
+}
+
+// formatMap formats the entries of a map sorted by key, the values of the keys redact returns
+// true for are replaced by <redacted>, redact may be nil
+func formatMap(v reflect.Value, redact func(key string) bool) string {
+	keys := make([]string, 0, v.Len())
+	values := make(map[string]reflect.Value, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		k := fmt.Sprint(iter.Key().Interface())
+		keys = append(keys, k)
+		values[k] = iter.Value()
+	}
+	sort.Strings(keys)
+
+	entries := make([]string, len(keys))
+	for i, k := range keys {
+		if redact != nil && redact(k) {
+			entries[i] = k + "=" + redactedText
+		} else {
+			entries[i] = k + "=" + formatValue(values[k])
+		}
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// prettyRowDescription formats every column on its own line, starting with its name
+//
+//   RowDescription<
+//     id    TableOID=0, ColumnIndex=0, TypeOID=23, ColumnLength=4, TypeModifier=-1, Format=Text
+//     name  TableOID=0, ColumnIndex=0, TypeOID=25, ColumnLength=-1, TypeModifier=-1, Format=Text
+//   >
+func (f *Formatter) prettyRowDescription(m *RowDescription) string {
+	names := make([]string, len(m.Fields))
+	attributes := make([]string, len(m.Fields))
+	for i, field := range m.Fields {
+		names[i] = string(field.ColumnName)
+
+		v := reflect.ValueOf(field)
+		attrs := make([]string, 0, v.NumField()-1)
+		for j := 0; j < v.NumField(); j++ {
+			if name := v.Type().Field(j).Name; name != "ColumnName" {
+				attrs = append(attrs, name+"="+formatValue(v.Field(j)))
+			}
+		}
+		attributes[i] = strings.Join(attrs, ", ")
+	}
+	return prettyColumns("RowDescription", names, attributes, "  ")
+}
+
+// prettyDataRow formats every value on its own line, starting with its column name
+//
+//   DataRow<
+//     id:   "1"
+//     name: nil
+//   >
+func (f *Formatter) prettyDataRow(m *DataRow) string {
+	f.mu.Lock()
+	columns := f.columns
+	f.mu.Unlock()
+
+	names := make([]string, len(m.Fields))
+	values := make([]string, len(m.Fields))
+	for i, value := range m.Fields {
+		// Columns are numbered when the last RowDescription does not describe this row
+		if len(columns) == len(m.Fields) {
+			names[i] = string(columns[i]) + ":"
+		} else {
+			names[i] = strconv.Itoa(i) + ":"
+		}
+		values[i] = formatValue(reflect.ValueOf(value))
+	}
+	return prettyColumns("DataRow", names, values, " ")
+}
+
+// prettyColumns formats a line per column with the names padded to the same width
+func prettyColumns(typ string, names []string, values []string, sep string) string {
+	width := 0
+	for _, name := range names {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(typ + "<\n")
+	for i, name := range names {
+		b.WriteString("  " + name + strings.Repeat(" ", width-len(name)) + sep + values[i] + "\n")
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// formatValue formats a field value
+func formatValue(v reflect.Value) string {
+	switch {
+	case v.Type() == bytesType:
+		if v.IsNil() {
+			return "nil"
+		}
+		return strconv.Quote(string(v.Bytes()))
+
+	case v.Kind() == reflect.Struct:
+		fields := make([]string, v.NumField())
+		for i := range fields {
+			fields[i] = v.Type().Field(i).Name + "=" + formatValue(v.Field(i))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+
+	case v.Kind() == reflect.Slice:
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = formatValue(v.Index(i))
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+
+	case v.Kind() == reflect.Map:
+		return formatMap(v, nil)
+
+	case v.Type().Implements(stringerType):
+		return v.Interface().(fmt.Stringer).String()
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,98 @@
+package pgproto_test
+
+import (
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+type FormatterTestSuite struct {
+	suite.Suite
+}
+
+func TestFormatterTestSuite(t *testing.T) {
+	suite.Run(t, new(FormatterTestSuite))
+}
+
+func (s *FormatterTestSuite) Test_String() {
+	cases := []struct {
+		message  pgproto.Message
+		expected string
+	}{
+		{
+			&pgproto.SimpleQuery{Query: []byte("SELECT 'a\tb'")},
+			`SimpleQuery<Query="SELECT 'a\tb'">`,
+		},
+		{
+			&pgproto.Sync{},
+			`Sync<>`,
+		},
+		{
+			&pgproto.StartupMessage{Options: map[string][]byte{"user": []byte("pgproto"), "database": []byte("db"), "application_name": []byte("psql")}},
+			`StartupMessage<SSLRequest=false, GSSEncRequest=false, Options={application_name="psql", database="db", user="pgproto"}>`,
+		},
+		{
+			&pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodMD5, Salt: []byte{'\x01', '\x02', '\x03', '\xff'}},
+			`AuthenticationRequest<Method=MD5, Salt=<redacted>, Mechanisms=[], Data=nil>`,
+		},
+		{
+			&pgproto.Bind{Statement: []byte("s1"), ParameterFormats: []pgproto.Format{pgproto.FormatBinary}, Parameters: [][]byte{[]byte("1"), nil}},
+			`Bind<Portal=nil, Statement="s1", ParameterFormats=[Binary], Parameters=["1", nil], ResultFormats=[]>`,
+		},
+		{
+			&pgproto.ReadyForQuery{Status: pgproto.READY_TRANSACTION},
+			`ReadyForQuery<Status=Transaction>`,
+		},
+		{
+			&pgproto.RowDescription{Fields: []pgproto.RowField{{ColumnName: []byte("id"), TypeOID: 23, ColumnLength: 4}}},
+			`RowDescription<Fields=[{ColumnName="id", TableOID=0, ColumnIndex=0, TypeOID=23, ColumnLength=4, TypeModifier=0, Format=Text}]>`,
+		},
+	}
+
+	for _, c := range cases {
+		// The output does not depend on map iteration order
+		for i := 0; i < 10; i++ {
+			s.Equal(c.expected, c.message.String())
+		}
+	}
+}
+
+func (s *FormatterTestSuite) Test_Redact() {
+	f := &pgproto.Formatter{
+		Redact: func(m pgproto.Message, field string) bool {
+			return field == "Password" || field == "Options.password"
+		},
+	}
+
+	s.Equal(
+		`PasswordMessage<Password=<redacted>>`,
+		f.Format(&pgproto.PasswordMessage{Password: []byte("secret")}),
+	)
+	s.Equal(
+		`StartupMessage<SSLRequest=false, GSSEncRequest=false, Options={password=<redacted>, user="pgproto"}>`,
+		f.Format(&pgproto.StartupMessage{Options: map[string][]byte{"user": []byte("pgproto"), "password": []byte("secret")}}),
+	)
+}
+
+func (s *FormatterTestSuite) Test_Pretty() {
+	f := &pgproto.Formatter{Pretty: true}
+
+	// Without a RowDescription the columns are numbered
+	s.Equal("DataRow<\n  0: \"1\"\n  1: nil\n>", f.Format(&pgproto.DataRow{Fields: [][]byte{[]byte("1"), nil}}))
+
+	s.Equal(
+		"RowDescription<\n"+
+			"  id    TableOID=0, ColumnIndex=0, TypeOID=23, ColumnLength=4, TypeModifier=-1, Format=Text\n"+
+			"  name  TableOID=0, ColumnIndex=0, TypeOID=25, ColumnLength=-1, TypeModifier=-1, Format=Text\n"+
+			">",
+		f.Format(&pgproto.RowDescription{Fields: []pgproto.RowField{
+			{ColumnName: []byte("id"), TypeOID: 23, ColumnLength: 4, TypeModifier: -1},
+			{ColumnName: []byte("name"), TypeOID: 25, ColumnLength: -1, TypeModifier: -1},
+		}}),
+	)
+	s.Equal("DataRow<\n  id:   \"1\"\n  name: nil\n>", f.Format(&pgproto.DataRow{Fields: [][]byte{[]byte("1"), nil}}))
+
+	// Other messages are formatted on a single line
+	s.Equal(`CommandCompletion<Tag="SELECT 1">`, f.Format(&pgproto.CommandCompletion{Tag: []byte("SELECT 1")}))
+}
@@ -0,0 +1,315 @@
+// Package pgconn implements the client side of a PostgreSQL connection on top of pgproto: dialing,
+// SSL negotiation, authentication and tracking of the session state reported by the server
+package pgconn
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/internal/scram"
+)
+
+// ErrSSLRefused is returned by Connect when TLSConfig is set but the server does not support SSL
+var ErrSSLRefused = errors.New("pgconn: server refused SSL")
+
+// Config describes how to connect and authenticate to a server
+type Config struct {
+	// Address is the TCP address of the server
+	Address string
+
+	// Dial is used to connect to Address, defaults to net.Dialer.DialContext
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// TLSConfig, when set, is used to require SSL
+	TLSConfig *tls.Config
+
+	// SSLOptional continues without SSL when the server refuses it instead of failing with
+	// ErrSSLRefused
+	SSLOptional bool
+
+	User     string
+	Password string
+	Database string
+
+	// Options are additional run-time parameters sent in the StartupMessage
+	Options map[string]string
+}
+
+// Conn is an authenticated connection to a server
+type Conn struct {
+	// PID and Key are the BackendKeyData used to cancel queries
+	PID int
+	Key int
+
+	// Parameters are the latest ParameterStatus values reported by the server
+	Parameters map[string]string
+
+	// Status is the transaction status from the latest ReadyForQuery
+	Status pgproto.ReadyStatus
+
+	config *Config
+	conn   net.Conn
+	r      *bufio.Reader
+
+	// Writes are safe for concurrent use so a connection can be handed between goroutines
+	wmu sync.Mutex
+	w   *bufio.Writer
+}
+
+// Connect will dial the server described by config, authenticate and wait until it is ready for queries
+func Connect(ctx context.Context, config *Config) (*Conn, error) {
+	conn, err := dial(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Conn{
+		Parameters: make(map[string]string),
+		config:     config,
+		conn:       conn,
+		r:          bufio.NewReader(conn),
+		w:          bufio.NewWriter(conn),
+	}
+
+	// Abort the startup when the context is done
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	err = c.startup()
+	if !stop() {
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// NetConn returns the underlying network connection
+func (c *Conn) NetConn() net.Conn { return c.conn }
+
+// Write will buffer m to be sent with the next Flush
+func (c *Conn) Write(m pgproto.ClientMessage) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := pgproto.WriteMessage(m, c.w)
+	return err
+}
+
+// Flush will send all buffered messages
+func (c *Conn) Flush() error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.w.Flush()
+}
+
+// Send will write msgs to the server
+func (c *Conn) Send(msgs ...pgproto.ClientMessage) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	for _, m := range msgs {
+		_, err := pgproto.WriteMessage(m, c.w)
+		if err != nil {
+			return err
+		}
+	}
+	return c.w.Flush()
+}
+
+// Receive will read the next message from the server, updating Parameters and Status
+func (c *Conn) Receive() (pgproto.ServerMessage, error) {
+	msg, err := pgproto.ParseServerMessage(c.r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch m := msg.(type) {
+	case *pgproto.ParameterStatus:
+		c.Parameters[string(m.Name)] = string(m.Value)
+	case *pgproto.ReadyForQuery:
+		c.Status = m.Status
+	}
+	return msg, nil
+}
+
+// Buffered returns whether messages from the server have been read but not yet received
+func (c *Conn) Buffered() bool { return c.r.Buffered() > 0 }
+
+// Exec will run query using the simple query protocol and return every message received up to and
+// including the next ReadyForQuery, the first error reported by the server is returned as a *pgproto.Error
+func (c *Conn) Exec(query string) ([]pgproto.ServerMessage, error) {
+	err := c.Send(&pgproto.SimpleQuery{Query: []byte(query)})
+	if err != nil {
+		return nil, err
+	}
+
+	var qerr error
+	msgs := make([]pgproto.ServerMessage, 0)
+	for {
+		msg, err := c.Receive()
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+
+		switch m := msg.(type) {
+		case *pgproto.Error:
+			if qerr == nil {
+				qerr = m
+			}
+		case *pgproto.ReadyForQuery:
+			return msgs, qerr
+		}
+	}
+}
+
+// Cancel will ask the server to cancel the query currently running on this connection
+//
+// The request is sent on a new connection and the server does not report whether it succeeded
+func (c *Conn) Cancel(ctx context.Context) error {
+	conn, err := dial(ctx, c.config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	_, err = pgproto.WriteMessage(&pgproto.CancelRequest{PID: c.PID, Key: c.Key}, conn)
+	if err != nil {
+		return err
+	}
+
+	// Wait for the server to close the connection so the request is processed before we return
+	var b [1]byte
+	conn.Read(b[:])
+	return nil
+}
+
+// Close will send a Termination message and close the connection
+func (c *Conn) Close() error {
+	c.Send(&pgproto.Termination{})
+	return c.conn.Close()
+}
+
+func (c *Conn) startup() error {
+	startup := &pgproto.StartupMessage{
+		Options: map[string][]byte{"user": []byte(c.config.User)},
+	}
+	if c.config.Database != "" {
+		startup.Options["database"] = []byte(c.config.Database)
+	}
+	for k, v := range c.config.Options {
+		startup.Options[k] = []byte(v)
+	}
+	err := c.Send(startup)
+	if err != nil {
+		return err
+	}
+
+	var sasl *scram.Client
+	for {
+		msg, err := c.Receive()
+		if err != nil {
+			return err
+		}
+
+		switch m := msg.(type) {
+		case *pgproto.AuthenticationRequest:
+			sasl, err = c.authenticate(m, sasl)
+			if err != nil {
+				return err
+			}
+		case *pgproto.BackendKeyData:
+			c.PID = m.PID
+			c.Key = m.Key
+		case *pgproto.Error:
+			return m
+		case *pgproto.ReadyForQuery:
+			return nil
+		}
+	}
+}
+
+// authenticate answers a single authentication request, returning the SCRAM exchange in progress
+func (c *Conn) authenticate(req *pgproto.AuthenticationRequest, sasl *scram.Client) (*scram.Client, error) {
+	password := []byte(c.config.Password)
+	switch req.Method {
+	case pgproto.AuthenticationMethodOK:
+		return nil, nil
+	case pgproto.AuthenticationMethodPlaintext:
+		return nil, c.Send(&pgproto.PasswordMessage{Password: password})
+	case pgproto.AuthenticationMethodMD5:
+		p := &pgproto.PasswordMessage{}
+		p.SetPassword([]byte(c.config.User), password, req.Salt)
+		return nil, c.Send(p)
+	case pgproto.AuthenticationMethodSASL:
+		for _, mechanism := range req.Mechanisms {
+			if string(mechanism) == scram.Mechanism {
+				sasl = scram.NewClient(c.config.Password)
+				return sasl, c.Send(&pgproto.SASLInitialResponse{
+					Mechanism: mechanism,
+					Data:      sasl.First(),
+				})
+			}
+		}
+		return nil, fmt.Errorf("pgconn: no supported SASL mechanism offered")
+	case pgproto.AuthenticationMethodSASLContinue:
+		if sasl == nil {
+			break
+		}
+		data, err := sasl.Final(req.Data)
+		if err != nil {
+			return nil, err
+		}
+		return sasl, c.Send(&pgproto.SASLResponse{Data: data})
+	case pgproto.AuthenticationMethodSASLFinal:
+		if sasl == nil {
+			break
+		}
+		return nil, sasl.Verify(req.Data)
+	}
+	return nil, fmt.Errorf("pgconn: unsupported authentication method %s", req.Method)
+}
+
+func dial(ctx context.Context, config *Config) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if config.Dial != nil {
+		conn, err = config.Dial(ctx, "tcp", config.Address)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", config.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if config.TLSConfig == nil {
+		return conn, nil
+	}
+
+	tlsConn, ok, err := pgproto.RequestSSL(conn, config.TLSConfig)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !ok {
+		if config.SSLOptional {
+			return conn, nil
+		}
+		conn.Close()
+		return nil, ErrSSLRefused
+	}
+	return tlsConn, nil
+}
@@ -0,0 +1,243 @@
+// Package scram implements the SCRAM-SHA-256 SASL mechanism (RFC 5802 and RFC 7677) as used by
+// PostgreSQL, without channel binding
+//
+// Passwords are used as is, SASLprep normalization is not applied.
+package scram
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Mechanism is the SASL mechanism name
+const Mechanism = "SCRAM-SHA-256"
+
+// DefaultIterations is the iteration count used by PostgreSQL when hashing passwords
+const DefaultIterations = 4096
+
+// gs2Header is sent by clients which do not support channel binding
+const gs2Header = "n,,"
+
+// ErrInvalidProof is returned when the client proof or server signature does not match
+var ErrInvalidProof = errors.New("scram: invalid proof")
+
+// Client is the client side of a single SCRAM-SHA-256 exchange
+type Client struct {
+	password        string
+	nonce           string
+	clientFirstBare string
+	serverSignature []byte
+}
+
+// NewClient returns a Client authenticating with password
+func NewClient(password string) *Client {
+	return &Client{
+		password: password,
+		nonce:    newNonce(),
+	}
+}
+
+// First returns the client-first-message
+//
+// PostgreSQL ignores the user name in the SCRAM exchange in favor of the one from the startup message
+func (c *Client) First() []byte {
+	c.clientFirstBare = "n=,r=" + c.nonce
+	return []byte(gs2Header + c.clientFirstBare)
+}
+
+// Final returns the client-final-message in response to the server-first-message
+func (c *Client) Final(serverFirst []byte) ([]byte, error) {
+	attrs, err := parseAttributes(serverFirst)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := attrs['r']
+	if !strings.HasPrefix(nonce, c.nonce) || len(nonce) == len(c.nonce) {
+		return nil, fmt.Errorf("scram: invalid server nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(attrs['s'])
+	if err != nil {
+		return nil, fmt.Errorf("scram: invalid salt: %v", err)
+	}
+	iterations, err := strconv.Atoi(attrs['i'])
+	if err != nil || iterations < 1 {
+		return nil, fmt.Errorf("scram: invalid iteration count %q", attrs['i'])
+	}
+
+	clientFinalWithoutProof := "c=" + base64.StdEncoding.EncodeToString([]byte(gs2Header)) + ",r=" + nonce
+	authMessage := []byte(c.clientFirstBare + "," + string(serverFirst) + "," + clientFinalWithoutProof)
+
+	saltedPassword := saltPassword(c.password, salt, iterations)
+	clientKey := computeHMAC(saltedPassword, []byte("Client Key"))
+	storedKey := sha256.Sum256(clientKey)
+	clientSignature := computeHMAC(storedKey[:], authMessage)
+
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ clientSignature[i]
+	}
+
+	serverKey := computeHMAC(saltedPassword, []byte("Server Key"))
+	c.serverSignature = computeHMAC(serverKey, authMessage)
+
+	return []byte(clientFinalWithoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof)), nil
+}
+
+// Verify checks the server-final-message proves the server knows the password
+func (c *Client) Verify(serverFinal []byte) error {
+	attrs, err := parseAttributes(serverFinal)
+	if err != nil {
+		return err
+	}
+	if e, ok := attrs['e']; ok {
+		return fmt.Errorf("scram: server error: %s", e)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(attrs['v'])
+	if err != nil {
+		return fmt.Errorf("scram: invalid server signature: %v", err)
+	}
+	if !hmac.Equal(signature, c.serverSignature) {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// Server is the server side of a single SCRAM-SHA-256 exchange
+type Server struct {
+	salt       []byte
+	iterations int
+	storedKey  []byte
+	serverKey  []byte
+
+	nonce           string
+	clientFirstBare string
+	serverFirst     string
+}
+
+// NewServer returns a Server verifying clients against password
+func NewServer(password string) *Server {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+
+	saltedPassword := saltPassword(password, salt, DefaultIterations)
+	storedKey := sha256.Sum256(computeHMAC(saltedPassword, []byte("Client Key")))
+	return &Server{
+		salt:       salt,
+		iterations: DefaultIterations,
+		storedKey:  storedKey[:],
+		serverKey:  computeHMAC(saltedPassword, []byte("Server Key")),
+	}
+}
+
+// First returns the server-first-message in response to the client-first-message
+func (s *Server) First(clientFirst []byte) ([]byte, error) {
+	// gs2-header "n,," or "y,," followed by the client-first-message-bare
+	parts := strings.SplitN(string(clientFirst), ",", 3)
+	if len(parts) != 3 || (parts[0] != "n" && parts[0] != "y") {
+		return nil, fmt.Errorf("scram: unsupported gs2 header")
+	}
+	s.clientFirstBare = parts[2]
+
+	attrs, err := parseAttributes([]byte(s.clientFirstBare))
+	if err != nil {
+		return nil, err
+	}
+	if attrs['r'] == "" {
+		return nil, fmt.Errorf("scram: missing client nonce")
+	}
+
+	s.nonce = attrs['r'] + newNonce()
+	s.serverFirst = fmt.Sprintf("r=%s,s=%s,i=%d", s.nonce, base64.StdEncoding.EncodeToString(s.salt), s.iterations)
+	return []byte(s.serverFirst), nil
+}
+
+// Final verifies the client-final-message and returns the server-final-message
+func (s *Server) Final(clientFinal []byte) ([]byte, error) {
+	i := bytes.LastIndex(clientFinal, []byte(",p="))
+	if i == -1 {
+		return nil, fmt.Errorf("scram: missing client proof")
+	}
+	clientFinalWithoutProof := clientFinal[:i]
+
+	attrs, err := parseAttributes(clientFinal)
+	if err != nil {
+		return nil, err
+	}
+	if attrs['r'] != s.nonce {
+		return nil, fmt.Errorf("scram: invalid nonce")
+	}
+	proof, err := base64.StdEncoding.DecodeString(attrs['p'])
+	if err != nil || len(proof) != sha256.Size {
+		return nil, fmt.Errorf("scram: invalid client proof")
+	}
+
+	authMessage := []byte(s.clientFirstBare + "," + s.serverFirst + "," + string(clientFinalWithoutProof))
+	clientSignature := computeHMAC(s.storedKey, authMessage)
+	clientKey := make([]byte, len(proof))
+	for i := range proof {
+		clientKey[i] = proof[i] ^ clientSignature[i]
+	}
+	storedKey := sha256.Sum256(clientKey)
+	if subtle.ConstantTimeCompare(storedKey[:], s.storedKey) != 1 {
+		return nil, ErrInvalidProof
+	}
+
+	serverSignature := computeHMAC(s.serverKey, authMessage)
+	return []byte("v=" + base64.StdEncoding.EncodeToString(serverSignature)), nil
+}
+
+// parseAttributes parses a comma separated list of single letter attributes, e.g. "r=abc,s=def"
+func parseAttributes(msg []byte) (map[byte]string, error) {
+	attrs := make(map[byte]string)
+	for _, part := range strings.Split(string(msg), ",") {
+		if len(part) < 2 || part[1] != '=' {
+			return nil, fmt.Errorf("scram: invalid attribute %q", part)
+		}
+		attrs[part[0]] = part[2:]
+	}
+	return attrs, nil
+}
+
+// saltPassword computes Hi(password, salt, iterations), which is PBKDF2 with HMAC-SHA-256
+// limited to a single block since the derived key is the size of the hash
+func saltPassword(password string, salt []byte, iterations int) []byte {
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write(salt)
+	mac.Write(binary.BigEndian.AppendUint32(nil, 1))
+	u := mac.Sum(nil)
+
+	result := make([]byte, len(u))
+	copy(result, u)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range result {
+			result[j] ^= u[j]
+		}
+	}
+	return result
+}
+
+func computeHMAC(key []byte, msg []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(msg)
+	return mac.Sum(nil)
+}
+
+func newNonce() string {
+	b := make([]byte, 18)
+	rand.Read(b)
+	return base64.RawStdEncoding.EncodeToString(b)
+}
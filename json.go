@@ -0,0 +1,349 @@
+package pgproto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+)
+
+// jsonMessageTypes creates an empty message for every message type, by the name used in its JSON "type"
+var jsonMessageTypes = map[string]func() Message{
+	"AuthenticationRequest":    func() Message { return &AuthenticationRequest{} },
+	"BackendKeyData":           func() Message { return &BackendKeyData{} },
+	"BinaryParameters":         func() Message { return &BinaryParameters{} },
+	"Bind":                     func() Message { return &Bind{} },
+	"BindComplete":             func() Message { return &BindComplete{} },
+	"CancelRequest":            func() Message { return &CancelRequest{} },
+	"Close":                    func() Message { return &Close{} },
+	"CloseComplete":            func() Message { return &CloseComplete{} },
+	"CommandCompletion":        func() Message { return &CommandCompletion{} },
+	"CopyBothResponse":         func() Message { return &CopyBothResponse{} },
+	"CopyData":                 func() Message { return &CopyData{} },
+	"CopyDone":                 func() Message { return &CopyDone{} },
+	"CopyFail":                 func() Message { return &CopyFail{} },
+	"CopyInResponse":           func() Message { return &CopyInResponse{} },
+	"CopyOutResponse":          func() Message { return &CopyOutResponse{} },
+	"DataRow":                  func() Message { return &DataRow{} },
+	"Describe":                 func() Message { return &Describe{} },
+	"EmptyQueryResponse":       func() Message { return &EmptyQueryResponse{} },
+	"Error":                    func() Message { return &Error{} },
+	"Execute":                  func() Message { return &Execute{} },
+	"Flush":                    func() Message { return &Flush{} },
+	"NegotiateProtocolVersion": func() Message { return &NegotiateProtocolVersion{} },
+	"NoData":                   func() Message { return &NoData{} },
+	"NoticeResponse":           func() Message { return &NoticeResponse{} },
+	"Notification":             func() Message { return &Notification{} },
+	"ParameterDescription":     func() Message { return &ParameterDescription{} },
+	"ParameterStatus":          func() Message { return &ParameterStatus{} },
+	"Parse":                    func() Message { return &Parse{} },
+	"ParseComplete":            func() Message { return &ParseComplete{} },
+	"PasswordMessage":          func() Message { return &PasswordMessage{} },
+	"PortalSuspended":          func() Message { return &PortalSuspended{} },
+	"ReadyForQuery":            func() Message { return &ReadyForQuery{} },
+	"RowDescription":           func() Message { return &RowDescription{} },
+	"SASLInitialResponse":      func() Message { return &SASLInitialResponse{} },
+	"SASLResponse":             func() Message { return &SASLResponse{} },
+	"SimpleQuery":              func() Message { return &SimpleQuery{} },
+	"SSLResponse":              func() Message { return &SSLResponse{} },
+	"StartupMessage":           func() Message { return &StartupMessage{} },
+	"Sync":                     func() Message { return &Sync{} },
+	"Termination":              func() Message { return &Termination{} },
+}
+
+// DecodeJSONMessage will return the message encoded in data by its MarshalJSON method
+//
+// Every message is encoded as a JSON object with its type, as returned in AsMap, and a payload
+// holding every field of the message struct by name, in the order they are declared:
+//
+//   {"type": "SimpleQuery", "payload": {"Query": "SELECT 1"}}
+//   {"type": "Sync", "payload": {}}
+//
+// Field values are encoded as follows:
+//
+//   []byte                     a string when valid UTF-8, {"base64": "..."} otherwise, null when nil
+//   integers, bool             a number, a bool
+//   Format                     "text" or "binary"
+//   ReadyStatus, ObjectType    the single character sent on the wire, e.g. "I" or "S"
+//   slices and maps            an array or object of the encoded values, null when nil
+//   RowField                   an object of its fields
+//
+// Unknown payload fields are an error, missing fields are left to their zero value. Other keys
+// next to "type" and "payload" are ignored.
+//
+// The values of fields redacted by the Redaction policy are encoded as {"redacted": true}, and
+// decoded as nil. Set Redaction to RedactNothing to encode messages which can be decoded as sent.
+func DecodeJSONMessage(data []byte) (Message, error) {
+	var j struct {
+		Type string `json:"type"`
+	}
+	err := json.Unmarshal(data, &j)
+	if err != nil {
+		return nil, err
+	}
+
+	newMessage, ok := jsonMessageTypes[j.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown message type %q", j.Type)
+	}
+	m := newMessage()
+	err = json.Unmarshal(data, m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// jsonMessage is the JSON representation of every message
+type jsonMessage struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// marshalJSONMessage encodes the message struct m as a message of type typ, applying the Redaction policy
+func marshalJSONMessage(typ string, m Message) ([]byte, error) {
+	v := reflect.ValueOf(m).Elem()
+	obj := make(jsonObject, v.NumField())
+	for i := range obj {
+		name := v.Type().Field(i).Name
+		field := v.Field(i)
+
+		var value interface{}
+		switch {
+		case !field.IsZero() && redacted(m, name):
+			value = jsonRedacted
+		case field.Kind() == reflect.Map && !field.IsNil():
+			values := make(map[string]interface{}, field.Len())
+			iter := field.MapRange()
+			for iter.Next() {
+				k := iter.Key().String()
+				if redacted(m, name+"."+k) {
+					values[k] = jsonRedacted
+				} else {
+					values[k] = toJSON(iter.Value())
+				}
+			}
+			value = values
+		default:
+			value = toJSON(field)
+		}
+		obj[i] = jsonField{name: name, value: value}
+	}
+
+	payload, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonMessage{Type: typ, Payload: payload})
+}
+
+// jsonRedacted replaces the values of redacted fields
+var jsonRedacted = map[string]bool{"redacted": true}
+
+// unmarshalJSONMessage decodes a message of type typ into the message struct m
+func unmarshalJSONMessage(data []byte, typ string, m Message) error {
+	var j jsonMessage
+	err := json.Unmarshal(data, &j)
+	if err != nil {
+		return err
+	}
+	if j.Type != typ {
+		return fmt.Errorf("expected message type %q, got %q", typ, j.Type)
+	}
+
+	v := reflect.ValueOf(m).Elem()
+	v.Set(reflect.Zero(v.Type()))
+	if len(j.Payload) == 0 {
+		return nil
+	}
+	err = fromJSON(j.Payload, v)
+	if err != nil {
+		return fmt.Errorf("%s: %v", typ, err)
+	}
+	return nil
+}
+
+var bytesType = reflect.TypeOf([]byte(nil))
+
+// toJSON converts v to a value encoding/json encodes following the schema of DecodeJSONMessage
+func toJSON(v reflect.Value) interface{} {
+	switch {
+	case v.Type() == bytesType:
+		return jsonBytes(v.Bytes())
+
+	case v.Kind() == reflect.Struct:
+		obj := make(jsonObject, v.NumField())
+		for i := range obj {
+			obj[i] = jsonField{name: v.Type().Field(i).Name, value: toJSON(v.Field(i))}
+		}
+		return obj
+
+	case v.Kind() == reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = toJSON(v.Index(i))
+		}
+		return values
+
+	case v.Kind() == reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		values := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = toJSON(iter.Value())
+		}
+		return values
+	}
+	return v.Interface()
+}
+
+// fromJSON decodes data into v following the schema of DecodeJSONMessage
+func fromJSON(data json.RawMessage, v reflect.Value) error {
+	switch {
+	case v.Type() == bytesType:
+		var b jsonBytes
+		err := json.Unmarshal(data, &b)
+		if err != nil {
+			return err
+		}
+		v.SetBytes(b)
+		return nil
+
+	case v.Kind() == reflect.Struct:
+		var fields map[string]json.RawMessage
+		err := json.Unmarshal(data, &fields)
+		if err != nil {
+			return err
+		}
+		for name, raw := range fields {
+			f := v.FieldByName(name)
+			if !f.IsValid() {
+				return fmt.Errorf("unknown field %q", name)
+			}
+			err = fromJSON(raw, f)
+			if err != nil {
+				return fmt.Errorf("field %s: %v", name, err)
+			}
+		}
+		return nil
+
+	case v.Kind() == reflect.Slice:
+		var values []json.RawMessage
+		err := json.Unmarshal(data, &values)
+		if err != nil {
+			return err
+		}
+		if values == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, raw := range values {
+			err = fromJSON(raw, s.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+
+	case v.Kind() == reflect.Map:
+		var values map[string]json.RawMessage
+		err := json.Unmarshal(data, &values)
+		if err != nil {
+			return err
+		}
+		if values == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(values))
+		for k, raw := range values {
+			value := reflect.New(v.Type().Elem()).Elem()
+			err = fromJSON(raw, value)
+			if err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), value)
+		}
+		v.Set(m)
+		return nil
+	}
+	return json.Unmarshal(data, v.Addr().Interface())
+}
+
+// jsonBytes encodes as a string when valid UTF-8, and as {"base64": "..."} otherwise, it decodes
+// {"redacted": true} as nil
+type jsonBytes []byte
+
+func (b jsonBytes) MarshalJSON() ([]byte, error) {
+	if b == nil {
+		return []byte("null"), nil
+	}
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *jsonBytes) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*b = nil
+		return nil
+	}
+
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*b = jsonBytes(s)
+		return nil
+	}
+
+	var encoded struct {
+		Base64   *string `json:"base64"`
+		Redacted bool    `json:"redacted"`
+	}
+	err := json.Unmarshal(data, &encoded)
+	if err == nil && encoded.Redacted {
+		*b = nil
+		return nil
+	}
+	if err != nil || encoded.Base64 == nil {
+		return fmt.Errorf("expected a string or {\"base64\": ...}")
+	}
+	*b, err = base64.StdEncoding.DecodeString(*encoded.Base64)
+	return err
+}
+
+// jsonObject encodes as an object with its fields in order
+type jsonObject []jsonField
+
+type jsonField struct {
+	name  string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBufferString("{")
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.name)
+		buf.Write(name)
+		buf.WriteByte(':')
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,132 @@
+package pgproto_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+type JSONTestSuite struct {
+	suite.Suite
+}
+
+func TestJSONTestSuite(t *testing.T) {
+	suite.Run(t, new(JSONTestSuite))
+}
+
+func (s *JSONTestSuite) Test_Encode() {
+	cases := []struct {
+		message  pgproto.Message
+		expected string
+	}{
+		{
+			&pgproto.SimpleQuery{Query: []byte("SELECT 1")},
+			`{"type":"SimpleQuery","payload":{"Query":"SELECT 1"}}`,
+		},
+		{
+			&pgproto.Sync{},
+			`{"type":"Sync","payload":{}}`,
+		},
+		{
+			&pgproto.ReadyForQuery{Status: pgproto.READY_TRANSACTION},
+			`{"type":"ReadyForQuery","payload":{"Status":"T"}}`,
+		},
+		{
+			&pgproto.Close{ObjectType: pgproto.ObjectTypePortal, Name: []byte("p1")},
+			`{"type":"Close","payload":{"ObjectType":"P","Name":"p1"}}`,
+		},
+		{
+			&pgproto.Bind{
+				Statement:        []byte("s1"),
+				ParameterFormats: []pgproto.Format{pgproto.FormatBinary},
+				Parameters:       [][]byte{{'\x00', '\x00', '\x00', '\xff'}, nil},
+			},
+			`{"type":"Bind","payload":{"Portal":null,"Statement":"s1","ParameterFormats":["binary"],` +
+				`"Parameters":[{"base64":"AAAA/w=="},null],"ResultFormats":null}}`,
+		},
+		{
+			&pgproto.StartupMessage{Options: map[string][]byte{"user": []byte("pgproto"), "database": []byte("db")}},
+			`{"type":"StartupMessage","payload":{"SSLRequest":false,"GSSEncRequest":false,"Options":{"database":"db","user":"pgproto"}}}`,
+		},
+		{
+			&pgproto.RowDescription{Fields: []pgproto.RowField{{ColumnName: []byte("id"), TypeOID: 23, ColumnLength: 4, TypeModifier: -1}}},
+			`{"type":"RowDescription","payload":{"Fields":[{"ColumnName":"id","TableOID":0,"ColumnIndex":0,"TypeOID":23,` +
+				`"ColumnLength":4,"TypeModifier":-1,"Format":"text"}]}}`,
+		},
+	}
+
+	for _, c := range cases {
+		encoded, err := json.Marshal(c.message)
+		s.Nil(err)
+		s.Equal(c.expected, string(encoded))
+	}
+}
+
+func (s *JSONTestSuite) Test_RoundTrip() {
+	// Secrets are only encoded when they are not redacted
+	pgproto.Redaction = pgproto.RedactNothing
+	defer func() { pgproto.Redaction = pgproto.RedactSecrets }()
+
+	password := &pgproto.PasswordMessage{}
+	password.SetPassword([]byte("pgproto"), []byte("secret"), []byte{'\x01', '\x02', '\x03', '\x04'})
+
+	messages := []pgproto.Message{
+		&pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodMD5, Salt: []byte{'\x01', '\xff', '\x03', '\x04'}},
+		&pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodSASL, Mechanisms: [][]byte{[]byte("SCRAM-SHA-256")}},
+		&pgproto.BackendKeyData{PID: 1234, Key: 5678},
+		&pgproto.Bind{Portal: []byte{}, Statement: []byte("s1"), Parameters: [][]byte{[]byte("1"), nil}, ResultFormats: []pgproto.Format{pgproto.FormatText}},
+		&pgproto.BindComplete{},
+		&pgproto.CancelRequest{PID: 1, Key: 2},
+		&pgproto.Close{ObjectType: pgproto.ObjectTypePreparedStatement, Name: []byte("s1")},
+		&pgproto.CommandCompletion{Tag: []byte("SELECT 1")},
+		&pgproto.CopyData{Data: []byte("1\t2\n")},
+		&pgproto.CopyDone{},
+		&pgproto.CopyInResponse{Format: pgproto.FormatText, ColumnFormats: []int{0, 0}},
+		&pgproto.DataRow{Fields: [][]byte{[]byte("1"), nil, {'\xde', '\xad'}}},
+		&pgproto.Describe{ObjectType: pgproto.ObjectTypePortal, Name: []byte{}},
+		&pgproto.Error{Severity: []byte("ERROR"), Code: []byte("42P01"), Message: []byte("relation \"missing\" does not exist")},
+		&pgproto.Execute{Portal: []byte{}, MaxRows: 10},
+		&pgproto.NoticeResponse{Severity: []byte("NOTICE"), Message: []byte("héllo")},
+		&pgproto.Parse{Name: []byte("s1"), Query: []byte("SELECT $1"), OIDs: []int{23}},
+		&pgproto.ParameterStatus{Name: []byte("server_version"), Value: []byte("16.1")},
+		password,
+		&pgproto.ReadyForQuery{Status: pgproto.READY_IDLE},
+		&pgproto.RowDescription{Fields: []pgproto.RowField{{ColumnName: []byte("id"), TypeOID: 23, Format: pgproto.FormatBinary}}},
+		&pgproto.SASLInitialResponse{Mechanism: []byte("SCRAM-SHA-256"), Data: []byte("n,,n=,r=abc")},
+		&pgproto.SSLResponse{Accepted: true},
+		&pgproto.StartupMessage{Options: map[string][]byte{"user": []byte("pgproto")}},
+		&pgproto.Termination{},
+	}
+
+	for _, m := range messages {
+		encoded, err := json.Marshal(m)
+		s.Require().Nil(err)
+
+		decoded, err := pgproto.DecodeJSONMessage(encoded)
+		s.Require().Nil(err, string(encoded))
+		s.Equal(m, decoded)
+		s.Equal(m.Encode(), decoded.Encode())
+	}
+}
+
+func (s *JSONTestSuite) Test_Decode() {
+	// Missing fields are left to their zero value and other keys are ignored
+	m, err := pgproto.DecodeJSONMessage([]byte(`{"time":"2024-01-02T15:04:05Z","type":"Execute","payload":{"Portal":"p1"}}`))
+	s.Nil(err)
+	s.Equal(&pgproto.Execute{Portal: []byte("p1")}, m)
+
+	_, err = pgproto.DecodeJSONMessage([]byte(`{"type":"Unknown","payload":{}}`))
+	s.NotNil(err)
+
+	_, err = pgproto.DecodeJSONMessage([]byte(`{"type":"SimpleQuery","payload":{"Qeury":"SELECT 1"}}`))
+	s.NotNil(err)
+
+	_, err = pgproto.DecodeJSONMessage([]byte(`{"type":"ReadyForQuery","payload":{"Status":"Idle"}}`))
+	s.NotNil(err)
+
+	// Unmarshaling into a message of another type fails
+	q := &pgproto.SimpleQuery{}
+	s.NotNil(json.Unmarshal([]byte(`{"type":"Sync","payload":{}}`), q))
+}
@@ -24,6 +24,16 @@ type ServerMessage interface {
 	server()
 }
 
+// UnknownMessageError is returned when parsing a message whose tag is not supported, the whole
+// message has been read from the io.Reader
+type UnknownMessageError struct {
+	Tag byte
+}
+
+func (e *UnknownMessageError) Error() string {
+	return fmt.Sprintf("unknown message tag '%c'", e.Tag)
+}
+
 // ParseClientMessage will read the next ClientMessage from the provided io.Reader
 func ParseClientMessage(r io.Reader) (ClientMessage, error) {
 	// Create a buffer
@@ -43,10 +53,14 @@ func ParseClientMessage(r io.Reader) (ClientMessage, error) {
 	// TODO: We need to handle this case better, it might not always start with \x00
 	//       We could just make calling `ParseStartupMessage` explicit
 	case '\x00':
-		msgReader, err := readStartupMessage(start, buf)
+		msgReader, code, err := readStartupMessage(start, buf)
 		if err != nil {
 			return nil, err
 		}
+		// Cancel requests are sent in place of a startup message
+		if code == cancelRequestCode {
+			return ParseCancelRequest(msgReader)
+		}
 		return ParseStartupMessage(msgReader)
 	default:
 		// Read the entire next message from the input reader
@@ -57,16 +71,14 @@ func ParseClientMessage(r io.Reader) (ClientMessage, error) {
 		switch start {
 		case 'p':
 			// Password message
+			// DEV: SASL responses share this tag, use `ParseAuthenticationResponse` to tell them apart
 			return ParsePasswordMessage(msgReader)
 		case 'Q':
 			// Simple query
 			return ParseSimpleQuery(msgReader)
-		case 't':
-			// Parameter description
-			return ParseParameterDescription(msgReader)
 		case 'B':
-			// Binary parameters
-			return ParseBinaryParameters(msgReader)
+			// Bind
+			return ParseBind(msgReader)
 		case 'P':
 			// Parse
 			return ParseParse(msgReader)
@@ -88,8 +100,17 @@ func ParseClientMessage(r io.Reader) (ClientMessage, error) {
 		case 'X':
 			// Termination
 			return ParseTermination(msgReader)
+		case 'd':
+			// Copy data
+			return ParseCopyData(msgReader)
+		case 'c':
+			// Copy done
+			return ParseCopyDone(msgReader)
+		case 'f':
+			// Copy fail
+			return ParseCopyFail(msgReader)
 		default:
-			return nil, fmt.Errorf("unknown message tag '%c'", start)
+			return nil, &UnknownMessageError{Tag: start}
 		}
 	}
 }
@@ -134,7 +155,7 @@ func ParseServerMessage(r io.Reader) (ServerMessage, error) {
 		return ParseRowDescription(msgReader)
 	case 't':
 		// Parameter description
-		return nil, fmt.Errorf("unhandled message tag %#v", start)
+		return ParseParameterDescription(msgReader)
 	case 'D':
 		// Data row
 		return ParseDataRow(msgReader)
@@ -156,6 +177,9 @@ func ParseServerMessage(r io.Reader) (ServerMessage, error) {
 	case 'd':
 		// Copy data
 		return ParseCopyData(msgReader)
+	case 'c':
+		// Copy done
+		return ParseCopyDone(msgReader)
 	case 'G':
 		// Copy in response
 		return ParseCopyInResponse(msgReader)
@@ -164,7 +188,7 @@ func ParseServerMessage(r io.Reader) (ServerMessage, error) {
 		return ParseCopyOutResponse(msgReader)
 	case 'V':
 		// Function call response
-		return nil, fmt.Errorf("unhandled message tag %#v", start)
+		return nil, &UnknownMessageError{Tag: start}
 	case 'n':
 		// No data
 		return ParseNoData(msgReader)
@@ -177,13 +201,19 @@ func ParseServerMessage(r io.Reader) (ServerMessage, error) {
 	case 'E':
 		// Error message
 		return ParseError(msgReader)
+	case 's':
+		// Portal suspended
+		return ParsePortalSuspended(msgReader)
+	case 'v':
+		// Negotiate protocol version
+		return ParseNegotiateProtocolVersion(msgReader)
 	default:
-		return nil, fmt.Errorf("unknown message tag '%c'", start)
+		return nil, &UnknownMessageError{Tag: start}
 	}
 }
 
-func readStartupMessage(start byte, buf *readBuffer) (io.Reader, error) {
-	// [int32 - length] [payload]
+func readStartupMessage(start byte, buf *readBuffer) (io.Reader, int, error) {
+	// [int32 - length] [int32 - protocol or request code] [payload]
 	// StartupMessage
 	// Read the next 3 bytes, prepend with the 1 we already read to parse the length from this message
 	s := [4]byte{
@@ -191,23 +221,26 @@ func readStartupMessage(start byte, buf *readBuffer) (io.Reader, error) {
 	}
 	_, err := buf.Read(s[1:])
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	l := bytesToInt(s[:])
+	if l < 8 || l > maxStartupLength {
+		return nil, 0, fmt.Errorf("unable to parse length from message")
+	}
 
 	// Read the rest of the message into a []byte
 	// DEV: Subtract 4 to account for the length of the in32 we just read
 	b := make([]byte, l-4)
 	_, err = buf.Read(b)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// Rebuild the message into a []byte
 	w := newWriteBuffer()
 	w.WriteInt(l)
 	w.WriteBytes(b)
-	return w.Reader(), nil
+	return w.Reader(), bytesToInt(b[:4]), nil
 }
 
 func readMessage(start byte, buf *readBuffer) (io.Reader, error) {
@@ -217,6 +250,9 @@ func readMessage(start byte, buf *readBuffer) (io.Reader, error) {
 	if err != nil {
 		return nil, err
 	}
+	if l < 4 || l > maxMessageLength {
+		return nil, fmt.Errorf("unable to parse length from message")
+	}
 
 	// Read the rest of the message into a []byte
 	// DEV: Subtract 4 to account for the length of the int32 we just read
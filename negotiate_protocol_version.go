@@ -0,0 +1,99 @@
+package pgproto
+
+import (
+	"io"
+)
+
+// NegotiateProtocolVersion is a server response sent when the client requested a newer minor
+// protocol version or protocol options the server does not support
+type NegotiateProtocolVersion struct {
+	MinorVersion     int
+	UnsupportedNames [][]byte
+}
+
+func (n *NegotiateProtocolVersion) server() {}
+
+// ParseNegotiateProtocolVersion will attempt to read a NegotiateProtocolVersion message from the io.Reader
+func ParseNegotiateProtocolVersion(r io.Reader) (*NegotiateProtocolVersion, error) {
+	b := newReadBuffer(r)
+
+	// 'v' [int32 - length] [int32 - minor version] [int32 - count] ([string - option] \0)...
+	err := b.ReadTag('v')
+	if err != nil {
+		return nil, err
+	}
+
+	b, err = b.ReadLength()
+	if err != nil {
+		return nil, err
+	}
+
+	n := &NegotiateProtocolVersion{}
+	n.MinorVersion, err = b.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+
+	// Each name takes at least its null terminator
+	count, err := b.ReadCount32(1)
+	if err != nil {
+		return nil, err
+	}
+
+	n.UnsupportedNames = make([][]byte, count)
+	for i := 0; i < count; i++ {
+		n.UnsupportedNames[i], err = b.ReadString(stripNull)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return n, nil
+}
+
+// Encode will return the byte representation of this message
+func (n *NegotiateProtocolVersion) Encode() []byte {
+	b := newWriteBuffer()
+	b.WriteInt(n.MinorVersion)
+	b.WriteInt(len(n.UnsupportedNames))
+	for _, name := range n.UnsupportedNames {
+		b.WriteString(name, writeNull)
+	}
+	b.Wrap('v')
+	return b.Bytes()
+}
+
+// AsMap method returns a common map representation of this message:
+//
+//   map[string]interface{}{
+//     "Type": "NegotiateProtocolVersion",
+//     "Payload": map[string]interface{}{
+//       "MinorVersion": <NegotiateProtocolVersion.MinorVersion>,
+//       "UnsupportedNames": <NegotiateProtocolVersion.UnsupportedNames>,
+//     },
+//   }
+func (n *NegotiateProtocolVersion) AsMap() map[string]interface{} {
+	names := make([]string, len(n.UnsupportedNames))
+	for i, name := range n.UnsupportedNames {
+		names[i] = string(name)
+	}
+	return map[string]interface{}{
+		"Type": "NegotiateProtocolVersion",
+		"Payload": map[string]interface{}{
+			"MinorVersion":     n.MinorVersion,
+			"UnsupportedNames": names,
+		},
+	}
+}
+
+func (n *NegotiateProtocolVersion) String() string { return messageToString(n) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (n *NegotiateProtocolVersion) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("NegotiateProtocolVersion", n)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (n *NegotiateProtocolVersion) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "NegotiateProtocolVersion", n)
+}
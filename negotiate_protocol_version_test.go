@@ -0,0 +1,84 @@
+package pgproto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+// 'v' [int32 - length] [int32 - minor version] [int32 - count] ([string - option] \0)...
+var rawNegotiateProtocolVersionMessage = []byte{
+	// Tag
+	'v',
+	// Length
+	'\x00', '\x00', '\x00', '\x13',
+	// Minor version
+	'\x00', '\x00', '\x00', '\x00',
+	// Count
+	'\x00', '\x00', '\x00', '\x01',
+	// Option "_pq_.a" \0
+	'_', 'p', 'q', '_', '.', 'a', '\x00',
+}
+
+type NegotiateProtocolVersionTestSuite struct {
+	suite.Suite
+}
+
+func TestNegotiateProtocolVersionTestSuite(t *testing.T) {
+	suite.Run(t, new(NegotiateProtocolVersionTestSuite))
+}
+
+func (s *NegotiateProtocolVersionTestSuite) Test_ParseNegotiateProtocolVersion() {
+	n, err := pgproto.ParseNegotiateProtocolVersion(bytes.NewReader(rawNegotiateProtocolVersionMessage))
+	s.Nil(err)
+	s.NotNil(n)
+	s.Equal(0, n.MinorVersion)
+	s.Equal([][]byte{[]byte("_pq_.a")}, n.UnsupportedNames)
+	s.Equal(rawNegotiateProtocolVersionMessage, n.Encode())
+
+	m, err := pgproto.ParseServerMessage(bytes.NewReader(rawNegotiateProtocolVersionMessage))
+	s.Nil(err)
+	s.Equal(n, m)
+}
+
+func (s *NegotiateProtocolVersionTestSuite) Test_ParseNegotiateProtocolVersion_Empty() {
+	n, err := pgproto.ParseNegotiateProtocolVersion(bytes.NewReader([]byte{}))
+	s.NotNil(err)
+	s.Nil(n)
+}
+
+func (s *NegotiateProtocolVersionTestSuite) Test_ParseNegotiateProtocolVersion_Malformed() {
+	// patch returns a copy of rawNegotiateProtocolVersionMessage with the bytes at offset replaced by b
+	patch := func(offset int, b ...byte) []byte {
+		raw := append([]byte{}, rawNegotiateProtocolVersionMessage...)
+		copy(raw[offset:], b)
+		return raw
+	}
+
+	for name, raw := range map[string][]byte{
+		"empty body":             {'v', '\x00', '\x00', '\x00', '\x04'},
+		"negative count":         patch(9, '\xff', '\xff', '\xff', '\xff'),
+		"too many names":         patch(9, '\x7f', '\xff', '\xff', '\xff'),
+		"message length below 4": patch(1, '\x00', '\x00', '\x00', '\x00'),
+	} {
+		s.NotPanics(func() {
+			n, err := pgproto.ParseNegotiateProtocolVersion(bytes.NewReader(raw))
+			s.NotNil(err, name)
+			s.Nil(n, name)
+		}, name)
+		s.NotPanics(func() {
+			m, err := pgproto.ParseServerMessage(bytes.NewReader(raw))
+			s.NotNil(err, name)
+			s.Nil(m, name)
+		}, name)
+	}
+}
+
+func (s *NegotiateProtocolVersionTestSuite) Test_NegotiateProtocolVersionEncode() {
+	n := &pgproto.NegotiateProtocolVersion{
+		UnsupportedNames: [][]byte{[]byte("_pq_.a")},
+	}
+	s.Equal(rawNegotiateProtocolVersionMessage, n.Encode())
+}
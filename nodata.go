@@ -40,3 +40,13 @@ func (n *NoData) AsMap() map[string]interface{} {
 }
 
 func (n *NoData) String() string { return messageToString(n) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (n *NoData) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("NoData", n)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (n *NoData) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "NoData", n)
+}
@@ -23,3 +23,13 @@ func (n *NoticeResponse) AsMap() map[string]interface{} {
 	return errorMap((*Error)(n), "NoticeResponse")
 }
 func (n *NoticeResponse) String() string { return messageToString(n) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (n *NoticeResponse) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("NoticeResponse", n)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (n *NoticeResponse) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "NoticeResponse", n)
+}
@@ -54,7 +54,7 @@ func (n *Notification) Encode() []byte {
 	buf.WriteInt(n.PID)
 	buf.WriteString(n.Channel, true)
 	buf.WriteString(n.Payload, true)
-	buf.Wrap('N')
+	buf.Wrap('A')
 	return buf.Bytes()
 }
 
@@ -70,3 +70,13 @@ func (n *Notification) AsMap() map[string]interface{} {
 }
 
 func (n *Notification) String() string { return messageToString(n) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (n *Notification) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("Notification", n)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (n *Notification) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "Notification", n)
+}
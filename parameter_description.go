@@ -4,17 +4,21 @@ import (
 	"io"
 )
 
+// ParameterDescription is a server response describing the parameters of a prepared statement
 type ParameterDescription struct {
 	OIDs []int
 }
 
+// DEV: ParameterDescription still implements ClientMessage, which it did before it was parsed as a
+//      server message, so existing code using it as one keeps compiling
 func (p *ParameterDescription) client() {}
+func (p *ParameterDescription) server() {}
 
 func ParseParameterDescription(r io.Reader) (*ParameterDescription, error) {
 	b := newReadBuffer(r)
 
 	// 't' [int32 - length] [int16 - parameter count] [int32 - parameter] ...
-	err := b.ReadTag('S')
+	err := b.ReadTag('t')
 	if err != nil {
 		return nil, err
 	}
@@ -24,7 +28,7 @@ func ParseParameterDescription(r io.Reader) (*ParameterDescription, error) {
 		return nil, err
 	}
 
-	count, err := buf.ReadInt16()
+	count, err := buf.ReadCount(4)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +38,7 @@ func ParseParameterDescription(r io.Reader) (*ParameterDescription, error) {
 	}
 
 	for i := 0; i < count; i++ {
-		p.OIDs[i], err = b.ReadInt()
+		p.OIDs[i], err = buf.ReadInt()
 		if err != nil {
 			return nil, err
 		}
@@ -64,3 +68,13 @@ func (p *ParameterDescription) AsMap() map[string]interface{} {
 }
 
 func (p *ParameterDescription) String() string { return messageToString(p) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (p *ParameterDescription) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("ParameterDescription", p)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (p *ParameterDescription) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "ParameterDescription", p)
+}
@@ -0,0 +1,54 @@
+package pgproto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+// 't' [int32 - length] [int16 - parameter count] [int32 - parameter] ...
+var rawParameterDescriptionMessage = []byte{
+	// Tag
+	't',
+	// Length
+	'\x00', '\x00', '\x00', '\x0e',
+	// Parameter count
+	'\x00', '\x02',
+	// int4
+	'\x00', '\x00', '\x00', '\x17',
+	// text
+	'\x00', '\x00', '\x00', '\x19',
+}
+
+type ParameterDescriptionTestSuite struct {
+	suite.Suite
+}
+
+func TestParameterDescriptionTestSuite(t *testing.T) {
+	suite.Run(t, new(ParameterDescriptionTestSuite))
+}
+
+func (s *ParameterDescriptionTestSuite) Test_ParseParameterDescription() {
+	m, err := pgproto.ParseServerMessage(bytes.NewReader(rawParameterDescriptionMessage))
+	s.Nil(err)
+	p, ok := m.(*pgproto.ParameterDescription)
+	s.True(ok)
+	s.Equal([]int{23, 25}, p.OIDs)
+	s.Equal(rawParameterDescriptionMessage, p.Encode())
+
+	// Only servers send ParameterDescription messages, it remains a ClientMessage for compatibility
+	var c pgproto.ClientMessage = p
+	s.NotNil(c)
+	_, err = pgproto.ParseClientMessage(bytes.NewReader(rawParameterDescriptionMessage))
+	s.NotNil(err)
+}
+
+func (s *ParameterDescriptionTestSuite) Test_ParseParameterDescription_Malformed() {
+	raw := append([]byte{}, rawParameterDescriptionMessage...)
+	raw[6] = '\x03'
+	p, err := pgproto.ParseParameterDescription(bytes.NewReader(raw))
+	s.NotNil(err)
+	s.Nil(p)
+}
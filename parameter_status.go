@@ -60,3 +60,13 @@ func (p *ParameterStatus) AsMap() map[string]interface{} {
 }
 
 func (p *ParameterStatus) String() string { return messageToString(p) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (p *ParameterStatus) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("ParameterStatus", p)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (p *ParameterStatus) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "ParameterStatus", p)
+}
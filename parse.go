@@ -38,7 +38,7 @@ func ParseParse(r io.Reader) (*Parse, error) {
 		return nil, err
 	}
 
-	count, err := buf.ReadInt16()
+	count, err := buf.ReadCount(4)
 	if err != nil {
 		return nil, err
 	}
@@ -79,3 +79,13 @@ func (p *Parse) AsMap() map[string]interface{} {
 }
 
 func (p *Parse) String() string { return messageToString(p) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (p *Parse) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("Parse", p)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (p *Parse) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "Parse", p)
+}
@@ -54,3 +54,13 @@ func (p *ParseComplete) AsMap() map[string]interface{} {
 }
 
 func (p *ParseComplete) String() string { return messageToString(p) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (p *ParseComplete) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("ParseComplete", p)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (p *ParseComplete) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "ParseComplete", p)
+}
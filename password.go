@@ -56,8 +56,18 @@ func (p *PasswordMessage) AsMap() map[string]interface{} {
 	return map[string]interface{}{
 		"Type": "PasswordMessage",
 		"Payload": map[string]interface{}{
-			"Password": p.Password,
+			"Password": redactString(p, "Password", p.Password),
 		},
 	}
 }
 func (p *PasswordMessage) String() string { return messageToString(p) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (p *PasswordMessage) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("PasswordMessage", p)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (p *PasswordMessage) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "PasswordMessage", p)
+}
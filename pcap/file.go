@@ -0,0 +1,333 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"time"
+)
+
+// ErrInvalidCapture is returned when the input is neither a pcap nor a pcapng file
+var ErrInvalidCapture = errors.New("pcap: not a pcap or pcapng file")
+
+// maxPacketSize bounds the memory allocated for a single packet or block of a capture file
+const maxPacketSize = 16 * 1024 * 1024
+
+// pcap file magic numbers, as read in big endian order
+const (
+	pcapMicroseconds        = 0xa1b2c3d4
+	pcapMicrosecondsSwapped = 0xd4c3b2a1
+	pcapNanoseconds         = 0xa1b23c4d
+	pcapNanosecondsSwapped  = 0x4d3cb2a1
+)
+
+// pcapng block types
+const (
+	blockSectionHeader        = 0x0a0d0d0a
+	blockInterfaceDescription = 0x00000001
+	blockPacket               = 0x00000002
+	blockSimplePacket         = 0x00000003
+	blockEnhancedPacket       = 0x00000006
+)
+
+// pcapng interface description options
+const (
+	optionEndOfOptions = 0
+	optionTSResolution = 9
+	optionTSOffset     = 14
+)
+
+// Packet is a single packet read from a capture file
+type Packet struct {
+	Time time.Time
+
+	// LinkType is the link layer header type of Data, e.g. 1 for Ethernet
+	LinkType uint32
+
+	// Data is the packet as captured starting with the link layer header
+	Data []byte
+
+	// Truncated is set when the packet was longer than the capture's snapshot length
+	Truncated bool
+}
+
+// PacketReader reads the packets of a pcap or pcapng file
+type PacketReader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+
+	// pcap files have a single link type and timestamp resolution
+	linkType    uint32
+	nanoseconds bool
+
+	// pcapng files describe them per interface, ng is set for pcapng files
+	ng         bool
+	interfaces []*pcapngInterface
+}
+
+type pcapngInterface struct {
+	linkType uint32
+	snapLen  uint32
+
+	// resolution is the timestamp resolution, a negative power of 10 or of 2 when binary is set
+	resolution uint8
+	binary     bool
+	offset     int64
+}
+
+// NewPacketReader will read the file header from r and return a PacketReader for its packets
+func NewPacketReader(r io.Reader) (*PacketReader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(4)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, ErrInvalidCapture
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PacketReader{r: br}
+	switch binary.BigEndian.Uint32(header) {
+	case blockSectionHeader:
+		p.ng = true
+		// The section header is read along with the other blocks
+		return p, nil
+	case pcapMicroseconds:
+		p.order = binary.BigEndian
+	case pcapMicrosecondsSwapped:
+		p.order = binary.LittleEndian
+	case pcapNanoseconds:
+		p.order = binary.BigEndian
+		p.nanoseconds = true
+	case pcapNanosecondsSwapped:
+		p.order = binary.LittleEndian
+		p.nanoseconds = true
+	default:
+		return nil, ErrInvalidCapture
+	}
+
+	// [uint32 - magic] [uint16 - major] [uint16 - minor] [int32 - zone] [uint32 - sigfigs] [uint32 - snaplen] [uint32 - link type]
+	buf := make([]byte, 24)
+	_, err = io.ReadFull(br, buf)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	p.linkType = p.order.Uint32(buf[20:]) & 0x0fffffff
+	return p, nil
+}
+
+// Next returns the next packet, or io.EOF once every packet has been read
+func (p *PacketReader) Next() (*Packet, error) {
+	if p.ng {
+		return p.nextBlock()
+	}
+
+	// [uint32 - seconds] [uint32 - microseconds or nanoseconds] [uint32 - captured length] [uint32 - original length]
+	buf := make([]byte, 16)
+	_, err := io.ReadFull(p.r, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	captured := p.order.Uint32(buf[8:])
+	if captured > maxPacketSize {
+		return nil, fmt.Errorf("pcap: packet of %d bytes is too large", captured)
+	}
+	data := make([]byte, captured)
+	_, err = io.ReadFull(p.r, data)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	frac := time.Duration(p.order.Uint32(buf[4:]))
+	if !p.nanoseconds {
+		frac *= time.Microsecond
+	}
+	return &Packet{
+		Time:      time.Unix(int64(p.order.Uint32(buf)), int64(frac)).UTC(),
+		LinkType:  p.linkType,
+		Data:      data,
+		Truncated: captured < p.order.Uint32(buf[12:]),
+	}, nil
+}
+
+// nextBlock reads pcapng blocks until a packet block is found
+func (p *PacketReader) nextBlock() (*Packet, error) {
+	for {
+		typ, body, err := p.readBlock()
+		if err != nil {
+			return nil, err
+		}
+
+		switch typ {
+		case blockInterfaceDescription:
+			// [uint16 - link type] [uint16 - reserved] [uint32 - snaplen] [options]
+			if len(body) < 8 {
+				return nil, fmt.Errorf("pcap: invalid interface description block")
+			}
+			iface := &pcapngInterface{
+				linkType:   uint32(p.order.Uint16(body)),
+				snapLen:    p.order.Uint32(body[4:]),
+				resolution: 6,
+			}
+			p.readInterfaceOptions(iface, body[8:])
+			p.interfaces = append(p.interfaces, iface)
+
+		case blockEnhancedPacket, blockPacket:
+			// Enhanced packet:
+			//   [uint32 - interface] [uint32 - timestamp high] [uint32 - timestamp low] [uint32 - captured length] [uint32 - original length] [data]
+			// Obsolete packet:
+			//   [uint16 - interface] [uint16 - drops] [uint32 - timestamp high] [uint32 - timestamp low] [uint32 - captured length] [uint32 - original length] [data]
+			if len(body) < 20 {
+				return nil, fmt.Errorf("pcap: invalid packet block")
+			}
+			id := p.order.Uint32(body)
+			if typ == blockPacket {
+				id = uint32(p.order.Uint16(body))
+			}
+			if id >= uint32(len(p.interfaces)) {
+				return nil, fmt.Errorf("pcap: packet block for unknown interface %d", id)
+			}
+			iface := p.interfaces[id]
+
+			captured := p.order.Uint32(body[12:])
+			if uint64(captured) > uint64(len(body)-20) {
+				return nil, fmt.Errorf("pcap: invalid packet block")
+			}
+			ts := uint64(p.order.Uint32(body[4:]))<<32 | uint64(p.order.Uint32(body[8:]))
+			return &Packet{
+				Time:      iface.time(ts),
+				LinkType:  iface.linkType,
+				Data:      body[20 : 20+captured],
+				Truncated: captured < p.order.Uint32(body[16:]),
+			}, nil
+
+		case blockSimplePacket:
+			// [uint32 - original length] [data]
+			if len(body) < 4 || len(p.interfaces) == 0 {
+				return nil, fmt.Errorf("pcap: invalid simple packet block")
+			}
+			iface := p.interfaces[0]
+			original := p.order.Uint32(body)
+			captured := original
+			if iface.snapLen > 0 && captured > iface.snapLen {
+				captured = iface.snapLen
+			}
+			if uint64(captured) > uint64(len(body)-4) {
+				captured = uint32(len(body) - 4)
+			}
+			// Simple packets do not have a timestamp
+			return &Packet{
+				LinkType:  iface.linkType,
+				Data:      body[4 : 4+captured],
+				Truncated: captured < original,
+			}, nil
+		}
+	}
+}
+
+// readBlock reads the next pcapng block, returning its type and body
+func (p *PacketReader) readBlock() (uint32, []byte, error) {
+	// [uint32 - type] [uint32 - total length] [body] [uint32 - total length]
+	header := make([]byte, 8)
+	_, err := io.ReadFull(p.r, header)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	typ := binary.BigEndian.Uint32(header)
+	if typ == blockSectionHeader {
+		// A new section may change the byte order, which the byte order magic following the header tells
+		magic, err := p.r.Peek(4)
+		if err != nil {
+			return 0, nil, unexpectedEOF(err)
+		}
+		switch binary.BigEndian.Uint32(magic) {
+		case 0x1a2b3c4d:
+			p.order = binary.BigEndian
+		case 0x4d3c2b1a:
+			p.order = binary.LittleEndian
+		default:
+			return 0, nil, ErrInvalidCapture
+		}
+		// Interface IDs are scoped to their section
+		p.interfaces = nil
+	} else if p.order == nil {
+		return 0, nil, ErrInvalidCapture
+	} else {
+		typ = p.order.Uint32(header)
+	}
+
+	length := p.order.Uint32(header[4:])
+	if length < 12 || length%4 != 0 || length > maxPacketSize {
+		return 0, nil, fmt.Errorf("pcap: invalid block length %d", length)
+	}
+	body := make([]byte, length-8)
+	_, err = io.ReadFull(p.r, body)
+	if err != nil {
+		return 0, nil, unexpectedEOF(err)
+	}
+	return typ, body[:len(body)-4], nil
+}
+
+// readInterfaceOptions reads the options of an interface description block which affect timestamps
+func (p *PacketReader) readInterfaceOptions(iface *pcapngInterface, options []byte) {
+	// [uint16 - code] [uint16 - length] [value padded to 4 bytes]
+	for len(options) >= 4 {
+		code := p.order.Uint16(options)
+		length := int(p.order.Uint16(options[2:]))
+		if code == optionEndOfOptions || 4+length > len(options) {
+			return
+		}
+		value := options[4 : 4+length]
+
+		switch {
+		case code == optionTSResolution && length == 1:
+			iface.binary = value[0]&0x80 != 0
+			iface.resolution = value[0] & 0x7f
+		case code == optionTSOffset && length == 8:
+			iface.offset = int64(p.order.Uint64(value))
+		}
+
+		options = options[4+(length+3)&^3:]
+	}
+}
+
+// time converts a timestamp in the interface's resolution to a time.Time
+func (i *pcapngInterface) time(ts uint64) time.Time {
+	var sec, nsec uint64
+	if i.binary {
+		if i.resolution >= 64 {
+			return time.Unix(i.offset, 0).UTC()
+		}
+		sec = ts >> i.resolution
+		// The fraction of a second scaled to nanoseconds, without overflowing 64 bits
+		hi, lo := bits.Mul64(ts&(1<<i.resolution-1), uint64(time.Second))
+		nsec, _ = bits.Div64(hi, lo, 1<<i.resolution)
+	} else {
+		unit := uint64(1)
+		for n := uint8(0); n < i.resolution && n < 19; n++ {
+			unit *= 10
+		}
+		sec = ts / unit
+		nsec = ts % unit
+		for n := i.resolution; n < 9; n++ {
+			nsec *= 10
+		}
+		for n := i.resolution; n > 9; n-- {
+			nsec /= 10
+		}
+	}
+	return time.Unix(i.offset+int64(sec), int64(nsec)).UTC()
+}
+
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF, since a packet was only partially read
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,209 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+// Link layer header types, see https://www.tcpdump.org/linktypes.html
+const (
+	LinkTypeNull      = 0
+	LinkTypeEthernet  = 1
+	LinkTypeRaw       = 101
+	LinkTypeLoop      = 108
+	LinkTypeLinuxSLL  = 113
+	LinkTypeIPv4      = 228
+	LinkTypeIPv6      = 229
+	LinkTypeLinuxSLL2 = 276
+)
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+	etherTypeQinQ = 0x88a8
+)
+
+const protocolTCP = 6
+
+// TCP flags
+const (
+	tcpFIN = 0x01
+	tcpSYN = 0x02
+	tcpRST = 0x04
+	tcpACK = 0x10
+)
+
+// segment is a TCP segment decoded from a packet
+type segment struct {
+	src, dst netip.AddrPort
+	seq      uint32
+	flags    byte
+	payload  []byte
+}
+
+// decodePacket returns the TCP segment carried by the packet, or false when it is not a TCP packet
+// this package can decode
+func decodePacket(p *Packet) (*segment, bool) {
+	data := p.Data
+	var etherType uint16
+
+	switch p.LinkType {
+	case LinkTypeNull, LinkTypeLoop:
+		// [uint32 - address family, in the capturing host's byte order]
+		if len(data) < 4 {
+			return nil, false
+		}
+		family := binary.LittleEndian.Uint32(data)
+		if family > 0xffff {
+			family = binary.BigEndian.Uint32(data)
+		}
+		data = data[4:]
+		switch family {
+		case 2:
+			etherType = etherTypeIPv4
+		case 10, 24, 28, 30:
+			// AF_INET6 differs between Linux, BSDs and Darwin
+			etherType = etherTypeIPv6
+		default:
+			return nil, false
+		}
+
+	case LinkTypeEthernet:
+		// [6 bytes - destination] [6 bytes - source] [uint16 - ether type]
+		if len(data) < 14 {
+			return nil, false
+		}
+		etherType = binary.BigEndian.Uint16(data[12:])
+		data = data[14:]
+		for etherType == etherTypeVLAN || etherType == etherTypeQinQ {
+			// [uint16 - tag control] [uint16 - ether type]
+			if len(data) < 4 {
+				return nil, false
+			}
+			etherType = binary.BigEndian.Uint16(data[2:])
+			data = data[4:]
+		}
+
+	case LinkTypeLinuxSLL:
+		// [uint16 - packet type] [uint16 - address type] [uint16 - address length] [8 bytes - address] [uint16 - protocol]
+		if len(data) < 16 {
+			return nil, false
+		}
+		etherType = binary.BigEndian.Uint16(data[14:])
+		data = data[16:]
+
+	case LinkTypeLinuxSLL2:
+		// [uint16 - protocol] [uint16 - reserved] [uint32 - interface] [uint16 - address type] [byte - packet type] [byte - address length] [8 bytes - address]
+		if len(data) < 20 {
+			return nil, false
+		}
+		etherType = binary.BigEndian.Uint16(data)
+		data = data[20:]
+
+	case LinkTypeRaw, LinkTypeIPv4, LinkTypeIPv6:
+		if len(data) < 1 {
+			return nil, false
+		}
+		switch data[0] >> 4 {
+		case 4:
+			etherType = etherTypeIPv4
+		case 6:
+			etherType = etherTypeIPv6
+		default:
+			return nil, false
+		}
+
+	default:
+		return nil, false
+	}
+
+	switch etherType {
+	case etherTypeIPv4:
+		return decodeIPv4(data)
+	case etherTypeIPv6:
+		return decodeIPv6(data)
+	}
+	return nil, false
+}
+
+func decodeIPv4(data []byte) (*segment, bool) {
+	// [byte - version and header length] [byte - TOS] [uint16 - total length] [uint16 - ID] [uint16 - flags and fragment offset]
+	// [byte - TTL] [byte - protocol] [uint16 - checksum] [4 bytes - source] [4 bytes - destination] [options]
+	if len(data) < 20 || data[0]>>4 != 4 {
+		return nil, false
+	}
+	headerLen := int(data[0]&0x0f) * 4
+	total := int(binary.BigEndian.Uint16(data[2:]))
+	if headerLen < 20 || total < headerLen || len(data) < headerLen {
+		return nil, false
+	}
+	// Fragmented packets are not reassembled, PostgreSQL traffic is not expected to be fragmented
+	if binary.BigEndian.Uint16(data[6:])&0x3fff != 0 || data[9] != protocolTCP {
+		return nil, false
+	}
+	// Ethernet frames can be padded past the end of the IP packet
+	if len(data) > total {
+		data = data[:total]
+	}
+
+	src, _ := netip.AddrFromSlice(data[12:16])
+	dst, _ := netip.AddrFromSlice(data[16:20])
+	return decodeTCP(src, dst, data[headerLen:])
+}
+
+func decodeIPv6(data []byte) (*segment, bool) {
+	// [uint32 - version, traffic class and flow label] [uint16 - payload length] [byte - next header] [byte - hop limit]
+	// [16 bytes - source] [16 bytes - destination]
+	if len(data) < 40 || data[0]>>4 != 6 {
+		return nil, false
+	}
+	payload := int(binary.BigEndian.Uint16(data[4:]))
+	next := data[6]
+	src, _ := netip.AddrFromSlice(data[8:24])
+	dst, _ := netip.AddrFromSlice(data[24:40])
+	data = data[40:]
+	if len(data) > payload {
+		data = data[:payload]
+	}
+
+	for next != protocolTCP {
+		switch next {
+		case 0, 43, 60:
+			// Hop-by-hop, routing and destination options
+			//   [byte - next header] [byte - length in 8 bytes units, not including the first 8] [options]
+			if len(data) < 8 {
+				return nil, false
+			}
+			length := (int(data[1]) + 1) * 8
+			if len(data) < length {
+				return nil, false
+			}
+			next = data[0]
+			data = data[length:]
+		default:
+			// Fragments and other protocols
+			return nil, false
+		}
+	}
+	return decodeTCP(src, dst, data)
+}
+
+func decodeTCP(src netip.Addr, dst netip.Addr, data []byte) (*segment, bool) {
+	// [uint16 - source port] [uint16 - destination port] [uint32 - sequence] [uint32 - acknowledgment]
+	// [byte - data offset] [byte - flags] [uint16 - window] [uint16 - checksum] [uint16 - urgent pointer] [options]
+	if len(data) < 20 {
+		return nil, false
+	}
+	offset := int(data[12]>>4) * 4
+	if offset < 20 || len(data) < offset {
+		return nil, false
+	}
+	return &segment{
+		src:     netip.AddrPortFrom(src.Unmap(), binary.BigEndian.Uint16(data)),
+		dst:     netip.AddrPortFrom(dst.Unmap(), binary.BigEndian.Uint16(data[2:])),
+		seq:     binary.BigEndian.Uint32(data[4:]),
+		flags:   data[13],
+		payload: data[offset:],
+	}, true
+}
@@ -0,0 +1,245 @@
+/*
+Package pcap decodes PostgreSQL traffic from packet captures.
+
+Both pcap and pcapng files, as written by tcpdump or Wireshark, are supported without cgo or libpcap.
+TCP connections to the configured ports are reassembled and each direction is parsed with
+pgproto.ParseClientMessage and pgproto.ParseServerMessage.
+
+	d, err := pcap.NewDecoder(f, 5432)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for {
+		m, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		log.Println(m.Time, m.Conn, m.Direction, m.Message)
+	}
+
+Connections are decoded until they are encrypted with SSL or GSSAPI. IP fragments are not reassembled.
+*/
+package pcap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/record"
+)
+
+// DefaultPort is the port decoded when NewDecoder is not given any
+const DefaultPort = 5432
+
+// ErrEncrypted is returned in a *StreamError once a connection is encrypted, its following messages
+// can not be decoded
+var ErrEncrypted = errors.New("connection is encrypted")
+
+// Conn is a TCP connection between a PostgreSQL client and server found in a capture
+type Conn struct {
+	// ID numbers the connections in the order they were first seen in the capture, starting at 1
+	ID uint64
+
+	Client netip.AddrPort
+	Server netip.AddrPort
+}
+
+func (c *Conn) String() string {
+	return fmt.Sprintf("#%d %s-%s", c.ID, c.Client, c.Server)
+}
+
+// Message is a single message decoded from a capture
+type Message struct {
+	// Time is the capture time of the packet completing the message
+	Time time.Time
+
+	Conn      *Conn
+	Direction record.Direction
+
+	// Raw is the message as sent on the wire
+	Raw []byte
+
+	// Message is Raw parsed, it is nil when Raw could not be parsed
+	Message pgproto.Message
+}
+
+// ParseError is returned by Decoder.Next along with the message when it could not be parsed,
+// the Decoder can still be used to decode the following messages
+type ParseError struct {
+	Message *Message
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("pcap: %s unable to parse %s message: %v", e.Message.Conn, e.Message.Direction, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// StreamError is returned by Decoder.Next when a direction of a connection can not be decoded any further,
+// e.g. because packets are missing from the capture, the Decoder can still be used to decode the
+// following messages of other connections
+type StreamError struct {
+	Conn      *Conn
+	Direction record.Direction
+	Time      time.Time
+	Err       error
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("pcap: %s %s stream: %v", e.Conn, e.Direction, e.Err)
+}
+
+func (e *StreamError) Unwrap() error { return e.Err }
+
+// Decoder decodes the PostgreSQL messages of a capture
+type Decoder struct {
+	packets *PacketReader
+	ports   map[uint16]bool
+
+	conns  map[connKey]*conn
+	nextID uint64
+
+	// queue holds the messages and errors decoded but not yet returned by Next
+	queue []decoded
+	eof   bool
+}
+
+type decoded struct {
+	msg *Message
+	err error
+}
+
+// NewDecoder returns a Decoder for the capture read from r, decoding connections to any of ports,
+// or to DefaultPort when none are given
+func NewDecoder(r io.Reader, ports ...uint16) (*Decoder, error) {
+	packets, err := NewPacketReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ports) == 0 {
+		ports = []uint16{DefaultPort}
+	}
+	d := &Decoder{
+		packets: packets,
+		ports:   make(map[uint16]bool),
+		conns:   make(map[connKey]*conn),
+	}
+	for _, p := range ports {
+		d.ports[p] = true
+	}
+	return d, nil
+}
+
+// Next returns the next message in the order they were completed in the capture, or io.EOF once
+// every packet has been read
+//
+// A *ParseError is returned along with a message which can not be parsed and a *StreamError when
+// a connection can not be decoded any further, Next can be called again after either.
+func (d *Decoder) Next() (*Message, error) {
+	for len(d.queue) == 0 {
+		if d.eof {
+			return nil, io.EOF
+		}
+
+		p, err := d.packets.Next()
+		if err == io.EOF {
+			d.eof = true
+			d.finish()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		d.packet(p)
+	}
+
+	next := d.queue[0]
+	d.queue = d.queue[1:]
+	return next.msg, next.err
+}
+
+// packet passes the TCP segment of p, if any, to the connection it belongs to
+func (d *Decoder) packet(p *Packet) {
+	seg, ok := decodePacket(p)
+	if !ok {
+		return
+	}
+
+	syn := seg.flags&(tcpSYN|tcpACK) == tcpSYN
+	c, fromClient := d.conns[connKey{seg.src, seg.dst}], true
+	if c == nil {
+		c, fromClient = d.conns[connKey{seg.dst, seg.src}], false
+	}
+
+	// A new connection reusing the addresses of a closed one
+	if c != nil && c.closed && syn {
+		c = nil
+	}
+
+	if c == nil {
+		var key connKey
+		switch {
+		case syn, d.ports[seg.dst.Port()]:
+			key = connKey{seg.src, seg.dst}
+		case d.ports[seg.src.Port()]:
+			key = connKey{seg.dst, seg.src}
+		default:
+			return
+		}
+		if !d.ports[key.server.Port()] {
+			return
+		}
+
+		d.nextID++
+		c = newConn(&Conn{ID: d.nextID, Client: key.client, Server: key.server}, !syn)
+		d.conns[key] = c
+		fromClient = key.client == seg.src
+	}
+
+	c.segment(d, p, seg, fromClient)
+}
+
+// finish reports the connections which could not be completely decoded once the capture ended
+func (d *Decoder) finish() {
+	conns := make([]*conn, 0, len(d.conns))
+	for _, c := range d.conns {
+		conns = append(conns, c)
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].ID < conns[j].ID })
+
+	for _, c := range conns {
+		for _, h := range []*half{c.client, c.server} {
+			if h.broken || c.encrypted {
+				continue
+			}
+			if len(h.pending) > 0 {
+				d.streamError(c, h, c.last, fmt.Errorf("capture is missing data, %d bytes could not be reassembled", h.pendingBytes))
+			} else if len(h.buf) > 0 {
+				d.streamError(c, h, c.last, fmt.Errorf("capture ended in the middle of a message"))
+			}
+		}
+	}
+}
+
+func (d *Decoder) message(m *Message, err error) {
+	if err != nil {
+		err = &ParseError{Message: m, Err: err}
+	}
+	d.queue = append(d.queue, decoded{msg: m, err: err})
+}
+
+func (d *Decoder) streamError(c *conn, h *half, t time.Time, err error) {
+	h.broken = true
+	d.queue = append(d.queue, decoded{err: &StreamError{Conn: c.Conn, Direction: h.direction, Time: t, Err: err}})
+}
@@ -0,0 +1,216 @@
+package pcap_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pcap"
+	"github.com/c653labs/pgproto/record"
+	"github.com/stretchr/testify/suite"
+)
+
+type PcapTestSuite struct {
+	suite.Suite
+}
+
+func TestPcapTestSuite(t *testing.T) {
+	suite.Run(t, new(PcapTestSuite))
+}
+
+type expectedMessage struct {
+	conn      uint64
+	direction record.Direction
+	message   pgproto.Message
+}
+
+// decode returns every message and error decoded from the capture fixture
+func (s *PcapTestSuite) decode(name string, ports ...uint16) ([]*pcap.Message, []error) {
+	f, err := os.Open("testdata/" + name)
+	s.Require().Nil(err)
+	defer f.Close()
+
+	d, err := pcap.NewDecoder(f, ports...)
+	s.Require().Nil(err)
+
+	msgs := make([]*pcap.Message, 0)
+	errs := make([]error, 0)
+	for {
+		m, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, errs
+}
+
+func (s *PcapTestSuite) assertMessages(expected []expectedMessage, msgs []*pcap.Message) {
+	s.Require().Len(msgs, len(expected))
+	for i, e := range expected {
+		s.Equal(e.conn, msgs[i].Conn.ID)
+		s.Equal(e.direction, msgs[i].Direction)
+		s.Equal(e.message.Encode(), msgs[i].Raw)
+		s.IsType(e.message, msgs[i].Message)
+	}
+}
+
+// The pcap fixture holds a single IPv4 connection over Ethernet, starting with its TCP handshake
+// and with a query split in two segments captured out of order and retransmitted
+func (s *PcapTestSuite) Test_Pcap() {
+	msgs, errs := s.decode("session.pcap")
+	s.Empty(errs)
+
+	password := &pgproto.PasswordMessage{}
+	password.SetPassword([]byte("pgproto"), []byte("secret"), []byte{'\x01', '\x02', '\x03', '\x04'})
+	s.assertMessages([]expectedMessage{
+		{1, record.Frontend, &pgproto.StartupMessage{SSLRequest: true}},
+		{1, record.Backend, &pgproto.SSLResponse{Accepted: false}},
+		{1, record.Frontend, &pgproto.StartupMessage{Options: map[string][]byte{"user": []byte("pgproto"), "database": []byte("pgproto")}}},
+		{1, record.Backend, &pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodMD5, Salt: []byte{'\x01', '\x02', '\x03', '\x04'}}},
+		{1, record.Frontend, password},
+		{1, record.Backend, &pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodOK}},
+		{1, record.Backend, &pgproto.ParameterStatus{Name: []byte("server_version"), Value: []byte("16.1")}},
+		{1, record.Backend, &pgproto.BackendKeyData{PID: 1234, Key: 5678}},
+		{1, record.Backend, &pgproto.ReadyForQuery{Status: pgproto.READY_IDLE}},
+		{1, record.Frontend, &pgproto.SimpleQuery{Query: []byte("SELECT 1")}},
+		{1, record.Backend, &pgproto.RowDescription{Fields: []pgproto.RowField{{ColumnName: []byte("?column?"), TypeOID: 23, ColumnLength: 4, TypeModifier: -1}}}},
+		{1, record.Backend, &pgproto.DataRow{Fields: [][]byte{[]byte("1")}}},
+		{1, record.Backend, &pgproto.CommandCompletion{Tag: []byte("SELECT 1")}},
+		{1, record.Backend, &pgproto.ReadyForQuery{Status: pgproto.READY_IDLE}},
+		{1, record.Frontend, &pgproto.Termination{}},
+	}, msgs)
+
+	conn := msgs[0].Conn
+	s.Equal(netip.MustParseAddrPort("10.0.0.1:51234"), conn.Client)
+	s.Equal(netip.MustParseAddrPort("10.0.0.2:5432"), conn.Server)
+	s.Equal("#1 10.0.0.1:51234-10.0.0.2:5432", conn.String())
+
+	s.Equal(time.Date(2024, 1, 2, 15, 4, 5, 6000000, time.UTC), msgs[0].Time)
+	// The query is complete once its first segment is retransmitted
+	s.Equal(time.Date(2024, 1, 2, 15, 4, 5, 15000000, time.UTC), msgs[9].Time)
+	s.Equal(&pgproto.SimpleQuery{Query: []byte("SELECT 1")}, msgs[9].Message)
+}
+
+// The pcapng fixture holds IPv6 connections over Linux cooked capture v2 with nanosecond timestamps,
+// one established before the capture started and one negotiating SSL
+func (s *PcapTestSuite) Test_Pcapng() {
+	msgs, errs := s.decode("session.pcapng", 6432)
+
+	s.assertMessages([]expectedMessage{
+		{1, record.Frontend, &pgproto.Parse{Name: []byte("s1"), Query: []byte("SELECT $1::int")}},
+		{1, record.Frontend, &pgproto.Bind{Statement: []byte("s1"), Parameters: [][]byte{[]byte("42")}}},
+		{1, record.Frontend, &pgproto.Execute{}},
+		{1, record.Frontend, &pgproto.Sync{}},
+		{1, record.Backend, &pgproto.ParseComplete{}},
+		{1, record.Backend, &pgproto.BindComplete{}},
+		{1, record.Backend, &pgproto.DataRow{Fields: [][]byte{[]byte("42")}}},
+		{1, record.Backend, &pgproto.CommandCompletion{Tag: []byte("SELECT 1")}},
+		{1, record.Backend, &pgproto.ReadyForQuery{Status: pgproto.READY_IDLE}},
+		{2, record.Frontend, &pgproto.StartupMessage{SSLRequest: true}},
+		{2, record.Backend, &pgproto.SSLResponse{Accepted: true}},
+	}, msgs)
+
+	s.Equal(netip.MustParseAddrPort("[fd00::1]:40001"), msgs[0].Conn.Client)
+	s.Equal(netip.MustParseAddrPort("[fd00::2]:6432"), msgs[0].Conn.Server)
+	s.Equal(time.Date(2024, 1, 2, 15, 4, 5, 1500000, time.UTC), msgs[0].Time)
+
+	// The second connection can not be decoded once encrypted
+	s.Require().Len(errs, 1)
+	var serr *pcap.StreamError
+	s.Require().True(errors.As(errs[0], &serr))
+	s.True(errors.Is(errs[0], pcap.ErrEncrypted))
+	s.Equal(uint64(2), serr.Conn.ID)
+}
+
+func (s *PcapTestSuite) Test_Ports() {
+	// Connections to other ports are ignored
+	msgs, errs := s.decode("session.pcapng")
+	s.Empty(msgs)
+	s.Empty(errs)
+}
+
+func (s *PcapTestSuite) Test_PacketReader() {
+	f, err := os.Open("testdata/session.pcap")
+	s.Require().Nil(err)
+	defer f.Close()
+
+	r, err := pcap.NewPacketReader(f)
+	s.Require().Nil(err)
+
+	count := 0
+	for {
+		p, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		s.Require().Nil(err)
+		s.Equal(uint32(pcap.LinkTypeEthernet), p.LinkType)
+		s.False(p.Truncated)
+		count++
+	}
+	// 16 packets of the connection and an unrelated HTTP request
+	s.Equal(17, count)
+}
+
+func (s *PcapTestSuite) Test_Truncated() {
+	raw, err := os.ReadFile("testdata/session.pcap")
+	s.Require().Nil(err)
+
+	d, err := pcap.NewDecoder(bytes.NewReader(raw[:len(raw)-10]))
+	s.Require().Nil(err)
+	for err == nil {
+		_, err = d.Next()
+	}
+	s.Equal(io.ErrUnexpectedEOF, err)
+}
+
+func (s *PcapTestSuite) Test_InvalidCapture() {
+	_, err := pcap.NewDecoder(bytes.NewReader([]byte("SELECT 1")))
+	s.Equal(pcap.ErrInvalidCapture, err)
+
+	_, err = pcap.NewDecoder(bytes.NewReader(nil))
+	s.Equal(pcap.ErrInvalidCapture, err)
+}
+
+func (s *PcapTestSuite) Test_ParseError() {
+	raw, err := os.ReadFile("testdata/session.pcap")
+	s.Require().Nil(err)
+
+	// Claim more row description fields than the message holds
+	i := bytes.Index(raw, []byte("?column?"))
+	s.Require().True(i > 2)
+	raw[i-2], raw[i-1] = '\x7f', '\xff'
+
+	d, err := pcap.NewDecoder(bytes.NewReader(raw))
+	s.Require().Nil(err)
+
+	var perr *pcap.ParseError
+	count := 0
+	for {
+		m, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.Require().True(errors.As(err, &perr), err)
+			s.Equal(perr.Message, m)
+			continue
+		}
+		count++
+	}
+	s.Require().NotNil(perr)
+	s.Equal(byte('T'), perr.Message.Raw[0])
+	s.True(perr.Message.Message == nil, "%#v", perr.Message.Message)
+	// The following messages are still decoded
+	s.Equal(14, count)
+}
@@ -0,0 +1,276 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/record"
+)
+
+// maxPending bounds the out of order data buffered for a direction of a connection, once exceeded
+// the missing data is assumed to not be part of the capture
+const maxPending = 4 * 1024 * 1024
+
+// maxMessageSize bounds the length of a message, larger lengths mean the stream is not PostgreSQL
+// traffic or was not decoded from the start of a message
+const maxMessageSize = 1 << 30
+
+// tlsHandshakeRecord is the first byte of a TLS ClientHello, sent by clients using direct SSL
+const tlsHandshakeRecord = '\x16'
+
+type connKey struct {
+	client, server netip.AddrPort
+}
+
+// conn is the decoding state of a connection
+type conn struct {
+	*Conn
+	client *half
+	server *half
+
+	// last is the time of the latest packet of the connection
+	last time.Time
+
+	// closed is set once either side has reset or finished the connection
+	closed bool
+
+	// Parsing state, see parse
+	startup    bool
+	sslRequest bool
+	encrypted  bool
+	auth       *pgproto.AuthenticationRequest
+}
+
+// half is a direction of a connection, reassembling its TCP stream
+type half struct {
+	direction record.Direction
+
+	// next is the sequence number of the next byte expected, once started
+	started bool
+	next    uint32
+
+	// pending holds the segments received ahead of next by sequence number
+	pending      map[uint32][]byte
+	pendingBytes int
+
+	// buf holds the reassembled data not yet parsed as messages
+	buf []byte
+
+	// broken is set once the stream can not be decoded any further
+	broken bool
+}
+
+// newConn returns the state of a new connection, established is set when the capture started after
+// the connection was established and its startup is assumed to be complete
+func newConn(c *Conn, established bool) *conn {
+	return &conn{
+		Conn:    c,
+		client:  &half{direction: record.Frontend, pending: make(map[uint32][]byte)},
+		server:  &half{direction: record.Backend, pending: make(map[uint32][]byte)},
+		startup: established,
+	}
+}
+
+// segment reassembles seg and decodes the messages it completes
+func (c *conn) segment(d *Decoder, p *Packet, seg *segment, fromClient bool) {
+	c.last = p.Time
+	h := c.server
+	if fromClient {
+		h = c.client
+	}
+	if seg.flags&(tcpFIN|tcpRST) != 0 {
+		c.closed = true
+	}
+	if h.broken || c.encrypted {
+		return
+	}
+
+	if seg.flags&tcpSYN != 0 {
+		if !h.started {
+			h.started = true
+			h.next = seg.seq + 1
+		}
+		return
+	}
+	if len(seg.payload) == 0 {
+		return
+	}
+	if p.Truncated {
+		d.streamError(c, h, p.Time, fmt.Errorf("packet was truncated by the capture"))
+		return
+	}
+
+	h.add(seg.seq, seg.payload)
+	if h.pendingBytes > maxPending {
+		d.streamError(c, h, p.Time, fmt.Errorf("capture is missing data"))
+		return
+	}
+	c.decode(d, h, p.Time)
+}
+
+// add reassembles a segment, appending it and any pending segments it is followed by to buf
+func (h *half) add(seq uint32, payload []byte) {
+	if !h.started {
+		// The capture started after the connection was established
+		h.started = true
+		h.next = seq
+	}
+
+	if int32(seq-h.next) > 0 {
+		if len(payload) > len(h.pending[seq]) {
+			h.pendingBytes += len(payload) - len(h.pending[seq])
+			h.pending[seq] = payload
+		}
+		return
+	}
+	h.append(seq, payload)
+
+	for progress := true; progress && len(h.pending) > 0; {
+		progress = false
+		for seq, payload := range h.pending {
+			if int32(seq-h.next) <= 0 {
+				delete(h.pending, seq)
+				h.pendingBytes -= len(payload)
+				h.append(seq, payload)
+				progress = true
+			}
+		}
+	}
+}
+
+// append the part of payload starting at seq which is past next, retransmitted data is ignored
+func (h *half) append(seq uint32, payload []byte) {
+	skip := int(h.next - seq)
+	if skip >= len(payload) {
+		return
+	}
+	h.buf = append(h.buf, payload[skip:]...)
+	h.next += uint32(len(payload) - skip)
+}
+
+// decode the complete messages reassembled in h
+func (c *conn) decode(d *Decoder, h *half, t time.Time) {
+	for !h.broken && !c.encrypted {
+		var length int
+		switch {
+		case h.direction == record.Backend && c.sslRequest:
+			// Single byte SSLResponse
+			//   'S' | 'N'
+			length = 1
+		case h.direction == record.Frontend && !c.startup:
+			// Startup message:
+			//   [int32 - length] [payload]
+			if len(h.buf) > 0 && h.buf[0] == tlsHandshakeRecord {
+				c.encrypted = true
+				d.streamError(c, h, t, ErrEncrypted)
+				return
+			}
+			if len(h.buf) < 4 {
+				return
+			}
+			length = int(binary.BigEndian.Uint32(h.buf))
+			if length < 8 || length > maxMessageSize {
+				d.streamError(c, h, t, fmt.Errorf("invalid startup message length %d", length))
+				return
+			}
+		default:
+			// Regular message:
+			//   [char - tag] [int32 - length] [payload]
+			if len(h.buf) < 5 {
+				return
+			}
+			l := binary.BigEndian.Uint32(h.buf[1:])
+			if l < 4 || l > maxMessageSize {
+				d.streamError(c, h, t, fmt.Errorf("invalid length %d for message '%c'", l, h.buf[0]))
+				return
+			}
+			length = 1 + int(l)
+		}
+		if len(h.buf) < length {
+			return
+		}
+
+		raw := make([]byte, length)
+		copy(raw, h.buf)
+		h.buf = h.buf[length:]
+
+		m := &Message{
+			Time:      t,
+			Conn:      c.Conn,
+			Direction: h.direction,
+			Raw:       raw,
+		}
+		var err error
+		m.Message, err = c.parse(h.direction, raw)
+		d.message(m, err)
+
+		if c.encrypted {
+			d.streamError(c, h, t, ErrEncrypted)
+		}
+	}
+}
+
+// parse raw, keeping track of the connection state needed to frame and parse later messages
+func (c *conn) parse(direction record.Direction, raw []byte) (pgproto.Message, error) {
+	buf := bytes.NewReader(raw)
+
+	if direction == record.Backend {
+		if c.sslRequest {
+			c.sslRequest = false
+			if raw[0] == 'G' {
+				// Accepted GSSAPI encryption
+				c.encrypted = true
+				return &pgproto.SSLResponse{Accepted: true}, nil
+			}
+			resp, err := pgproto.ParseSSLResponse(buf)
+			if err != nil {
+				return nil, err
+			}
+			c.encrypted = resp.Accepted
+			return resp, nil
+		}
+
+		msg, err := pgproto.ParseServerMessage(buf)
+		if err != nil {
+			return nil, err
+		}
+		if auth, ok := msg.(*pgproto.AuthenticationRequest); ok {
+			c.auth = auth
+		}
+		return msg, nil
+	}
+
+	if !c.startup {
+		// Startup, SSL and cancel requests are the only messages without a type byte
+		msg, err := pgproto.ParseClientMessage(buf)
+		if err != nil {
+			return nil, err
+		}
+		if startup, ok := msg.(*pgproto.StartupMessage); ok {
+			if startup.SSLRequest || startup.GSSEncRequest {
+				c.sslRequest = true
+			} else {
+				c.startup = true
+			}
+		}
+		return msg, nil
+	}
+
+	// DEV: The parse functions return typed nil messages along with their errors, which must not end
+	//      up in Message.Message
+	var msg pgproto.ClientMessage
+	var err error
+	if raw[0] == 'p' {
+		msg, err = pgproto.ParseAuthenticationResponse(buf, c.auth)
+	} else {
+		msg, err = pgproto.ParseClientMessage(buf)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,108 @@
+/*
+Package pgcopy encodes and decodes the rows of COPY streams in the text and CSV formats, sent as
+CopyData messages up to a CopyDone.
+
+A Writer sends rows as CopyData messages of at most Options.ChunkSize bytes, e.g. the data of a
+COPY FROM STDIN once the server sent a CopyInResponse:
+
+	w, err := pgcopy.NewWriter(conn, pgcopy.Options{CSV: true, Header: true})
+	err = w.WriteHeader([]string{"id", "name"})
+	err = w.Write([][]byte{[]byte("1"), nil})
+	err = w.Close()
+
+A Reader returns the rows of the CopyData messages it reads up to a CopyDone, e.g. the data of a
+COPY TO STDOUT once the server sent a CopyOutResponse, whose boundaries may split rows and values:
+
+	r, err := pgcopy.NewReader(conn, pgcopy.Options{})
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		...
+	}
+
+Values are the text representations of the values of the columns, which pgtype converts, and NULL
+is a nil value. The Options must match the options of the COPY command.
+*/
+package pgcopy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultChunkSize is the size of the CopyData messages sent by a Writer when ChunkSize is not set
+const DefaultChunkSize = 64 * 1024
+
+// Options are the format options of the COPY command of a stream
+type Options struct {
+	// CSV selects the CSV format, the text format is used otherwise
+	CSV bool
+
+	// Delimiter separates the values of a row, defaults to a tab in the text format and a comma in
+	// the CSV format. The text format does not accept a backslash, a period, a lowercase letter or a
+	// digit, which are read as backslash sequences or values.
+	Delimiter byte
+
+	// Quote quotes the values of the CSV format, defaults to a double quote
+	Quote byte
+
+	// Escape precedes the quotes and escapes in the quoted values of the CSV format, defaults to Quote
+	Escape byte
+
+	// Null represents NULL, it defaults to \N in the text format and is an unquoted empty string in
+	// the CSV format when empty
+	Null string
+
+	// NullSet is set when Null is the null string even when empty, e.g. for NULL '' in the text
+	// format, empty values can not be told apart from NULL then
+	NullSet bool
+
+	// Header is set when the first line of the stream holds the names of the columns
+	Header bool
+
+	// ChunkSize is the maximum size of the CopyData messages sent by a Writer, defaults to
+	// DefaultChunkSize
+	ChunkSize int
+}
+
+// resolve returns o with its defaults set, failing for options the server does not accept
+func (o Options) resolve() (Options, error) {
+	if o.Delimiter == 0 {
+		o.Delimiter = '\t'
+		if o.CSV {
+			o.Delimiter = ','
+		}
+	}
+	if o.CSV {
+		if o.Quote == 0 {
+			o.Quote = '"'
+		}
+		if o.Escape == 0 {
+			o.Escape = o.Quote
+		}
+	} else if o.Null == "" && !o.NullSet {
+		o.Null = `\N`
+	}
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = DefaultChunkSize
+	}
+
+	switch {
+	case o.Delimiter == '\n' || o.Delimiter == '\r':
+		return o, errors.New("pgcopy: the delimiter can not be a newline or carriage return")
+	case !o.CSV && strings.IndexByte(`\.abcdefghijklmnopqrstuvwxyz0123456789`, o.Delimiter) >= 0:
+		return o, fmt.Errorf("pgcopy: the delimiter of the text format can not be %q", o.Delimiter)
+	case o.CSV && o.Delimiter == o.Quote:
+		return o, errors.New("pgcopy: the delimiter and the quote must differ")
+	case strings.ContainsAny(o.Null, "\r\n"):
+		return o, errors.New("pgcopy: the null string can not contain a newline or carriage return")
+	case strings.IndexByte(o.Null, o.Delimiter) >= 0:
+		return o, errors.New("pgcopy: the null string can not contain the delimiter")
+	case o.CSV && strings.IndexByte(o.Null, o.Quote) >= 0:
+		return o, errors.New("pgcopy: the null string can not contain the quote")
+	}
+	return o, nil
+}
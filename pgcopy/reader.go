@@ -0,0 +1,341 @@
+package pgcopy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/c653labs/pgproto"
+)
+
+// Reader decodes the rows of the CopyData messages read from an io.Reader, in the format of its
+// Options, up to a CopyDone
+type Reader struct {
+	r    *bufio.Reader
+	opts Options
+
+	// line is the number of the line being read, from 1
+	line int
+
+	// header holds the names of the columns once the header line is read, when the Options have one
+	header     []string
+	headerRead bool
+}
+
+// NewReader returns a Reader reading messages from r, which is not read past the CopyDone
+//
+// ErrorResponse and CopyFail messages, a COPY the server or the client aborted, are returned as
+// errors, and NoticeResponse, ParameterStatus and NotificationResponse messages the server may send
+// during a COPY, as well as Flush and Sync messages of the client, are skipped.
+func NewReader(r io.Reader, opts Options) (*Reader, error) {
+	opts, err := opts.resolve()
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{r: bufio.NewReader(&dataReader{r: r}), opts: opts}, nil
+}
+
+// Header returns the names of the columns of the header line, when the Options have a Header
+func (r *Reader) Header() ([]string, error) {
+	if !r.opts.Header {
+		return nil, errors.New("pgcopy: the stream has no header")
+	}
+	if !r.headerRead {
+		row, err := r.read()
+		if err != nil {
+			return nil, err
+		}
+		r.headerRead = true
+		r.header = make([]string, len(row))
+		for i, name := range row {
+			r.header[i] = string(name)
+		}
+	}
+	return r.header, nil
+}
+
+// Read returns the values of the next row, NULL values are nil, or io.EOF at the end of the stream
+func (r *Reader) Read() ([][]byte, error) {
+	if r.opts.Header && !r.headerRead {
+		_, err := r.Header()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r.read()
+}
+
+// read returns the values of the next line
+func (r *Reader) read() ([][]byte, error) {
+	r.line++
+	if r.opts.CSV {
+		return r.readCSV()
+	}
+	return r.readText()
+}
+
+// endOfData skips the rest of the stream after a line of \., the end of the data
+func (r *Reader) endOfData() ([][]byte, error) {
+	_, err := io.Copy(io.Discard, r.r)
+	if err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// readText returns the values of a line of the text format, which ends with the first newline as
+// newlines within values are escaped
+func (r *Reader) readText() ([][]byte, error) {
+	line, err := r.r.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte{'\n'}), []byte{'\r'})
+	if string(line) == `\.` {
+		return r.endOfData()
+	}
+
+	var row [][]byte
+	start := 0
+	for i := 0; i <= len(line); i++ {
+		switch {
+		case i+1 < len(line) && line[i] == '\\':
+			i++
+		case i == len(line) || line[i] == r.opts.Delimiter:
+			raw := line[start:min(i, len(line))]
+			start = i + 1
+			if string(raw) == r.opts.Null {
+				row = append(row, nil)
+				continue
+			}
+			v, err := unescape(raw)
+			if err != nil {
+				return nil, fmt.Errorf("pgcopy: line %d: %w", r.line, err)
+			}
+			row = append(row, v)
+		}
+	}
+	return row, nil
+}
+
+// unescape returns raw, a value of the text format, with its backslash sequences replaced
+func unescape(raw []byte) ([]byte, error) {
+	v := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			v = append(v, raw[i])
+			continue
+		}
+		i++
+		if i == len(raw) {
+			return nil, errors.New("unterminated backslash sequence")
+		}
+
+		switch c := raw[i]; c {
+		case 'b':
+			v = append(v, '\b')
+		case 'f':
+			v = append(v, '\f')
+		case 'n':
+			v = append(v, '\n')
+		case 'r':
+			v = append(v, '\r')
+		case 't':
+			v = append(v, '\t')
+		case 'v':
+			v = append(v, '\v')
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			// Up to three octal digits
+			b := c - '0'
+			for n := 1; n < 3 && i+1 < len(raw) && raw[i+1] >= '0' && raw[i+1] <= '7'; n++ {
+				i++
+				b = b<<3 | (raw[i] - '0')
+			}
+			v = append(v, b)
+		case 'x':
+			// Up to two hexadecimal digits, a lone x is itself
+			var b byte
+			n := 0
+			for ; n < 2 && i+1 < len(raw) && isHex(raw[i+1]); n++ {
+				i++
+				b = b<<4 | hexValue(raw[i])
+			}
+			if n == 0 {
+				b = 'x'
+			}
+			v = append(v, b)
+		default:
+			v = append(v, c)
+		}
+	}
+	return v, nil
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func hexValue(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	}
+	return c - '0'
+}
+
+// readCSV returns the values of a row of the CSV format, quoted values may span lines
+func (r *Reader) readCSV() ([][]byte, error) {
+	var row [][]byte
+	value := []byte{}
+	quoted, inQuotes, started := false, false, false
+	line := r.line
+
+	// end appends the value read to the row, unquoted values equal to the null string are NULL
+	end := func() {
+		if !quoted && string(value) == r.opts.Null {
+			row = append(row, nil)
+		} else {
+			row = append(row, value)
+		}
+		value = []byte{}
+		quoted = false
+	}
+
+	for {
+		c, err := r.r.ReadByte()
+		if err == io.EOF {
+			if inQuotes {
+				return nil, fmt.Errorf("pgcopy: line %d: unterminated quoted value", line)
+			}
+			if !started {
+				return nil, io.EOF
+			}
+			end()
+			return row, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		started = true
+
+		if inQuotes {
+			if c == r.opts.Escape {
+				next, err := r.r.Peek(1)
+				if err == nil && (next[0] == r.opts.Quote || next[0] == r.opts.Escape) {
+					r.r.ReadByte()
+					value = append(value, next[0])
+					continue
+				}
+			}
+			if c == r.opts.Quote {
+				inQuotes = false
+				continue
+			}
+			if c == '\n' {
+				r.line++
+			}
+			value = append(value, c)
+			continue
+		}
+
+		switch c {
+		case r.opts.Quote:
+			inQuotes, quoted = true, true
+		case r.opts.Delimiter:
+			end()
+		case '\r', '\n':
+			if c == '\r' {
+				if next, err := r.r.Peek(1); err == nil && next[0] == '\n' {
+					r.r.ReadByte()
+				}
+			}
+			// A line of an unquoted \. is the end of the data
+			if len(row) == 0 && !quoted && string(value) == `\.` {
+				return r.endOfData()
+			}
+			end()
+			return row, nil
+		default:
+			value = append(value, c)
+		}
+	}
+}
+
+// maxMessageLength is the largest message length read, the limit PostgreSQL itself applies
+const maxMessageLength = 1<<30 - 1
+
+// dataReader reads the data of the CopyData messages of a stream up to its CopyDone
+type dataReader struct {
+	r    io.Reader
+	data []byte
+	done bool
+}
+
+func (d *dataReader) Read(p []byte) (int, error) {
+	for len(d.data) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		err := d.next()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.data)
+	d.data = d.data[n:]
+	return n, nil
+}
+
+// next reads the next message of the stream
+func (d *dataReader) next() error {
+	// [byte - tag] [int32 - length] [bytes - payload]
+	header := make([]byte, 5)
+	_, err := io.ReadFull(d.r, header)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	l := int(int32(binary.BigEndian.Uint32(header[1:])))
+	if l < 4 || l > maxMessageLength {
+		return fmt.Errorf("pgcopy: invalid message length %d", l)
+	}
+	payload := make([]byte, l-4)
+	_, err = io.ReadFull(d.r, payload)
+	if err != nil {
+		return err
+	}
+	msg := io.MultiReader(bytes.NewReader(header), bytes.NewReader(payload))
+
+	switch header[0] {
+	case 'd':
+		d.data = payload
+	case 'c':
+		d.done = true
+	case 'E':
+		e, err := pgproto.ParseError(msg)
+		if err != nil {
+			return err
+		}
+		return e
+	case 'f':
+		f, err := pgproto.ParseCopyFail(msg)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("pgcopy: COPY failed: %s", f.Message)
+	case 'N', 'S', 'A', 'H':
+	default:
+		return fmt.Errorf("pgcopy: unexpected message %q during COPY", header[0])
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package pgcopy_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgcopy"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReaderTestSuite struct {
+	suite.Suite
+}
+
+func TestReaderTestSuite(t *testing.T) {
+	suite.Run(t, new(ReaderTestSuite))
+}
+
+// stream returns the encoded messages of a COPY stream sending each of data as a CopyData, ended by
+// a CopyDone and followed by a CommandCompletion
+func stream(data ...string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	for _, d := range data {
+		pgproto.WriteMessage(&pgproto.CopyData{Data: []byte(d)}, buf)
+	}
+	pgproto.WriteMessage(&pgproto.CopyDone{}, buf)
+	pgproto.WriteMessage(&pgproto.CommandCompletion{Tag: []byte("COPY 2")}, buf)
+	return buf
+}
+
+// read returns every row of the stream r
+func (s *ReaderTestSuite) read(r io.Reader, opts pgcopy.Options) [][][]byte {
+	reader, err := pgcopy.NewReader(r, opts)
+	s.Require().Nil(err)
+	var rows [][][]byte
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return rows
+		}
+		s.Require().Nil(err)
+		rows = append(rows, row)
+	}
+}
+
+func (s *ReaderTestSuite) Test_Text() {
+	buf := stream("1\ta\\tb\\\\c\\nd\t\\N\n", "2\t\\101\\x41\\x\\q\\\t\n3\\|\t\r\n")
+	s.Equal([][][]byte{
+		{[]byte("1"), []byte("a\tb\\c\nd"), nil},
+		{[]byte("2"), []byte("AAxq\t")},
+		{[]byte("3|"), []byte("")},
+	}, s.read(buf, pgcopy.Options{}))
+
+	// The stream is not read past the CopyDone
+	msg, err := pgproto.ParseServerMessage(buf)
+	s.Nil(err)
+	s.Equal(&pgproto.CommandCompletion{Tag: []byte("COPY 2")}, msg)
+
+	// Rows may be split between messages, a line of \. ends the data
+	s.Equal([][][]byte{
+		{[]byte("a b"), []byte("")},
+		{nil, []byte(`\N`)},
+	}, s.read(stream("a b|", "\n", "NULL|\\", "\\N\n\\.\nignored\n"), pgcopy.Options{Delimiter: '|', Null: "NULL"}))
+
+	// NULL '' reads empty values as NULL
+	s.Equal([][][]byte{
+		{[]byte("a"), nil, nil},
+	}, s.read(stream("a\t\t\n"), pgcopy.Options{NullSet: true}))
+
+	// A last line without a newline is a row
+	s.Equal([][][]byte{{[]byte("a")}}, s.read(stream("a"), pgcopy.Options{}))
+
+	reader, err := pgcopy.NewReader(stream("a\\\n"), pgcopy.Options{})
+	s.Require().Nil(err)
+	_, err = reader.Read()
+	s.EqualError(err, "pgcopy: line 1: unterminated backslash sequence")
+}
+
+func (s *ReaderTestSuite) Test_CSV() {
+	s.Equal([][][]byte{
+		{[]byte("1"), []byte("a,b"), []byte(`say "hi"`), []byte(""), nil},
+		{[]byte("line\nbreak"), []byte(`\.`)},
+		{[]byte("crlf"), nil},
+	}, s.read(stream("1,\"a,b\",\"say \"\"hi\"\"\",\"\",\n\"line\n", "break\",\"\\.\"\ncrlf,\r\n\\.\n"), pgcopy.Options{CSV: true}))
+
+	s.Equal([][][]byte{
+		{[]byte("it's"), []byte(`a\b`), []byte("NULL"), nil},
+	}, s.read(stream("'it\\'s';'a\\\\b';'NULL';NULL\n"), pgcopy.Options{CSV: true, Delimiter: ';', Quote: '\'', Escape: '\\', Null: "NULL"}))
+
+	reader, err := pgcopy.NewReader(stream("\"a\n"), pgcopy.Options{CSV: true})
+	s.Require().Nil(err)
+	_, err = reader.Read()
+	s.EqualError(err, "pgcopy: line 1: unterminated quoted value")
+}
+
+func (s *ReaderTestSuite) Test_Header() {
+	reader, err := pgcopy.NewReader(stream("id,full name\n1,a b\n"), pgcopy.Options{CSV: true, Header: true})
+	s.Require().Nil(err)
+	row, err := reader.Read()
+	s.Nil(err)
+	s.Equal([][]byte{[]byte("1"), []byte("a b")}, row)
+	header, err := reader.Header()
+	s.Nil(err)
+	s.Equal([]string{"id", "full name"}, header)
+
+	reader, err = pgcopy.NewReader(stream("1\n"), pgcopy.Options{})
+	s.Require().Nil(err)
+	_, err = reader.Header()
+	s.NotNil(err)
+}
+
+func (s *ReaderTestSuite) Test_RoundTrip() {
+	rows := [][][]byte{
+		{[]byte("1"), []byte("tab\there"), []byte("back\\slash"), nil},
+		{[]byte(""), []byte("quote \" and , comma"), []byte("new\nline\r\n"), []byte(`\.`)},
+		{[]byte(`\N`), []byte("NULL"), []byte{0x00, 0xff}, []byte(" ")},
+	}
+	for _, opts := range []pgcopy.Options{
+		{ChunkSize: 1},
+		{ChunkSize: 7, Delimiter: ',', Null: "NULL"},
+		{ChunkSize: 1, CSV: true},
+		{ChunkSize: 3, CSV: true, Quote: '\'', Escape: '\\', Null: `\N`},
+	} {
+		buf := &bytes.Buffer{}
+		w, err := pgcopy.NewWriter(buf, opts)
+		s.Require().Nil(err)
+		for _, row := range rows {
+			s.Nil(w.Write(row))
+		}
+		s.Nil(w.Close())
+		s.Equal(rows, s.read(buf, opts), opts)
+	}
+}
+
+func (s *ReaderTestSuite) Test_Messages() {
+	// Notices and parameters sent by the server during the COPY are skipped
+	buf := &bytes.Buffer{}
+	pgproto.WriteMessage(&pgproto.CopyData{Data: []byte("a\n")}, buf)
+	pgproto.WriteMessage(&pgproto.NoticeResponse{Severity: []byte("NOTICE"), Message: []byte("notice")}, buf)
+	pgproto.WriteMessage(&pgproto.ParameterStatus{Name: []byte("TimeZone"), Value: []byte("UTC")}, buf)
+	pgproto.WriteMessage(&pgproto.CopyData{Data: []byte("b\n")}, buf)
+	pgproto.WriteMessage(&pgproto.CopyDone{}, buf)
+	s.Equal([][][]byte{{[]byte("a")}, {[]byte("b")}}, s.read(buf, pgcopy.Options{}))
+
+	// The COPY fails with an ErrorResponse of the server or a CopyFail of the client
+	buf = &bytes.Buffer{}
+	pgproto.WriteMessage(&pgproto.CopyData{Data: []byte("a\n")}, buf)
+	pgproto.WriteMessage(&pgproto.Error{Severity: []byte("ERROR"), Code: []byte("22P02"), Message: []byte("invalid input")}, buf)
+	reader, err := pgcopy.NewReader(buf, pgcopy.Options{})
+	s.Require().Nil(err)
+	_, err = reader.Read()
+	s.Nil(err)
+	_, err = reader.Read()
+	var e *pgproto.Error
+	s.True(errors.As(err, &e))
+	s.Equal("22P02", string(e.Code))
+
+	buf = &bytes.Buffer{}
+	pgproto.WriteMessage(&pgproto.CopyFail{Message: []byte("aborted")}, buf)
+	reader, err = pgcopy.NewReader(buf, pgcopy.Options{})
+	s.Require().Nil(err)
+	_, err = reader.Read()
+	s.EqualError(err, "pgcopy: COPY failed: aborted")
+
+	buf = &bytes.Buffer{}
+	pgproto.WriteMessage(&pgproto.ReadyForQuery{Status: pgproto.READY_IDLE}, buf)
+	reader, err = pgcopy.NewReader(buf, pgcopy.Options{})
+	s.Require().Nil(err)
+	_, err = reader.Read()
+	s.EqualError(err, `pgcopy: unexpected message 'Z' during COPY`)
+
+	// A stream ending before its CopyDone
+	reader, err = pgcopy.NewReader(&bytes.Buffer{}, pgcopy.Options{})
+	s.Require().Nil(err)
+	_, err = reader.Read()
+	s.True(errors.Is(err, io.ErrUnexpectedEOF), err)
+
+	// Lengths are rejected before allocating the message
+	for _, invalid := range [][]byte{
+		{'d', '\x00', '\x00', '\x00', '\x03'},
+		{'d', '\x40', '\x00', '\x00', '\x00'},
+		{'d', '\xff', '\xff', '\xff', '\xff'},
+	} {
+		reader, err = pgcopy.NewReader(bytes.NewReader(invalid), pgcopy.Options{})
+		s.Require().Nil(err)
+		_, err = reader.Read()
+		s.NotNil(err, invalid)
+		s.Contains(err.Error(), "pgcopy: invalid message length")
+	}
+}
@@ -0,0 +1,157 @@
+package pgcopy
+
+import (
+	"errors"
+	"io"
+
+	"github.com/c653labs/pgproto"
+)
+
+// Writer encodes rows in the format of its Options and writes them to an io.Writer as CopyData
+// messages, rows may be split between messages
+type Writer struct {
+	w    io.Writer
+	opts Options
+	buf  []byte
+
+	// header is set until the header line is written, when the Options require one
+	header bool
+}
+
+// NewWriter returns a Writer writing CopyData messages to w
+func NewWriter(w io.Writer, opts Options) (*Writer, error) {
+	opts, err := opts.resolve()
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, opts: opts, header: opts.Header}, nil
+}
+
+// WriteHeader writes the header line of the names of the columns, it must be written before the
+// rows when the Options have a Header
+func (w *Writer) WriteHeader(names []string) error {
+	if !w.header {
+		return errors.New("pgcopy: the header is not expected")
+	}
+	w.header = false
+
+	fields := make([][]byte, len(names))
+	for i, name := range names {
+		fields[i] = []byte(name)
+	}
+	return w.Write(fields)
+}
+
+// Write writes a row of values, nil values are NULL, the row is sent once ChunkSize bytes are buffered
+func (w *Writer) Write(row [][]byte) error {
+	if w.header {
+		return errors.New("pgcopy: the header must be written first")
+	}
+
+	for i, v := range row {
+		if i > 0 {
+			w.buf = append(w.buf, w.opts.Delimiter)
+		}
+		switch {
+		case v == nil:
+			w.buf = append(w.buf, w.opts.Null...)
+		case w.opts.CSV:
+			w.buf = w.appendCSV(w.buf, v)
+		default:
+			w.buf = w.appendText(w.buf, v)
+		}
+	}
+	w.buf = append(w.buf, '\n')
+	return w.send(false)
+}
+
+// appendText appends v to buf escaped by backslashes, the escapes of control characters and
+// backslash sequences of the delimiter
+func (w *Writer) appendText(buf []byte, v []byte) []byte {
+	// A value equal to the null string has its first byte escaped, as the null string is matched
+	// before backslash sequences are replaced
+	if len(v) > 0 && string(v) == w.opts.Null {
+		c := v[0]
+		buf = append(buf, '\\', '0'+c>>6, '0'+c>>3&7, '0'+c&7)
+		v = v[1:]
+	}
+
+	for _, c := range v {
+		switch c {
+		case '\b':
+			buf = append(buf, '\\', 'b')
+		case '\f':
+			buf = append(buf, '\\', 'f')
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		case '\v':
+			buf = append(buf, '\\', 'v')
+		case '\\':
+			buf = append(buf, '\\', '\\')
+		default:
+			if c == w.opts.Delimiter {
+				buf = append(buf, '\\')
+			}
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// appendCSV appends v to buf, quoted when it holds a delimiter, quote or line break, or would be
+// read as NULL or the end of the data otherwise
+func (w *Writer) appendCSV(buf []byte, v []byte) []byte {
+	quote := string(v) == w.opts.Null || string(v) == `\.`
+	for _, c := range v {
+		if c == w.opts.Delimiter || c == w.opts.Quote || c == '\n' || c == '\r' {
+			quote = true
+			break
+		}
+	}
+	if !quote {
+		return append(buf, v...)
+	}
+
+	buf = append(buf, w.opts.Quote)
+	for _, c := range v {
+		if c == w.opts.Quote || c == w.opts.Escape {
+			buf = append(buf, w.opts.Escape)
+		}
+		buf = append(buf, c)
+	}
+	return append(buf, w.opts.Quote)
+}
+
+// send writes the buffered data as CopyData messages of ChunkSize bytes, and the rest with all
+func (w *Writer) send(all bool) error {
+	data := w.buf
+	for len(data) >= w.opts.ChunkSize || all && len(data) > 0 {
+		n := min(len(data), w.opts.ChunkSize)
+		_, err := pgproto.WriteMessage(&pgproto.CopyData{Data: data[:n]}, w.w)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	w.buf = append(w.buf[:0], data...)
+	return nil
+}
+
+// Flush writes the buffered rows
+func (w *Writer) Flush() error {
+	return w.send(true)
+}
+
+// Close writes the buffered rows and a CopyDone message ending the stream
+func (w *Writer) Close() error {
+	err := w.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = pgproto.WriteMessage(&pgproto.CopyDone{}, w.w)
+	return err
+}
@@ -0,0 +1,138 @@
+package pgcopy_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgcopy"
+	"github.com/stretchr/testify/suite"
+)
+
+type WriterTestSuite struct {
+	suite.Suite
+}
+
+func TestWriterTestSuite(t *testing.T) {
+	suite.Run(t, new(WriterTestSuite))
+}
+
+// messages returns the messages written to buf
+func (s *WriterTestSuite) messages(buf *bytes.Buffer) []pgproto.ClientMessage {
+	var msgs []pgproto.ClientMessage
+	for buf.Len() > 0 {
+		msg, err := pgproto.ParseClientMessage(buf)
+		s.Require().Nil(err)
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// write returns the data of the rows written with opts
+func (s *WriterTestSuite) write(opts pgcopy.Options, rows ...[][]byte) string {
+	buf := &bytes.Buffer{}
+	w, err := pgcopy.NewWriter(buf, opts)
+	s.Require().Nil(err)
+	for _, row := range rows {
+		s.Require().Nil(w.Write(row))
+	}
+	s.Require().Nil(w.Close())
+
+	msgs := s.messages(buf)
+	s.Require().NotEmpty(msgs)
+	s.Equal(&pgproto.CopyDone{}, msgs[len(msgs)-1])
+	var data []byte
+	for _, msg := range msgs[:len(msgs)-1] {
+		data = append(data, msg.(*pgproto.CopyData).Data...)
+	}
+	return string(data)
+}
+
+func (s *WriterTestSuite) Test_Text() {
+	s.Equal("1\ta\\tb\\\\c\\nd\\r\t\\N\n2\t\t\\N\n", s.write(pgcopy.Options{},
+		[][]byte{[]byte("1"), []byte("a\tb\\c\nd\r"), nil},
+		[][]byte{[]byte("2"), []byte(""), nil},
+	))
+	s.Equal("a\\|b|NULL|\\b\\f\\v|\\116ULL\n", s.write(pgcopy.Options{Delimiter: '|', Null: "NULL"},
+		[][]byte{[]byte("a|b"), nil, []byte("\b\f\v"), []byte("NULL")},
+	))
+	// NULL '' writes NULL and empty values alike
+	s.Equal("a\t\t\n", s.write(pgcopy.Options{NullSet: true},
+		[][]byte{[]byte("a"), nil, []byte("")},
+	))
+}
+
+func (s *WriterTestSuite) Test_CSV() {
+	s.Equal("1,\"a,b\",\"say \"\"hi\"\"\",\"\",\n\"line\nbreak\",\"\\.\"\n", s.write(pgcopy.Options{CSV: true},
+		[][]byte{[]byte("1"), []byte("a,b"), []byte(`say "hi"`), []byte(""), nil},
+		[][]byte{[]byte("line\nbreak"), []byte(`\.`)},
+	))
+	s.Equal("'it\\'s';a\\b;'NULL';NULL\n", s.write(pgcopy.Options{CSV: true, Delimiter: ';', Quote: '\'', Escape: '\\', Null: "NULL"},
+		[][]byte{[]byte("it's"), []byte(`a\b`), []byte("NULL"), nil},
+	))
+}
+
+func (s *WriterTestSuite) Test_Header() {
+	buf := &bytes.Buffer{}
+	w, err := pgcopy.NewWriter(buf, pgcopy.Options{CSV: true, Header: true})
+	s.Require().Nil(err)
+	s.NotNil(w.Write([][]byte{[]byte("1")}))
+	s.Nil(w.WriteHeader([]string{"id", "full name"}))
+	s.NotNil(w.WriteHeader([]string{"id"}))
+	s.Nil(w.Write([][]byte{[]byte("1"), []byte("a b")}))
+	s.Nil(w.Close())
+
+	msgs := s.messages(buf)
+	s.Equal([]pgproto.ClientMessage{
+		&pgproto.CopyData{Data: []byte("id,full name\n1,a b\n")},
+		&pgproto.CopyDone{},
+	}, msgs)
+
+	w, err = pgcopy.NewWriter(buf, pgcopy.Options{})
+	s.Require().Nil(err)
+	s.NotNil(w.WriteHeader([]string{"id"}))
+}
+
+func (s *WriterTestSuite) Test_Chunks() {
+	buf := &bytes.Buffer{}
+	w, err := pgcopy.NewWriter(buf, pgcopy.Options{ChunkSize: 4})
+	s.Require().Nil(err)
+
+	// Rows are sent once a chunk is full
+	s.Nil(w.Write([][]byte{[]byte("ab")}))
+	s.Equal(0, buf.Len())
+	s.Nil(w.Write([][]byte{[]byte("cdefgh")}))
+	s.Equal([]pgproto.ClientMessage{
+		&pgproto.CopyData{Data: []byte("ab\nc")},
+		&pgproto.CopyData{Data: []byte("defg")},
+	}, s.messages(buf))
+
+	s.Nil(w.Flush())
+	s.Equal([]pgproto.ClientMessage{&pgproto.CopyData{Data: []byte("h\n")}}, s.messages(buf))
+	s.Nil(w.Close())
+	s.Equal([]pgproto.ClientMessage{&pgproto.CopyDone{}}, s.messages(buf))
+}
+
+func (s *WriterTestSuite) Test_Options() {
+	for _, opts := range []pgcopy.Options{
+		{Delimiter: '\n'},
+		{Delimiter: '\\'},
+		{Delimiter: '.'},
+		{Delimiter: 'n'},
+		{Delimiter: 't'},
+		{Delimiter: '0'},
+		{CSV: true, Delimiter: '"'},
+		{CSV: true, Null: `"`},
+		{Null: "a\nb"},
+		{Null: "a\tb"},
+	} {
+		_, err := pgcopy.NewWriter(&bytes.Buffer{}, opts)
+		s.NotNil(err, opts)
+		_, err = pgcopy.NewReader(&bytes.Buffer{}, opts)
+		s.NotNil(err, opts)
+	}
+
+	// Letters and digits are only backslash sequences in the text format
+	_, err := pgcopy.NewWriter(&bytes.Buffer{}, pgcopy.Options{CSV: true, Delimiter: 'n'})
+	s.Nil(err)
+}
@@ -0,0 +1,150 @@
+package pgtest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/internal/scram"
+)
+
+// Client is a minimal PostgreSQL client used to drive servers and proxies from tests
+type Client struct {
+	Conn       net.Conn
+	Parameters map[string]string
+	PID        int
+	Key        int
+
+	r *bufio.Reader
+}
+
+// Connect will dial addr, send a startup message with options and authenticate using password
+func Connect(addr string, options map[string]string, password string) (*Client, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := NewClient(conn, options, password)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewClient will start a session on an already established connection, e.g. one upgraded with
+// pgproto.RequestSSL, and wait until the server is ready for queries
+func NewClient(conn net.Conn, options map[string]string, password string) (*Client, error) {
+	c := &Client{
+		Conn:       conn,
+		Parameters: make(map[string]string),
+		r:          bufio.NewReader(conn),
+	}
+
+	startup := &pgproto.StartupMessage{Options: make(map[string][]byte)}
+	for k, v := range options {
+		startup.Options[k] = []byte(v)
+	}
+	err := c.Send(startup)
+	if err != nil {
+		return nil, err
+	}
+
+	var sasl *scram.Client
+	for {
+		msg, err := c.Receive()
+		if err != nil {
+			return nil, err
+		}
+
+		switch m := msg.(type) {
+		case *pgproto.AuthenticationRequest:
+			switch m.Method {
+			case pgproto.AuthenticationMethodOK:
+			case pgproto.AuthenticationMethodSASL:
+				sasl = scram.NewClient(password)
+				err = c.Send(&pgproto.SASLInitialResponse{Mechanism: []byte(scram.Mechanism), Data: sasl.First()})
+			case pgproto.AuthenticationMethodSASLContinue, pgproto.AuthenticationMethodSASLFinal:
+				if sasl == nil {
+					return nil, fmt.Errorf("unexpected authentication method %s", m.Method)
+				}
+				if m.Method == pgproto.AuthenticationMethodSASLFinal {
+					err = sasl.Verify(m.Data)
+					break
+				}
+				var data []byte
+				data, err = sasl.Final(m.Data)
+				if err == nil {
+					err = c.Send(&pgproto.SASLResponse{Data: data})
+				}
+			case pgproto.AuthenticationMethodPlaintext:
+				err = c.Send(&pgproto.PasswordMessage{Password: []byte(password)})
+			case pgproto.AuthenticationMethodMD5:
+				p := &pgproto.PasswordMessage{}
+				p.SetPassword(startup.Options["user"], []byte(password), m.Salt)
+				err = c.Send(p)
+			default:
+				err = fmt.Errorf("unsupported authentication method %s", m.Method)
+			}
+			if err != nil {
+				return nil, err
+			}
+		case *pgproto.ParameterStatus:
+			c.Parameters[string(m.Name)] = string(m.Value)
+		case *pgproto.BackendKeyData:
+			c.PID = m.PID
+			c.Key = m.Key
+		case *pgproto.Error:
+			return nil, fmt.Errorf("%s: %s", m.Severity, m.Message)
+		case *pgproto.ReadyForQuery:
+			return c, nil
+		}
+	}
+}
+
+// Send will write msgs to the server
+func (c *Client) Send(msgs ...pgproto.ClientMessage) error {
+	buf := make([]byte, 0)
+	for _, m := range msgs {
+		buf = append(buf, m.Encode()...)
+	}
+	_, err := c.Conn.Write(buf)
+	return err
+}
+
+// Receive will read the next message from the server
+func (c *Client) Receive() (pgproto.ServerMessage, error) {
+	return pgproto.ParseServerMessage(c.r)
+}
+
+// ReceiveUntilReady will read messages from the server up to and including the next ReadyForQuery
+func (c *Client) ReceiveUntilReady() ([]pgproto.ServerMessage, error) {
+	msgs := make([]pgproto.ServerMessage, 0)
+	for {
+		msg, err := c.Receive()
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+		if _, ok := msg.(*pgproto.ReadyForQuery); ok {
+			return msgs, nil
+		}
+	}
+}
+
+// Query will run a simple query and return every message received in response
+func (c *Client) Query(query string) ([]pgproto.ServerMessage, error) {
+	err := c.Send(&pgproto.SimpleQuery{Query: []byte(query)})
+	if err != nil {
+		return nil, err
+	}
+	return c.ReceiveUntilReady()
+}
+
+// Close will send a Termination message and close the connection
+func (c *Client) Close() error {
+	c.Send(&pgproto.Termination{})
+	return c.Conn.Close()
+}
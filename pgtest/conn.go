@@ -0,0 +1,389 @@
+package pgtest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/c653labs/pgproto"
+)
+
+// Result is the response to a single query
+type Result struct {
+	// Fields describes the returned columns, a nil Fields means the query does not return rows
+	Fields []pgproto.RowField
+
+	// Rows are sent as DataRow messages, a nil value is sent as NULL
+	Rows [][][]byte
+
+	// Tag is the command completion tag, defaults to "SELECT <rows>" or the query's first keyword
+	Tag string
+
+	// ParameterOIDs are reported when the query is described as a prepared statement
+	ParameterOIDs []int
+}
+
+// Handler answers queries sent to a Server, params are nil for simple queries
+//
+// Query is also called with nil params when a prepared statement is described, the Fields and
+// ParameterOIDs of the Result are used for the description and the rows are discarded.
+//
+// Returning a *pgproto.Error sends it to the client as is, any other error is sent as an internal error
+type Handler interface {
+	Query(c *Conn, query string, params [][]byte) (*Result, error)
+}
+
+// HandlerFunc adapts an ordinary function to a Handler
+type HandlerFunc func(c *Conn, query string, params [][]byte) (*Result, error)
+
+// Query calls f(c, query, params)
+func (f HandlerFunc) Query(c *Conn, query string, params [][]byte) (*Result, error) {
+	return f(c, query, params)
+}
+
+// ErrQueryCanceled is the error PostgreSQL reports for a query interrupted by a CancelRequest,
+// handlers can return it once Conn.Canceled is closed
+var ErrQueryCanceled = &pgproto.Error{
+	Severity: []byte("ERROR"),
+	Code:     []byte("57014"),
+	Message:  []byte("canceling statement due to user request"),
+}
+
+// sessionCommands are answered by Responses with an empty result unless registered explicitly
+var sessionCommands = map[string]bool{
+	"BEGIN":      true,
+	"START":      true,
+	"COMMIT":     true,
+	"END":        true,
+	"ROLLBACK":   true,
+	"ABORT":      true,
+	"SET":        true,
+	"RESET":      true,
+	"DISCARD":    true,
+	"DEALLOCATE": true,
+}
+
+// Responses is a Handler answering each query with a fixed Result, unknown queries return an error
+//
+// Transaction control and session commands, e.g. BEGIN, SET or DISCARD ALL, succeed without a Result
+type Responses map[string]*Result
+
+// Query returns the Result registered for query
+func (r Responses) Query(c *Conn, query string, params [][]byte) (*Result, error) {
+	if result, ok := r[query]; ok {
+		return result, nil
+	}
+	if sessionCommands[keyword(query)] {
+		return &Result{}, nil
+	}
+	return nil, &pgproto.Error{
+		Severity: []byte("ERROR"),
+		Code:     []byte("42601"),
+		Message:  []byte(fmt.Sprintf("unexpected query %q", query)),
+	}
+}
+
+// Conn is a single client connection to a Server
+type Conn struct {
+	PID     int
+	Key     int
+	Startup *pgproto.StartupMessage
+
+	server *Server
+	conn   net.Conn
+	r      *bufio.Reader
+	w      *bufio.Writer
+
+	mu       sync.Mutex
+	queries  []string
+	canceled chan struct{}
+
+	status     pgproto.ReadyStatus
+	statements map[string]*statement
+	portals    map[string]*portal
+	failed     bool
+}
+
+type statement struct {
+	query string
+	oids  []int
+}
+
+type portal struct {
+	statement *statement
+	params    [][]byte
+	result    *Result
+}
+
+// User returns the user name sent in the startup message
+func (c *Conn) User() string { return string(c.Startup.Options["user"]) }
+
+// Database returns the database name sent in the startup message, defaulting to the user name
+func (c *Conn) Database() string {
+	if db, ok := c.Startup.Options["database"]; ok {
+		return string(db)
+	}
+	return c.User()
+}
+
+// Queries returns every query received on this connection so far, in order
+func (c *Conn) Queries() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.queries...)
+}
+
+// Canceled returns a channel which is closed when a CancelRequest is received for the query
+// currently being handled, it is only valid for the duration of a Handler call
+func (c *Conn) Canceled() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.canceled == nil {
+		c.canceled = make(chan struct{})
+	}
+	return c.canceled
+}
+
+func (c *Conn) cancel() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.canceled == nil {
+		c.canceled = make(chan struct{})
+	}
+	select {
+	case <-c.canceled:
+	default:
+		close(c.canceled)
+	}
+}
+
+func (c *Conn) serve() {
+	for {
+		msg, err := pgproto.ParseClientMessage(c.r)
+		if err != nil {
+			return
+		}
+
+		// After an error in the extended query protocol everything is discarded until the next Sync
+		if _, ok := msg.(*pgproto.Sync); !ok && c.failed {
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *pgproto.SimpleQuery:
+			c.simpleQuery(string(m.Query))
+		case *pgproto.Parse:
+			c.statements[string(m.Name)] = &statement{query: string(m.Query), oids: m.OIDs}
+			c.send(&pgproto.ParseComplete{})
+		case *pgproto.Bind:
+			stmt, ok := c.statements[string(m.Statement)]
+			if !ok {
+				c.sendError(fmt.Errorf("prepared statement \"%s\" does not exist", m.Statement))
+				continue
+			}
+			c.portals[string(m.Portal)] = &portal{statement: stmt, params: m.Parameters}
+			c.send(&pgproto.BindComplete{})
+		case *pgproto.Describe:
+			c.describe(m)
+		case *pgproto.Execute:
+			c.execute(m)
+		case *pgproto.Close:
+			if m.ObjectType == pgproto.ObjectTypePortal {
+				delete(c.portals, string(m.Name))
+			} else {
+				delete(c.statements, string(m.Name))
+			}
+			c.send(&pgproto.CloseComplete{})
+		case *pgproto.Sync:
+			c.failed = false
+			delete(c.portals, "")
+			c.sendReady()
+		case *pgproto.Flush:
+		case *pgproto.Termination:
+			return
+		default:
+			c.sendError(fmt.Errorf("unsupported message %s", msg))
+		}
+
+		// Only flush once the client is waiting on us
+		if c.r.Buffered() == 0 {
+			err = c.w.Flush()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *Conn) simpleQuery(query string) {
+	defer func() {
+		// Errors in simple queries do not require a Sync to recover
+		c.failed = false
+		c.sendReady()
+	}()
+
+	if len(query) == 0 {
+		c.send(&pgproto.EmptyQueryResponse{})
+		return
+	}
+
+	result, err := c.query(query, nil)
+	if err != nil {
+		c.sendError(err)
+		return
+	}
+	if result.Fields != nil {
+		c.send(&pgproto.RowDescription{Fields: result.Fields})
+	}
+	c.sendResult(query, result)
+}
+
+func (c *Conn) describe(m *pgproto.Describe) {
+	if m.ObjectType == pgproto.ObjectTypePortal {
+		p, ok := c.portals[string(m.Name)]
+		if !ok {
+			c.sendError(fmt.Errorf("portal \"%s\" does not exist", m.Name))
+			return
+		}
+
+		// Describing a portal requires running the query, keep the result for the Execute
+		result, err := c.query(p.statement.query, p.params)
+		if err != nil {
+			c.sendError(err)
+			return
+		}
+		p.result = result
+		c.sendFields(result.Fields)
+		return
+	}
+
+	stmt, ok := c.statements[string(m.Name)]
+	if !ok {
+		c.sendError(fmt.Errorf("prepared statement \"%s\" does not exist", m.Name))
+		return
+	}
+
+	result, err := c.server.Handler.Query(c, stmt.query, nil)
+	if err != nil {
+		c.sendError(err)
+		return
+	}
+	if result == nil {
+		result = &Result{}
+	}
+
+	oids := result.ParameterOIDs
+	if oids == nil {
+		oids = stmt.oids
+	}
+	c.send(&pgproto.ParameterDescription{OIDs: oids})
+	c.sendFields(result.Fields)
+}
+
+func (c *Conn) execute(m *pgproto.Execute) {
+	p, ok := c.portals[string(m.Portal)]
+	if !ok {
+		c.sendError(fmt.Errorf("portal \"%s\" does not exist", m.Portal))
+		return
+	}
+
+	if len(p.statement.query) == 0 {
+		c.send(&pgproto.EmptyQueryResponse{})
+		return
+	}
+
+	result := p.result
+	if result == nil {
+		var err error
+		result, err = c.query(p.statement.query, p.params)
+		if err != nil {
+			c.sendError(err)
+			return
+		}
+	}
+	p.result = nil
+	c.sendResult(p.statement.query, result)
+}
+
+func (c *Conn) query(query string, params [][]byte) (*Result, error) {
+	c.mu.Lock()
+	c.queries = append(c.queries, query)
+	c.canceled = make(chan struct{})
+	c.mu.Unlock()
+
+	// Only ending the transaction is allowed once a query in it has failed
+	kw := keyword(query)
+	if c.status == pgproto.READY_ERROR && kw != "ROLLBACK" && kw != "ABORT" && kw != "COMMIT" && kw != "END" {
+		return nil, &pgproto.Error{
+			Severity: []byte("ERROR"),
+			Code:     []byte("25P02"),
+			Message:  []byte("current transaction is aborted, commands ignored until end of transaction block"),
+		}
+	}
+
+	result, err := c.server.Handler.Query(c, query, params)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = &Result{}
+	}
+
+	switch kw {
+	case "BEGIN", "START":
+		c.status = pgproto.READY_TRANSACTION
+	case "COMMIT", "END", "ROLLBACK", "ABORT":
+		c.status = pgproto.READY_IDLE
+	case "DISCARD", "DEALLOCATE":
+		if strings.HasSuffix(strings.ToUpper(strings.TrimRight(query, "; ")), " ALL") {
+			c.statements = make(map[string]*statement)
+		}
+	}
+	return result, nil
+}
+
+func (c *Conn) sendFields(fields []pgproto.RowField) {
+	if fields == nil {
+		c.send(&pgproto.NoData{})
+		return
+	}
+	c.send(&pgproto.RowDescription{Fields: fields})
+}
+
+func (c *Conn) sendResult(query string, result *Result) {
+	for _, row := range result.Rows {
+		c.send(&pgproto.DataRow{Fields: row})
+	}
+
+	tag := result.Tag
+	if tag == "" {
+		tag = commandTag(query, result)
+	}
+	c.send(&pgproto.CommandCompletion{Tag: []byte(tag)})
+}
+
+func (c *Conn) sendError(err error) {
+	c.failed = true
+	if c.status == pgproto.READY_TRANSACTION {
+		c.status = pgproto.READY_ERROR
+	}
+	if e, ok := err.(*pgproto.Error); ok {
+		c.send(e)
+		return
+	}
+	c.send(&pgproto.Error{
+		Severity: []byte("ERROR"),
+		Code:     []byte("XX000"),
+		Message:  []byte(err.Error()),
+	})
+}
+
+func (c *Conn) sendReady() {
+	c.send(&pgproto.ReadyForQuery{Status: c.status})
+}
+
+func (c *Conn) send(m pgproto.ServerMessage) {
+	pgproto.WriteMessage(m, c.w)
+}
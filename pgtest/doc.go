@@ -0,0 +1,15 @@
+/*
+Package pgtest provides an in-process PostgreSQL backend and a minimal client for testing code
+built on pgproto, such as proxies and poolers, without a real PostgreSQL server.
+
+	srv := pgtest.NewServer(pgtest.Responses{
+		"SELECT 1": {
+			Fields: []pgproto.RowField{{ColumnName: []byte("?column?"), TypeOID: 23}},
+			Rows:   [][][]byte{{[]byte("1")}},
+		},
+	})
+	defer srv.Close()
+
+	client, err := pgtest.Connect(srv.Addr, map[string]string{"user": "postgres"}, "")
+*/
+package pgtest
@@ -0,0 +1,326 @@
+package pgtest
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/internal/scram"
+)
+
+// DefaultParameters are the ParameterStatus values sent to every client after authentication
+var DefaultParameters = map[string]string{
+	"server_version":              "17.0",
+	"server_encoding":             "UTF8",
+	"client_encoding":             "UTF8",
+	"DateStyle":                   "ISO, MDY",
+	"IntervalStyle":               "postgres",
+	"TimeZone":                    "UTC",
+	"integer_datetimes":           "on",
+	"standard_conforming_strings": "on",
+}
+
+// Server is an in-process PostgreSQL backend listening on a loopback address
+type Server struct {
+	// Addr is the address the server is listening on, set once the server is started
+	Addr string
+
+	// Handler answers the queries sent by clients
+	Handler Handler
+
+	// Users maps user names to passwords, when nil every user is trusted without a password
+	Users map[string]string
+
+	// AuthMethod is the method used to authenticate Users, one of Plaintext, MD5 or SASL (SCRAM-SHA-256),
+	// defaults to MD5
+	AuthMethod pgproto.AuthenticationMethod
+
+	// Parameters are sent as ParameterStatus messages, defaults to DefaultParameters
+	Parameters map[string]string
+
+	// TLSConfig, when set, is used to accept SSL connections
+	TLSConfig *tls.Config
+
+	listener net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	conns    map[*Conn]struct{}
+	nextPID  int
+}
+
+// NewServer starts and returns a new Server using h to answer queries
+func NewServer(h Handler) *Server {
+	s := NewUnstartedServer(h)
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer returns a new Server which is not yet listening,
+// the caller can configure it before calling Start
+func NewUnstartedServer(h Handler) *Server {
+	return &Server{
+		Handler:    h,
+		AuthMethod: pgproto.AuthenticationMethodMD5,
+		conns:      make(map[*Conn]struct{}),
+	}
+}
+
+// Start will start listening on a loopback address and serving connections
+func (s *Server) Start() {
+	if s.listener != nil {
+		panic("pgtest: Server already started")
+	}
+	if s.Parameters == nil {
+		s.Parameters = DefaultParameters
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("pgtest: failed to listen: %v", err))
+	}
+	s.listener = l
+	s.Addr = l.Addr().String()
+
+	s.wg.Add(1)
+	go s.serve()
+}
+
+// Close will stop listening and close all open client connections
+func (s *Server) Close() {
+	s.listener.Close()
+
+	s.mu.Lock()
+	for c := range s.conns {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// Conns returns the currently open client connections
+func (s *Server) Conns() []*Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conns := make([]*Conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].PID < conns[j].PID })
+	return conns
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.serveConn(conn)
+		}()
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	conn, msg, err := pgproto.AcceptSSL(conn, s.TLSConfig)
+	if err != nil {
+		return
+	}
+	startup, ok := msg.(*pgproto.StartupMessage)
+	if !ok {
+		if cancel, ok := msg.(*pgproto.CancelRequest); ok {
+			s.cancel(cancel)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	s.nextPID++
+	c := &Conn{
+		PID:        s.nextPID,
+		Key:        randomInt(),
+		Startup:    startup,
+		server:     s,
+		conn:       conn,
+		r:          bufio.NewReader(conn),
+		w:          bufio.NewWriter(conn),
+		status:     pgproto.READY_IDLE,
+		statements: make(map[string]*statement),
+		portals:    make(map[string]*portal),
+	}
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+
+	if !c.authenticate() {
+		return
+	}
+	c.serve()
+}
+
+// cancel will interrupt the query running on the connection identified by the request, if any
+func (s *Server) cancel(req *pgproto.CancelRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.conns {
+		if c.PID == req.PID && c.Key == req.Key {
+			c.cancel()
+			return
+		}
+	}
+}
+
+// authenticate the client and send the initial session state, reporting whether the client may continue
+func (c *Conn) authenticate() bool {
+	user := c.User()
+	if c.server.Users != nil {
+		password, ok := c.server.Users[user]
+		if !ok || !c.checkPassword(user, password) {
+			c.send(&pgproto.Error{
+				Severity: []byte("FATAL"),
+				Code:     []byte("28P01"),
+				Message:  []byte(fmt.Sprintf("password authentication failed for user \"%s\"", user)),
+			})
+			c.w.Flush()
+			return false
+		}
+	}
+
+	c.send(&pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodOK})
+
+	names := make([]string, 0, len(c.server.Parameters))
+	for name := range c.server.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.send(&pgproto.ParameterStatus{
+			Name:  []byte(name),
+			Value: []byte(c.server.Parameters[name]),
+		})
+	}
+
+	c.send(&pgproto.BackendKeyData{PID: c.PID, Key: c.Key})
+	c.sendReady()
+	return c.w.Flush() == nil
+}
+
+func (c *Conn) checkPassword(user string, password string) bool {
+	switch c.server.AuthMethod {
+	case pgproto.AuthenticationMethodSASL:
+		return c.checkSCRAM(password)
+	case pgproto.AuthenticationMethodPlaintext:
+		c.send(&pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodPlaintext})
+		if c.w.Flush() != nil {
+			return false
+		}
+
+		msg, err := pgproto.ParsePasswordMessage(c.r)
+		if err != nil {
+			return false
+		}
+		return string(msg.Password) == password
+	default:
+		salt := make([]byte, 4)
+		rand.Read(salt)
+		c.send(&pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodMD5, Salt: salt})
+		if c.w.Flush() != nil {
+			return false
+		}
+
+		msg, err := pgproto.ParsePasswordMessage(c.r)
+		if err != nil {
+			return false
+		}
+		return msg.PasswordValid([]byte(user), []byte(password), salt)
+	}
+}
+
+// checkSCRAM runs a SCRAM-SHA-256 exchange, sending the final server message on success
+func (c *Conn) checkSCRAM(password string) bool {
+	req := &pgproto.AuthenticationRequest{
+		Method:     pgproto.AuthenticationMethodSASL,
+		Mechanisms: [][]byte{[]byte(scram.Mechanism)},
+	}
+	c.send(req)
+	if c.w.Flush() != nil {
+		return false
+	}
+
+	msg, err := pgproto.ParseAuthenticationResponse(c.r, req)
+	if err != nil {
+		return false
+	}
+	initial, ok := msg.(*pgproto.SASLInitialResponse)
+	if !ok || string(initial.Mechanism) != scram.Mechanism {
+		return false
+	}
+
+	server := scram.NewServer(password)
+	data, err := server.First(initial.Data)
+	if err != nil {
+		return false
+	}
+	req = &pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodSASLContinue, Data: data}
+	c.send(req)
+	if c.w.Flush() != nil {
+		return false
+	}
+
+	msg, err = pgproto.ParseAuthenticationResponse(c.r, req)
+	if err != nil {
+		return false
+	}
+	resp, ok := msg.(*pgproto.SASLResponse)
+	if !ok {
+		return false
+	}
+	data, err = server.Final(resp.Data)
+	if err != nil {
+		return false
+	}
+	c.send(&pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodSASLFinal, Data: data})
+	return true
+}
+
+func randomInt() int {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return int(int32(uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])))
+}
+
+// commandTag returns the default command completion tag for a query
+func commandTag(query string, result *Result) string {
+	if result.Fields != nil {
+		return fmt.Sprintf("SELECT %d", len(result.Rows))
+	}
+	return keyword(query)
+}
+
+// keyword returns the upper cased first keyword of query
+func keyword(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimRight(fields[0], ";"))
+}
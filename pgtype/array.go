@@ -0,0 +1,636 @@
+package pgtype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/c653labs/pgproto"
+)
+
+// arrayMaxDimensions is the largest number of dimensions of an array, MAXDIM in PostgreSQL
+const arrayMaxDimensions = 6
+
+// Array is an array value, its elements in row major order and the length and lower bound of each of
+// its dimensions. Empty arrays have no dimensions.
+type Array struct {
+	Elements   []interface{}
+	Dimensions []ArrayDimension
+}
+
+// ArrayDimension is the length of a dimension of an array, and the index of its first element,
+// which is 1 unless set otherwise
+type ArrayDimension struct {
+	Length     int32
+	LowerBound int32
+}
+
+// validate returns an error unless the dimensions of a hold its elements
+func (a Array) validate() error {
+	if len(a.Dimensions) > arrayMaxDimensions {
+		return fmt.Errorf("%d dimensions exceed the maximum of %d", len(a.Dimensions), arrayMaxDimensions)
+	}
+	n := 0
+	if len(a.Dimensions) > 0 {
+		n = 1
+		for _, d := range a.Dimensions {
+			if d.Length < 0 || (d.Length > 0 && n > len(a.Elements)/int(d.Length)) {
+				return fmt.Errorf("dimensions %v do not match the %d elements", a.Dimensions, len(a.Elements))
+			}
+			n *= int(d.Length)
+		}
+	}
+	if n != len(a.Elements) {
+		return fmt.Errorf("dimensions %v do not match the %d elements", a.Dimensions, len(a.Elements))
+	}
+	return nil
+}
+
+// convert allows scanning arrays into slices and Go arrays, nested as deep as the array has dimensions
+func (a Array) convert(t reflect.Type) (interface{}, error) {
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return nil, nil
+	}
+	d := reflect.New(t).Elem()
+	if len(a.Dimensions) == 0 {
+		if t.Kind() == reflect.Slice {
+			d.Set(reflect.MakeSlice(t, 0, 0))
+		}
+		return d.Interface(), nil
+	}
+	_, err := a.fill(d, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return d.Interface(), nil
+}
+
+// fill stores the elements of the dimension dim in d, from the element i, and returns the index of the
+// element following them
+func (a Array) fill(d reflect.Value, dim, i int) (int, error) {
+	n := int(a.Dimensions[dim].Length)
+	switch d.Kind() {
+	case reflect.Slice:
+		d.Set(reflect.MakeSlice(d.Type(), n, n))
+	case reflect.Array:
+		if d.Len() != n {
+			return 0, fmt.Errorf("cannot scan an array of length %d into %s", n, d.Type())
+		}
+	default:
+		return 0, fmt.Errorf("cannot scan a %d dimensional array into %s: %w", len(a.Dimensions), d.Type(), errMismatch)
+	}
+
+	var err error
+	for k := 0; k < n; k++ {
+		e := d.Index(k)
+		if dim+1 == len(a.Dimensions) {
+			err = assignValue(e, a.Elements[i])
+			if err != nil {
+				return 0, err
+			}
+			i++
+			continue
+		}
+
+		// The sub-arrays of interfaces are slices of interfaces
+		if e.Kind() == reflect.Interface {
+			sub := reflect.New(reflect.TypeOf([]interface{}{})).Elem()
+			i, err = a.fill(sub, dim+1, i)
+			if err != nil {
+				return 0, err
+			}
+			e.Set(sub)
+			continue
+		}
+		i, err = a.fill(e, dim+1, i)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return i, nil
+}
+
+// ArrayCodec converts arrays of ElementOID values to and from an Array, whose elements are converted
+// by the codec of ElementOID in the TypeMap
+//
+// Arrays are scanned into slices, nested for each dimension, whose elements the values of ElementOID
+// are scanned into. Arrays, and slices and Go arrays nested as deep as their number of dimensions,
+// are encoded. Byte slices and arrays are elements, e.g. of bytea arrays, rather than dimensions.
+type ArrayCodec struct {
+	ElementOID int
+
+	// Delimiter separates the elements in the text format, a comma when zero. Only box arrays use
+	// another, a semicolon.
+	Delimiter byte
+}
+
+// delimiter returns the delimiter of the elements in the text format
+func (c ArrayCodec) delimiter() byte {
+	if c.Delimiter == 0 {
+		return ','
+	}
+	return c.Delimiter
+}
+
+// DecodeText decodes an array in curly braces, e.g. {{1,2},{3,NULL}}, whose elements are quoted when
+// they contain special characters, e.g. {"a b","c\"d",NULL,"NULL"}. Lower bounds other than 1 are
+// given before the array, e.g. [0:1]={1,2}.
+func (c ArrayCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	p := arrayParser{src: string(src), delimiter: c.delimiter(), leaf: -1}
+
+	var bounds []ArrayDimension
+	p.skipSpace()
+	for p.peek() == '[' {
+		d, err := p.parseBounds()
+		if err != nil {
+			return nil, err
+		}
+		bounds = append(bounds, d)
+		p.skipSpace()
+	}
+	if len(bounds) > 0 && !p.consume('=') {
+		return nil, fmt.Errorf("invalid array %q: expected '=' after the dimensions", src)
+	}
+
+	p.skipSpace()
+	err := p.parseArray(0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid array %q: %w", src, err)
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("invalid array %q: unexpected text after the array", src)
+	}
+
+	var a Array
+	for _, n := range p.lengths {
+		a.Dimensions = append(a.Dimensions, ArrayDimension{Length: int32(n), LowerBound: 1})
+	}
+	if len(bounds) > 0 {
+		if len(bounds) != len(a.Dimensions) {
+			return nil, fmt.Errorf("invalid array %q: the dimensions do not match the array", src)
+		}
+		for i, d := range bounds {
+			if d.Length != a.Dimensions[i].Length {
+				return nil, fmt.Errorf("invalid array %q: the dimensions do not match the array", src)
+			}
+		}
+		a.Dimensions = bounds
+	}
+
+	a.Elements = make([]interface{}, len(p.elements))
+	for i, e := range p.elements {
+		if e == nil {
+			continue
+		}
+		a.Elements[i], err = m.decode(c.ElementOID, pgproto.FormatText, []byte(*e))
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i+1, err)
+		}
+	}
+	if len(a.Dimensions) == 0 {
+		a.Elements = nil
+	}
+	return a, nil
+}
+
+func (c ArrayCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	a, err := c.array(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(a.Dimensions) == 0 {
+		return append(buf, "{}"...), nil
+	}
+
+	for _, d := range a.Dimensions {
+		if d.LowerBound != 1 {
+			for _, d := range a.Dimensions {
+				buf = append(buf, '[')
+				buf = strconv.AppendInt(buf, int64(d.LowerBound), 10)
+				buf = append(buf, ':')
+				buf = strconv.AppendInt(buf, int64(d.LowerBound)+int64(d.Length)-1, 10)
+				buf = append(buf, ']')
+			}
+			buf = append(buf, '=')
+			break
+		}
+	}
+
+	i := 0
+	return c.appendText(m, buf, a, 0, &i)
+}
+
+// appendText appends the elements of the dimension dim of a, from the element *i
+func (c ArrayCodec) appendText(m *TypeMap, buf []byte, a Array, dim int, i *int) ([]byte, error) {
+	buf = append(buf, '{')
+	for k := 0; k < int(a.Dimensions[dim].Length); k++ {
+		if k > 0 {
+			buf = append(buf, c.delimiter())
+		}
+		if dim+1 < len(a.Dimensions) {
+			var err error
+			buf, err = c.appendText(m, buf, a, dim+1, i)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		text, err := m.encode(c.ElementOID, pgproto.FormatText, a.Elements[*i])
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", *i+1, err)
+		}
+		*i++
+		if text == nil {
+			buf = append(buf, "NULL"...)
+			continue
+		}
+		buf = c.appendElement(buf, string(text))
+	}
+	return append(buf, '}'), nil
+}
+
+// appendElement appends the text of an element, quoted when it is empty, NULL, or contains special
+// characters
+func (c ArrayCodec) appendElement(buf []byte, text string) []byte {
+	special := `{}"\ ` + "\t\n\r\v\f" + string(c.delimiter())
+	if text != "" && !strings.EqualFold(text, "NULL") && !strings.ContainsAny(text, special) {
+		return append(buf, text...)
+	}
+
+	buf = append(buf, '"')
+	for i := 0; i < len(text); i++ {
+		if text[i] == '"' || text[i] == '\\' {
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, text[i])
+	}
+	return append(buf, '"')
+}
+
+// DecodeBinary decodes the number of dimensions, whether there are NULL elements, and the element OID
+// as 32 bit integers, followed by the length and lower bound of each dimension as 32 bit integers,
+// and by the elements, each the 32 bit length of its value, -1 for NULL, and the value
+func (c ArrayCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	if len(src) < 12 {
+		return nil, fmt.Errorf("expected at least 12 bytes, got %d", len(src))
+	}
+	ndim := int32(binary.BigEndian.Uint32(src))
+	elementOID := int(binary.BigEndian.Uint32(src[8:]))
+	if ndim < 0 || ndim > arrayMaxDimensions {
+		return nil, fmt.Errorf("invalid number of dimensions %d", ndim)
+	}
+	src = src[12:]
+	if len(src) < 8*int(ndim) {
+		return nil, errors.New("array dimensions are truncated")
+	}
+
+	var a Array
+	n := 1
+	for k := 0; k < int(ndim); k++ {
+		d := ArrayDimension{
+			Length:     int32(binary.BigEndian.Uint32(src)),
+			LowerBound: int32(binary.BigEndian.Uint32(src[4:])),
+		}
+		// Each element takes at least 4 bytes
+		if d.Length < 0 || (d.Length > 0 && n > len(src)/4/int(d.Length)) {
+			return nil, fmt.Errorf("invalid array length %d", d.Length)
+		}
+		n *= int(d.Length)
+		a.Dimensions = append(a.Dimensions, d)
+		src = src[8:]
+	}
+	if ndim == 0 {
+		n = 0
+	}
+
+	a.Elements = make([]interface{}, n)
+	for k := range a.Elements {
+		if len(src) < 4 {
+			return nil, errors.New("array elements are truncated")
+		}
+		length := int32(binary.BigEndian.Uint32(src))
+		src = src[4:]
+		if length < -1 {
+			return nil, fmt.Errorf("invalid length %d of element %d", length, k+1)
+		}
+		if length == -1 {
+			continue
+		}
+		if int(length) > len(src) {
+			return nil, errors.New("array elements are truncated")
+		}
+		var err error
+		a.Elements[k], err = m.decode(elementOID, pgproto.FormatBinary, src[:length])
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", k+1, err)
+		}
+		src = src[length:]
+	}
+	if len(src) > 0 {
+		return nil, fmt.Errorf("%d unexpected bytes after the elements", len(src))
+	}
+	if n == 0 {
+		a.Elements = nil
+	}
+	return a, nil
+}
+
+func (c ArrayCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	a, err := c.array(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var nulls uint32
+	elements := make([][]byte, len(a.Elements))
+	for i, e := range a.Elements {
+		elements[i], err = m.encode(c.ElementOID, pgproto.FormatBinary, e)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i+1, err)
+		}
+		if elements[i] == nil {
+			nulls = 1
+		}
+	}
+
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(a.Dimensions)))
+	buf = binary.BigEndian.AppendUint32(buf, nulls)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(c.ElementOID))
+	for _, d := range a.Dimensions {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(d.Length))
+		buf = binary.BigEndian.AppendUint32(buf, uint32(d.LowerBound))
+	}
+	for _, e := range elements {
+		if e == nil {
+			buf = binary.BigEndian.AppendUint32(buf, 0xffffffff)
+			continue
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(e)))
+		buf = append(buf, e...)
+	}
+	return buf, nil
+}
+
+// array converts v, an Array or nested slices or Go arrays, to an Array
+func (ArrayCodec) array(v interface{}) (Array, error) {
+	if a, ok := v.(Array); ok {
+		return a, a.validate()
+	}
+	r := reflect.ValueOf(v)
+	if !isDimension(r) {
+		return Array{}, fmt.Errorf("cannot encode %T", v)
+	}
+
+	// The dimensions are the lengths of the first sub-array of each level
+	var a Array
+	for d := r; isDimension(d); d = elem(d.Index(0)) {
+		if d.Len() == 0 {
+			return Array{}, nil
+		}
+		a.Dimensions = append(a.Dimensions, ArrayDimension{Length: int32(d.Len()), LowerBound: 1})
+	}
+	if len(a.Dimensions) > arrayMaxDimensions {
+		return Array{}, fmt.Errorf("%d dimensions exceed the maximum of %d", len(a.Dimensions), arrayMaxDimensions)
+	}
+	return a, a.flatten(r, 0)
+}
+
+// flatten appends the elements of d, the dimension dim, to the elements of a
+func (a *Array) flatten(d reflect.Value, dim int) error {
+	if d.Len() != int(a.Dimensions[dim].Length) {
+		return errors.New("multidimensional arrays must have sub-arrays of matching dimensions")
+	}
+	for k := 0; k < d.Len(); k++ {
+		e := elem(d.Index(k))
+		if dim+1 == len(a.Dimensions) {
+			a.Elements = append(a.Elements, e.Interface())
+			continue
+		}
+		if !isDimension(e) {
+			return errors.New("multidimensional arrays must have sub-arrays of matching dimensions")
+		}
+		err := a.flatten(e, dim+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isDimension returns whether r is a slice or Go array holding a dimension of an array, rather than an
+// element, which byte slices and arrays are
+func isDimension(r reflect.Value) bool {
+	switch r.Kind() {
+	case reflect.Slice, reflect.Array:
+		return r.Type().Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+// elem returns the value in the interface r, or r when it is not an interface
+func elem(r reflect.Value) reflect.Value {
+	for r.Kind() == reflect.Interface && !r.IsNil() {
+		r = r.Elem()
+	}
+	return r
+}
+
+// arrayParser parses the text representation of arrays
+type arrayParser struct {
+	src       string
+	pos       int
+	delimiter byte
+
+	// lengths are the lengths of the dimensions, elements the elements with nil for NULL, and leaf the
+	// depth of the elements, -1 until the first one
+	lengths  []int
+	elements []*string
+	leaf     int
+}
+
+// peek returns the next byte, 0 at the end of the text
+func (p *arrayParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+// consume skips the next byte when it is c, and returns whether it was
+func (p *arrayParser) consume(c byte) bool {
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// skipSpace skips the whitespace PostgreSQL allows around the elements
+func (p *arrayParser) skipSpace() {
+	for p.pos < len(p.src) && isArraySpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// parseBounds parses the bounds of a dimension, [lower:upper] or [upper] for a lower bound of 1
+func (p *arrayParser) parseBounds() (ArrayDimension, error) {
+	end := strings.IndexByte(p.src[p.pos:], ']')
+	if end < 0 {
+		return ArrayDimension{}, fmt.Errorf("invalid array dimensions %q", p.src)
+	}
+	bounds := p.src[p.pos+1 : p.pos+end]
+	p.pos += end + 1
+
+	lower, upper := "1", bounds
+	if i := strings.IndexByte(bounds, ':'); i >= 0 {
+		lower, upper = bounds[:i], bounds[i+1:]
+	}
+	l, err := strconv.ParseInt(strings.TrimSpace(lower), 10, 32)
+	if err != nil {
+		return ArrayDimension{}, fmt.Errorf("invalid array dimensions %q", p.src)
+	}
+	u, err := strconv.ParseInt(strings.TrimSpace(upper), 10, 32)
+	if err != nil || u < l-1 || u-l+1 > 1<<31-1 {
+		return ArrayDimension{}, fmt.Errorf("invalid array dimensions %q", p.src)
+	}
+	return ArrayDimension{Length: int32(u - l + 1), LowerBound: int32(l)}, nil
+}
+
+// parseArray parses the sub-array in curly braces of the dimension dim
+func (p *arrayParser) parseArray(dim int) error {
+	if dim >= arrayMaxDimensions {
+		return fmt.Errorf("the number of dimensions exceeds the maximum of %d", arrayMaxDimensions)
+	}
+	if !p.consume('{') {
+		return errors.New("expected '{'")
+	}
+	if dim == len(p.lengths) {
+		p.lengths = append(p.lengths, -1)
+	}
+
+	p.skipSpace()
+	if p.consume('}') {
+		if dim > 0 {
+			return errors.New("empty sub-array")
+		}
+		p.lengths = nil
+		return nil
+	}
+
+	n := 0
+	for {
+		p.skipSpace()
+		if p.peek() == '{' {
+			if p.leaf >= 0 && dim >= p.leaf {
+				return errors.New("multidimensional arrays must have sub-arrays of matching dimensions")
+			}
+			err := p.parseArray(dim + 1)
+			if err != nil {
+				return err
+			}
+		} else {
+			if p.leaf >= 0 && p.leaf != dim || len(p.lengths) > dim+1 {
+				return errors.New("multidimensional arrays must have sub-arrays of matching dimensions")
+			}
+			p.leaf = dim
+			e, err := p.parseElement()
+			if err != nil {
+				return err
+			}
+			p.elements = append(p.elements, e)
+		}
+		n++
+
+		p.skipSpace()
+		if p.consume(p.delimiter) {
+			continue
+		}
+		if p.consume('}') {
+			break
+		}
+		return fmt.Errorf("unexpected character at offset %d", p.pos)
+	}
+
+	if p.lengths[dim] >= 0 && p.lengths[dim] != n {
+		return errors.New("multidimensional arrays must have sub-arrays of matching dimensions")
+	}
+	p.lengths[dim] = n
+	return nil
+}
+
+// parseElement parses an element, quoted or not, whose special characters may be escaped by a
+// backslash, and returns nil for NULL
+func (p *arrayParser) parseElement() (*string, error) {
+	var b strings.Builder
+	if p.consume('"') {
+		for {
+			if p.pos >= len(p.src) {
+				return nil, errors.New("unterminated quoted element")
+			}
+			c := p.src[p.pos]
+			p.pos++
+			switch c {
+			case '"':
+				s := b.String()
+				return &s, nil
+			case '\\':
+				if p.pos >= len(p.src) {
+					return nil, errors.New("unterminated quoted element")
+				}
+				c = p.src[p.pos]
+				p.pos++
+			}
+			b.WriteByte(c)
+		}
+	}
+
+	// Whitespace is trimmed around unquoted elements unless escaped
+	escaped, trailing := false, 0
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == p.delimiter || c == '}' {
+			break
+		}
+		if c == '{' || c == '"' {
+			return nil, fmt.Errorf("unexpected character at offset %d", p.pos)
+		}
+		p.pos++
+		if c == '\\' {
+			if p.pos >= len(p.src) {
+				return nil, errors.New("unterminated escape")
+			}
+			c = p.src[p.pos]
+			p.pos++
+			escaped = true
+			trailing = 0
+		} else if isArraySpace(c) {
+			trailing++
+		} else {
+			trailing = 0
+		}
+		b.WriteByte(c)
+	}
+	s := b.String()
+	s = s[:len(s)-trailing]
+	if s == "" && !escaped {
+		return nil, fmt.Errorf("unexpected character at offset %d", p.pos)
+	}
+	if !escaped && strings.EqualFold(s, "NULL") {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// isArraySpace returns whether c is whitespace around the elements of an array
+func isArraySpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package pgtype_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtype"
+	"github.com/stretchr/testify/suite"
+)
+
+type ArrayTestSuite struct {
+	suite.Suite
+	m *pgtype.TypeMap
+}
+
+func TestArrayTestSuite(t *testing.T) {
+	suite.Run(t, new(ArrayTestSuite))
+}
+
+func (s *ArrayTestSuite) SetupTest() {
+	s.m = pgtype.NewTypeMap()
+}
+
+func (s *ArrayTestSuite) Test_Formats() {
+	cases := []struct {
+		oid    int
+		text   string
+		binary []byte
+		array  pgtype.Array
+	}{
+		{
+			pgtype.Int4ArrayOID,
+			"{1,NULL}",
+			[]byte{
+				0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x17,
+				0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01,
+				0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01,
+				0xff, 0xff, 0xff, 0xff,
+			},
+			pgtype.Array{Elements: []interface{}{int32(1), nil}, Dimensions: []pgtype.ArrayDimension{{Length: 2, LowerBound: 1}}},
+		},
+		{
+			pgtype.Int2ArrayOID,
+			"[0:1][1:1]={{7},{-8}}",
+			[]byte{
+				0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x15,
+				0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01,
+				0x00, 0x00, 0x00, 0x02, 0x00, 0x07,
+				0x00, 0x00, 0x00, 0x02, 0xff, 0xf8,
+			},
+			pgtype.Array{Elements: []interface{}{int16(7), int16(-8)}, Dimensions: []pgtype.ArrayDimension{{Length: 2, LowerBound: 0}, {Length: 1, LowerBound: 1}}},
+		},
+		{
+			pgtype.TextArrayOID,
+			`{"a b","c\"d",NULL,"NULL","",é}`,
+			[]byte{
+				0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x19,
+				0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x01,
+				0x00, 0x00, 0x00, 0x03, 'a', ' ', 'b',
+				0x00, 0x00, 0x00, 0x03, 'c', '"', 'd',
+				0xff, 0xff, 0xff, 0xff,
+				0x00, 0x00, 0x00, 0x04, 'N', 'U', 'L', 'L',
+				0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x02, 0xc3, 0xa9,
+			},
+			pgtype.Array{Elements: []interface{}{"a b", `c"d`, nil, "NULL", "", "é"}, Dimensions: []pgtype.ArrayDimension{{Length: 6, LowerBound: 1}}},
+		},
+		{
+			pgtype.Float8ArrayOID,
+			"{}",
+			[]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xbd},
+			pgtype.Array{},
+		},
+	}
+
+	for _, c := range cases {
+		v, err := s.m.Decode(c.oid, pgproto.FormatText, []byte(c.text))
+		s.Nil(err, c.text)
+		s.Equal(c.array, v, c.text)
+		v, err = s.m.Decode(c.oid, pgproto.FormatBinary, c.binary)
+		s.Nil(err, c.text)
+		s.Equal(c.array, v, c.text)
+
+		encoded, err := s.m.Encode(c.oid, pgproto.FormatText, c.array)
+		s.Nil(err, c.text)
+		s.Equal(c.text, string(encoded))
+		encoded, err = s.m.Encode(c.oid, pgproto.FormatBinary, c.array)
+		s.Nil(err, c.text)
+		s.Equal(c.binary, encoded, c.text)
+	}
+
+	// Element lengths below -1 are invalid rather than NULL
+	_, err := s.m.Decode(pgtype.Int4ArrayOID, pgproto.FormatBinary, []byte{
+		0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x17,
+		0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01,
+		0xff, 0xff, 0xff, 0xfe,
+	})
+	s.NotNil(err)
+}
+
+func (s *ArrayTestSuite) Test_DecodeText() {
+	cases := []struct {
+		oid      int
+		text     string
+		expected []interface{}
+	}{
+		// Whitespace around unquoted elements is trimmed, and any character may be escaped
+		{pgtype.TextArrayOID, `{ a , b c ,\ d\ ,e\,f,"g\\" , null}`, []interface{}{"a", "b c", " d ", "e,f", `g\`, nil}},
+		{pgtype.TextArrayOID, `{\NULL,"null"}`, []interface{}{"NULL", "null"}},
+		{pgtype.ByteaArrayOID, `{"\\x00ff",NULL}`, []interface{}{[]byte{0x00, 0xff}, nil}},
+		{pgtype.DateArrayOID, `{2000-01-01,infinity}`, []interface{}{time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC), pgtype.Infinity}},
+		{pgtype.BoolArrayOID, "[1:2]={t,f}", []interface{}{true, false}},
+		{pgtype.BoolArrayOID, "[2]={t,f}", []interface{}{true, false}},
+	}
+	for _, c := range cases {
+		v, err := s.m.Decode(c.oid, pgproto.FormatText, []byte(c.text))
+		s.Nil(err, c.text)
+		s.Equal(c.expected, v.(pgtype.Array).Elements, c.text)
+	}
+
+	for _, invalid := range []string{
+		"", "{", "{1", "{1,}", "{,1}", "{1}}", "{1} x", "{{1},2}", "{1,{2}}", "{{1,2},{3}}", "{{}}",
+		`{"1}`, `{1"2"}`, "[1:3]={1,2}", "[1:2]{1,2}", "[a]={1}", "{{{{{{{1}}}}}}}", "{a}",
+	} {
+		_, err := s.m.Decode(pgtype.Int4ArrayOID, pgproto.FormatText, []byte(invalid))
+		s.NotNil(err, invalid)
+	}
+
+	// Box arrays are delimited by semicolons
+	c := pgtype.ArrayCodec{ElementOID: pgtype.TextOID, Delimiter: ';'}
+	v, err := c.DecodeText(s.m, []byte(`{a,b;"c;d"}`))
+	s.Nil(err)
+	s.Equal([]interface{}{"a,b", "c;d"}, v.(pgtype.Array).Elements)
+	encoded, err := c.EncodeText(s.m, nil, []string{"a,b", "c;d"})
+	s.Nil(err)
+	s.Equal(`{a,b;"c;d"}`, string(encoded))
+}
+
+func (s *ArrayTestSuite) Test_Scan() {
+	src := []byte("{{1,2,3},{4,5,6}}")
+
+	var ints [][]int
+	s.Nil(s.m.Scan(pgtype.Int8ArrayOID, pgproto.FormatText, src, &ints))
+	s.Equal([][]int{{1, 2, 3}, {4, 5, 6}}, ints)
+
+	var fixed [2][3]int64
+	s.Nil(s.m.Scan(pgtype.Int8ArrayOID, pgproto.FormatText, src, &fixed))
+	s.Equal([2][3]int64{{1, 2, 3}, {4, 5, 6}}, fixed)
+
+	var nested []interface{}
+	s.Nil(s.m.Scan(pgtype.Int8ArrayOID, pgproto.FormatText, src, &nested))
+	s.Equal([]interface{}{[]interface{}{int64(1), int64(2), int64(3)}, []interface{}{int64(4), int64(5), int64(6)}}, nested)
+
+	var flat []int
+	s.NotNil(s.m.Scan(pgtype.Int8ArrayOID, pgproto.FormatText, src, &flat))
+	var short [2][2]int64
+	s.NotNil(s.m.Scan(pgtype.Int8ArrayOID, pgproto.FormatText, src, &short))
+
+	// NULL elements are scanned into pointers
+	var ptrs []*string
+	s.Nil(s.m.Scan(pgtype.TextArrayOID, pgproto.FormatText, []byte("{a,NULL}"), &ptrs))
+	s.Equal(2, len(ptrs))
+	s.Equal("a", *ptrs[0])
+	s.Nil(ptrs[1])
+	var strs []string
+	s.NotNil(s.m.Scan(pgtype.TextArrayOID, pgproto.FormatText, []byte("{a,NULL}"), &strs))
+
+	var floats []float64
+	s.Nil(s.m.Scan(pgtype.NumericArrayOID, pgproto.FormatText, []byte("{1.5,-2}"), &floats))
+	s.Equal([]float64{1.5, -2}, floats)
+
+	// Empty arrays are empty slices
+	s.Nil(s.m.Scan(pgtype.Int4ArrayOID, pgproto.FormatText, []byte("{}"), &ints))
+	s.NotNil(ints)
+	s.Equal(0, len(ints))
+
+	// The text of an array is stored in a string
+	var text string
+	s.Nil(s.m.Scan(pgtype.Int4ArrayOID, pgproto.FormatText, src, &text))
+	s.Equal(string(src), text)
+}
+
+func (s *ArrayTestSuite) Test_Encode() {
+	cases := []struct {
+		oid      int
+		value    interface{}
+		expected string
+	}{
+		{pgtype.Int4ArrayOID, []int{1, 2, 3}, "{1,2,3}"},
+		{pgtype.Int4ArrayOID, [][]int64{{1, 2}, {3, 4}}, "{{1,2},{3,4}}"},
+		{pgtype.Int4ArrayOID, []interface{}{[]interface{}{1, nil}, []int{3, 4}}, "{{1,NULL},{3,4}}"},
+		{pgtype.Int4ArrayOID, [2]int16{1, 2}, "{1,2}"},
+		{pgtype.Int4ArrayOID, []int{}, "{}"},
+		{pgtype.Int4ArrayOID, [][]int{{}, {}}, "{}"},
+		{pgtype.TextArrayOID, []string{"a b", `c"d\`, "NULL", "", "{x}", "y,z", "ok"}, `{"a b","c\"d\\","NULL","","{x}","y,z",ok}`},
+		{pgtype.TextArrayOID, []*string{nil}, "{NULL}"},
+		{pgtype.ByteaArrayOID, []interface{}{[]byte{0x00, 0xff}, nil}, `{"\\x00ff",NULL}`},
+		{pgtype.TimestampArrayOID, []time.Time{time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)}, `{"2000-01-01 00:00:00"}`},
+		{pgtype.UUIDArrayOID, []pgtype.UUID{{0xa0, 0xee, 0xbc, 0x99, 0x9c, 0x0b, 0x4e, 0xf8, 0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11}}, "{a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11}"},
+	}
+	for _, c := range cases {
+		encoded, err := s.m.Encode(c.oid, pgproto.FormatText, c.value)
+		s.Nil(err, c.value)
+		s.Equal(c.expected, string(encoded), c.value)
+
+		// The binary representation holds the same array
+		binary, err := s.m.Encode(c.oid, pgproto.FormatBinary, c.value)
+		s.Nil(err, c.value)
+		fromBinary, err := s.m.Decode(c.oid, pgproto.FormatBinary, binary)
+		s.Nil(err, c.value)
+		fromText, err := s.m.Decode(c.oid, pgproto.FormatText, encoded)
+		s.Nil(err, c.value)
+		s.Equal(fromText, fromBinary, c.value)
+	}
+
+	for _, invalid := range []interface{}{
+		[][]int{{1, 2}, {3}},
+		[]interface{}{[]int{1}, 2},
+		[]interface{}{1, []int{2}},
+		[]int64{1 << 40},
+		pgtype.Array{Elements: []interface{}{1}, Dimensions: []pgtype.ArrayDimension{{Length: 2, LowerBound: 1}}},
+		42,
+	} {
+		_, err := s.m.Encode(pgtype.Int4ArrayOID, pgproto.FormatText, invalid)
+		s.NotNil(err, invalid)
+		_, err = s.m.Encode(pgtype.Int4ArrayOID, pgproto.FormatBinary, invalid)
+		s.NotNil(err, invalid)
+	}
+}
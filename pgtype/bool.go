@@ -0,0 +1,52 @@
+package pgtype
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// BoolCodec converts bool values to and from a Go bool
+type BoolCodec struct{}
+
+// DecodeText accepts every representation PostgreSQL accepts for booleans, e.g. "t", "yes" or "on"
+func (BoolCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	switch strings.ToLower(strings.TrimSpace(string(src))) {
+	case "t", "true", "y", "yes", "on", "1":
+		return true, nil
+	case "f", "false", "n", "no", "off", "0":
+		return false, nil
+	}
+	return nil, fmt.Errorf("invalid boolean %q", src)
+}
+
+func (BoolCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	r := reflect.ValueOf(v)
+	if r.Kind() != reflect.Bool {
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	if r.Bool() {
+		return append(buf, 't'), nil
+	}
+	return append(buf, 'f'), nil
+}
+
+// DecodeBinary decodes a single byte, 1 for true and 0 for false
+func (BoolCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	err := checkSize(src, 1)
+	if err != nil {
+		return nil, err
+	}
+	return src[0] != 0, nil
+}
+
+func (BoolCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	r := reflect.ValueOf(v)
+	if r.Kind() != reflect.Bool {
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	if r.Bool() {
+		return append(buf, 1), nil
+	}
+	return append(buf, 0), nil
+}
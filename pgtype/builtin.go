@@ -0,0 +1,89 @@
+package pgtype
+
+// builtinTypes are the base, range and multirange types of the pg_type catalog of PostgreSQL, and the
+// pseudo-types values are returned as. Their array types are registered from their ArrayOID.
+var builtinTypes = []Type{
+	{Name: "bool", OID: BoolOID, ArrayOID: BoolArrayOID, Category: 'B', Len: 1, Codec: BoolCodec{}},
+	{Name: "bytea", OID: ByteaOID, ArrayOID: ByteaArrayOID, Category: 'U', Len: -1, Codec: ByteaCodec{}},
+	{Name: "char", OID: CharOID, ArrayOID: CharArrayOID, Category: 'Z', Len: 1, Codec: CharCodec{}},
+	{Name: "name", OID: NameOID, ArrayOID: NameArrayOID, Category: 'S', Len: 64, Codec: TextCodec{}},
+	{Name: "int8", OID: Int8OID, ArrayOID: Int8ArrayOID, Category: 'N', Len: 8, Codec: IntCodec{Size: 8}},
+	{Name: "int2", OID: Int2OID, ArrayOID: Int2ArrayOID, Category: 'N', Len: 2, Codec: IntCodec{Size: 2}},
+	{Name: "int2vector", OID: Int2VectorOID, ArrayOID: Int2VectorArrayOID, Category: 'A', Len: -1},
+	{Name: "int4", OID: Int4OID, ArrayOID: Int4ArrayOID, Category: 'N', Len: 4, Codec: IntCodec{Size: 4}},
+	{Name: "regproc", OID: RegProcOID, ArrayOID: RegProcArrayOID, Category: 'N', Len: 4},
+	{Name: "text", OID: TextOID, ArrayOID: TextArrayOID, Category: 'S', Len: -1, Codec: TextCodec{}},
+	{Name: "oid", OID: OIDOID, ArrayOID: OIDArrayOID, Category: 'N', Len: 4, Codec: OIDCodec{}},
+	{Name: "tid", OID: TIDOID, ArrayOID: TIDArrayOID, Category: 'U', Len: 6},
+	{Name: "xid", OID: XIDOID, ArrayOID: XIDArrayOID, Category: 'U', Len: 4, Codec: OIDCodec{}},
+	{Name: "cid", OID: CIDOID, ArrayOID: CIDArrayOID, Category: 'U', Len: 4, Codec: OIDCodec{}},
+	{Name: "oidvector", OID: OIDVectorOID, ArrayOID: OIDVectorArrayOID, Category: 'A', Len: -1},
+	{Name: "json", OID: JSONOID, ArrayOID: JSONArrayOID, Category: 'U', Len: -1, Codec: JSONCodec{}},
+	{Name: "xml", OID: XMLOID, ArrayOID: XMLArrayOID, Category: 'U', Len: -1, Codec: TextCodec{}},
+	{Name: "pg_node_tree", OID: PgNodeTreeOID, Category: 'Z', Len: -1, Codec: TextCodec{}},
+	{Name: "xid8", OID: XID8OID, ArrayOID: XID8ArrayOID, Category: 'U', Len: 8},
+	{Name: "point", OID: PointOID, ArrayOID: PointArrayOID, Category: 'G', Len: 16, Codec: PointCodec{}},
+	{Name: "lseg", OID: LsegOID, ArrayOID: LsegArrayOID, Category: 'G', Len: 32, Codec: LsegCodec{}},
+	{Name: "path", OID: PathOID, ArrayOID: PathArrayOID, Category: 'G', Len: -1, Codec: PathCodec{}},
+	{Name: "box", OID: BoxOID, ArrayOID: BoxArrayOID, Category: 'G', Len: 32, Delimiter: ';', Codec: BoxCodec{}},
+	{Name: "polygon", OID: PolygonOID, ArrayOID: PolygonArrayOID, Category: 'G', Len: -1, Codec: PolygonCodec{}},
+	{Name: "line", OID: LineOID, ArrayOID: LineArrayOID, Category: 'G', Len: 24, Codec: LineCodec{}},
+	{Name: "float4", OID: Float4OID, ArrayOID: Float4ArrayOID, Category: 'N', Len: 4, Codec: FloatCodec{Size: 4}},
+	{Name: "float8", OID: Float8OID, ArrayOID: Float8ArrayOID, Category: 'N', Len: 8, Codec: FloatCodec{Size: 8}},
+	{Name: "unknown", OID: UnknownOID, Category: 'X', Len: -2, Codec: TextCodec{}},
+	{Name: "circle", OID: CircleOID, ArrayOID: CircleArrayOID, Category: 'G', Len: 24, Codec: CircleCodec{}},
+	{Name: "money", OID: MoneyOID, ArrayOID: MoneyArrayOID, Category: 'N', Len: 8},
+	{Name: "macaddr", OID: MACAddrOID, ArrayOID: MACAddrArrayOID, Category: 'U', Len: 6, Codec: MACAddrCodec{Size: 6}},
+	{Name: "inet", OID: InetOID, ArrayOID: InetArrayOID, Category: 'I', Len: -1, Codec: InetCodec{}},
+	{Name: "cidr", OID: CIDROID, ArrayOID: CIDRArrayOID, Category: 'I', Len: -1, Codec: InetCodec{CIDR: true}},
+	{Name: "macaddr8", OID: MACAddr8OID, ArrayOID: MACAddr8ArrayOID, Category: 'U', Len: 8, Codec: MACAddrCodec{Size: 8}},
+	{Name: "aclitem", OID: ACLItemOID, ArrayOID: ACLItemArrayOID, Category: 'U', Len: 16},
+	{Name: "bpchar", OID: BPCharOID, ArrayOID: BPCharArrayOID, Category: 'S', Len: -1, Codec: TextCodec{}},
+	{Name: "varchar", OID: VarcharOID, ArrayOID: VarcharArrayOID, Category: 'S', Len: -1, Codec: TextCodec{}},
+	{Name: "date", OID: DateOID, ArrayOID: DateArrayOID, Category: 'D', Len: 4, Codec: DateCodec{}},
+	{Name: "time", OID: TimeOID, ArrayOID: TimeArrayOID, Category: 'D', Len: 8, Codec: TimeCodec{}},
+	{Name: "timestamp", OID: TimestampOID, ArrayOID: TimestampArrayOID, Category: 'D', Len: 8, Codec: TimestampCodec{}},
+	{Name: "timestamptz", OID: TimestamptzOID, ArrayOID: TimestamptzArrayOID, Category: 'D', Len: 8, Codec: TimestampCodec{WithTimeZone: true}},
+	{Name: "interval", OID: IntervalOID, ArrayOID: IntervalArrayOID, Category: 'T', Len: 16, Codec: IntervalCodec{}},
+	{Name: "timetz", OID: TimetzOID, ArrayOID: TimetzArrayOID, Category: 'D', Len: 12, Codec: TimeCodec{WithTimeZone: true}},
+	{Name: "bit", OID: BitOID, ArrayOID: BitArrayOID, Category: 'V', Len: -1},
+	{Name: "varbit", OID: VarbitOID, ArrayOID: VarbitArrayOID, Category: 'V', Len: -1},
+	{Name: "numeric", OID: NumericOID, ArrayOID: NumericArrayOID, Category: 'N', Len: -1, Codec: NumericCodec{}},
+	{Name: "refcursor", OID: RefcursorOID, ArrayOID: RefcursorArrayOID, Category: 'U', Len: -1, Codec: TextCodec{}},
+	{Name: "regprocedure", OID: RegProcedureOID, ArrayOID: RegProcedureArrayOID, Category: 'N', Len: 4},
+	{Name: "regoper", OID: RegOperOID, ArrayOID: RegOperArrayOID, Category: 'N', Len: 4},
+	{Name: "regoperator", OID: RegOperatorOID, ArrayOID: RegOperatorArrayOID, Category: 'N', Len: 4},
+	{Name: "regclass", OID: RegClassOID, ArrayOID: RegClassArrayOID, Category: 'N', Len: 4},
+	{Name: "regcollation", OID: RegCollationOID, ArrayOID: RegCollationArrayOID, Category: 'N', Len: 4},
+	{Name: "regtype", OID: RegTypeOID, ArrayOID: RegTypeArrayOID, Category: 'N', Len: 4},
+	{Name: "regrole", OID: RegRoleOID, ArrayOID: RegRoleArrayOID, Category: 'N', Len: 4},
+	{Name: "regnamespace", OID: RegNamespaceOID, ArrayOID: RegNamespaceArrayOID, Category: 'N', Len: 4},
+	{Name: "uuid", OID: UUIDOID, ArrayOID: UUIDArrayOID, Category: 'U', Len: 16, Codec: UUIDCodec{}},
+	{Name: "pg_lsn", OID: PgLSNOID, ArrayOID: PgLSNArrayOID, Category: 'U', Len: 8},
+	{Name: "tsvector", OID: TSVectorOID, ArrayOID: TSVectorArrayOID, Category: 'U', Len: -1},
+	{Name: "gtsvector", OID: GTSVectorOID, ArrayOID: GTSVectorArrayOID, Category: 'U', Len: -1},
+	{Name: "tsquery", OID: TSQueryOID, ArrayOID: TSQueryArrayOID, Category: 'U', Len: -1},
+	{Name: "regconfig", OID: RegConfigOID, ArrayOID: RegConfigArrayOID, Category: 'N', Len: 4},
+	{Name: "regdictionary", OID: RegDictionaryOID, ArrayOID: RegDictionaryArrayOID, Category: 'N', Len: 4},
+	{Name: "jsonb", OID: JSONBOID, ArrayOID: JSONBArrayOID, Category: 'U', Len: -1, Codec: JSONCodec{WithVersion: true}},
+	{Name: "jsonpath", OID: JSONPathOID, ArrayOID: JSONPathArrayOID, Category: 'U', Len: -1, Codec: JSONPathCodec{}},
+	{Name: "txid_snapshot", OID: TxidSnapshotOID, ArrayOID: TxidSnapshotArrayOID, Category: 'U', Len: -1},
+	{Name: "pg_snapshot", OID: PgSnapshotOID, ArrayOID: PgSnapshotArrayOID, Category: 'U', Len: -1},
+
+	{Name: "int4range", OID: Int4RangeOID, ArrayOID: Int4RangeArrayOID, Category: 'R', Len: -1, Codec: RangeCodec{ElementOID: Int4OID}},
+	{Name: "numrange", OID: NumRangeOID, ArrayOID: NumRangeArrayOID, Category: 'R', Len: -1, Codec: RangeCodec{ElementOID: NumericOID}},
+	{Name: "tsrange", OID: TsRangeOID, ArrayOID: TsRangeArrayOID, Category: 'R', Len: -1, Codec: RangeCodec{ElementOID: TimestampOID}},
+	{Name: "tstzrange", OID: TstzRangeOID, ArrayOID: TstzRangeArrayOID, Category: 'R', Len: -1, Codec: RangeCodec{ElementOID: TimestamptzOID}},
+	{Name: "daterange", OID: DateRangeOID, ArrayOID: DateRangeArrayOID, Category: 'R', Len: -1, Codec: RangeCodec{ElementOID: DateOID}},
+	{Name: "int8range", OID: Int8RangeOID, ArrayOID: Int8RangeArrayOID, Category: 'R', Len: -1, Codec: RangeCodec{ElementOID: Int8OID}},
+	{Name: "int4multirange", OID: Int4MultirangeOID, ArrayOID: Int4MultirangeArrayOID, Category: 'R', Len: -1, Codec: MultirangeCodec{ElementOID: Int4OID}},
+	{Name: "nummultirange", OID: NumMultirangeOID, ArrayOID: NumMultirangeArrayOID, Category: 'R', Len: -1, Codec: MultirangeCodec{ElementOID: NumericOID}},
+	{Name: "tsmultirange", OID: TsMultirangeOID, ArrayOID: TsMultirangeArrayOID, Category: 'R', Len: -1, Codec: MultirangeCodec{ElementOID: TimestampOID}},
+	{Name: "tstzmultirange", OID: TstzMultirangeOID, ArrayOID: TstzMultirangeArrayOID, Category: 'R', Len: -1, Codec: MultirangeCodec{ElementOID: TimestamptzOID}},
+	{Name: "datemultirange", OID: DateMultirangeOID, ArrayOID: DateMultirangeArrayOID, Category: 'R', Len: -1, Codec: MultirangeCodec{ElementOID: DateOID}},
+	{Name: "int8multirange", OID: Int8MultirangeOID, ArrayOID: Int8MultirangeArrayOID, Category: 'R', Len: -1, Codec: MultirangeCodec{ElementOID: Int8OID}},
+
+	{Name: "record", OID: RecordOID, ArrayOID: RecordArrayOID, Category: 'P', Len: -1, Codec: CompositeCodec{}},
+	{Name: "cstring", OID: CstringOID, ArrayOID: CstringArrayOID, Category: 'P', Len: -2, Codec: TextCodec{}},
+	{Name: "void", OID: VoidOID, Category: 'P', Len: 4},
+}
@@ -0,0 +1,66 @@
+package pgtype
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// ByteaCodec converts bytea values to and from a Go byte slice
+//
+// Both the hex and the escape output formats, selected by the bytea_output setting, are decoded.
+// Values are encoded in the hex format. Byte slices and strings are encoded.
+type ByteaCodec struct{}
+
+func (ByteaCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	// Hex format: \x followed by two hex digits per byte
+	if len(src) >= 2 && src[0] == '\\' && src[1] == 'x' {
+		b := make([]byte, hex.DecodedLen(len(src)-2))
+		_, err := hex.Decode(b, src[2:])
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+
+	// Escape format: printable bytes as is, \\ for a backslash and \ooo for other bytes
+	b := make([]byte, 0, len(src))
+	for i := 0; i < len(src); i++ {
+		if src[i] != '\\' {
+			b = append(b, src[i])
+			continue
+		}
+		switch {
+		case i+1 < len(src) && src[i+1] == '\\':
+			b = append(b, '\\')
+			i++
+		case i+3 < len(src) && isOctal(src[i+1:i+4]):
+			b = append(b, (src[i+1]-'0')<<6|(src[i+2]-'0')<<3|(src[i+3]-'0'))
+			i += 3
+		default:
+			return nil, fmt.Errorf("invalid escape sequence at offset %d", i)
+		}
+	}
+	return b, nil
+}
+
+func (ByteaCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	s, ok := toString(v)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	buf = append(buf, '\\', 'x')
+	return hex.AppendEncode(buf, []byte(s)), nil
+}
+
+// DecodeBinary returns a copy of the raw bytes
+func (ByteaCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	return append([]byte{}, src...), nil
+}
+
+func (ByteaCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	s, ok := toString(v)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	return append(buf, s...), nil
+}
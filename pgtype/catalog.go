@@ -0,0 +1,175 @@
+package pgtype
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/c653labs/pgproto"
+)
+
+// firstNormalOID is the first OID of the objects created in a database, FirstNormalObjectId in
+// PostgreSQL, below which are the built-in objects
+const firstNormalOID = 16384
+
+// typesQuery selects the attributes of types LoadTypes registers: their OID, name, typtype,
+// category, length, delimiter, element and array types, the base type of domains, the subtype of
+// ranges and multiranges, and the names and types of the fields of composites
+const typesQuery = `SELECT t.oid, t.typname, t.typtype, t.typcategory, t.typlen, t.typdelim, t.typelem, t.typarray,
+	t.typbasetype, COALESCE(r.rngsubtype, 0),
+	(SELECT array_agg(a.attname ORDER BY a.attnum) FROM pg_attribute a
+		WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped),
+	(SELECT array_agg(a.atttypid ORDER BY a.attnum) FROM pg_attribute a
+		WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped)
+FROM pg_type t
+LEFT JOIN pg_range r ON t.oid IN (r.rngtypid, r.rngmultitypid)
+WHERE `
+
+// TypesQuery returns the simple query loading the types named names and their array types from the
+// pg_type catalog, or every type created in the database, enums, domains, composites, ranges and the
+// types of extensions, without names. LoadTypes registers the types of the DataRows it returns.
+//
+// The query requires PostgreSQL 14 or later, which has multiranges.
+func TypesQuery(names ...string) string {
+	if len(names) == 0 {
+		return fmt.Sprintf("%st.oid >= %d ORDER BY t.oid", typesQuery, firstNormalOID)
+	}
+
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteLiteral(name)
+	}
+	list := strings.Join(quoted, ", ")
+	return fmt.Sprintf(
+		"%st.oid IN (SELECT oid FROM pg_type WHERE typname IN (%s) UNION SELECT typarray FROM pg_type WHERE typname IN (%s)) ORDER BY t.oid",
+		typesQuery, list, list,
+	)
+}
+
+// quoteLiteral quotes s as a string literal, escaping its quotes and backslashes
+func quoteLiteral(s string) string {
+	s = strings.ReplaceAll(s, "'", "''")
+	if strings.Contains(s, `\`) {
+		return `E'` + strings.ReplaceAll(s, `\`, `\\`) + `'`
+	}
+	return "'" + s + "'"
+}
+
+// catalogType is a type as selected by TypesQuery
+type catalogType struct {
+	Type
+
+	typtype  byte
+	elem     int
+	base     int
+	subtype  int
+	attnames []string
+	atttypes []int
+}
+
+// LoadTypes registers the types of rows, the DataRows returned by the query of TypesQuery in the text
+// format
+//
+// Enums are converted as text, domains by the codec of their base type, and composites, ranges,
+// multiranges and arrays by codecs of the types of their values. Other types, e.g. the types of
+// extensions, are converted by the codec registered for their name by RegisterCodec, which applies to
+// any type, and have no codec otherwise.
+func (m *TypeMap) LoadTypes(rows []*pgproto.DataRow) error {
+	types := make([]*catalogType, len(rows))
+	for i, row := range rows {
+		t, err := m.catalogType(row)
+		if err != nil {
+			return fmt.Errorf("pgtype: row %d: %w", i+1, err)
+		}
+		types[i] = t
+
+		switch t.typtype {
+		case 'e':
+			t.Codec = TextCodec{}
+		case 'c':
+			if len(t.attnames) != len(t.atttypes) {
+				return fmt.Errorf("pgtype: row %d: %d field names for %d field types", i+1, len(t.attnames), len(t.atttypes))
+			}
+			fields := make([]CompositeField, len(t.attnames))
+			for k := range fields {
+				fields[k] = CompositeField{Name: t.attnames[k], OID: t.atttypes[k]}
+			}
+			t.Codec = CompositeCodec{Fields: fields}
+		case 'r':
+			t.Codec = RangeCodec{ElementOID: t.subtype}
+		case 'm':
+			t.Codec = MultirangeCodec{ElementOID: t.subtype}
+		}
+		if c, ok := m.codecs[t.Name]; ok {
+			t.Codec = c
+		}
+		m.RegisterType(&t.Type)
+	}
+
+	// Arrays and domains depend on types which may follow them
+	for _, t := range types {
+		if t.Codec == nil && t.Category == 'A' && t.elem != 0 {
+			var delimiter byte
+			if elem, ok := m.oids[t.elem]; ok {
+				delimiter = elem.Delimiter
+			}
+			t.Codec = ArrayCodec{ElementOID: t.elem, Delimiter: delimiter}
+		}
+	}
+	// Domains may be based on domains, up to a type with a codec
+	for changed := true; changed; {
+		changed = false
+		for _, t := range types {
+			if t.typtype != 'd' || t.Codec != nil {
+				continue
+			}
+			if base, ok := m.oids[t.base]; ok && base.Codec != nil {
+				t.Codec = base.Codec
+				changed = true
+			}
+		}
+	}
+	return nil
+}
+
+// catalogType scans a row of the query of TypesQuery
+func (m *TypeMap) catalogType(row *pgproto.DataRow) (*catalogType, error) {
+	if len(row.Fields) != 12 {
+		return nil, fmt.Errorf("expected 12 columns, got %d", len(row.Fields))
+	}
+
+	t := &catalogType{}
+	columns := []struct {
+		oid int
+		dst interface{}
+	}{
+		{OIDOID, &t.OID},
+		{NameOID, &t.Name},
+		{CharOID, &t.typtype},
+		{CharOID, &t.Category},
+		{Int2OID, &t.Len},
+		{CharOID, &t.Delimiter},
+		{OIDOID, &t.elem},
+		{OIDOID, &t.ArrayOID},
+		{OIDOID, &t.base},
+		{OIDOID, &t.subtype},
+		{NameArrayOID, &t.attnames},
+		{OIDArrayOID, &t.atttypes},
+	}
+	for i, c := range columns {
+		// Only types without fields have NULL field names and types
+		if row.Fields[i] == nil {
+			if i < 10 {
+				return nil, fmt.Errorf("column %d is NULL", i+1)
+			}
+			continue
+		}
+		v, err := m.decode(c.oid, pgproto.FormatText, row.Fields[i])
+		if err == nil {
+			err = assign(c.dst, v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %w", i+1, err)
+		}
+	}
+	return t, nil
+}
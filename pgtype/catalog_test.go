@@ -0,0 +1,180 @@
+package pgtype_test
+
+import (
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtype"
+	"github.com/stretchr/testify/suite"
+)
+
+type CatalogTestSuite struct {
+	suite.Suite
+	m *pgtype.TypeMap
+}
+
+func TestCatalogTestSuite(t *testing.T) {
+	suite.Run(t, new(CatalogTestSuite))
+}
+
+func (s *CatalogTestSuite) SetupTest() {
+	s.m = pgtype.NewTypeMap()
+}
+
+// catalogRow returns a DataRow of the query of TypesQuery, nil fields are NULL
+func catalogRow(fields ...interface{}) *pgproto.DataRow {
+	row := &pgproto.DataRow{}
+	for _, f := range fields {
+		if f == nil {
+			row.Fields = append(row.Fields, nil)
+			continue
+		}
+		row.Fields = append(row.Fields, []byte(f.(string)))
+	}
+	return row
+}
+
+// hstoreCodec converts hstore values as text
+type hstoreCodec struct {
+	pgtype.TextCodec
+}
+
+func (s *CatalogTestSuite) Test_Builtin() {
+	t, ok := s.m.TypeForOID(pgtype.Int4OID)
+	s.True(ok)
+	s.Equal("int4", t.Name)
+	s.Equal(pgtype.Int4ArrayOID, t.ArrayOID)
+	s.Equal(byte('N'), t.Category)
+	s.Equal(4, t.Len)
+
+	t, ok = s.m.TypeForName("_text")
+	s.True(ok)
+	s.Equal(pgtype.TextArrayOID, t.OID)
+	s.Equal(byte('A'), t.Category)
+	s.Equal(-1, t.Len)
+	s.Equal(pgtype.ArrayCodec{ElementOID: pgtype.TextOID}, t.Codec)
+
+	t, ok = s.m.TypeForName("_box")
+	s.True(ok)
+	s.Equal(pgtype.ArrayCodec{ElementOID: pgtype.BoxOID, Delimiter: ';'}, t.Codec)
+
+	// Every type with an array has it registered
+	for _, oid := range []int{pgtype.BoolOID, pgtype.UUIDOID, pgtype.InetOID, pgtype.CircleOID, pgtype.Int8MultirangeOID, pgtype.MoneyOID, pgtype.RegClassOID} {
+		t, ok := s.m.TypeForOID(oid)
+		s.True(ok, oid)
+		array, ok := s.m.TypeForOID(t.ArrayOID)
+		s.True(ok, oid)
+		s.Equal("_"+t.Name, array.Name)
+	}
+
+	// Types without codecs are converted as strings and bytes
+	t, ok = s.m.TypeForOID(pgtype.MoneyOID)
+	s.True(ok)
+	s.Nil(t.Codec)
+	v, err := s.m.Decode(pgtype.MoneyOID, pgproto.FormatText, []byte("$1.50"))
+	s.Nil(err)
+	s.Equal("$1.50", v)
+	v, err = s.m.Decode(pgtype.MoneyArrayOID, pgproto.FormatText, []byte(`{$1.50,"$1,000.00"}`))
+	s.Nil(err)
+	s.Equal(pgtype.Array{Elements: []interface{}{"$1.50", "$1,000.00"}, Dimensions: []pgtype.ArrayDimension{{Length: 2, LowerBound: 1}}}, v)
+	_, err = s.m.Encode(pgtype.MoneyOID, pgproto.FormatText, 150)
+	s.NotNil(err)
+	encoded, err := s.m.Encode(pgtype.MoneyOID, pgproto.FormatText, "$1.50")
+	s.Nil(err)
+	s.Equal("$1.50", string(encoded))
+}
+
+func (s *CatalogTestSuite) Test_RegisterCodec() {
+	s.m.RegisterCodec("money", pgtype.TextCodec{})
+	t, ok := s.m.TypeForOID(pgtype.MoneyOID)
+	s.True(ok)
+	s.Equal(pgtype.TextCodec{}, t.Codec)
+
+	// Custom types of any OID
+	s.m.RegisterType(&pgtype.Type{Name: "ltree", OID: 16500, Codec: pgtype.TextCodec{}})
+	v, err := s.m.Decode(16500, pgproto.FormatText, []byte("a.b.c"))
+	s.Nil(err)
+	s.Equal("a.b.c", v)
+}
+
+func (s *CatalogTestSuite) Test_LoadTypes() {
+	s.m.RegisterCodec("hstore", hstoreCodec{})
+	rows := []*pgproto.DataRow{
+		catalogRow("16399", "_mood", "b", "A", "-1", ",", "16400", "0", "0", "0", nil, nil),
+		catalogRow("16400", "mood", "e", "E", "4", ",", "0", "16399", "0", "0", nil, nil),
+		catalogRow("16410", "item", "c", "C", "-1", ",", "0", "16409", "0", "0", "{id,name,mood}", "{23,25,16400}"),
+		catalogRow("16420", "posint", "d", "N", "4", ",", "0", "16419", "23", "0", nil, nil),
+		catalogRow("16421", "smallposint", "d", "N", "4", ",", "0", "0", "16420", "0", nil, nil),
+		catalogRow("16430", "floatrange", "r", "R", "-1", ",", "0", "16429", "0", "701", nil, nil),
+		catalogRow("16431", "floatmultirange", "m", "R", "-1", ",", "0", "16432", "0", "701", nil, nil),
+		catalogRow("16440", "hstore", "b", "U", "-1", ",", "0", "16441", "0", "0", nil, nil),
+		catalogRow("16441", "_hstore", "b", "A", "-1", ",", "16440", "0", "0", "0", nil, nil),
+		catalogRow("16450", "cube", "b", "U", "-1", ",", "0", "0", "0", "0", nil, nil),
+	}
+	s.Nil(s.m.LoadTypes(rows))
+
+	t, ok := s.m.TypeForName("mood")
+	s.True(ok)
+	s.Equal(16400, t.OID)
+	s.Equal(16399, t.ArrayOID)
+	s.Equal(byte('E'), t.Category)
+	s.Equal(4, t.Len)
+
+	var moods []string
+	s.Nil(s.m.Scan(16399, pgproto.FormatText, []byte("{happy,sad}"), &moods))
+	s.Equal([]string{"happy", "sad"}, moods)
+
+	var item struct {
+		ID   int
+		Name string
+		Mood string
+	}
+	s.Nil(s.m.Scan(16410, pgproto.FormatText, []byte("(1,a,happy)"), &item))
+	s.Equal(1, item.ID)
+	s.Equal("a", item.Name)
+	s.Equal("happy", item.Mood)
+
+	v, err := s.m.Decode(16421, pgproto.FormatBinary, []byte{0x00, 0x00, 0x00, 0x2a})
+	s.Nil(err)
+	s.Equal(int32(42), v)
+
+	var r pgtype.Range[float64]
+	s.Nil(s.m.Scan(16430, pgproto.FormatText, []byte("[1.5,2.5)"), &r))
+	s.Equal(pgtype.Range[float64]{Lower: 1.5, Upper: 2.5, LowerBound: pgtype.Inclusive, UpperBound: pgtype.Exclusive}, r)
+	var mr pgtype.Multirange[float64]
+	s.Nil(s.m.Scan(16431, pgproto.FormatText, []byte("{[1.5,2.5)}"), &mr))
+	s.Equal(pgtype.Multirange[float64]{r}, mr)
+
+	t, ok = s.m.TypeForOID(16440)
+	s.True(ok)
+	s.Equal(hstoreCodec{}, t.Codec)
+	var hstores []string
+	s.Nil(s.m.Scan(16441, pgproto.FormatText, []byte(`{"\"a\"=>\"1\""}`), &hstores))
+	s.Equal([]string{`"a"=>"1"`}, hstores)
+
+	// Extension types without a registered codec are registered without one
+	t, ok = s.m.TypeForName("cube")
+	s.True(ok)
+	s.Nil(t.Codec)
+	v, err = s.m.Decode(16450, pgproto.FormatText, []byte("(1, 2)"))
+	s.Nil(err)
+	s.Equal("(1, 2)", v)
+
+	for _, invalid := range [][]*pgproto.DataRow{
+		{catalogRow("16400", "mood", "e")},
+		{catalogRow(nil, "mood", "e", "E", "4", ",", "0", "16399", "0", "0", nil, nil)},
+		{catalogRow("x", "mood", "e", "E", "4", ",", "0", "16399", "0", "0", nil, nil)},
+		{catalogRow("16410", "item", "c", "C", "-1", ",", "0", "16409", "0", "0", "{id,name}", "{23}")},
+	} {
+		s.NotNil(s.m.LoadTypes(invalid))
+	}
+}
+
+func (s *CatalogTestSuite) Test_TypesQuery() {
+	s.Contains(pgtype.TypesQuery(), "FROM pg_type t")
+	s.Contains(pgtype.TypesQuery(), "WHERE t.oid >= 16384 ")
+
+	query := pgtype.TypesQuery("hstore", "it's", `a\b`)
+	s.Contains(query, `typname IN ('hstore', 'it''s', E'a\\b')`)
+	s.Contains(query, "SELECT typarray FROM pg_type")
+}
@@ -0,0 +1,439 @@
+package pgtype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/c653labs/pgproto"
+)
+
+// CompositeField is a field of a composite type, its name and type OID
+type CompositeField struct {
+	Name string
+	OID  int
+}
+
+// Composite is a composite value, the values of its Fields in order with nil for NULL
+//
+// The fields of record values have no names, and their values are strings when they are decoded in
+// the text format, which does not give their types.
+type Composite struct {
+	Fields []CompositeField
+	Values []interface{}
+}
+
+// convert allows scanning composite values into structs, slices and maps keyed by the field names
+func (c Composite) convert(t reflect.Type) (interface{}, error) {
+	d := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Struct:
+		fields, err := compositeStructFields(t, c.Fields, len(c.Values))
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range c.Values {
+			err = assignValue(d.FieldByIndex(fields[i]), v)
+			if err != nil {
+				return nil, fmt.Errorf("field %d: %w", i+1, err)
+			}
+		}
+	case reflect.Slice:
+		d.Set(reflect.MakeSlice(t, len(c.Values), len(c.Values)))
+		for i, v := range c.Values {
+			err := assignValue(d.Index(i), v)
+			if err != nil {
+				return nil, fmt.Errorf("field %d: %w", i+1, err)
+			}
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String || !namedFields(c.Fields) {
+			return nil, nil
+		}
+		d.Set(reflect.MakeMapWithSize(t, len(c.Values)))
+		for i, v := range c.Values {
+			e := reflect.New(t.Elem()).Elem()
+			err := assignValue(e, v)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", c.Fields[i].Name, err)
+			}
+			d.SetMapIndex(reflect.ValueOf(c.Fields[i].Name).Convert(t.Key()), e)
+		}
+	default:
+		return nil, nil
+	}
+	return d.Interface(), nil
+}
+
+// namedFields returns whether fields have names, which the fields of records do not
+func namedFields(fields []CompositeField) bool {
+	for _, f := range fields {
+		if f.Name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// compositeStructFields returns the indexes of the fields of the struct type t holding the n fields
+// of a composite value
+func compositeStructFields(t reflect.Type, fields []CompositeField, n int) ([][]int, error) {
+	exported := scannedFields(t)
+	indexes := make([][]int, n)
+	if !namedFields(fields) {
+		if len(exported) != n {
+			return nil, fmt.Errorf("cannot scan a record of %d fields into %s, which has %d", n, t, len(exported))
+		}
+		for i, f := range exported {
+			indexes[i] = f.Index
+		}
+		return indexes, nil
+	}
+
+	if len(fields) < n {
+		return nil, fmt.Errorf("expected %d fields, got %d", n, len(fields))
+	}
+	for i, field := range fields[:n] {
+		var ok bool
+		indexes[i], ok = fieldByName(exported, field.Name)
+		if !ok {
+			return nil, fmt.Errorf("%s has no field for %q", t, field.Name)
+		}
+	}
+	return indexes, nil
+}
+
+// scannedFields returns the fields of the struct type t values are scanned into, its exported fields
+// not tagged `pg:"-"`, including those of embedded structs but not of embedded pointers
+func scannedFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for _, f := range reflect.VisibleFields(t) {
+		if f.IsExported() && !f.Anonymous && f.Tag.Get("pg") != "-" && !embeddedPointer(t, f.Index) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// embeddedPointer returns whether the field of t at index is promoted from an embedded pointer
+func embeddedPointer(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		t = t.Field(i).Type
+		if t.Kind() == reflect.Ptr {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldByName returns the index of the field of fields named name by its pg tag, or by its own name
+// regardless of case and underscores when it has no tag, e.g. UserName for user_name
+func fieldByName(fields []reflect.StructField, name string) ([]int, bool) {
+	for _, f := range fields {
+		tag := f.Tag.Get("pg")
+		if tag == name || (tag == "" && strings.EqualFold(f.Name, strings.ReplaceAll(name, "_", ""))) {
+			return f.Index, true
+		}
+	}
+	return nil, false
+}
+
+// CompositeCodec converts values of the composite type of Fields to and from a Composite, whose
+// values are converted by the codecs of their type in the TypeMap
+//
+// Without Fields, it converts record values, which can only be decoded. The fields of a composite type
+// are given by the catalog, e.g.
+//
+//   SELECT attname, atttypid FROM pg_attribute
+//   WHERE attrelid = (SELECT typrelid FROM pg_type WHERE oid = $1) AND attnum > 0 AND NOT attisdropped
+//   ORDER BY attnum
+//
+// Composite values are scanned into structs, into slices of any type their values are scanned into,
+// and into maps keyed by their field names. Struct fields are matched by the name of their pg tag,
+// e.g. `pg:"name"`, or by their own name regardless of case and underscores, e.g. UserName for
+// user_name, and fields tagged `pg:"-"` are skipped. The fields of records, which have no names, are
+// matched in order with the exported struct fields.
+//
+// Composites of as many values as Fields, structs, slices of interfaces and maps keyed by field names,
+// missing ones for NULL, are encoded.
+type CompositeCodec struct {
+	Fields []CompositeField
+}
+
+// DecodeText decodes the fields in parentheses separated by commas, quoted when they contain special
+// characters, e.g. (1,,"a b"). Fields are NULL when empty, and quoted empty strings otherwise.
+func (c CompositeCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	p := compositeParser{src: string(src)}
+	texts, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Fields) == 0 {
+		values := make([]interface{}, len(texts))
+		for i, text := range texts {
+			if text != nil {
+				values[i] = *text
+			}
+		}
+		return Composite{Values: values}, nil
+	}
+
+	if len(texts) != len(c.Fields) {
+		return nil, fmt.Errorf("expected %d fields, got %d", len(c.Fields), len(texts))
+	}
+	values := make([]interface{}, len(texts))
+	for i, text := range texts {
+		if text == nil {
+			continue
+		}
+		values[i], err = m.decode(c.Fields[i].OID, pgproto.FormatText, []byte(*text))
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %w", i+1, err)
+		}
+	}
+	return Composite{Fields: c.Fields, Values: values}, nil
+}
+
+func (c CompositeCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	values, err := c.values(v)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = append(buf, '(')
+	for i, value := range values {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		text, err := m.encode(c.Fields[i].OID, pgproto.FormatText, value)
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %w", i+1, err)
+		}
+		if text != nil {
+			buf = appendCompositeField(buf, string(text))
+		}
+	}
+	return append(buf, ')'), nil
+}
+
+// appendCompositeField appends the text of a field, quoted when it is empty or contains special
+// characters
+func appendCompositeField(buf []byte, text string) []byte {
+	if text != "" && !strings.ContainsAny(text, `(),"\ `+"\t\n\r\v\f") {
+		return append(buf, text...)
+	}
+
+	buf = append(buf, '"')
+	for i := 0; i < len(text); i++ {
+		if text[i] == '"' || text[i] == '\\' {
+			buf = append(buf, text[i])
+		}
+		buf = append(buf, text[i])
+	}
+	return append(buf, '"')
+}
+
+// DecodeBinary decodes the number of fields, a 32 bit integer, followed by the fields, each the OID
+// of its type and the 32 bit length of its value, -1 for NULL, and the value
+func (c CompositeCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	if len(src) < 4 {
+		return nil, fmt.Errorf("expected at least 4 bytes, got %d", len(src))
+	}
+	n := int32(binary.BigEndian.Uint32(src))
+	src = src[4:]
+	// Each field takes at least 8 bytes
+	if n < 0 || int(n) > len(src)/8 {
+		return nil, fmt.Errorf("invalid number of fields %d", n)
+	}
+	if len(c.Fields) > 0 && int(n) != len(c.Fields) {
+		return nil, fmt.Errorf("expected %d fields, got %d", len(c.Fields), n)
+	}
+
+	composite := Composite{Fields: c.Fields, Values: make([]interface{}, n)}
+	if len(c.Fields) == 0 {
+		composite.Fields = make([]CompositeField, n)
+	}
+	for i := range composite.Values {
+		if len(src) < 8 {
+			return nil, errors.New("composite fields are truncated")
+		}
+		oid := int(binary.BigEndian.Uint32(src))
+		length := int32(binary.BigEndian.Uint32(src[4:]))
+		src = src[8:]
+		if len(c.Fields) == 0 {
+			composite.Fields[i].OID = oid
+		}
+		if length < -1 {
+			return nil, fmt.Errorf("invalid length %d of field %d", length, i+1)
+		}
+		if length == -1 {
+			continue
+		}
+		if int(length) > len(src) {
+			return nil, errors.New("composite fields are truncated")
+		}
+		var err error
+		composite.Values[i], err = m.decode(oid, pgproto.FormatBinary, src[:length])
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %w", i+1, err)
+		}
+		src = src[length:]
+	}
+	if len(src) > 0 {
+		return nil, fmt.Errorf("%d unexpected bytes after the fields", len(src))
+	}
+	return composite, nil
+}
+
+func (c CompositeCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	values, err := c.values(v)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(values)))
+	for i, value := range values {
+		field, err := m.encode(c.Fields[i].OID, pgproto.FormatBinary, value)
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %w", i+1, err)
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(c.Fields[i].OID))
+		if field == nil {
+			buf = binary.BigEndian.AppendUint32(buf, 0xffffffff)
+			continue
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(field)))
+		buf = append(buf, field...)
+	}
+	return buf, nil
+}
+
+// values converts v to the values of the Fields in order
+func (c CompositeCodec) values(v interface{}) ([]interface{}, error) {
+	if len(c.Fields) == 0 {
+		return nil, errors.New("cannot encode a record, its fields are unknown")
+	}
+	if composite, ok := v.(Composite); ok {
+		if len(composite.Values) != len(c.Fields) {
+			return nil, fmt.Errorf("expected %d fields, got %d", len(c.Fields), len(composite.Values))
+		}
+		return composite.Values, nil
+	}
+
+	values := make([]interface{}, len(c.Fields))
+	r := reflect.ValueOf(v)
+	switch {
+	case r.Kind() == reflect.Struct:
+		fields, err := compositeStructFields(r.Type(), c.Fields, len(c.Fields))
+		if err != nil {
+			return nil, err
+		}
+		for i, index := range fields {
+			values[i] = r.FieldByIndex(index).Interface()
+		}
+	case r.Kind() == reflect.Slice && r.Type().Elem().Kind() == reflect.Interface:
+		if r.Len() != len(c.Fields) {
+			return nil, fmt.Errorf("expected %d fields, got %d", len(c.Fields), r.Len())
+		}
+		for i := range values {
+			values[i] = r.Index(i).Interface()
+		}
+	case r.Kind() == reflect.Map && r.Type().Key().Kind() == reflect.String:
+		for i, f := range c.Fields {
+			value := r.MapIndex(reflect.ValueOf(f.Name).Convert(r.Type().Key()))
+			if value.IsValid() {
+				values[i] = value.Interface()
+			}
+		}
+	default:
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	return values, nil
+}
+
+// compositeParser parses the text representation of composite values
+type compositeParser struct {
+	src string
+	pos int
+}
+
+// parse parses the fields, with nil for NULL
+func (p *compositeParser) parse() ([]*string, error) {
+	if !strings.HasPrefix(p.src, "(") {
+		return nil, fmt.Errorf("invalid composite %q: expected '('", p.src)
+	}
+	p.pos = 1
+
+	var fields []*string
+	for {
+		field, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("invalid composite %q: expected ')'", p.src)
+		}
+		c := p.src[p.pos]
+		p.pos++
+		if c == ')' {
+			break
+		}
+	}
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("invalid composite %q: unexpected %q", p.src, p.src[p.pos:])
+	}
+	return fields, nil
+}
+
+// parseField parses a field up to the next comma or closing parenthesis, nil when it is empty. Quoted
+// parts of the field, in which quotes are doubled, and characters following a backslash are taken
+// literally.
+func (p *compositeParser) parseField() (*string, error) {
+	var b strings.Builder
+	present := false
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch c {
+		case ',', ')':
+			if !present {
+				return nil, nil
+			}
+			s := b.String()
+			return &s, nil
+		case '\\':
+			if p.pos+1 >= len(p.src) {
+				return nil, fmt.Errorf("invalid composite %q: unterminated escape", p.src)
+			}
+			b.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case '"':
+			p.pos++
+			for {
+				if p.pos >= len(p.src) {
+					return nil, fmt.Errorf("invalid composite %q: unterminated quoted field", p.src)
+				}
+				c = p.src[p.pos]
+				p.pos++
+				if c == '\\' && p.pos < len(p.src) {
+					c = p.src[p.pos]
+					p.pos++
+				} else if c == '"' {
+					if p.pos < len(p.src) && p.src[p.pos] == '"' {
+						p.pos++
+					} else {
+						break
+					}
+				}
+				b.WriteByte(c)
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+		present = true
+	}
+	return nil, fmt.Errorf("invalid composite %q: expected ')'", p.src)
+}
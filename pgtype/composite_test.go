@@ -0,0 +1,216 @@
+package pgtype_test
+
+import (
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtype"
+	"github.com/stretchr/testify/suite"
+)
+
+type CompositeTestSuite struct {
+	suite.Suite
+	m *pgtype.TypeMap
+}
+
+func TestCompositeTestSuite(t *testing.T) {
+	suite.Run(t, new(CompositeTestSuite))
+}
+
+// itemOID is the OID of the item composite type, (id int4, name text, note text)
+const itemOID = 16390
+
+var itemFields = []pgtype.CompositeField{
+	{Name: "id", OID: pgtype.Int4OID},
+	{Name: "name", OID: pgtype.TextOID},
+	{Name: "note", OID: pgtype.TextOID},
+}
+
+func (s *CompositeTestSuite) SetupTest() {
+	s.m = pgtype.NewTypeMap()
+	s.m.RegisterType(&pgtype.Type{Name: "item", OID: itemOID, Codec: pgtype.CompositeCodec{Fields: itemFields}})
+}
+
+func (s *CompositeTestSuite) Test_Formats() {
+	text := `(42,"a b",)`
+	binary := []byte{
+		0x00, 0x00, 0x00, 0x03,
+		0x00, 0x00, 0x00, 0x17, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x2a,
+		0x00, 0x00, 0x00, 0x19, 0x00, 0x00, 0x00, 0x03, 'a', ' ', 'b',
+		0x00, 0x00, 0x00, 0x19, 0xff, 0xff, 0xff, 0xff,
+	}
+	value := pgtype.Composite{Fields: itemFields, Values: []interface{}{int32(42), "a b", nil}}
+
+	v, err := s.m.Decode(itemOID, pgproto.FormatText, []byte(text))
+	s.Nil(err)
+	s.Equal(value, v)
+	v, err = s.m.Decode(itemOID, pgproto.FormatBinary, binary)
+	s.Nil(err)
+	s.Equal(value, v)
+
+	encoded, err := s.m.Encode(itemOID, pgproto.FormatText, value)
+	s.Nil(err)
+	s.Equal(text, string(encoded))
+	encoded, err = s.m.Encode(itemOID, pgproto.FormatBinary, value)
+	s.Nil(err)
+	s.Equal(binary, encoded)
+
+	// Records have the OIDs of their fields in the binary format only
+	v, err = s.m.Decode(pgtype.RecordOID, pgproto.FormatBinary, binary)
+	s.Nil(err)
+	s.Equal(pgtype.Composite{
+		Fields: []pgtype.CompositeField{{OID: pgtype.Int4OID}, {OID: pgtype.TextOID}, {OID: pgtype.TextOID}},
+		Values: []interface{}{int32(42), "a b", nil},
+	}, v)
+	v, err = s.m.Decode(pgtype.RecordOID, pgproto.FormatText, []byte(text))
+	s.Nil(err)
+	s.Equal(pgtype.Composite{Values: []interface{}{"42", "a b", nil}}, v)
+	_, err = s.m.Encode(pgtype.RecordOID, pgproto.FormatText, []interface{}{1})
+	s.NotNil(err)
+
+	for _, invalid := range [][]byte{
+		{0x00, 0x00, 0x00},
+		{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x17, 0xff, 0xff, 0xff, 0xff},
+		{0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x17, 0xff, 0xff, 0xff, 0xfe},
+		{0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x17, 0x00, 0x00, 0x00, 0x04, 0x00},
+		append(append([]byte{}, binary...), 0x00),
+	} {
+		_, err = s.m.Decode(pgtype.RecordOID, pgproto.FormatBinary, invalid)
+		s.NotNil(err, invalid)
+	}
+	// The number of fields must match the type
+	_, err = s.m.Decode(itemOID, pgproto.FormatBinary, []byte{0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x17, 0xff, 0xff, 0xff, 0xff})
+	s.NotNil(err)
+}
+
+func (s *CompositeTestSuite) Test_Text() {
+	cases := []struct {
+		text   string
+		values []interface{}
+	}{
+		{`(1,"",)`, []interface{}{int32(1), "", nil}},
+		{`(1,"a ""quoted"" \\ name",x)`, []interface{}{int32(1), `a "quoted" \ name`, "x"}},
+		{`(1,a\,b,"(x)")`, []interface{}{int32(1), "a,b", "(x)"}},
+		{`(,ab"c,d"e, f )`, []interface{}{nil, "abc,de", " f "}},
+	}
+	for _, c := range cases {
+		v, err := s.m.Decode(itemOID, pgproto.FormatText, []byte(c.text))
+		s.Nil(err, c.text)
+		s.Equal(c.values, v.(pgtype.Composite).Values, c.text)
+	}
+
+	encoded, err := s.m.Encode(itemOID, pgproto.FormatText, []interface{}{1, `a "quoted" \ name`, ""})
+	s.Nil(err)
+	s.Equal(`(1,"a ""quoted"" \\ name","")`, string(encoded))
+
+	for _, invalid := range []string{"", "1,a,b", "(1,a,b", "(1,a,b)x", `(1,"a,b)`, "(1,a)", "(1,a,b,c)", "(x,a,b)"} {
+		_, err = s.m.Decode(itemOID, pgproto.FormatText, []byte(invalid))
+		s.NotNil(err, invalid)
+	}
+}
+
+func (s *CompositeTestSuite) Test_Scan() {
+	type item struct {
+		ID      int
+		Label   string  `pg:"name"`
+		Note    *string `pg:"note"`
+		Ignored string  `pg:"-"`
+	}
+
+	var i item
+	s.Nil(s.m.Scan(itemOID, pgproto.FormatText, []byte(`(42,"a b",)`), &i))
+	s.Equal(item{ID: 42, Label: "a b"}, i)
+
+	var p *item
+	s.Nil(s.m.Scan(itemOID, pgproto.FormatText, []byte(`(1,a,b)`), &p))
+	note := "b"
+	s.Equal(&item{ID: 1, Label: "a", Note: &note}, p)
+
+	var m map[string]interface{}
+	s.Nil(s.m.Scan(itemOID, pgproto.FormatText, []byte(`(42,"a b",)`), &m))
+	s.Equal(map[string]interface{}{"id": int32(42), "name": "a b", "note": nil}, m)
+
+	var values []interface{}
+	s.Nil(s.m.Scan(itemOID, pgproto.FormatText, []byte(`(42,"a b",)`), &values))
+	s.Equal([]interface{}{int32(42), "a b", nil}, values)
+
+	// Struct fields of record values are matched in order
+	var pair struct {
+		Key   string
+		Value string
+	}
+	s.Nil(s.m.Scan(pgtype.RecordOID, pgproto.FormatText, []byte(`(answer,42)`), &pair))
+	s.Equal("answer", pair.Key)
+	s.Equal("42", pair.Value)
+	s.NotNil(s.m.Scan(pgtype.RecordOID, pgproto.FormatText, []byte(`(answer,42,x)`), &pair))
+
+	var strs []string
+	s.Nil(s.m.Scan(pgtype.RecordOID, pgproto.FormatText, []byte(`(answer,42)`), &strs))
+	s.Equal([]string{"answer", "42"}, strs)
+
+	// Every field must have a destination
+	var partial struct{ ID int }
+	s.NotNil(s.m.Scan(itemOID, pgproto.FormatText, []byte(`(42,"a b",)`), &partial))
+
+	// Nested composites and arrays of composites
+	s.m.RegisterType(&pgtype.Type{Name: "_item", OID: itemOID + 1, Codec: pgtype.ArrayCodec{ElementOID: itemOID}})
+	var items []item
+	s.Nil(s.m.Scan(itemOID+1, pgproto.FormatText, []byte(`{"(1,a,)","(2,b,)"}`), &items))
+	s.Equal([]item{{ID: 1, Label: "a"}, {ID: 2, Label: "b"}}, items)
+
+	s.m.RegisterType(&pgtype.Type{Name: "order", OID: itemOID + 2, Codec: pgtype.CompositeCodec{Fields: []pgtype.CompositeField{
+		{Name: "item", OID: itemOID},
+		{Name: "quantity", OID: pgtype.Int4OID},
+	}}})
+	var order struct {
+		Item     item
+		Quantity int
+	}
+	s.Nil(s.m.Scan(itemOID+2, pgproto.FormatText, []byte(`("(1,""a b"",)",3)`), &order))
+	s.Equal(item{ID: 1, Label: "a b"}, order.Item)
+	s.Equal(3, order.Quantity)
+}
+
+func (s *CompositeTestSuite) Test_Encode() {
+	type item struct {
+		ID   int32
+		Name string
+		Note *string
+	}
+	cases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{item{ID: 1, Name: "a b"}, `(1,"a b",)`},
+		{&item{ID: 1, Name: ""}, `(1,"",)`},
+		{map[string]interface{}{"id": 2, "note": "x"}, `(2,,x)`},
+		{[]interface{}{3, "c", nil}, `(3,c,)`},
+	}
+	for _, c := range cases {
+		encoded, err := s.m.Encode(itemOID, pgproto.FormatText, c.value)
+		s.Nil(err, c.value)
+		s.Equal(c.expected, string(encoded), c.value)
+
+		// The binary representation holds the same value
+		binary, err := s.m.Encode(itemOID, pgproto.FormatBinary, c.value)
+		s.Nil(err, c.value)
+		fromBinary, err := s.m.Decode(itemOID, pgproto.FormatBinary, binary)
+		s.Nil(err, c.value)
+		fromText, err := s.m.Decode(itemOID, pgproto.FormatText, encoded)
+		s.Nil(err, c.value)
+		s.Equal(fromText, fromBinary, c.value)
+	}
+
+	for _, invalid := range []interface{}{
+		42,
+		[]interface{}{1, "a"},
+		[]interface{}{"x", "a", "b"},
+		struct{ ID int }{1},
+		pgtype.Composite{Values: []interface{}{1}},
+	} {
+		_, err := s.m.Encode(itemOID, pgproto.FormatText, invalid)
+		s.NotNil(err, invalid)
+		_, err = s.m.Encode(itemOID, pgproto.FormatBinary, invalid)
+		s.NotNil(err, invalid)
+	}
+}
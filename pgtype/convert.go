@@ -0,0 +1,266 @@
+package pgtype
+
+import (
+	"database/sql"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"reflect"
+)
+
+// errMismatch is wrapped by the errors of values which can not be stored in a destination type
+var errMismatch = errors.New("type mismatch")
+
+// converter is implemented by decoded values which can be stored in other types than their own
+type converter interface {
+	// convert returns the value converted to t, or nil when it does not convert to t
+	convert(t reflect.Type) (interface{}, error)
+}
+
+// assign stores the decoded value v, nil for NULL, in the value dst points to
+func assign(dst interface{}, v interface{}) error {
+	d := reflect.ValueOf(dst)
+	if d.Kind() != reflect.Ptr || d.IsNil() {
+		return fmt.Errorf("cannot scan into %T, a non nil pointer is required", dst)
+	}
+	return assignValue(d.Elem(), v)
+}
+
+// assignValue stores v in d, which must be settable
+func assignValue(d reflect.Value, v interface{}) error {
+	// Pointers are allocated for values, and set to nil for NULL
+	if d.Kind() == reflect.Ptr {
+		if v == nil {
+			d.Set(reflect.Zero(d.Type()))
+			return nil
+		}
+		p := reflect.New(d.Type().Elem())
+		err := assignValue(p.Elem(), v)
+		if err != nil {
+			return err
+		}
+		d.Set(p)
+		return nil
+	}
+
+	if s, ok := d.Addr().Interface().(sql.Scanner); ok {
+		return s.Scan(driverValue(v))
+	}
+
+	if v == nil {
+		switch d.Kind() {
+		case reflect.Interface, reflect.Slice, reflect.Map:
+			d.Set(reflect.Zero(d.Type()))
+			return nil
+		}
+		return fmt.Errorf("cannot scan NULL into %s, use a pointer: %w", d.Type(), errMismatch)
+	}
+
+	if j, ok := v.(json.RawMessage); ok && unmarshalsJSON(d.Type()) {
+		return json.Unmarshal(j, d.Addr().Interface())
+	}
+
+	if p, ok := v.(netip.Prefix); ok && d.Type() == addrType {
+		addr, err := singleAddr(p)
+		if err != nil {
+			return err
+		}
+		v = addr
+	}
+
+	if c, ok := v.(converter); ok {
+		converted, err := c.convert(d.Type())
+		if err != nil {
+			return err
+		}
+		if converted != nil {
+			v = converted
+		}
+	}
+
+	src := reflect.ValueOf(v)
+	if src.Kind() == reflect.Slice && src.Type().Elem().Kind() == reflect.Uint8 {
+		// Decoded bytes may be reused by the caller
+		src = reflect.ValueOf(append([]byte{}, src.Bytes()...)).Convert(src.Type())
+	}
+	if src.Type().AssignableTo(d.Type()) {
+		d.Set(src)
+		return nil
+	}
+
+	switch d.Kind() {
+	case reflect.Bool:
+		if src.Kind() == reflect.Bool {
+			d.SetBool(src.Bool())
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := toInt64(v); ok && !d.OverflowInt(i) {
+			d.SetInt(i)
+			return nil
+		} else if ok || isInteger(src.Kind()) {
+			return fmt.Errorf("%v overflows %s", v, d.Type())
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u, ok := toUint64(v); ok && !d.OverflowUint(u) {
+			d.SetUint(u)
+			return nil
+		} else if ok || isInteger(src.Kind()) {
+			return fmt.Errorf("%v overflows %s", v, d.Type())
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if f, ok := toFloat64(v); ok && !d.OverflowFloat(f) {
+			d.SetFloat(f)
+			return nil
+		} else if ok {
+			return fmt.Errorf("%v overflows %s", v, d.Type())
+		} else if isInteger(src.Kind()) {
+			return fmt.Errorf("%v can not be represented exactly by %s", v, d.Type())
+		}
+
+	case reflect.String:
+		if s, ok := toString(v); ok {
+			d.SetString(s)
+			return nil
+		}
+		if t, ok := v.(encoding.TextMarshaler); ok {
+			text, err := t.MarshalText()
+			if err != nil {
+				return err
+			}
+			d.SetString(string(text))
+			return nil
+		}
+
+	case reflect.Slice:
+		if d.Type().Elem().Kind() == reflect.Uint8 {
+			if s, ok := toString(v); ok {
+				d.SetBytes([]byte(s))
+				return nil
+			}
+		}
+	}
+
+	if src.Kind() == d.Kind() && src.Type().ConvertibleTo(d.Type()) {
+		d.Set(src.Convert(d.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into %s: %w", v, d.Type(), errMismatch)
+}
+
+// driverValue converts v to one of the types sql.Scanner implementations are given
+func driverValue(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, bool, int64, float64, string, []byte:
+		return v
+	}
+	if i, ok := toInt64(v); ok {
+		return i
+	}
+	if f, ok := toFloat64(v); ok {
+		return f
+	}
+	return v
+}
+
+// indirect returns the value v points to, or nil when it is a nil pointer
+func indirect(v interface{}) interface{} {
+	r := reflect.ValueOf(v)
+	for r.Kind() == reflect.Ptr {
+		if r.IsNil() {
+			return nil
+		}
+		r = r.Elem()
+	}
+	if !r.IsValid() {
+		return nil
+	}
+	return r.Interface()
+}
+
+// checkSize returns an error unless the binary value src is n bytes long
+func checkSize(src []byte, n int) error {
+	if len(src) != n {
+		return fmt.Errorf("expected %d bytes, got %d", n, len(src))
+	}
+	return nil
+}
+
+// kindOf returns the kind of the type of v
+func kindOf(v interface{}) reflect.Kind {
+	return reflect.ValueOf(v).Kind()
+}
+
+// isInteger returns whether k is the kind of a signed or unsigned integer
+func isInteger(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// toInt64 converts any integer to an int64, it fails for unsigned integers above math.MaxInt64
+func toInt64(v interface{}) (int64, bool) {
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return r.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if r.Uint() > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(r.Uint()), true
+	}
+	return 0, false
+}
+
+// toUint64 converts any non negative integer to a uint64
+func toUint64(v interface{}) (uint64, bool) {
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if r.Int() < 0 {
+			return 0, false
+		}
+		return uint64(r.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return r.Uint(), true
+	}
+	return 0, false
+}
+
+// toFloat64 converts any float, or integer a float64 represents exactly, to a float64
+func toFloat64(v interface{}) (float64, bool) {
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return r.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f := float64(r.Int())
+		return f, f < math.MaxInt64 && int64(f) == r.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f := float64(r.Uint())
+		return f, f < math.MaxUint64 && uint64(f) == r.Uint()
+	}
+	return 0, false
+}
+
+// toString converts strings and byte slices to a string
+func toString(v interface{}) (string, bool) {
+	r := reflect.ValueOf(v)
+	switch {
+	case r.Kind() == reflect.String:
+		return r.String(), true
+	case r.Kind() == reflect.Slice && r.Type().Elem().Kind() == reflect.Uint8:
+		return string(r.Bytes()), true
+	}
+	return "", false
+}
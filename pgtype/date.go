@@ -0,0 +1,78 @@
+package pgtype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DateCodec converts date values to and from a time.Time at midnight UTC, infinite dates are
+// decoded as an InfinityModifier
+//
+// The date of the wall clock of a time.Time is encoded, along with InfinityModifier values.
+type DateCodec struct{}
+
+// DecodeText decodes a date in any DateStyle, e.g. 1997-12-17 or 17.12.1997, followed by BC before
+// the year 1
+func (DateCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	if inf := infinity(string(src)); inf != 0 {
+		return inf, nil
+	}
+	dt, err := m.parseDateTime(string(src))
+	if err != nil {
+		return nil, err
+	}
+	if dt.clock != 0 || dt.zone != "" {
+		return nil, fmt.Errorf("invalid date %q", src)
+	}
+	return dt.in(time.UTC), nil
+}
+
+// EncodeText encodes the date in the ISO style, which is accepted whatever the DateStyle
+func (c DateCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case InfinityModifier:
+		return append(buf, v.String()...), nil
+	case time.Time:
+		return appendBC(appendDate(buf, v), v), nil
+	}
+	return nil, fmt.Errorf("cannot encode %T", v)
+}
+
+// DecodeBinary decodes the number of days since 2000-01-01 as an int32, the largest and smallest
+// int32 are infinity and -infinity
+func (DateCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	err := checkSize(src, 4)
+	if err != nil {
+		return nil, err
+	}
+	switch days := int32(binary.BigEndian.Uint32(src)); days {
+	case math.MaxInt32:
+		return Infinity, nil
+	case math.MinInt32:
+		return NegativeInfinity, nil
+	default:
+		return time.Date(2000, time.January, 1+int(days), 0, 0, 0, 0, time.UTC), nil
+	}
+}
+
+func (DateCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	var days int64
+	switch v := v.(type) {
+	case InfinityModifier:
+		days = math.MaxInt32
+		if v == NegativeInfinity {
+			days = math.MinInt32
+		}
+	case time.Time:
+		date := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)
+		days = (date.Unix() - postgresEpoch) / 86400
+		if days <= math.MinInt32 || days >= math.MaxInt32 {
+			return nil, fmt.Errorf("%v is out of range", v)
+		}
+	default:
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	return binary.BigEndian.AppendUint32(buf, uint32(int32(days))), nil
+}
@@ -0,0 +1,381 @@
+package pgtype
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InfinityModifier is the decoded value of the infinite dates and timestamps, which a time.Time can
+// not represent, and marks infinite Numeric values
+type InfinityModifier int8
+
+const (
+	Infinity         InfinityModifier = 1
+	NegativeInfinity InfinityModifier = -1
+)
+
+func (i InfinityModifier) String() string {
+	switch i {
+	case Infinity:
+		return "infinity"
+	case NegativeInfinity:
+		return "-infinity"
+	}
+	return "finite"
+}
+
+// postgresEpoch is 2000-01-01 00:00:00 UTC in seconds since the Unix epoch, the binary representation
+// of dates and timestamps counts from it
+const postgresEpoch = 946684800
+
+// infinity returns the InfinityModifier of the text representation src, or 0 when it is finite
+func infinity(src string) InfinityModifier {
+	switch src {
+	case "infinity":
+		return Infinity
+	case "-infinity":
+		return NegativeInfinity
+	}
+	return 0
+}
+
+// dateOrder returns the order of the day and month of dates in the SQL and Postgres DateStyles, "MDY"
+// or "DMY", from the DateStyle parameter
+func (m *TypeMap) dateOrder() string {
+	style := strings.ToUpper(m.Parameter("DateStyle"))
+	if strings.Contains(style, "DMY") || strings.Contains(style, "EURO") {
+		return "DMY"
+	}
+	return "MDY"
+}
+
+// timeLocation returns the location of the TimeZone parameter, or nil when it is unknown
+func (m *TypeMap) timeLocation() *time.Location {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.location
+}
+
+// dateTime is a date and time of day as represented in text, before its time zone is resolved
+type dateTime struct {
+	// year is the astronomical year, 0 is 1 BC
+	year, month, day int
+	clock            time.Duration
+
+	// zone is the time zone offset or abbreviation following the time, if any
+	zone string
+}
+
+// parseDateTime parses the text representation of a date or timestamp in any DateStyle:
+//
+//   ISO        1997-12-17 07:37:16.123-08
+//   SQL        12/17/1997 07:37:16.123 PST
+//   Postgres   Wed Dec 17 07:37:16.123 1997 PST
+//   German     17.12.1997 07:37:16.123 PST
+//
+// each followed by BC for dates before the year 1. The order of the day and month of the SQL and
+// Postgres styles is given by the DateStyle parameter.
+func (m *TypeMap) parseDateTime(src string) (dateTime, error) {
+	var dt dateTime
+	fields := strings.Fields(src)
+	bc := len(fields) > 1 && fields[len(fields)-1] == "BC"
+	if bc {
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) == 0 {
+		return dt, fmt.Errorf("invalid date %q", src)
+	}
+
+	var err error
+	if c := fields[0][0]; (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') {
+		// Postgres style: the day of the week, the month name and day in the DateStyle order, the
+		// time and the year
+		if len(fields) < 5 {
+			return dt, fmt.Errorf("invalid date %q", src)
+		}
+		month, day := fields[1], fields[2]
+		if month[0] >= '0' && month[0] <= '9' {
+			month, day = day, month
+		}
+		dt.month = monthNumber(month)
+		dt.day, _ = strconv.Atoi(day)
+		dt.clock, dt.zone, err = parseClock(fields[3])
+		if err != nil {
+			return dt, err
+		}
+		dt.year, err = strconv.Atoi(fields[4])
+		if err != nil {
+			return dt, fmt.Errorf("invalid date %q", src)
+		}
+		fields = fields[5:]
+	} else {
+		dt.year, dt.month, dt.day, err = m.parseDate(fields[0])
+		if err != nil {
+			return dt, err
+		}
+		fields = fields[1:]
+		if len(fields) > 0 {
+			dt.clock, dt.zone, err = parseClock(fields[0])
+			if err != nil {
+				return dt, err
+			}
+			fields = fields[1:]
+		}
+	}
+
+	// The time zone abbreviation follows the time in the non ISO styles
+	if len(fields) > 0 && dt.zone == "" {
+		dt.zone = fields[0]
+		fields = fields[1:]
+	}
+	if len(fields) > 0 || dt.month < 1 || dt.month > 12 || dt.day < 1 || dt.day > 31 {
+		return dt, fmt.Errorf("invalid date %q", src)
+	}
+	if bc {
+		dt.year = 1 - dt.year
+	}
+	return dt, nil
+}
+
+// parseDate parses the date part of a date or timestamp
+func (m *TypeMap) parseDate(src string) (year, month, day int, err error) {
+	i := strings.IndexAny(src, "-/.")
+	if i < 0 {
+		return 0, 0, 0, fmt.Errorf("invalid date %q", src)
+	}
+	parts := strings.Split(src, src[i:i+1])
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("invalid date %q", src)
+	}
+
+	var ymd [3]int
+	for j, part := range parts {
+		ymd[j], err = strconv.Atoi(part)
+		if err != nil || part == "" || part[0] < '0' || part[0] > '9' {
+			return 0, 0, 0, fmt.Errorf("invalid date %q", src)
+		}
+	}
+
+	switch {
+	case src[i] == '-' && len(parts[0]) >= 4:
+		// ISO
+		return ymd[0], ymd[1], ymd[2], nil
+	case src[i] == '.':
+		// German
+		return ymd[2], ymd[1], ymd[0], nil
+	case m.dateOrder() == "DMY":
+		// SQL or Postgres
+		return ymd[2], ymd[1], ymd[0], nil
+	}
+	return ymd[2], ymd[0], ymd[1], nil
+}
+
+// parseClock parses a time of day, HH:MM[:SS[.ffffff]], and returns the time zone offset following it
+func parseClock(src string) (time.Duration, string, error) {
+	clock, zone := src, ""
+	if i := strings.IndexAny(src, "+-"); i >= 0 {
+		clock, zone = src[:i], src[i:]
+	}
+
+	parts := strings.Split(clock, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, "", fmt.Errorf("invalid time %q", src)
+	}
+	hour, err1 := strconv.Atoi(parts[0])
+	minute, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || hour < 0 || hour > 24 || minute < 0 || minute > 59 {
+		return 0, "", fmt.Errorf("invalid time %q", src)
+	}
+
+	var micro int64
+	if len(parts) == 3 {
+		seconds, fraction, _ := strings.Cut(parts[2], ".")
+		s, err := strconv.Atoi(seconds)
+		if err != nil || s < 0 || s > 60 || len(seconds) != 2 {
+			return 0, "", fmt.Errorf("invalid time %q", src)
+		}
+		micro = int64(s) * 1e6
+		if fraction != "" {
+			// Only microseconds are kept
+			if len(fraction) > 6 {
+				fraction = fraction[:6]
+			}
+			f, err := strconv.Atoi(fraction + strings.Repeat("0", 6-len(fraction)))
+			if err != nil || f < 0 {
+				return 0, "", fmt.Errorf("invalid time %q", src)
+			}
+			micro += int64(f)
+		}
+	}
+	micro += int64(hour)*3600e6 + int64(minute)*60e6
+	if micro > 24*3600e6 {
+		return 0, "", fmt.Errorf("invalid time %q", src)
+	}
+	return time.Duration(micro) * time.Microsecond, zone, nil
+}
+
+// parseOffset parses a time zone offset, +HH[:MM[:SS]], and returns it in seconds east of UTC
+func parseOffset(src string) (int, bool) {
+	if len(src) < 2 || (src[0] != '+' && src[0] != '-') {
+		return 0, false
+	}
+	offset := 0
+	for i, part := range strings.Split(src[1:], ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil || i > 2 || len(part) != 2 || n < 0 || (i > 0 && n > 59) {
+			return 0, false
+		}
+		offset += n * []int{3600, 60, 1}[i]
+	}
+	if src[0] == '-' {
+		offset = -offset
+	}
+	return offset, true
+}
+
+// monthNumber returns the number of the month abbreviated as name, e.g. Dec, or 0
+func monthNumber(name string) int {
+	for month := time.January; month <= time.December; month++ {
+		if strings.EqualFold(name, month.String()[:3]) {
+			return int(month)
+		}
+	}
+	return 0
+}
+
+// in returns the time of dt in loc
+func (dt dateTime) in(loc *time.Location) time.Time {
+	return time.Date(dt.year, time.Month(dt.month), dt.day, 0, 0, 0, int(dt.clock), loc)
+}
+
+// zonedTime returns the time of dt, resolving its time zone with the TimeZone parameter, which is also
+// the location of the returned time when it is known
+func (m *TypeMap) zonedTime(dt dateTime) (time.Time, error) {
+	loc := m.timeLocation()
+	if offset, ok := parseOffset(dt.zone); ok {
+		t := dt.in(time.FixedZone("", offset))
+		if loc != nil {
+			t = t.In(loc)
+		}
+		return t, nil
+	}
+
+	if dt.zone != "" && loc != nil {
+		t := dt.in(loc)
+		if name, _ := t.Zone(); name == dt.zone {
+			return t, nil
+		}
+		// The same wall time happens twice when clocks are turned back, the abbreviation tells which
+		for _, d := range []time.Duration{-time.Hour, time.Hour, -30 * time.Minute, 30 * time.Minute} {
+			other := t.Add(d)
+			if name, _ := other.Zone(); name == dt.zone && clockOf(other) == dt.clock {
+				return other, nil
+			}
+		}
+	}
+
+	switch {
+	case dt.zone == "" && loc != nil:
+		return dt.in(loc), nil
+	case dt.zone == "" || dt.zone == "UTC" || dt.zone == "GMT":
+		return dt.in(time.UTC), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown time zone %q for the TimeZone %q", dt.zone, m.Parameter("TimeZone"))
+}
+
+// appendDate appends the ISO representation of the date of t, followed by BC before the year 1
+func appendDate(buf []byte, t time.Time) []byte {
+	year := t.Year()
+	if year <= 0 {
+		year = 1 - year
+	}
+	buf = appendInt(buf, year, 4)
+	buf = append(buf, '-')
+	buf = appendInt(buf, int(t.Month()), 2)
+	buf = append(buf, '-')
+	return appendInt(buf, t.Day(), 2)
+}
+
+// appendBC appends BC when t is before the year 1
+func appendBC(buf []byte, t time.Time) []byte {
+	if t.Year() <= 0 {
+		return append(buf, " BC"...)
+	}
+	return buf
+}
+
+// appendClock appends a time of day as HH:MM:SS, followed by the microseconds when not 0
+func appendClock(buf []byte, clock time.Duration) []byte {
+	micro := int64(clock / time.Microsecond)
+	buf = appendInt(buf, int(micro/3600e6), 2)
+	buf = append(buf, ':')
+	buf = appendInt(buf, int(micro/60e6%60), 2)
+	buf = append(buf, ':')
+	buf = appendInt(buf, int(micro/1e6%60), 2)
+	if micro%1e6 != 0 {
+		buf = append(buf, '.')
+		buf = append(buf, strings.TrimRight(fmt.Sprintf("%06d", micro%1e6), "0")...)
+	}
+	return buf
+}
+
+// appendOffset appends a time zone offset in seconds east of UTC as +HH[:MM[:SS]]
+func appendOffset(buf []byte, offset int) []byte {
+	if offset < 0 {
+		buf = append(buf, '-')
+		offset = -offset
+	} else {
+		buf = append(buf, '+')
+	}
+	buf = appendInt(buf, offset/3600, 2)
+	if offset%3600 != 0 {
+		buf = append(buf, ':')
+		buf = appendInt(buf, offset/60%60, 2)
+	}
+	if offset%60 != 0 {
+		buf = append(buf, ':')
+		buf = appendInt(buf, offset%60, 2)
+	}
+	return buf
+}
+
+// appendInt appends i with at least width digits
+func appendInt(buf []byte, i int, width int) []byte {
+	s := strconv.Itoa(i)
+	for n := len(s); n < width; n++ {
+		buf = append(buf, '0')
+	}
+	return append(buf, s...)
+}
+
+// clockOf returns the time of day of the wall clock of t
+func clockOf(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
+
+// roundMicro rounds t to the microsecond precision of PostgreSQL
+func roundMicro(t time.Time) time.Time {
+	return t.Round(time.Microsecond)
+}
+
+// floorDiv returns a divided by b rounded towards negative infinity, along with the remainder
+func floorDiv(a, b int64) (int64, int64) {
+	q, r := a/b, a%b
+	if r < 0 {
+		q--
+		r += b
+	}
+	return q, r
+}
+
+// checkInt32 returns an error unless i fits in an int32
+func checkInt32(i int64) error {
+	if i < math.MinInt32 || i > math.MaxInt32 {
+		return fmt.Errorf("%d is out of range", i)
+	}
+	return nil
+}
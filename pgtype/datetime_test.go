@@ -0,0 +1,288 @@
+package pgtype_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtype"
+	"github.com/stretchr/testify/suite"
+)
+
+type DateTimeTestSuite struct {
+	suite.Suite
+	m *pgtype.TypeMap
+}
+
+func TestDateTimeTestSuite(t *testing.T) {
+	suite.Run(t, new(DateTimeTestSuite))
+}
+
+func (s *DateTimeTestSuite) SetupTest() {
+	s.m = pgtype.NewTypeMap()
+}
+
+func (s *DateTimeTestSuite) decode(oid int, text string) interface{} {
+	v, err := s.m.Decode(oid, pgproto.FormatText, []byte(text))
+	s.Require().Nil(err, text)
+	return v
+}
+
+func (s *DateTimeTestSuite) Test_DateStyle() {
+	date := time.Date(1997, time.December, 17, 0, 0, 0, 0, time.UTC)
+	timestamp := time.Date(1997, time.December, 17, 7, 37, 16, 500000000, time.UTC)
+
+	cases := []struct {
+		style     string
+		date      string
+		timestamp string
+	}{
+		{"ISO, MDY", "1997-12-17", "1997-12-17 07:37:16.5"},
+		{"ISO, DMY", "1997-12-17", "1997-12-17 07:37:16.5"},
+		{"SQL, MDY", "12/17/1997", "12/17/1997 07:37:16.50"},
+		{"SQL, DMY", "17/12/1997", "17/12/1997 07:37:16.50"},
+		{"Postgres, MDY", "12-17-1997", "Wed Dec 17 07:37:16.5 1997"},
+		{"Postgres, DMY", "17-12-1997", "Wed 17 Dec 07:37:16.5 1997"},
+		{"German, DMY", "17.12.1997", "17.12.1997 07:37:16.50"},
+	}
+
+	for _, c := range cases {
+		s.m.SetParameter("DateStyle", c.style)
+		s.Equal(date, s.decode(pgtype.DateOID, c.date), c.style)
+		s.Equal(timestamp, s.decode(pgtype.TimestampOID, c.timestamp), c.style)
+	}
+}
+
+func (s *DateTimeTestSuite) Test_Date() {
+	s.Equal(time.Date(-43, time.March, 15, 0, 0, 0, 0, time.UTC), s.decode(pgtype.DateOID, "0044-03-15 BC"))
+	s.Equal(time.Date(10000, time.January, 1, 0, 0, 0, 0, time.UTC), s.decode(pgtype.DateOID, "10000-01-01"))
+	s.Equal(pgtype.Infinity, s.decode(pgtype.DateOID, "infinity"))
+	s.Equal(pgtype.NegativeInfinity, s.decode(pgtype.DateOID, "-infinity"))
+
+	cases := []struct {
+		value  interface{}
+		text   string
+		binary []byte
+	}{
+		{time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC), "2000-01-01", []byte{0x00, 0x00, 0x00, 0x00}},
+		{time.Date(1999, time.December, 31, 0, 0, 0, 0, time.UTC), "1999-12-31", []byte{0xff, 0xff, 0xff, 0xff}},
+		{time.Date(0, time.December, 31, 0, 0, 0, 0, time.UTC), "0001-12-31 BC", []byte{0xff, 0xf4, 0xdb, 0xf8}},
+		{pgtype.Infinity, "infinity", []byte{0x7f, 0xff, 0xff, 0xff}},
+		{pgtype.NegativeInfinity, "-infinity", []byte{0x80, 0x00, 0x00, 0x00}},
+	}
+	for _, c := range cases {
+		s.roundTrip(pgtype.DateOID, c.value, c.text, c.binary)
+	}
+
+	// The date of the wall clock is encoded
+	encoded, err := s.m.Encode(pgtype.DateOID, pgproto.FormatText, time.Date(2024, time.March, 1, 23, 0, 0, 0, time.FixedZone("", -5*3600)))
+	s.Nil(err)
+	s.Equal("2024-03-01", string(encoded))
+
+	// Infinite dates can not be stored in a time.Time
+	var t time.Time
+	s.NotNil(s.m.Scan(pgtype.DateOID, pgproto.FormatText, []byte("infinity"), &t))
+}
+
+func (s *DateTimeTestSuite) Test_Timestamp() {
+	cases := []struct {
+		value  interface{}
+		text   string
+		binary []byte
+	}{
+		{time.Date(2000, time.January, 1, 0, 0, 1, 500000000, time.UTC), "2000-01-01 00:00:01.5", []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x16, 0xe3, 0x60}},
+		{time.Date(1999, time.December, 31, 23, 59, 59, 999999000, time.UTC), "1999-12-31 23:59:59.999999", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{time.Date(0, time.January, 1, 12, 0, 0, 0, time.UTC), "0001-01-01 12:00:00 BC", []byte{0xff, 0x1f, 0xc6, 0x47, 0x2a, 0x9c, 0xd0, 0x00}},
+		{pgtype.Infinity, "infinity", []byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{pgtype.NegativeInfinity, "-infinity", []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+	}
+	for _, c := range cases {
+		s.roundTrip(pgtype.TimestampOID, c.value, c.text, c.binary)
+	}
+
+	// The wall clock is encoded whatever the location, and rounded to microseconds
+	t := time.Date(2024, time.March, 1, 23, 0, 0, 1500, time.FixedZone("", -5*3600))
+	encoded, err := s.m.Encode(pgtype.TimestampOID, pgproto.FormatText, t)
+	s.Nil(err)
+	s.Equal("2024-03-01 23:00:00.000002", string(encoded))
+}
+
+func (s *DateTimeTestSuite) Test_Timestamptz() {
+	s.m.SetParameter("TimeZone", "America/Los_Angeles")
+	la, err := time.LoadLocation("America/Los_Angeles")
+	s.Require().Nil(err)
+	expected := time.Date(1997, time.December, 17, 7, 37, 16, 0, la)
+
+	for style, text := range map[string]string{
+		"ISO, MDY":      "1997-12-17 07:37:16-08",
+		"SQL, MDY":      "12/17/1997 07:37:16.00 PST",
+		"Postgres, MDY": "Wed Dec 17 07:37:16 1997 PST",
+		"German, DMY":   "17.12.1997 07:37:16.00 PST",
+	} {
+		s.m.SetParameter("DateStyle", style)
+		v := s.decode(pgtype.TimestamptzOID, text)
+		s.True(expected.Equal(v.(time.Time)), style)
+		s.Equal(la, v.(time.Time).Location(), style)
+	}
+
+	// Times repeated when clocks are turned back are told apart by their abbreviation
+	s.m.SetParameter("DateStyle", "SQL, MDY")
+	pdt := s.decode(pgtype.TimestamptzOID, "11/05/2023 01:30:00 PDT").(time.Time)
+	pst := s.decode(pgtype.TimestamptzOID, "11/05/2023 01:30:00 PST").(time.Time)
+	s.Equal(time.Hour, pst.Sub(pdt))
+
+	// Offsets of any precision
+	s.m.SetParameter("DateStyle", "ISO, MDY")
+	v := s.decode(pgtype.TimestamptzOID, "1900-01-01 00:00:00+05:53:28")
+	s.True(time.Date(1899, time.December, 31, 18, 6, 32, 0, time.UTC).Equal(v.(time.Time)))
+
+	// The offset is kept when the TimeZone is unknown
+	s.m.SetParameter("TimeZone", "<+03>-03")
+	v = s.decode(pgtype.TimestamptzOID, "2024-01-02 03:04:05+03")
+	_, offset := v.(time.Time).Zone()
+	s.Equal(3*3600, offset)
+
+	// timestamptz values are encoded in UTC
+	cases := []struct {
+		value  interface{}
+		text   string
+		binary []byte
+	}{
+		{time.Date(2000, time.January, 1, 0, 0, 1, 500000000, time.UTC), "2000-01-01 00:00:01.5+00", []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x16, 0xe3, 0x60}},
+		{time.Date(0, time.January, 1, 12, 0, 0, 0, time.UTC), "0001-01-01 12:00:00+00 BC", []byte{0xff, 0x1f, 0xc6, 0x47, 0x2a, 0x9c, 0xd0, 0x00}},
+		{pgtype.Infinity, "infinity", []byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+	s.m.SetParameter("TimeZone", "UTC")
+	for _, c := range cases {
+		s.roundTrip(pgtype.TimestamptzOID, c.value, c.text, c.binary)
+	}
+	encoded, err := s.m.Encode(pgtype.TimestamptzOID, pgproto.FormatText, time.Date(1999, time.December, 31, 19, 0, 1, 0, time.FixedZone("", -5*3600)))
+	s.Nil(err)
+	s.Equal("2000-01-01 00:00:01+00", string(encoded))
+
+	// Binary values are decoded in the TimeZone
+	s.m.SetParameter("TimeZone", "America/Los_Angeles")
+	v, err = s.m.Decode(pgtype.TimestamptzOID, pgproto.FormatBinary, []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+	s.Nil(err)
+	s.Equal(la, v.(time.Time).Location())
+	s.True(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Equal(v.(time.Time)))
+}
+
+func (s *DateTimeTestSuite) Test_Time() {
+	cases := []struct {
+		oid    int
+		value  interface{}
+		text   string
+		binary []byte
+	}{
+		{pgtype.TimeOID, time.Hour, "01:00:00", []byte{0x00, 0x00, 0x00, 0x00, 0xd6, 0x93, 0xa4, 0x00}},
+		{pgtype.TimeOID, 4*time.Hour + 5*time.Minute + 6*time.Second + 789*time.Millisecond, "04:05:06.789", []byte{0x00, 0x00, 0x00, 0x03, 0x6c, 0x97, 0xca, 0x88}},
+		{pgtype.TimeOID, 24 * time.Hour, "24:00:00", []byte{0x00, 0x00, 0x00, 0x14, 0x1d, 0xd7, 0x60, 0x00}},
+		{pgtype.TimetzOID, pgtype.TimeTZ{Time: time.Hour, Offset: -8 * 3600}, "01:00:00-08", []byte{0x00, 0x00, 0x00, 0x00, 0xd6, 0x93, 0xa4, 0x00, 0x00, 0x00, 0x70, 0x80}},
+		{pgtype.TimetzOID, pgtype.TimeTZ{Time: time.Microsecond, Offset: 5*3600 + 30*60}, "00:00:00.000001+05:30", []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0xff, 0xff, 0xb2, 0xa8}},
+	}
+	for _, c := range cases {
+		s.roundTrip(c.oid, c.value, c.text, c.binary)
+	}
+
+	// The wall clock of a time.Time is encoded
+	t := time.Date(2024, time.March, 1, 23, 1, 2, 0, time.FixedZone("", -5*3600))
+	encoded, err := s.m.Encode(pgtype.TimeOID, pgproto.FormatText, t)
+	s.Nil(err)
+	s.Equal("23:01:02", string(encoded))
+	encoded, err = s.m.Encode(pgtype.TimetzOID, pgproto.FormatText, t)
+	s.Nil(err)
+	s.Equal("23:01:02-05", string(encoded))
+
+	var d time.Duration
+	s.Nil(s.m.Scan(pgtype.TimeOID, pgproto.FormatText, []byte("12:30:00"), &d))
+	s.Equal(12*time.Hour+30*time.Minute, d)
+
+	s.NotNil(s.m.Scan(pgtype.TimeOID, pgproto.FormatText, []byte("25:00:00"), &d))
+	_, err = s.m.Encode(pgtype.TimeOID, pgproto.FormatText, 25*time.Hour)
+	s.NotNil(err)
+	_, err = s.m.Encode(pgtype.TimetzOID, pgproto.FormatText, time.Hour)
+	s.NotNil(err)
+}
+
+func (s *DateTimeTestSuite) Test_IntervalStyle() {
+	cases := []struct {
+		style    string
+		text     string
+		expected pgtype.Interval
+	}{
+		{"postgres", "1 year 2 mons", pgtype.Interval{Months: 14}},
+		{"postgres", "3 days 04:05:06", pgtype.Interval{Days: 3, Microseconds: 14706e6}},
+		{"postgres", "-1 years -2 mons +3 days -04:05:06.5", pgtype.Interval{Months: -14, Days: 3, Microseconds: -14706.5e6}},
+		{"postgres", "00:00:00", pgtype.Interval{}},
+		{"postgres_verbose", "@ 1 year 2 mons", pgtype.Interval{Months: 14}},
+		{"postgres_verbose", "@ 3 days 4 hours 5 mins 6 secs", pgtype.Interval{Days: 3, Microseconds: 14706e6}},
+		{"postgres_verbose", "@ 1 year 2 mons -3 days 4 hours 5 mins 6.5 secs ago", pgtype.Interval{Months: -14, Days: 3, Microseconds: -14706.5e6}},
+		{"postgres_verbose", "@ 0", pgtype.Interval{}},
+		{"sql_standard", "1-2", pgtype.Interval{Months: 14}},
+		{"sql_standard", "3 4:05:06", pgtype.Interval{Days: 3, Microseconds: 14706e6}},
+		{"sql_standard", "-1-2 +3 -4:05:06.5", pgtype.Interval{Months: -14, Days: 3, Microseconds: -14706.5e6}},
+		{"sql_standard", "-3 4:05:06", pgtype.Interval{Days: -3, Microseconds: -14706e6}},
+		{"sql_standard", "0", pgtype.Interval{}},
+		{"iso_8601", "P1Y2M", pgtype.Interval{Months: 14}},
+		{"iso_8601", "P3DT4H5M6S", pgtype.Interval{Days: 3, Microseconds: 14706e6}},
+		{"iso_8601", "P-1Y-2M3DT-4H-5M-6.5S", pgtype.Interval{Months: -14, Days: 3, Microseconds: -14706.5e6}},
+		{"iso_8601", "PT0S", pgtype.Interval{}},
+	}
+
+	for _, c := range cases {
+		s.m.SetParameter("IntervalStyle", c.style)
+		s.Equal(c.expected, s.decode(pgtype.IntervalOID, c.text), c.text)
+	}
+
+	for _, text := range []string{"1 fortnight", "1 year 2", "P1X", "1:60:00"} {
+		s.m.SetParameter("IntervalStyle", "postgres")
+		_, err := s.m.Decode(pgtype.IntervalOID, pgproto.FormatText, []byte(text))
+		s.NotNil(err, text)
+	}
+}
+
+func (s *DateTimeTestSuite) Test_Interval() {
+	cases := []struct {
+		value  interface{}
+		text   string
+		binary []byte
+	}{
+		{pgtype.Interval{}, "PT0S", []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{pgtype.Interval{Months: 14, Days: 3, Microseconds: 14706.5e6}, "P1Y2M3DT4H5M6.5S", []byte{0, 0, 0, 0x03, 0x6c, 0x93, 0x61, 0xa0, 0, 0, 0, 0x03, 0, 0, 0, 0x0e}},
+		{pgtype.Interval{Months: -1, Microseconds: -1}, "P-1MT-0.000001S", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}},
+		{pgtype.Interval{Days: -2}, "P-2D", []byte{0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xfe, 0, 0, 0, 0}},
+	}
+	for _, c := range cases {
+		s.roundTrip(pgtype.IntervalOID, c.value, c.text, c.binary)
+	}
+
+	s.Equal(pgtype.Interval{Months: math.MaxInt32, Days: math.MaxInt32, Microseconds: math.MaxInt64}, s.decode(pgtype.IntervalOID, "infinity"))
+
+	encoded, err := s.m.Encode(pgtype.IntervalOID, pgproto.FormatText, 90*time.Minute)
+	s.Nil(err)
+	s.Equal("PT1H30M", string(encoded))
+
+	// Intervals without months are stored in a time.Duration
+	var d time.Duration
+	s.Nil(s.m.Scan(pgtype.IntervalOID, pgproto.FormatText, []byte("1 day 01:00:00.5"), &d))
+	s.Equal(25*time.Hour+500*time.Millisecond, d)
+	s.NotNil(s.m.Scan(pgtype.IntervalOID, pgproto.FormatText, []byte("1 mon"), &d))
+	s.NotNil(s.m.Scan(pgtype.IntervalOID, pgproto.FormatText, []byte("200000 days"), &d))
+}
+
+// roundTrip checks the text and binary representations of v decode to v and back
+func (s *DateTimeTestSuite) roundTrip(oid int, v interface{}, text string, binary []byte) {
+	encoded, err := s.m.Encode(oid, pgproto.FormatText, v)
+	s.Nil(err, v)
+	s.Equal(text, string(encoded), v)
+	s.Equal(v, s.decode(oid, text), text)
+
+	encoded, err = s.m.Encode(oid, pgproto.FormatBinary, v)
+	s.Nil(err, v)
+	s.Equal(binary, encoded, v)
+	decoded, err := s.m.Decode(oid, pgproto.FormatBinary, binary)
+	s.Nil(err, v)
+	s.Equal(v, decoded, v)
+}
@@ -0,0 +1,79 @@
+package pgtype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FloatCodec converts float4 and float8 values, of Size 4 and 8 bytes, to and from a float32 or
+// float64
+//
+// NaN and infinite values are supported. Integers are encoded when a float64 represents them exactly.
+type FloatCodec struct {
+	Size int
+}
+
+func (c FloatCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(string(src)), c.Size*8)
+	if err != nil {
+		return nil, err
+	}
+	if c.Size == 4 {
+		return float32(f), nil
+	}
+	return f, nil
+}
+
+func (c FloatCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	f, ok := toFloat64(v)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+
+	if c.Size == 4 && !math.IsInf(f, 0) && math.Abs(f) > math.MaxFloat32 {
+		return nil, fmt.Errorf("%v is out of range for float4", v)
+	}
+	return appendFloat(buf, f, c.Size*8), nil
+}
+
+// appendFloat appends the text of a float of bitSize bits, NaN, Infinity and -Infinity included
+func appendFloat(buf []byte, f float64, bitSize int) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(buf, "NaN"...)
+	case math.IsInf(f, 1):
+		return append(buf, "Infinity"...)
+	case math.IsInf(f, -1):
+		return append(buf, "-Infinity"...)
+	}
+	return strconv.AppendFloat(buf, f, 'g', -1, bitSize)
+}
+
+// DecodeBinary decodes a big endian IEEE 754 float of Size bytes
+func (c FloatCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	err := checkSize(src, c.Size)
+	if err != nil {
+		return nil, err
+	}
+	if c.Size == 4 {
+		return math.Float32frombits(binary.BigEndian.Uint32(src)), nil
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(src)), nil
+}
+
+func (c FloatCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	f, ok := toFloat64(v)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	if c.Size == 4 {
+		if !math.IsInf(f, 0) && math.Abs(f) > math.MaxFloat32 {
+			return nil, fmt.Errorf("%v is out of range for float4", v)
+		}
+		return binary.BigEndian.AppendUint32(buf, math.Float32bits(float32(f))), nil
+	}
+	return binary.BigEndian.AppendUint64(buf, math.Float64bits(f)), nil
+}
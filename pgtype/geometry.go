@@ -0,0 +1,627 @@
+package pgtype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Point is a point value, e.g. (1,2)
+type Point struct {
+	X, Y float64
+}
+
+// String returns the text of p, e.g. (1,2)
+func (p Point) String() string {
+	return string(p.appendText(nil))
+}
+
+func (p Point) appendText(buf []byte) []byte {
+	buf = append(buf, '(')
+	buf = appendFloat(buf, p.X, 64)
+	buf = append(buf, ',')
+	buf = appendFloat(buf, p.Y, 64)
+	return append(buf, ')')
+}
+
+func (p Point) appendBinary(buf []byte) []byte {
+	buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(p.X))
+	return binary.BigEndian.AppendUint64(buf, math.Float64bits(p.Y))
+}
+
+// Line is a line value, the points of which verify A*x + B*y + C = 0, e.g. {1,-1,0}
+type Line struct {
+	A, B, C float64
+}
+
+// String returns the text of l, e.g. {1,-1,0}
+func (l Line) String() string {
+	buf := append([]byte{'{'}, appendFloat(nil, l.A, 64)...)
+	buf = append(buf, ',')
+	buf = appendFloat(buf, l.B, 64)
+	buf = append(buf, ',')
+	buf = appendFloat(buf, l.C, 64)
+	return string(append(buf, '}'))
+}
+
+// Lseg is a line segment value between two points, e.g. [(1,2),(3,4)]
+type Lseg [2]Point
+
+// String returns the text of l, e.g. [(1,2),(3,4)]
+func (l Lseg) String() string {
+	return string(appendPoints([]byte{'['}, l[:], ']'))
+}
+
+// Box is a box value of two opposite corners, e.g. (3,4),(1,2)
+//
+// PostgreSQL stores the upper right corner in High and the lower left corner in Low, whichever
+// corners are given.
+type Box struct {
+	High, Low Point
+}
+
+// String returns the text of b, e.g. (3,4),(1,2)
+func (b Box) String() string {
+	return string(appendPoints(nil, []Point{b.High, b.Low}, 0))
+}
+
+// Path is a path value, open, e.g. [(1,2),(3,4)], or Closed, e.g. ((1,2),(3,4),(5,6))
+type Path struct {
+	Points []Point
+	Closed bool
+}
+
+// String returns the text of p, e.g. [(1,2),(3,4)]
+func (p Path) String() string {
+	if p.Closed {
+		return string(appendPoints([]byte{'('}, p.Points, ')'))
+	}
+	return string(appendPoints([]byte{'['}, p.Points, ']'))
+}
+
+// Polygon is a polygon value of its vertices, e.g. ((1,2),(3,4),(5,6))
+type Polygon []Point
+
+// String returns the text of p, e.g. ((1,2),(3,4),(5,6))
+func (p Polygon) String() string {
+	return string(appendPoints([]byte{'('}, p, ')'))
+}
+
+// Circle is a circle value, e.g. <(1,2),3>
+type Circle struct {
+	Center Point
+	Radius float64
+}
+
+// String returns the text of c, e.g. <(1,2),3>
+func (c Circle) String() string {
+	buf := c.Center.appendText([]byte{'<'})
+	buf = append(buf, ',')
+	buf = appendFloat(buf, c.Radius, 64)
+	return string(append(buf, '>'))
+}
+
+// appendPoints appends the text of points separated by commas, followed by end unless it is zero
+func appendPoints(buf []byte, points []Point, end byte) []byte {
+	for i, p := range points {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = p.appendText(buf)
+	}
+	if end != 0 {
+		buf = append(buf, end)
+	}
+	return buf
+}
+
+// PointCodec converts point values to and from a Point
+//
+// Points and strings of their text are encoded, as by the codecs of the other geometric types.
+type PointCodec struct{}
+
+func (PointCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	return parseGeometry(src, "point", (*geometryParser).parsePoint)
+}
+
+func (PointCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	p, err := geometric(v, "point", (*geometryParser).parsePoint)
+	if err != nil {
+		return nil, err
+	}
+	return p.appendText(buf), nil
+}
+
+// DecodeBinary decodes the coordinates, each a big endian float8
+func (PointCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	f, err := decodeFloats(src, 2)
+	if err != nil {
+		return nil, err
+	}
+	return Point{f[0], f[1]}, nil
+}
+
+func (PointCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	p, err := geometric(v, "point", (*geometryParser).parsePoint)
+	if err != nil {
+		return nil, err
+	}
+	return p.appendBinary(buf), nil
+}
+
+// LineCodec converts line values to and from a Line
+type LineCodec struct{}
+
+func (LineCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	return parseGeometry(src, "line", (*geometryParser).parseLine)
+}
+
+func (LineCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	l, err := geometric(v, "line", (*geometryParser).parseLine)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, l.String()...), nil
+}
+
+// DecodeBinary decodes A, B and C, each a big endian float8
+func (LineCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	f, err := decodeFloats(src, 3)
+	if err != nil {
+		return nil, err
+	}
+	return Line{f[0], f[1], f[2]}, nil
+}
+
+func (LineCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	l, err := geometric(v, "line", (*geometryParser).parseLine)
+	if err != nil {
+		return nil, err
+	}
+	buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(l.A))
+	buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(l.B))
+	return binary.BigEndian.AppendUint64(buf, math.Float64bits(l.C)), nil
+}
+
+// LsegCodec converts lseg values to and from a Lseg
+type LsegCodec struct{}
+
+func (LsegCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	return parseGeometry(src, "lseg", (*geometryParser).parseLseg)
+}
+
+func (LsegCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	l, err := geometric(v, "lseg", (*geometryParser).parseLseg)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, l.String()...), nil
+}
+
+// DecodeBinary decodes the two points, each two big endian float8
+func (LsegCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	f, err := decodeFloats(src, 4)
+	if err != nil {
+		return nil, err
+	}
+	return Lseg{{f[0], f[1]}, {f[2], f[3]}}, nil
+}
+
+func (LsegCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	l, err := geometric(v, "lseg", (*geometryParser).parseLseg)
+	if err != nil {
+		return nil, err
+	}
+	return l[1].appendBinary(l[0].appendBinary(buf)), nil
+}
+
+// BoxCodec converts box values to and from a Box
+type BoxCodec struct{}
+
+func (BoxCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	return parseGeometry(src, "box", (*geometryParser).parseBox)
+}
+
+func (BoxCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	b, err := geometric(v, "box", (*geometryParser).parseBox)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, b.String()...), nil
+}
+
+// DecodeBinary decodes the upper right and lower left corners, each two big endian float8
+func (BoxCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	f, err := decodeFloats(src, 4)
+	if err != nil {
+		return nil, err
+	}
+	return Box{High: Point{f[0], f[1]}, Low: Point{f[2], f[3]}}, nil
+}
+
+func (BoxCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	b, err := geometric(v, "box", (*geometryParser).parseBox)
+	if err != nil {
+		return nil, err
+	}
+	return b.Low.appendBinary(b.High.appendBinary(buf)), nil
+}
+
+// PathCodec converts path values to and from a Path
+type PathCodec struct{}
+
+func (PathCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	return parseGeometry(src, "path", (*geometryParser).parsePath)
+}
+
+func (PathCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	p, err := geometric(v, "path", (*geometryParser).parsePath)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, p.String()...), nil
+}
+
+// DecodeBinary decodes whether the path is closed, a byte, the number of points, a big endian int32,
+// and the points, each two big endian float8
+func (PathCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	if len(src) < 1 {
+		return nil, fmt.Errorf("expected at least 1 byte, got %d", len(src))
+	}
+	points, err := decodePoints(src[1:])
+	if err != nil {
+		return nil, err
+	}
+	return Path{Points: points, Closed: src[0] != 0}, nil
+}
+
+func (PathCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	p, err := geometric(v, "path", (*geometryParser).parsePath)
+	if err != nil {
+		return nil, err
+	}
+	var closed byte
+	if p.Closed {
+		closed = 1
+	}
+	return appendBinaryPoints(append(buf, closed), p.Points), nil
+}
+
+// PolygonCodec converts polygon values to and from a Polygon
+type PolygonCodec struct{}
+
+func (PolygonCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	return parseGeometry(src, "polygon", (*geometryParser).parsePolygon)
+}
+
+func (PolygonCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	p, err := geometric(v, "polygon", (*geometryParser).parsePolygon)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, p.String()...), nil
+}
+
+// DecodeBinary decodes the number of vertices, a big endian int32, and the vertices, each two big
+// endian float8
+func (PolygonCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	points, err := decodePoints(src)
+	if err != nil {
+		return nil, err
+	}
+	return Polygon(points), nil
+}
+
+func (PolygonCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	p, err := geometric(v, "polygon", (*geometryParser).parsePolygon)
+	if err != nil {
+		return nil, err
+	}
+	return appendBinaryPoints(buf, p), nil
+}
+
+// CircleCodec converts circle values to and from a Circle
+type CircleCodec struct{}
+
+func (CircleCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	return parseGeometry(src, "circle", (*geometryParser).parseCircle)
+}
+
+func (CircleCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	c, err := geometric(v, "circle", (*geometryParser).parseCircle)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, c.String()...), nil
+}
+
+// DecodeBinary decodes the center, two big endian float8, and the radius, a big endian float8
+func (CircleCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	f, err := decodeFloats(src, 3)
+	if err != nil {
+		return nil, err
+	}
+	return Circle{Center: Point{f[0], f[1]}, Radius: f[2]}, nil
+}
+
+func (CircleCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	c, err := geometric(v, "circle", (*geometryParser).parseCircle)
+	if err != nil {
+		return nil, err
+	}
+	return binary.BigEndian.AppendUint64(c.Center.appendBinary(buf), math.Float64bits(c.Radius)), nil
+}
+
+// geometric converts v, a T or a string parse parses, to a T
+func geometric[T any](v interface{}, name string, parse func(*geometryParser) (T, error)) (T, error) {
+	if g, ok := v.(T); ok {
+		return g, nil
+	}
+	if s, ok := toString(v); ok {
+		return parseGeometry([]byte(s), name, parse)
+	}
+	var zero T
+	return zero, fmt.Errorf("cannot encode %T", v)
+}
+
+// decodeFloats decodes n big endian float8
+func decodeFloats(src []byte, n int) ([]float64, error) {
+	err := checkSize(src, n*8)
+	if err != nil {
+		return nil, err
+	}
+	f := make([]float64, n)
+	for i := range f {
+		f[i] = math.Float64frombits(binary.BigEndian.Uint64(src[i*8:]))
+	}
+	return f, nil
+}
+
+// decodePoints decodes the number of points, a big endian int32, and the points
+func decodePoints(src []byte) ([]Point, error) {
+	if len(src) < 4 {
+		return nil, fmt.Errorf("expected at least 4 bytes, got %d", len(src))
+	}
+	n := int(int32(binary.BigEndian.Uint32(src)))
+	if n < 0 || n > (len(src)-4)/16 {
+		return nil, fmt.Errorf("invalid number of points %d", n)
+	}
+	f, err := decodeFloats(src[4:], n*2)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = Point{f[i*2], f[i*2+1]}
+	}
+	return points, nil
+}
+
+// appendBinaryPoints appends the number of points, a big endian int32, and the points
+func appendBinaryPoints(buf []byte, points []Point) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(points)))
+	for _, p := range points {
+		buf = p.appendBinary(buf)
+	}
+	return buf
+}
+
+// parseGeometry parses the text of a geometric value, the whole of src
+func parseGeometry[T any](src []byte, name string, parse func(*geometryParser) (T, error)) (T, error) {
+	p := &geometryParser{name: name, src: string(src)}
+	g, err := parse(p)
+	if err != nil {
+		return g, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.src) {
+		return g, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	return g, nil
+}
+
+// geometryParser parses the text of the geometric values of type name
+type geometryParser struct {
+	name string
+	src  string
+	pos  int
+}
+
+func (p *geometryParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid %s %q: %s", p.name, p.src, fmt.Sprintf(format, args...))
+}
+
+// consume skips the whitespace and the next byte when it is c, and returns whether it was
+func (p *geometryParser) consume(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect skips c, which must be the next byte after the whitespace
+func (p *geometryParser) expect(c byte) error {
+	if !p.consume(c) {
+		return p.errorf("expected '%c'", c)
+	}
+	return nil
+}
+
+// skipSpace skips the whitespace allowed around numbers and delimiters
+func (p *geometryParser) skipSpace() {
+	for p.pos < len(p.src) && isArraySpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// parseFloat parses a float8, e.g. 1.5, -1e+20 or Infinity
+func (p *geometryParser) parseFloat() (float64, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) && !isArraySpace(p.src[p.pos]) {
+		switch p.src[p.pos] {
+		case ',', '(', ')', '[', ']', '<', '>', '{', '}':
+			return p.float(p.src[start:p.pos])
+		}
+		p.pos++
+	}
+	return p.float(p.src[start:p.pos])
+}
+
+func (p *geometryParser) float(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, p.errorf("invalid number %q", s)
+	}
+	return f, nil
+}
+
+// parsePoint parses a point in parentheses, e.g. (1,2)
+func (p *geometryParser) parsePoint() (Point, error) {
+	var pt Point
+	err := p.expect('(')
+	if err != nil {
+		return pt, err
+	}
+	pt.X, err = p.parseFloat()
+	if err != nil {
+		return pt, err
+	}
+	err = p.expect(',')
+	if err != nil {
+		return pt, err
+	}
+	pt.Y, err = p.parseFloat()
+	if err != nil {
+		return pt, err
+	}
+	return pt, p.expect(')')
+}
+
+// parsePoints parses points separated by commas, followed by end
+func (p *geometryParser) parsePoints(end byte) ([]Point, error) {
+	var points []Point
+	for {
+		pt, err := p.parsePoint()
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, pt)
+		if p.consume(end) {
+			return points, nil
+		}
+		err = p.expect(',')
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseLine parses the coefficients of a line in braces, e.g. {1,-1,0}
+func (p *geometryParser) parseLine() (Line, error) {
+	var l Line
+	err := p.expect('{')
+	if err != nil {
+		return l, err
+	}
+	for i, f := range []*float64{&l.A, &l.B, &l.C} {
+		if i > 0 {
+			err = p.expect(',')
+			if err != nil {
+				return l, err
+			}
+		}
+		*f, err = p.parseFloat()
+		if err != nil {
+			return l, err
+		}
+	}
+	return l, p.expect('}')
+}
+
+// parseLseg parses two points in brackets, e.g. [(1,2),(3,4)]
+func (p *geometryParser) parseLseg() (Lseg, error) {
+	var l Lseg
+	err := p.expect('[')
+	if err != nil {
+		return l, err
+	}
+	points, err := p.parsePoints(']')
+	if err != nil {
+		return l, err
+	}
+	if len(points) != 2 {
+		return l, p.errorf("expected 2 points, got %d", len(points))
+	}
+	return Lseg{points[0], points[1]}, nil
+}
+
+// parseBox parses two opposite corners, e.g. (3,4),(1,2)
+func (p *geometryParser) parseBox() (Box, error) {
+	var b Box
+	var err error
+	b.High, err = p.parsePoint()
+	if err != nil {
+		return b, err
+	}
+	err = p.expect(',')
+	if err != nil {
+		return b, err
+	}
+	b.Low, err = p.parsePoint()
+	return b, err
+}
+
+// parsePath parses the points of an open path in brackets, e.g. [(1,2),(3,4)], or of a closed path
+// in parentheses, e.g. ((1,2),(3,4))
+func (p *geometryParser) parsePath() (Path, error) {
+	var path Path
+	end := byte(']')
+	if p.consume('(') {
+		path.Closed = true
+		end = ')'
+	} else if !p.consume('[') {
+		return path, p.errorf("expected '[' or '('")
+	}
+	var err error
+	path.Points, err = p.parsePoints(end)
+	return path, err
+}
+
+// parsePolygon parses the vertices of a polygon in parentheses, e.g. ((1,2),(3,4),(5,6))
+func (p *geometryParser) parsePolygon() (Polygon, error) {
+	err := p.expect('(')
+	if err != nil {
+		return nil, err
+	}
+	points, err := p.parsePoints(')')
+	if err != nil {
+		return nil, err
+	}
+	return Polygon(points), nil
+}
+
+// parseCircle parses a center and a radius in angle brackets, e.g. <(1,2),3>
+func (p *geometryParser) parseCircle() (Circle, error) {
+	var c Circle
+	err := p.expect('<')
+	if err != nil {
+		return c, err
+	}
+	c.Center, err = p.parsePoint()
+	if err != nil {
+		return c, err
+	}
+	err = p.expect(',')
+	if err != nil {
+		return c, err
+	}
+	c.Radius, err = p.parseFloat()
+	if err != nil {
+		return c, err
+	}
+	return c, p.expect('>')
+}
@@ -0,0 +1,203 @@
+package pgtype_test
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtype"
+	"github.com/stretchr/testify/suite"
+)
+
+type GeometryTestSuite struct {
+	suite.Suite
+	m *pgtype.TypeMap
+}
+
+func TestGeometryTestSuite(t *testing.T) {
+	suite.Run(t, new(GeometryTestSuite))
+}
+
+func (s *GeometryTestSuite) SetupTest() {
+	s.m = pgtype.NewTypeMap()
+}
+
+// float8s returns the binary representation of floats, each a big endian float8
+func float8s(floats ...float64) []byte {
+	var b []byte
+	for _, f := range floats {
+		b = binary.BigEndian.AppendUint64(b, math.Float64bits(f))
+	}
+	return b
+}
+
+func (s *GeometryTestSuite) Test_Formats() {
+	cases := []struct {
+		oid    int
+		text   string
+		binary []byte
+		value  interface{}
+	}{
+		{
+			pgtype.PointOID,
+			"(1.5,-0.25)",
+			[]byte{0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xbf, 0xd0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			pgtype.Point{X: 1.5, Y: -0.25},
+		},
+		{
+			pgtype.LineOID,
+			"{1,-1,0}",
+			float8s(1, -1, 0),
+			pgtype.Line{A: 1, B: -1, C: 0},
+		},
+		{
+			pgtype.LsegOID,
+			"[(1,2),(3,4)]",
+			float8s(1, 2, 3, 4),
+			pgtype.Lseg{{X: 1, Y: 2}, {X: 3, Y: 4}},
+		},
+		{
+			pgtype.BoxOID,
+			"(3,4),(1,2)",
+			float8s(3, 4, 1, 2),
+			pgtype.Box{High: pgtype.Point{X: 3, Y: 4}, Low: pgtype.Point{X: 1, Y: 2}},
+		},
+		{
+			pgtype.PathOID,
+			"[(1,2),(3,4)]",
+			append([]byte{0x00, 0x00, 0x00, 0x00, 0x02}, float8s(1, 2, 3, 4)...),
+			pgtype.Path{Points: []pgtype.Point{{X: 1, Y: 2}, {X: 3, Y: 4}}},
+		},
+		{
+			pgtype.PathOID,
+			"((0,0),(1,1),(1,0))",
+			append([]byte{0x01, 0x00, 0x00, 0x00, 0x03}, float8s(0, 0, 1, 1, 1, 0)...),
+			pgtype.Path{Points: []pgtype.Point{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 0}}, Closed: true},
+		},
+		{
+			pgtype.PolygonOID,
+			"((0,0),(1,1),(1,0))",
+			append([]byte{0x00, 0x00, 0x00, 0x03}, float8s(0, 0, 1, 1, 1, 0)...),
+			pgtype.Polygon{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 0}},
+		},
+		{
+			pgtype.CircleOID,
+			"<(1,2),3>",
+			float8s(1, 2, 3),
+			pgtype.Circle{Center: pgtype.Point{X: 1, Y: 2}, Radius: 3},
+		},
+		{
+			pgtype.PointOID,
+			"(Infinity,-1e+300)",
+			float8s(math.Inf(1), -1e300),
+			pgtype.Point{X: math.Inf(1), Y: -1e300},
+		},
+	}
+
+	for _, c := range cases {
+		v, err := s.m.Decode(c.oid, pgproto.FormatText, []byte(c.text))
+		s.Nil(err, c.text)
+		s.Equal(c.value, v, c.text)
+		v, err = s.m.Decode(c.oid, pgproto.FormatBinary, c.binary)
+		s.Nil(err, c.text)
+		s.Equal(c.value, v, c.text)
+
+		encoded, err := s.m.Encode(c.oid, pgproto.FormatText, c.value)
+		s.Nil(err, c.text)
+		s.Equal(c.text, string(encoded))
+		encoded, err = s.m.Encode(c.oid, pgproto.FormatBinary, c.value)
+		s.Nil(err, c.text)
+		s.Equal(c.binary, encoded, c.text)
+	}
+
+	for _, invalid := range [][]byte{{}, {0x00}, {0x00, 0x00, 0x00, 0x00, 0x01}, {0x00, 0xff, 0xff, 0xff, 0xff}, append([]byte{0x00, 0x00, 0x00, 0x00, 0x01}, float8s(1)...)} {
+		_, err := s.m.Decode(pgtype.PathOID, pgproto.FormatBinary, invalid)
+		s.NotNil(err, invalid)
+	}
+	_, err := s.m.Decode(pgtype.CircleOID, pgproto.FormatBinary, float8s(1, 2))
+	s.NotNil(err)
+}
+
+func (s *GeometryTestSuite) Test_Text() {
+	// Whitespace is allowed around numbers and delimiters
+	v, err := s.m.Decode(pgtype.CircleOID, pgproto.FormatText, []byte(" < ( 1 , 2 ) , 3 > "))
+	s.Nil(err)
+	s.Equal(pgtype.Circle{Center: pgtype.Point{X: 1, Y: 2}, Radius: 3}, v)
+
+	cases := []struct {
+		oid     int
+		invalid []string
+	}{
+		{pgtype.PointOID, []string{"", "(1,2", "(1;2)", "(a,2)", "(1,2)x", "(1,2,3)"}},
+		{pgtype.LineOID, []string{"{1,2}", "(1,2,3)", "{1,2,3"}},
+		{pgtype.LsegOID, []string{"[(1,2)]", "[(1,2),(3,4),(5,6)]", "((1,2),(3,4))"}},
+		{pgtype.BoxOID, []string{"(1,2)", "(1,2),(3,4),(5,6)"}},
+		{pgtype.PathOID, []string{"[]", "[(1,2),(3,4))", "{(1,2)}"}},
+		{pgtype.PolygonOID, []string{"()", "[(1,2)]"}},
+		{pgtype.CircleOID, []string{"<(1,2)>", "<(1,2),3"}},
+	}
+	for _, c := range cases {
+		for _, invalid := range c.invalid {
+			_, err = s.m.Decode(c.oid, pgproto.FormatText, []byte(invalid))
+			s.NotNil(err, invalid)
+		}
+	}
+}
+
+func (s *GeometryTestSuite) Test_Scan() {
+	var p *pgtype.Point
+	s.Nil(s.m.Scan(pgtype.PointOID, pgproto.FormatText, []byte("(1,2)"), &p))
+	s.Equal(&pgtype.Point{X: 1, Y: 2}, p)
+	s.Nil(s.m.Scan(pgtype.PointOID, pgproto.FormatText, nil, &p))
+	s.Nil(p)
+
+	var path pgtype.Path
+	s.Nil(s.m.Scan(pgtype.PathOID, pgproto.FormatBinary, append([]byte{0x01, 0x00, 0x00, 0x00, 0x01}, float8s(1, 2)...), &path))
+	s.Equal(pgtype.Path{Points: []pgtype.Point{{X: 1, Y: 2}}, Closed: true}, path)
+
+	var str string
+	s.Nil(s.m.Scan(pgtype.BoxOID, pgproto.FormatText, []byte("(3,4),(1,2)"), &str))
+	s.Equal("(3,4),(1,2)", str)
+
+	// Box arrays are delimited by semicolons
+	var boxes []pgtype.Box
+	s.Nil(s.m.Scan(pgtype.BoxArrayOID, pgproto.FormatText, []byte("{(3,4),(1,2);(1,1),(0,0)}"), &boxes))
+	s.Equal([]pgtype.Box{
+		{High: pgtype.Point{X: 3, Y: 4}, Low: pgtype.Point{X: 1, Y: 2}},
+		{High: pgtype.Point{X: 1, Y: 1}, Low: pgtype.Point{X: 0, Y: 0}},
+	}, boxes)
+	encoded, err := s.m.Encode(pgtype.BoxArrayOID, pgproto.FormatText, boxes)
+	s.Nil(err)
+	s.Equal("{(3,4),(1,2);(1,1),(0,0)}", string(encoded))
+
+	var points []pgtype.Point
+	s.Nil(s.m.Scan(pgtype.PointArrayOID, pgproto.FormatText, []byte(`{"(1,2)","(3,4)"}`), &points))
+	s.Equal([]pgtype.Point{{X: 1, Y: 2}, {X: 3, Y: 4}}, points)
+}
+
+func (s *GeometryTestSuite) Test_Encode() {
+	cases := []struct {
+		oid      int
+		value    interface{}
+		expected string
+	}{
+		{pgtype.PointOID, &pgtype.Point{X: 1, Y: 2}, "(1,2)"},
+		{pgtype.PointOID, "( 1 , 2 )", "(1,2)"},
+		{pgtype.PolygonOID, []byte("((0,0),(1,1),(1,0))"), "((0,0),(1,1),(1,0))"},
+		{pgtype.PointOID, pgtype.Point{X: math.NaN(), Y: math.Inf(-1)}, "(NaN,-Infinity)"},
+	}
+	for _, c := range cases {
+		encoded, err := s.m.Encode(c.oid, pgproto.FormatText, c.value)
+		s.Nil(err, c.value)
+		s.Equal(c.expected, string(encoded), c.value)
+	}
+
+	for _, invalid := range []interface{}{42, "(1,2", pgtype.Circle{}} {
+		_, err := s.m.Encode(pgtype.PointOID, pgproto.FormatText, invalid)
+		s.NotNil(err, invalid)
+		_, err = s.m.Encode(pgtype.PointOID, pgproto.FormatBinary, invalid)
+		s.NotNil(err, invalid)
+	}
+	s.Equal("<(1,2),0.5>", pgtype.Circle{Center: pgtype.Point{X: 1, Y: 2}, Radius: 0.5}.String())
+}
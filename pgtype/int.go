@@ -0,0 +1,84 @@
+package pgtype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// IntCodec converts int2, int4 and int8 values, of Size 2, 4 and 8 bytes, to and from an int16,
+// int32 or int64
+//
+// Any Go integer is encoded when the value fits in Size bytes.
+type IntCodec struct {
+	Size int
+}
+
+func (c IntCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	i, err := strconv.ParseInt(string(src), 10, c.Size*8)
+	if err != nil {
+		return nil, err
+	}
+	return c.value(i), nil
+}
+
+func (c IntCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	i, err := c.int64(v)
+	if err != nil {
+		return nil, err
+	}
+	return strconv.AppendInt(buf, i, 10), nil
+}
+
+// DecodeBinary decodes a big endian two's complement integer of Size bytes
+func (c IntCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	err := checkSize(src, c.Size)
+	if err != nil {
+		return nil, err
+	}
+	switch c.Size {
+	case 2:
+		return int16(binary.BigEndian.Uint16(src)), nil
+	case 4:
+		return int32(binary.BigEndian.Uint32(src)), nil
+	}
+	return int64(binary.BigEndian.Uint64(src)), nil
+}
+
+func (c IntCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	i, err := c.int64(v)
+	if err != nil {
+		return nil, err
+	}
+	switch c.Size {
+	case 2:
+		return binary.BigEndian.AppendUint16(buf, uint16(i)), nil
+	case 4:
+		return binary.BigEndian.AppendUint32(buf, uint32(i)), nil
+	}
+	return binary.BigEndian.AppendUint64(buf, uint64(i)), nil
+}
+
+// value returns i as the Go type of values of this size
+func (c IntCodec) value(i int64) interface{} {
+	switch c.Size {
+	case 2:
+		return int16(i)
+	case 4:
+		return int32(i)
+	}
+	return i
+}
+
+// int64 converts the Go integer v to an int64, failing when it does not fit in Size bytes
+func (c IntCodec) int64(v interface{}) (int64, error) {
+	i, ok := toInt64(v)
+	if !ok && !isInteger(kindOf(v)) {
+		return 0, fmt.Errorf("cannot encode %T", v)
+	}
+	bits := uint(c.Size * 8)
+	if !ok || i < -1<<(bits-1) || i > 1<<(bits-1)-1 {
+		return 0, fmt.Errorf("%v is out of range for %d byte integers", v, c.Size)
+	}
+	return i, nil
+}
@@ -0,0 +1,430 @@
+package pgtype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Interval is an interval value, its months, days and microseconds are kept apart as their length
+// depends on the date they are added to
+//
+// The infinite intervals of PostgreSQL 17 have every field set to its largest value for infinity,
+// and to its smallest value for -infinity.
+type Interval struct {
+	Months       int32
+	Days         int32
+	Microseconds int64
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// convert allows scanning intervals without months into a time.Duration, days are 24 hours long
+func (i Interval) convert(t reflect.Type) (interface{}, error) {
+	if t != durationType {
+		return nil, nil
+	}
+	if i.Months != 0 {
+		return nil, fmt.Errorf("cannot scan an interval of %d months into %s", i.Months, t)
+	}
+	const maxDays = math.MaxInt64 / int64(24*time.Hour)
+	const maxMicroseconds = math.MaxInt64 / int64(time.Microsecond)
+	if int64(i.Days) > maxDays || int64(i.Days) < -maxDays || i.Microseconds > maxMicroseconds || i.Microseconds < -maxMicroseconds {
+		return nil, fmt.Errorf("interval overflows %s", t)
+	}
+	days := time.Duration(i.Days) * 24 * time.Hour
+	d := days + time.Duration(i.Microseconds)*time.Microsecond
+	if (days > 0 && i.Microseconds > 0 && d < 0) || (days < 0 && i.Microseconds < 0 && d > 0) {
+		return nil, fmt.Errorf("interval overflows %s", t)
+	}
+	return d, nil
+}
+
+// IntervalCodec converts interval values to and from an Interval
+//
+// Intervals and time.Duration values, rounded to microseconds, are encoded.
+type IntervalCodec struct{}
+
+// DecodeText decodes an interval in the IntervalStyle, any of:
+//
+//	postgres           1 year 2 mons -3 days +04:05:06.5
+//	postgres_verbose   @ 1 year 2 mons -3 days 4 hours 5 mins 6.5 secs ago
+//	sql_standard       +1-2 -3 +4:05:06.5
+//	iso_8601           P1Y2M-3DT4H5M6.5S
+func (IntervalCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	s := string(src)
+	switch {
+	case s == "infinity":
+		return Interval{Months: math.MaxInt32, Days: math.MaxInt32, Microseconds: math.MaxInt64}, nil
+	case s == "-infinity":
+		return Interval{Months: math.MinInt32, Days: math.MinInt32, Microseconds: math.MinInt64}, nil
+	case strings.HasPrefix(s, "P"):
+		return parseISOInterval(s)
+	case strings.HasPrefix(s, "@"):
+		return parseVerboseInterval(s)
+	case m.Parameter("IntervalStyle") == "sql_standard":
+		return parseSQLInterval(s)
+	}
+	return parsePostgresInterval(s)
+}
+
+// EncodeText encodes the interval in the ISO 8601 format, which is accepted whatever the IntervalStyle
+func (c IntervalCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	i, err := c.interval(v)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = append(buf, 'P')
+	if years := i.Months / 12; years != 0 {
+		buf = append(strconv.AppendInt(buf, int64(years), 10), 'Y')
+	}
+	if months := i.Months % 12; months != 0 {
+		buf = append(strconv.AppendInt(buf, int64(months), 10), 'M')
+	}
+	if i.Days != 0 {
+		buf = append(strconv.AppendInt(buf, int64(i.Days), 10), 'D')
+	}
+	if i.Microseconds != 0 || (i.Months == 0 && i.Days == 0) {
+		buf = append(buf, 'T')
+		if hours := i.Microseconds / 3600e6; hours != 0 {
+			buf = append(strconv.AppendInt(buf, hours, 10), 'H')
+		}
+		if minutes := i.Microseconds / 60e6 % 60; minutes != 0 {
+			buf = append(strconv.AppendInt(buf, minutes, 10), 'M')
+		}
+		if micro := i.Microseconds % 60e6; micro != 0 || i.Microseconds == 0 {
+			if micro < 0 {
+				buf = append(buf, '-')
+				micro = -micro
+			}
+			buf = strconv.AppendInt(buf, micro/1e6, 10)
+			if micro%1e6 != 0 {
+				buf = append(buf, '.')
+				buf = append(buf, strings.TrimRight(fmt.Sprintf("%06d", micro%1e6), "0")...)
+			}
+			buf = append(buf, 'S')
+		}
+	}
+	return buf, nil
+}
+
+// DecodeBinary decodes the microseconds as an int64, followed by the days and months as int32s
+func (IntervalCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	err := checkSize(src, 16)
+	if err != nil {
+		return nil, err
+	}
+	return Interval{
+		Microseconds: int64(binary.BigEndian.Uint64(src)),
+		Days:         int32(binary.BigEndian.Uint32(src[8:])),
+		Months:       int32(binary.BigEndian.Uint32(src[12:])),
+	}, nil
+}
+
+func (c IntervalCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	i, err := c.interval(v)
+	if err != nil {
+		return nil, err
+	}
+	buf = binary.BigEndian.AppendUint64(buf, uint64(i.Microseconds))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(i.Days))
+	return binary.BigEndian.AppendUint32(buf, uint32(i.Months)), nil
+}
+
+// interval converts v to an Interval
+func (IntervalCodec) interval(v interface{}) (Interval, error) {
+	switch v := v.(type) {
+	case Interval:
+		return v, nil
+	case time.Duration:
+		return Interval{Microseconds: int64(v.Round(time.Microsecond) / time.Microsecond)}, nil
+	}
+	return Interval{}, fmt.Errorf("cannot encode %T", v)
+}
+
+// intervalUnits are the units of the postgres and postgres_verbose styles, as months, days or
+// microseconds
+var intervalUnits = map[string]Interval{
+	"year":  {Months: 12},
+	"years": {Months: 12},
+	"mon":   {Months: 1},
+	"mons":  {Months: 1},
+	"day":   {Days: 1},
+	"days":  {Days: 1},
+	"hour":  {Microseconds: 3600e6},
+	"hours": {Microseconds: 3600e6},
+	"min":   {Microseconds: 60e6},
+	"mins":  {Microseconds: 60e6},
+	"sec":   {Microseconds: 1e6},
+	"secs":  {Microseconds: 1e6},
+}
+
+// parsePostgresInterval parses the postgres style, numbers of years, mons and days followed by a
+// signed time
+func parsePostgresInterval(src string) (Interval, error) {
+	var i Interval
+	fields := strings.Fields(src)
+	for n := 0; n < len(fields); n++ {
+		if strings.Contains(fields[n], ":") {
+			micro, err := parseIntervalTime(fields[n])
+			if err != nil {
+				return i, err
+			}
+			i.Microseconds += micro
+			continue
+		}
+
+		if n+1 == len(fields) {
+			return i, fmt.Errorf("invalid interval %q", src)
+		}
+		err := i.add(fields[n], fields[n+1])
+		if err != nil {
+			return i, fmt.Errorf("invalid interval %q", src)
+		}
+		n++
+	}
+	return i, nil
+}
+
+// parseVerboseInterval parses the postgres_verbose style, an @ followed by numbers of units, the
+// whole interval is negated when followed by ago
+func parseVerboseInterval(src string) (Interval, error) {
+	var i Interval
+	fields := strings.Fields(strings.TrimPrefix(src, "@"))
+	ago := len(fields) > 0 && fields[len(fields)-1] == "ago"
+	if ago {
+		fields = fields[:len(fields)-1]
+	}
+	// The zero interval is @ 0
+	if len(fields) == 1 && fields[0] == "0" {
+		return i, nil
+	}
+	if len(fields)%2 != 0 {
+		return i, fmt.Errorf("invalid interval %q", src)
+	}
+	for n := 0; n < len(fields); n += 2 {
+		err := i.add(fields[n], fields[n+1])
+		if err != nil {
+			return i, fmt.Errorf("invalid interval %q", src)
+		}
+	}
+	if ago {
+		i = Interval{Months: -i.Months, Days: -i.Days, Microseconds: -i.Microseconds}
+	}
+	return i, nil
+}
+
+// add adds the number of the unit to i, seconds may have a fraction
+func (i *Interval) add(number, unit string) error {
+	u, ok := intervalUnits[unit]
+	if !ok {
+		return fmt.Errorf("unknown unit %q", unit)
+	}
+	if u.Microseconds != 0 {
+		micro, err := parseMicroseconds(number, u.Microseconds)
+		if err != nil {
+			return err
+		}
+		i.Microseconds += micro
+		return nil
+	}
+
+	n, err := strconv.ParseInt(number, 10, 32)
+	if err != nil {
+		return err
+	}
+	i.Months += int32(n) * u.Months
+	i.Days += int32(n) * u.Days
+	return nil
+}
+
+// parseSQLInterval parses the sql_standard style: years-months, days and a time, either each with
+// its own sign or all with the sign of the first
+func parseSQLInterval(src string) (Interval, error) {
+	var i Interval
+	fields := strings.Fields(src)
+	if len(fields) == 0 {
+		return i, fmt.Errorf("invalid interval %q", src)
+	}
+
+	// Without a sign on the other fields, the sign of the first field applies to every field
+	negative := false
+	if strings.HasPrefix(fields[0], "-") {
+		negative = true
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "-") || strings.HasPrefix(f, "+") {
+				negative = false
+			}
+		}
+		if negative {
+			fields[0] = fields[0][1:]
+		}
+	}
+
+	for _, f := range fields {
+		sign, unsigned := int64(1), f
+		if strings.HasPrefix(f, "-") || strings.HasPrefix(f, "+") {
+			if f[0] == '-' {
+				sign = -1
+			}
+			unsigned = f[1:]
+		}
+
+		switch {
+		case strings.Contains(unsigned, ":"):
+			micro, err := parseIntervalTime(unsigned)
+			if err != nil {
+				return i, err
+			}
+			i.Microseconds += sign * micro
+		case strings.Contains(unsigned, "-"):
+			years, months, _ := strings.Cut(unsigned, "-")
+			y, err1 := strconv.ParseInt(years, 10, 32)
+			m, err2 := strconv.ParseInt(months, 10, 32)
+			if err1 != nil || err2 != nil {
+				return i, fmt.Errorf("invalid interval %q", src)
+			}
+			i.Months += int32(sign * (y*12 + m))
+		default:
+			d, err := strconv.ParseInt(unsigned, 10, 32)
+			if err != nil {
+				return i, fmt.Errorf("invalid interval %q", src)
+			}
+			i.Days += int32(sign * d)
+		}
+	}
+
+	if negative {
+		i = Interval{Months: -i.Months, Days: -i.Days, Microseconds: -i.Microseconds}
+	}
+	return i, nil
+}
+
+// parseISOInterval parses the iso_8601 style, P followed by numbers of years, months, weeks and
+// days, then T followed by numbers of hours, minutes and seconds
+func parseISOInterval(src string) (Interval, error) {
+	var i Interval
+	s := strings.TrimPrefix(src, "P")
+	inTime := false
+	for s != "" {
+		if s[0] == 'T' && !inTime {
+			inTime = true
+			s = s[1:]
+			continue
+		}
+
+		n := strings.IndexAny(s, "YMWDHS")
+		if n <= 0 {
+			return i, fmt.Errorf("invalid interval %q", src)
+		}
+		number, designator := s[:n], s[n]
+		s = s[n+1:]
+
+		var err error
+		var v int64
+		switch {
+		case !inTime && designator == 'Y':
+			v, err = strconv.ParseInt(number, 10, 32)
+			i.Months += int32(v * 12)
+		case !inTime && designator == 'M':
+			v, err = strconv.ParseInt(number, 10, 32)
+			i.Months += int32(v)
+		case !inTime && designator == 'W':
+			v, err = strconv.ParseInt(number, 10, 32)
+			i.Days += int32(v * 7)
+		case !inTime && designator == 'D':
+			v, err = strconv.ParseInt(number, 10, 32)
+			i.Days += int32(v)
+		case inTime && designator == 'H':
+			v, err = parseMicroseconds(number, 3600e6)
+			i.Microseconds += v
+		case inTime && designator == 'M':
+			v, err = parseMicroseconds(number, 60e6)
+			i.Microseconds += v
+		case inTime && designator == 'S':
+			v, err = parseMicroseconds(number, 1e6)
+			i.Microseconds += v
+		default:
+			err = fmt.Errorf("unexpected %c", designator)
+		}
+		if err != nil {
+			return i, fmt.Errorf("invalid interval %q", src)
+		}
+	}
+	return i, nil
+}
+
+// parseIntervalTime parses a signed time of an interval, [+-]H:MM:SS[.ffffff], the hours are not
+// limited to a day
+func parseIntervalTime(src string) (int64, error) {
+	sign, s := int64(1), src
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid interval time %q", src)
+	}
+	hours, err1 := strconv.ParseInt(parts[0], 10, 64)
+	minutes, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil || hours < 0 || minutes < 0 || minutes > 59 {
+		return 0, fmt.Errorf("invalid interval time %q", src)
+	}
+	var micro int64
+	if len(parts) == 3 {
+		var err error
+		micro, err = parseMicroseconds(parts[2], 1e6)
+		if err != nil || micro < 0 || micro >= 60e6 {
+			return 0, fmt.Errorf("invalid interval time %q", src)
+		}
+	}
+	return sign * (hours*3600e6 + minutes*60e6 + micro), nil
+}
+
+// parseMicroseconds parses a signed decimal number of units of unit microseconds, e.g. 6.5 seconds,
+// as microseconds
+func parseMicroseconds(number string, unit int64) (int64, error) {
+	sign, s := int64(1), number
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" && fraction == "" {
+		return 0, fmt.Errorf("invalid number %q", number)
+	}
+	w := int64(0)
+	if whole != "" {
+		var err error
+		w, err = strconv.ParseInt(whole, 10, 64)
+		if err != nil || w < 0 {
+			return 0, fmt.Errorf("invalid number %q", number)
+		}
+	}
+	if w > math.MaxInt64/unit {
+		return 0, fmt.Errorf("%s is out of range", number)
+	}
+	micro := w * unit
+
+	// The fraction is kept to the microsecond
+	for n, d := 0, unit/10; n < len(fraction); n, d = n+1, d/10 {
+		c := fraction[n]
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("invalid number %q", number)
+		}
+		micro += int64(c-'0') * d
+	}
+	return sign * micro, nil
+}
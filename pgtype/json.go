@@ -0,0 +1,115 @@
+package pgtype
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// jsonbVersion is the version of the binary representation of jsonb and jsonpath values, the byte
+// preceding their text
+const jsonbVersion = 1
+
+// JSONCodec converts json values, and jsonb values WithVersion, to and from a json.RawMessage
+//
+// Values are scanned into byte slices and strings as their text, and unmarshaled by encoding/json
+// into any other type, e.g. a map[string]any or a struct. Byte slices, strings and json.RawMessage
+// are encoded as the JSON text they hold, any other value is marshaled by encoding/json.
+type JSONCodec struct {
+	// WithVersion is set for jsonb, whose binary representation is the text preceded by a version
+	// byte
+	WithVersion bool
+}
+
+func (JSONCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	return json.RawMessage(append([]byte{}, src...)), nil
+}
+
+func (JSONCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	if s, ok := toString(v); ok {
+		if !json.Valid([]byte(s)) {
+			return nil, fmt.Errorf("invalid JSON %q", s)
+		}
+		return append(buf, s...), nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, b...), nil
+}
+
+// DecodeBinary decodes the text of the value, preceded WithVersion by the version byte 1
+func (c JSONCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	if c.WithVersion {
+		text, err := versioned(src)
+		if err != nil {
+			return nil, err
+		}
+		src = text
+	}
+	return c.DecodeText(m, src)
+}
+
+func (c JSONCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	if c.WithVersion {
+		buf = append(buf, jsonbVersion)
+	}
+	return c.EncodeText(m, buf, v)
+}
+
+// JSONPathCodec converts jsonpath values to and from a Go string
+//
+// Strings and byte slices are encoded.
+type JSONPathCodec struct{}
+
+func (JSONPathCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	return string(src), nil
+}
+
+func (JSONPathCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	return TextCodec{}.EncodeText(m, buf, v)
+}
+
+// DecodeBinary decodes the text of the path preceded by the version byte 1
+func (c JSONPathCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	text, err := versioned(src)
+	if err != nil {
+		return nil, err
+	}
+	return c.DecodeText(m, text)
+}
+
+func (c JSONPathCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	return c.EncodeText(m, append(buf, jsonbVersion), v)
+}
+
+// versioned returns the text following the version byte of a binary jsonb or jsonpath value
+func versioned(src []byte) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, errors.New("missing version byte")
+	}
+	if src[0] != jsonbVersion {
+		return nil, fmt.Errorf("unsupported version %d", src[0])
+	}
+	return src[1:], nil
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unmarshalsJSON returns whether JSON values are unmarshaled into values of type t rather than stored
+// as is, which they are in interfaces, strings and byte slices unless they implement json.Unmarshaler
+func unmarshalsJSON(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Interface, reflect.String:
+		return false
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	}
+	return true
+}
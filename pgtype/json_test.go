@@ -0,0 +1,146 @@
+package pgtype_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtype"
+	"github.com/stretchr/testify/suite"
+)
+
+type JSONTestSuite struct {
+	suite.Suite
+	m *pgtype.TypeMap
+}
+
+func TestJSONTestSuite(t *testing.T) {
+	suite.Run(t, new(JSONTestSuite))
+}
+
+func (s *JSONTestSuite) SetupTest() {
+	s.m = pgtype.NewTypeMap()
+}
+
+func (s *JSONTestSuite) Test_Formats() {
+	text := []byte(`{"a": 1, "b": [true, null]}`)
+	jsonb := append([]byte{0x01}, text...)
+
+	v, err := s.m.Decode(pgtype.JSONOID, pgproto.FormatText, text)
+	s.Nil(err)
+	s.Equal(json.RawMessage(text), v)
+	v, err = s.m.Decode(pgtype.JSONOID, pgproto.FormatBinary, text)
+	s.Nil(err)
+	s.Equal(json.RawMessage(text), v)
+	v, err = s.m.Decode(pgtype.JSONBOID, pgproto.FormatText, text)
+	s.Nil(err)
+	s.Equal(json.RawMessage(text), v)
+	v, err = s.m.Decode(pgtype.JSONBOID, pgproto.FormatBinary, jsonb)
+	s.Nil(err)
+	s.Equal(json.RawMessage(text), v)
+
+	encoded, err := s.m.Encode(pgtype.JSONBOID, pgproto.FormatBinary, json.RawMessage(text))
+	s.Nil(err)
+	s.Equal(jsonb, encoded)
+	encoded, err = s.m.Encode(pgtype.JSONOID, pgproto.FormatBinary, json.RawMessage(text))
+	s.Nil(err)
+	s.Equal(text, encoded)
+
+	// Only the first version of the binary representation exists
+	_, err = s.m.Decode(pgtype.JSONBOID, pgproto.FormatBinary, []byte{0x02, '{', '}'})
+	s.NotNil(err)
+	_, err = s.m.Decode(pgtype.JSONBOID, pgproto.FormatBinary, []byte{})
+	s.NotNil(err)
+}
+
+func (s *JSONTestSuite) Test_Scan() {
+	src := []byte(`{"name": "pgproto", "tags": ["wire", "protocol"]}`)
+
+	var m map[string]interface{}
+	s.Nil(s.m.Scan(pgtype.JSONBOID, pgproto.FormatText, src, &m))
+	s.Equal(map[string]interface{}{"name": "pgproto", "tags": []interface{}{"wire", "protocol"}}, m)
+
+	type project struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	var p *project
+	s.Nil(s.m.Scan(pgtype.JSONOID, pgproto.FormatText, src, &p))
+	s.Equal(&project{Name: "pgproto", Tags: []string{"wire", "protocol"}}, p)
+
+	var str string
+	s.Nil(s.m.Scan(pgtype.JSONBOID, pgproto.FormatBinary, append([]byte{0x01}, src...), &str))
+	s.Equal(string(src), str)
+
+	var b []byte
+	s.Nil(s.m.Scan(pgtype.JSONOID, pgproto.FormatText, src, &b))
+	s.Equal(src, b)
+
+	var raw json.RawMessage
+	s.Nil(s.m.Scan(pgtype.JSONOID, pgproto.FormatText, src, &raw))
+	s.Equal(json.RawMessage(src), raw)
+
+	var v interface{}
+	s.Nil(s.m.Scan(pgtype.JSONOID, pgproto.FormatText, src, &v))
+	s.Equal(json.RawMessage(src), v)
+
+	var i int
+	s.NotNil(s.m.Scan(pgtype.JSONOID, pgproto.FormatText, src, &i))
+	s.Nil(s.m.Scan(pgtype.JSONOID, pgproto.FormatText, []byte("42"), &i))
+	s.Equal(42, i)
+
+	s.Nil(s.m.Scan(pgtype.JSONOID, pgproto.FormatText, nil, &m))
+	s.Nil(m)
+
+	var messages []json.RawMessage
+	s.Nil(s.m.Scan(pgtype.JSONBArrayOID, pgproto.FormatText, []byte(`{"{\"a\": 1}","null"}`), &messages))
+	s.Equal([]json.RawMessage{json.RawMessage(`{"a": 1}`), json.RawMessage("null")}, messages)
+}
+
+func (s *JSONTestSuite) Test_Encode() {
+	cases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{map[string]interface{}{"b": 2, "a": []int{1}}, `{"a":[1],"b":2}`},
+		{struct {
+			Name string `json:"name"`
+		}{"pgproto"}, `{"name":"pgproto"}`},
+		{`[1, "two"]`, `[1, "two"]`},
+		{[]byte(`null`), `null`},
+		{json.RawMessage(`{}`), `{}`},
+		{42, `42`},
+		{true, `true`},
+	}
+	for _, c := range cases {
+		encoded, err := s.m.Encode(pgtype.JSONOID, pgproto.FormatText, c.value)
+		s.Nil(err, c.value)
+		s.Equal(c.expected, string(encoded), c.value)
+	}
+
+	_, err := s.m.Encode(pgtype.JSONOID, pgproto.FormatText, "not json")
+	s.NotNil(err)
+	_, err = s.m.Encode(pgtype.JSONOID, pgproto.FormatText, make(chan int))
+	s.NotNil(err)
+}
+
+func (s *JSONTestSuite) Test_JSONPath() {
+	path := `$.tags[*] ? (@ like_regex "^w")`
+
+	v, err := s.m.Decode(pgtype.JSONPathOID, pgproto.FormatText, []byte(path))
+	s.Nil(err)
+	s.Equal(path, v)
+	v, err = s.m.Decode(pgtype.JSONPathOID, pgproto.FormatBinary, append([]byte{0x01}, path...))
+	s.Nil(err)
+	s.Equal(path, v)
+
+	encoded, err := s.m.Encode(pgtype.JSONPathOID, pgproto.FormatBinary, path)
+	s.Nil(err)
+	s.Equal(append([]byte{0x01}, path...), encoded)
+	encoded, err = s.m.Encode(pgtype.JSONPathOID, pgproto.FormatText, path)
+	s.Nil(err)
+	s.Equal([]byte(path), encoded)
+
+	_, err = s.m.Decode(pgtype.JSONPathOID, pgproto.FormatBinary, []byte("$.a"))
+	s.NotNil(err)
+}
@@ -0,0 +1,246 @@
+package pgtype
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+)
+
+// Address families of the binary representation of inet and cidr values
+const (
+	pgAFInet  = 2
+	pgAFInet6 = 3
+)
+
+var addrType = reflect.TypeOf(netip.Addr{})
+
+// singleAddr returns the address of p, an inet value scanned into a netip.Addr, which must be a
+// single address
+func singleAddr(p netip.Prefix) (netip.Addr, error) {
+	if !p.IsSingleIP() {
+		return netip.Addr{}, fmt.Errorf("cannot scan the network %s into %s: %w", p, addrType, errMismatch)
+	}
+	return p.Addr(), nil
+}
+
+// InetCodec converts inet values, and cidr values when CIDR is set, to and from a netip.Prefix
+//
+// The address of inet values is kept as is, host bits included, e.g. 192.168.0.5/24. Values of a
+// single address, e.g. 192.168.0.5/32, are scanned into a netip.Addr. netip.Prefix, netip.Addr, net.IP
+// and net.IPNet values and strings are encoded.
+type InetCodec struct {
+	CIDR bool
+}
+
+// DecodeText decodes an address followed by the number of bits of the network, which inet values
+// without host bits omit, e.g. 192.168.0.5, 192.168.0.0/24 or 2001:db8::/32
+func (c InetCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	p, err := parsePrefix(string(src))
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// EncodeText encodes the address and the number of bits of the network, omitted for inet values of a
+// single address
+func (c InetCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	p, err := c.prefix(v)
+	if err != nil {
+		return nil, err
+	}
+	if !c.CIDR && p.IsSingleIP() {
+		return p.Addr().AppendTo(buf), nil
+	}
+	return p.AppendTo(buf), nil
+}
+
+// DecodeBinary decodes the address family, 2 for IPv4 and 3 for IPv6, the number of bits of the
+// network, whether the value is a cidr, and the length of the address, each a byte, followed by the
+// address
+func (c InetCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	if len(src) < 4 {
+		return nil, fmt.Errorf("expected at least 4 bytes, got %d", len(src))
+	}
+	family, bits, length := src[0], int(src[1]), int(src[3])
+	err := checkSize(src[4:], length)
+	if err != nil {
+		return nil, err
+	}
+	if (family == pgAFInet && length != 4) || (family == pgAFInet6 && length != 16) {
+		return nil, fmt.Errorf("invalid address length %d for the family %d", length, family)
+	} else if family != pgAFInet && family != pgAFInet6 {
+		return nil, fmt.Errorf("invalid address family %d", family)
+	}
+
+	addr, _ := netip.AddrFromSlice(src[4:])
+	if bits > addr.BitLen() {
+		return nil, fmt.Errorf("invalid number of bits %d", bits)
+	}
+	return netip.PrefixFrom(addr, bits), nil
+}
+
+func (c InetCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	p, err := c.prefix(v)
+	if err != nil {
+		return nil, err
+	}
+	family := byte(pgAFInet)
+	if p.Addr().Is6() {
+		family = pgAFInet6
+	}
+	var cidr byte
+	if c.CIDR {
+		cidr = 1
+	}
+	addr := p.Addr().AsSlice()
+	buf = append(buf, family, byte(p.Bits()), cidr, byte(len(addr)))
+	return append(buf, addr...), nil
+}
+
+// prefix converts v to a netip.Prefix
+func (InetCodec) prefix(v interface{}) (netip.Prefix, error) {
+	var p netip.Prefix
+	switch v := v.(type) {
+	case netip.Prefix:
+		p = v
+	case netip.Addr:
+		if v.Zone() != "" {
+			return p, fmt.Errorf("invalid IP address %s, zones are not supported", v)
+		}
+		p = netip.PrefixFrom(v, v.BitLen())
+	case net.IP:
+		addr, ok := netip.AddrFromSlice(v)
+		if !ok {
+			return p, fmt.Errorf("invalid IP address %v", []byte(v))
+		}
+		if v.To4() != nil {
+			addr = addr.Unmap()
+		}
+		p = netip.PrefixFrom(addr, addr.BitLen())
+	case net.IPNet:
+		addr, ok := netip.AddrFromSlice(v.IP)
+		if !ok {
+			return p, fmt.Errorf("invalid IP address %v", []byte(v.IP))
+		}
+		if v.IP.To4() != nil {
+			addr = addr.Unmap()
+		}
+		ones, bits := v.Mask.Size()
+		if bits != addr.BitLen() {
+			return p, fmt.Errorf("invalid network %s", &v)
+		}
+		p = netip.PrefixFrom(addr, ones)
+	default:
+		s, ok := toString(v)
+		if !ok {
+			return p, fmt.Errorf("cannot encode %T", v)
+		}
+		var err error
+		p, err = parsePrefix(s)
+		if err != nil {
+			return p, err
+		}
+	}
+
+	if !p.IsValid() {
+		return p, fmt.Errorf("invalid network %s", p)
+	}
+	return p, nil
+}
+
+// parsePrefix parses an address, followed by the number of bits of the network unless it is a single
+// address
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if addr.Zone() != "" {
+		return netip.Prefix{}, fmt.Errorf("invalid IP address %s, zones are not supported", s)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// MACAddrCodec converts macaddr values, of Size 6 bytes, and macaddr8 values, of Size 8 bytes, to
+// and from a net.HardwareAddr
+//
+// net.HardwareAddr values and strings are encoded. 6 byte addresses are encoded as macaddr8 values by
+// inserting FF:FE in their middle, as PostgreSQL does.
+type MACAddrCodec struct {
+	Size int
+}
+
+// DecodeText decodes the hex digits of the address, in pairs separated by colons, e.g.
+// 08:00:2b:01:02:03, or in any of the other forms PostgreSQL accepts, e.g. 0800.2b01.0203
+func (c MACAddrCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	return c.parse(string(src))
+}
+
+func (c MACAddrCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	addr, err := c.addr(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, addr.String()...), nil
+}
+
+// DecodeBinary decodes the Size bytes of the address
+func (c MACAddrCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	err := checkSize(src, c.Size)
+	if err != nil {
+		return nil, err
+	}
+	return net.HardwareAddr(append([]byte{}, src...)), nil
+}
+
+func (c MACAddrCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	addr, err := c.addr(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, addr...), nil
+}
+
+// parse parses the text of an address, hex digits optionally separated by colons, hyphens or dots
+func (c MACAddrCodec) parse(s string) (net.HardwareAddr, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == ':' || r == '-' || r == '.' {
+			return -1
+		}
+		return r
+	}, s)
+	addr, err := hex.DecodeString(digits)
+	if err != nil || (len(addr) != c.Size && (c.Size != 8 || len(addr) != 6)) {
+		return nil, fmt.Errorf("invalid MAC address %q", s)
+	}
+	return c.eui64(addr), nil
+}
+
+// addr converts v to an address of Size bytes
+func (c MACAddrCodec) addr(v interface{}) (net.HardwareAddr, error) {
+	if addr, ok := v.(net.HardwareAddr); ok {
+		if len(addr) != c.Size && (c.Size != 8 || len(addr) != 6) {
+			return nil, fmt.Errorf("%s is not a %d byte MAC address", addr, c.Size)
+		}
+		return c.eui64(addr), nil
+	}
+	if s, ok := toString(v); ok {
+		return c.parse(s)
+	}
+	return nil, fmt.Errorf("cannot encode %T", v)
+}
+
+// eui64 returns the 8 byte form of a 6 byte address for macaddr8 values, or addr
+func (c MACAddrCodec) eui64(addr net.HardwareAddr) net.HardwareAddr {
+	if c.Size == 8 && len(addr) == 6 {
+		return net.HardwareAddr{addr[0], addr[1], addr[2], 0xff, 0xfe, addr[3], addr[4], addr[5]}
+	}
+	return addr
+}
@@ -0,0 +1,206 @@
+package pgtype_test
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtype"
+	"github.com/stretchr/testify/suite"
+)
+
+type NetTestSuite struct {
+	suite.Suite
+	m *pgtype.TypeMap
+}
+
+func TestNetTestSuite(t *testing.T) {
+	suite.Run(t, new(NetTestSuite))
+}
+
+func (s *NetTestSuite) SetupTest() {
+	s.m = pgtype.NewTypeMap()
+}
+
+func (s *NetTestSuite) Test_Formats() {
+	mac, _ := net.ParseMAC("08:00:2b:01:02:03")
+	mac8, _ := net.ParseMAC("08:00:2b:01:02:03:04:05")
+	cases := []struct {
+		oid    int
+		text   string
+		binary []byte
+		value  interface{}
+	}{
+		{
+			pgtype.InetOID,
+			"192.168.0.5/24",
+			[]byte{0x02, 0x18, 0x00, 0x04, 0xc0, 0xa8, 0x00, 0x05},
+			netip.MustParsePrefix("192.168.0.5/24"),
+		},
+		{
+			pgtype.InetOID,
+			"10.0.0.1",
+			[]byte{0x02, 0x20, 0x00, 0x04, 0x0a, 0x00, 0x00, 0x01},
+			netip.MustParsePrefix("10.0.0.1/32"),
+		},
+		{
+			pgtype.InetOID,
+			"::1",
+			[]byte{0x03, 0x80, 0x00, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+			netip.MustParsePrefix("::1/128"),
+		},
+		{
+			pgtype.CIDROID,
+			"10.0.0.0/8",
+			[]byte{0x02, 0x08, 0x01, 0x04, 0x0a, 0x00, 0x00, 0x00},
+			netip.MustParsePrefix("10.0.0.0/8"),
+		},
+		{
+			pgtype.CIDROID,
+			"10.0.0.1/32",
+			[]byte{0x02, 0x20, 0x01, 0x04, 0x0a, 0x00, 0x00, 0x01},
+			netip.MustParsePrefix("10.0.0.1/32"),
+		},
+		{
+			pgtype.CIDROID,
+			"2001:db8::/32",
+			[]byte{0x03, 0x20, 0x01, 0x10, 0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			netip.MustParsePrefix("2001:db8::/32"),
+		},
+		{
+			pgtype.MACAddrOID,
+			"08:00:2b:01:02:03",
+			[]byte{0x08, 0x00, 0x2b, 0x01, 0x02, 0x03},
+			mac,
+		},
+		{
+			pgtype.MACAddr8OID,
+			"08:00:2b:01:02:03:04:05",
+			[]byte{0x08, 0x00, 0x2b, 0x01, 0x02, 0x03, 0x04, 0x05},
+			mac8,
+		},
+	}
+
+	for _, c := range cases {
+		v, err := s.m.Decode(c.oid, pgproto.FormatText, []byte(c.text))
+		s.Nil(err, c.text)
+		s.Equal(c.value, v, c.text)
+		v, err = s.m.Decode(c.oid, pgproto.FormatBinary, c.binary)
+		s.Nil(err, c.text)
+		s.Equal(c.value, v, c.text)
+
+		encoded, err := s.m.Encode(c.oid, pgproto.FormatText, c.value)
+		s.Nil(err, c.text)
+		s.Equal(c.text, string(encoded))
+		encoded, err = s.m.Encode(c.oid, pgproto.FormatBinary, c.value)
+		s.Nil(err, c.text)
+		s.Equal(c.binary, encoded, c.text)
+	}
+
+	for _, invalid := range [][]byte{
+		{0x02, 0x20, 0x00},
+		{0x02, 0x20, 0x00, 0x04, 0x0a, 0x00, 0x00},
+		{0x02, 0x20, 0x00, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+		{0x04, 0x20, 0x00, 0x04, 0x0a, 0x00, 0x00, 0x01},
+		{0x02, 0x21, 0x00, 0x04, 0x0a, 0x00, 0x00, 0x01},
+	} {
+		_, err := s.m.Decode(pgtype.InetOID, pgproto.FormatBinary, invalid)
+		s.NotNil(err, invalid)
+	}
+	_, err := s.m.Decode(pgtype.MACAddrOID, pgproto.FormatBinary, []byte{0x08, 0x00, 0x2b, 0x01, 0x02})
+	s.NotNil(err)
+}
+
+func (s *NetTestSuite) Test_MACAddr() {
+	// All the forms PostgreSQL accepts are decoded
+	for _, text := range []string{"08:00:2b:01:02:03", "08-00-2b-01-02-03", "08002b:010203", "08002b-010203", "0800.2b01.0203", "0800-2b01-0203", "08002b010203", "08:00:2B:01:02:03"} {
+		v, err := s.m.Decode(pgtype.MACAddrOID, pgproto.FormatText, []byte(text))
+		s.Nil(err, text)
+		s.Equal(net.HardwareAddr{0x08, 0x00, 0x2b, 0x01, 0x02, 0x03}, v, text)
+	}
+	for _, invalid := range []string{"", "08:00:2b:01:02", "08:00:2b:01:02:03:04:05", "08:00:2b:01:02:0g"} {
+		_, err := s.m.Decode(pgtype.MACAddrOID, pgproto.FormatText, []byte(invalid))
+		s.NotNil(err, invalid)
+	}
+
+	// 6 byte addresses are stored as macaddr8 values with FF:FE inserted
+	encoded, err := s.m.Encode(pgtype.MACAddr8OID, pgproto.FormatBinary, net.HardwareAddr{0x08, 0x00, 0x2b, 0x01, 0x02, 0x03})
+	s.Nil(err)
+	s.Equal([]byte{0x08, 0x00, 0x2b, 0xff, 0xfe, 0x01, 0x02, 0x03}, encoded)
+	encoded, err = s.m.Encode(pgtype.MACAddr8OID, pgproto.FormatText, "08:00:2b:01:02:03")
+	s.Nil(err)
+	s.Equal("08:00:2b:ff:fe:01:02:03", string(encoded))
+
+	_, err = s.m.Encode(pgtype.MACAddrOID, pgproto.FormatBinary, net.HardwareAddr{0x08, 0x00, 0x2b, 0xff, 0xfe, 0x01, 0x02, 0x03})
+	s.NotNil(err)
+	_, err = s.m.Encode(pgtype.MACAddrOID, pgproto.FormatText, 42)
+	s.NotNil(err)
+
+	var addrs []net.HardwareAddr
+	s.Nil(s.m.Scan(pgtype.MACAddrArrayOID, pgproto.FormatText, []byte("{08:00:2b:01:02:03,NULL}"), &addrs))
+	s.Equal([]net.HardwareAddr{{0x08, 0x00, 0x2b, 0x01, 0x02, 0x03}, nil}, addrs)
+}
+
+func (s *NetTestSuite) Test_Scan() {
+	var addr netip.Addr
+	s.Nil(s.m.Scan(pgtype.InetOID, pgproto.FormatText, []byte("192.168.0.5"), &addr))
+	s.Equal(netip.MustParseAddr("192.168.0.5"), addr)
+	s.Nil(s.m.Scan(pgtype.InetOID, pgproto.FormatBinary, []byte{0x03, 0x80, 0x00, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}, &addr))
+	s.Equal(netip.MustParseAddr("::1"), addr)
+
+	// Networks are not single addresses
+	s.NotNil(s.m.Scan(pgtype.InetOID, pgproto.FormatBinary, []byte{0x02, 0x18, 0x00, 0x04, 0xc0, 0xa8, 0x00, 0x05}, &addr))
+
+	var p *netip.Prefix
+	s.Nil(s.m.Scan(pgtype.CIDROID, pgproto.FormatText, []byte("10.0.0.0/8"), &p))
+	s.Equal(netip.MustParsePrefix("10.0.0.0/8"), *p)
+	s.Nil(s.m.Scan(pgtype.CIDROID, pgproto.FormatText, nil, &p))
+	s.Nil(p)
+
+	var str string
+	s.Nil(s.m.Scan(pgtype.InetOID, pgproto.FormatBinary, []byte{0x02, 0x18, 0x00, 0x04, 0xc0, 0xa8, 0x00, 0x05}, &str))
+	s.Equal("192.168.0.5/24", str)
+
+	var addrs []netip.Addr
+	s.Nil(s.m.Scan(pgtype.InetArrayOID, pgproto.FormatText, []byte("{10.0.0.1,::1}"), &addrs))
+	s.Equal([]netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1")}, addrs)
+}
+
+func (s *NetTestSuite) Test_Encode() {
+	_, network, _ := net.ParseCIDR("10.1.0.0/16")
+	cases := []struct {
+		oid      int
+		value    interface{}
+		expected string
+	}{
+		{pgtype.InetOID, netip.MustParseAddr("10.0.0.1"), "10.0.0.1"},
+		{pgtype.CIDROID, netip.MustParseAddr("10.0.0.1"), "10.0.0.1/32"},
+		{pgtype.InetOID, net.ParseIP("10.0.0.1"), "10.0.0.1"},
+		{pgtype.InetOID, net.ParseIP("2001:db8::1"), "2001:db8::1"},
+		{pgtype.CIDROID, network, "10.1.0.0/16"},
+		{pgtype.InetOID, "192.168.0.5/24", "192.168.0.5/24"},
+		{pgtype.InetOID, []byte("::ffff:10.0.0.1"), "::ffff:10.0.0.1"},
+	}
+	for _, c := range cases {
+		encoded, err := s.m.Encode(c.oid, pgproto.FormatText, c.value)
+		s.Nil(err, c.value)
+		s.Equal(c.expected, string(encoded), c.value)
+
+		// The binary representation holds the same value
+		binary, err := s.m.Encode(c.oid, pgproto.FormatBinary, c.value)
+		s.Nil(err, c.value)
+		fromBinary, err := s.m.Decode(c.oid, pgproto.FormatBinary, binary)
+		s.Nil(err, c.value)
+		fromText, err := s.m.Decode(c.oid, pgproto.FormatText, encoded)
+		s.Nil(err, c.value)
+		s.Equal(fromText, fromBinary, c.value)
+	}
+
+	for _, invalid := range []interface{}{42, "10.0.0.1/33", "fe80::1%eth0", "host", netip.Addr{}, net.IP{0x0a}} {
+		_, err := s.m.Encode(pgtype.InetOID, pgproto.FormatText, invalid)
+		s.NotNil(err, invalid)
+		_, err = s.m.Encode(pgtype.InetOID, pgproto.FormatBinary, invalid)
+		s.NotNil(err, invalid)
+	}
+}
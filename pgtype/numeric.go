@@ -0,0 +1,482 @@
+package pgtype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/c653labs/pgproto"
+)
+
+// Limits of the numeric type, the digits before and after the decimal point
+const (
+	numericMaxDigits = 131072
+	numericMaxScale  = 16383
+)
+
+// Signs of the binary representation of numeric values
+const (
+	numericPositive    = 0x0000
+	numericNegative    = 0x4000
+	numericNaN         = 0xC000
+	numericPositiveInf = 0xD000
+	numericNegativeInf = 0xF000
+)
+
+// Numeric is a numeric value, the decimal number Int × 10^-Scale, NaN, or infinite when Infinity
+// is set
+//
+// Scale is the number of digits after the decimal point, which PostgreSQL keeps, e.g. 1.50 has an Int
+// of 150 and a Scale of 2. A nil Int is zero.
+type Numeric struct {
+	Int      *big.Int
+	Scale    int32
+	NaN      bool
+	Infinity InfinityModifier
+}
+
+// ParseNumeric parses a decimal number, optionally with an exponent, e.g. -12.50 or 1.5e-3, or NaN,
+// Infinity or -Infinity
+func ParseNumeric(s string) (Numeric, error) {
+	t := strings.TrimSpace(s)
+	switch strings.ToLower(t) {
+	case "nan":
+		return Numeric{NaN: true}, nil
+	case "infinity", "+infinity", "inf", "+inf":
+		return Numeric{Infinity: Infinity}, nil
+	case "-infinity", "-inf":
+		return Numeric{Infinity: NegativeInfinity}, nil
+	}
+
+	mantissa, exponent := t, 0
+	if i := strings.IndexAny(t, "eE"); i >= 0 {
+		e, err := strconv.Atoi(t[i+1:])
+		if err != nil || e > numericMaxDigits || e < -numericMaxDigits-numericMaxScale {
+			return Numeric{}, fmt.Errorf("invalid numeric %q", s)
+		}
+		mantissa, exponent = t[:i], e
+	}
+	negative := strings.HasPrefix(mantissa, "-")
+	if negative || strings.HasPrefix(mantissa, "+") {
+		mantissa = mantissa[1:]
+	}
+
+	integer, fraction, _ := strings.Cut(mantissa, ".")
+	digits := integer + fraction
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return Numeric{}, fmt.Errorf("invalid numeric %q", s)
+	}
+	i, _ := new(big.Int).SetString(digits, 10)
+	scale := len(fraction) - exponent
+	if scale < 0 {
+		i.Mul(i, pow10(-scale))
+		scale = 0
+	}
+	if scale > numericMaxScale || len(i.String())-scale > numericMaxDigits {
+		return Numeric{}, fmt.Errorf("numeric %q is out of range", s)
+	}
+	if negative {
+		i.Neg(i)
+	}
+	return Numeric{Int: i, Scale: int32(scale)}, nil
+}
+
+// String returns the decimal representation of n with its Scale digits after the decimal point
+func (n Numeric) String() string {
+	switch {
+	case n.NaN:
+		return "NaN"
+	case n.Infinity == Infinity:
+		return "Infinity"
+	case n.Infinity == NegativeInfinity:
+		return "-Infinity"
+	}
+
+	abs, scale := n.digits()
+	if len(abs) <= scale {
+		abs = strings.Repeat("0", scale-len(abs)+1) + abs
+	}
+	s := abs
+	if scale > 0 {
+		s = abs[:len(abs)-scale] + "." + abs[len(abs)-scale:]
+	}
+	if n.Int != nil && n.Int.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// MarshalText implements encoding.TextMarshaler, encoding n like String
+func (n Numeric) MarshalText() ([]byte, error) {
+	return []byte(n.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, decoding the representations ParseNumeric accepts
+func (n *Numeric) UnmarshalText(text []byte) error {
+	v, err := ParseNumeric(string(text))
+	if err != nil {
+		return err
+	}
+	*n = v
+	return nil
+}
+
+// Rat returns the exact value of n, it fails for NaN and infinite values
+func (n Numeric) Rat() (*big.Rat, error) {
+	if n.NaN || n.Infinity != 0 {
+		return nil, fmt.Errorf("%s is not a rational number", n)
+	}
+	r := new(big.Rat)
+	if n.Int != nil {
+		r.SetInt(n.Int)
+	}
+	if n.Scale > 0 {
+		r.Quo(r, new(big.Rat).SetInt(pow10(int(n.Scale))))
+	} else if n.Scale < 0 {
+		r.Mul(r, new(big.Rat).SetInt(pow10(int(-n.Scale))))
+	}
+	return r, nil
+}
+
+// Float returns n as a big.Float of at least 64 bits of precision, and enough to represent Int
+// exactly, whose Acc method reports whether the value was rounded. It fails for NaN.
+func (n Numeric) Float() (*big.Float, error) {
+	if n.NaN {
+		return nil, errors.New("NaN is not representable by a big.Float")
+	}
+	if n.Infinity != 0 {
+		return new(big.Float).SetInf(n.Infinity == NegativeInfinity), nil
+	}
+	r, err := n.Rat()
+	if err != nil {
+		return nil, err
+	}
+	prec := uint(64)
+	if n.Int != nil && uint(n.Int.BitLen()) > prec {
+		prec = uint(n.Int.BitLen())
+	}
+	return new(big.Float).SetPrec(prec).SetRat(r), nil
+}
+
+// Float64 returns the float64 nearest to n, and whether it is exact. Values beyond the range of a
+// float64 are infinite, and NaN and infinite values are exact.
+func (n Numeric) Float64() (f float64, exact bool) {
+	switch {
+	case n.NaN:
+		return math.NaN(), true
+	case n.Infinity != 0:
+		return math.Inf(int(n.Infinity)), true
+	}
+	r, _ := n.Rat()
+	return r.Float64()
+}
+
+// digits returns the decimal digits of the absolute value of n and its scale, made non negative
+func (n Numeric) digits() (string, int) {
+	abs := "0"
+	if n.Int != nil {
+		abs = new(big.Int).Abs(n.Int).String()
+	}
+	scale := int(n.Scale)
+	if scale < 0 {
+		if abs != "0" {
+			abs += strings.Repeat("0", -scale)
+		}
+		scale = 0
+	}
+	return abs, scale
+}
+
+// integer returns n as an integer, failing unless its value is one
+func (n Numeric) integer(t reflect.Type) (*big.Int, error) {
+	r, err := n.Rat()
+	if err != nil {
+		return nil, fmt.Errorf("cannot scan %s into %s", n, t)
+	}
+	if !r.IsInt() {
+		return nil, fmt.Errorf("%s can not be represented exactly by %s", n, t)
+	}
+	return r.Num(), nil
+}
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+)
+
+// convert allows scanning numeric values into a big.Int, big.Rat, big.Float, integers when they are
+// integers, and floats, rounded to the nearest float unless they overflow
+func (n Numeric) convert(t reflect.Type) (interface{}, error) {
+	switch t {
+	case bigIntType:
+		i, err := n.integer(t)
+		if err != nil {
+			return nil, err
+		}
+		return *i, nil
+	case bigRatType:
+		r, err := n.Rat()
+		if err != nil {
+			return nil, fmt.Errorf("cannot scan %s into %s", n, t)
+		}
+		return *r, nil
+	case bigFloatType:
+		f, err := n.Float()
+		if err != nil {
+			return nil, fmt.Errorf("cannot scan %s into %s", n, t)
+		}
+		return *f, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, err := n.integer(t)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case i.IsInt64():
+			return i.Int64(), nil
+		case i.IsUint64():
+			return i.Uint64(), nil
+		}
+		return nil, fmt.Errorf("%s overflows %s", n, t)
+
+	case reflect.Float32, reflect.Float64:
+		f, _ := n.Float64()
+		if t.Kind() == reflect.Float32 {
+			if math.IsInf(float64(float32(f)), 0) && n.Infinity == 0 {
+				return nil, fmt.Errorf("%s overflows %s", n, t)
+			}
+			return float32(f), nil
+		}
+		if math.IsInf(f, 0) && n.Infinity == 0 {
+			return nil, fmt.Errorf("%s overflows %s", n, t)
+		}
+		return f, nil
+	}
+	return nil, nil
+}
+
+// NumericTypeModifier returns the precision and scale declared for the numeric column described by
+// f, e.g. 10 and 2 for numeric(10,2), ok is false when it has none
+func NumericTypeModifier(f pgproto.RowField) (precision, scale int, ok bool) {
+	// The modifier is ((precision << 16) | scale) + VARHDRSZ, the scale being an 11 bit signed integer
+	// since PostgreSQL 15, and -1 without one
+	if f.TypeOID != NumericOID || f.TypeModifier < 4 {
+		return 0, 0, false
+	}
+	typmod := f.TypeModifier - 4
+	return (typmod >> 16) & 0xffff, ((typmod & 0x7ff) ^ 0x400) - 0x400, true
+}
+
+// NumericCodec converts numeric values to and from a Numeric
+//
+// Numeric, big.Int, big.Rat with a finite decimal representation, big.Float, integer and float values
+// are encoded, as well as decimal strings. Floats are encoded as the shortest decimal they are parsed
+// back from.
+type NumericCodec struct{}
+
+func (NumericCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	return ParseNumeric(string(src))
+}
+
+func (c NumericCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	n, err := c.numeric(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, n.String()...), nil
+}
+
+// DecodeBinary decodes the number of digits, the weight of the first digit, the sign and the scale as
+// 16 bit integers, followed by the base 10000 digits as 16 bit integers. The value is the sum of each
+// digit × 10000^weight, the weight decreasing by one from a digit to the next.
+func (NumericCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	if len(src) < 8 {
+		return nil, fmt.Errorf("expected at least 8 bytes, got %d", len(src))
+	}
+	ndigits := int(binary.BigEndian.Uint16(src))
+	weight := int(int16(binary.BigEndian.Uint16(src[2:])))
+	sign := binary.BigEndian.Uint16(src[4:])
+	scale := int(binary.BigEndian.Uint16(src[6:]))
+	err := checkSize(src, 8+2*ndigits)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sign {
+	case numericNaN:
+		return Numeric{NaN: true}, nil
+	case numericPositiveInf:
+		return Numeric{Infinity: Infinity}, nil
+	case numericNegativeInf:
+		return Numeric{Infinity: NegativeInfinity}, nil
+	case numericPositive, numericNegative:
+	default:
+		return nil, fmt.Errorf("invalid sign 0x%04x", sign)
+	}
+
+	i := new(big.Int)
+	base := big.NewInt(10000)
+	digit := new(big.Int)
+	for k := 0; k < ndigits; k++ {
+		d := binary.BigEndian.Uint16(src[8+2*k:])
+		if d > 9999 {
+			return nil, fmt.Errorf("invalid digit %d", d)
+		}
+		i.Mul(i, base).Add(i, digit.SetUint64(uint64(d)))
+	}
+
+	// The digits are an integer of the weight of the last digit, scaled to Scale decimal digits
+	if exp := 4*(weight-ndigits+1) + scale; exp > 0 {
+		i.Mul(i, pow10(exp))
+	} else if exp < 0 {
+		i.Quo(i, pow10(-exp))
+	}
+	if sign == numericNegative {
+		i.Neg(i)
+	}
+	return Numeric{Int: i, Scale: int32(scale)}, nil
+}
+
+func (c NumericCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	n, err := c.numeric(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var sign uint16
+	switch {
+	case n.NaN:
+		sign = numericNaN
+	case n.Infinity == Infinity:
+		sign = numericPositiveInf
+	case n.Infinity == NegativeInfinity:
+		sign = numericNegativeInf
+	case n.Int != nil && n.Int.Sign() < 0:
+		sign = numericNegative
+	}
+	if sign != numericPositive && sign != numericNegative {
+		return append(buf, 0, 0, 0, 0, byte(sign>>8), byte(sign), 0, 0), nil
+	}
+
+	abs, scale := n.digits()
+	if scale > numericMaxScale {
+		return nil, fmt.Errorf("scale %d is out of range", scale)
+	}
+
+	// Align the digits on groups of four from the decimal point
+	fraction := scale + (4-scale%4)%4
+	abs += strings.Repeat("0", fraction-scale)
+	integer := len(abs) - fraction
+	if integer < 0 {
+		abs = strings.Repeat("0", -integer) + abs
+		integer = 0
+	}
+	lead := (4 - integer%4) % 4
+	abs = strings.Repeat("0", lead) + abs
+	weight := (integer+lead)/4 - 1
+
+	digits := make([]uint16, 0, len(abs)/4)
+	for k := 0; k < len(abs); k += 4 {
+		d, _ := strconv.ParseUint(abs[k:k+4], 10, 16)
+		digits = append(digits, uint16(d))
+	}
+	for len(digits) > 0 && digits[0] == 0 {
+		digits = digits[1:]
+		weight--
+	}
+	for len(digits) > 0 && digits[len(digits)-1] == 0 {
+		digits = digits[:len(digits)-1]
+	}
+	if len(digits) == 0 {
+		weight = 0
+	}
+	if len(digits) > math.MaxInt16 || weight > math.MaxInt16 || weight < math.MinInt16 {
+		return nil, fmt.Errorf("%s is out of range", n)
+	}
+
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(digits)))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(int16(weight)))
+	buf = binary.BigEndian.AppendUint16(buf, sign)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(scale))
+	for _, d := range digits {
+		buf = binary.BigEndian.AppendUint16(buf, d)
+	}
+	return buf, nil
+}
+
+// numeric converts v to a Numeric
+func (NumericCodec) numeric(v interface{}) (Numeric, error) {
+	switch v := v.(type) {
+	case Numeric:
+		return v, nil
+	case big.Int:
+		return Numeric{Int: new(big.Int).Set(&v)}, nil
+	case *big.Int:
+		return Numeric{Int: new(big.Int).Set(v)}, nil
+	case big.Rat:
+		return ratNumeric(&v)
+	case *big.Rat:
+		return ratNumeric(v)
+	case big.Float:
+		return floatNumeric(&v)
+	case *big.Float:
+		return floatNumeric(v)
+	}
+
+	if i, ok := toInt64(v); ok {
+		return Numeric{Int: big.NewInt(i)}, nil
+	}
+	if u, ok := toUint64(v); ok {
+		return Numeric{Int: new(big.Int).SetUint64(u)}, nil
+	}
+	if k := kindOf(v); k == reflect.Float32 || k == reflect.Float64 {
+		f := reflect.ValueOf(v).Float()
+		switch {
+		case math.IsNaN(f):
+			return Numeric{NaN: true}, nil
+		case math.IsInf(f, 0):
+			return Numeric{Infinity: InfinityModifier(math.Copysign(1, f))}, nil
+		}
+		bits := 64
+		if k == reflect.Float32 {
+			bits = 32
+		}
+		return ParseNumeric(strconv.FormatFloat(f, 'f', -1, bits))
+	}
+	if s, ok := toString(v); ok {
+		return ParseNumeric(s)
+	}
+	return Numeric{}, fmt.Errorf("cannot encode %T", v)
+}
+
+// ratNumeric converts r to a Numeric, failing when its decimal representation is not finite, e.g. 1/3
+func ratNumeric(r *big.Rat) (Numeric, error) {
+	prec, exact := r.FloatPrec()
+	if !exact {
+		return Numeric{}, fmt.Errorf("%s has no finite decimal representation", r)
+	}
+	return ParseNumeric(r.FloatString(prec))
+}
+
+// floatNumeric converts f to a Numeric, as the shortest decimal it is parsed back from
+func floatNumeric(f *big.Float) (Numeric, error) {
+	if f.IsInf() {
+		return Numeric{Infinity: InfinityModifier(f.Sign())}, nil
+	}
+	return ParseNumeric(f.Text('f', -1))
+}
+
+// pow10 returns 10^n
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,178 @@
+package pgtype_test
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtype"
+	"github.com/stretchr/testify/suite"
+)
+
+type NumericTestSuite struct {
+	suite.Suite
+	m *pgtype.TypeMap
+}
+
+func TestNumericTestSuite(t *testing.T) {
+	suite.Run(t, new(NumericTestSuite))
+}
+
+func (s *NumericTestSuite) SetupTest() {
+	s.m = pgtype.NewTypeMap()
+}
+
+func (s *NumericTestSuite) Test_Formats() {
+	cases := []struct {
+		text   string
+		binary []byte
+	}{
+		{"12345.678", []byte{0x00, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, 0x03, 0x00, 0x01, 0x09, 0x29, 0x1a, 0x7c}},
+		{"1.50", []byte{0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x01, 0x13, 0x88}},
+		{"-0.0001", []byte{0x00, 0x01, 0xff, 0xff, 0x40, 0x00, 0x00, 0x04, 0x00, 0x01}},
+		{"100000000000000000000", []byte{0x00, 0x01, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}},
+		{"0", []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"0.00", []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02}},
+		{"NaN", []byte{0x00, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00}},
+		{"Infinity", []byte{0x00, 0x00, 0x00, 0x00, 0xd0, 0x00, 0x00, 0x00}},
+		{"-Infinity", []byte{0x00, 0x00, 0x00, 0x00, 0xf0, 0x00, 0x00, 0x00}},
+	}
+
+	for _, c := range cases {
+		text, err := s.m.Decode(pgtype.NumericOID, pgproto.FormatText, []byte(c.text))
+		s.Require().Nil(err, c.text)
+		binary, err := s.m.Decode(pgtype.NumericOID, pgproto.FormatBinary, c.binary)
+		s.Require().Nil(err, c.text)
+		s.Equal(c.text, text.(pgtype.Numeric).String())
+		s.Equal(c.text, binary.(pgtype.Numeric).String())
+
+		encoded, err := s.m.Encode(pgtype.NumericOID, pgproto.FormatText, text)
+		s.Nil(err, c.text)
+		s.Equal(c.text, string(encoded))
+		encoded, err = s.m.Encode(pgtype.NumericOID, pgproto.FormatBinary, text)
+		s.Nil(err, c.text)
+		s.Equal(c.binary, encoded, c.text)
+	}
+
+	n, err := pgtype.ParseNumeric("-1.5e-3")
+	s.Nil(err)
+	s.Equal("-0.0015", n.String())
+	n, err = pgtype.ParseNumeric("25E2")
+	s.Nil(err)
+	s.Equal("2500", n.String())
+	for _, invalid := range []string{"", "-", ".", "1.2.3", "--1", "1e", "0x10", "1e1000000"} {
+		_, err = pgtype.ParseNumeric(invalid)
+		s.NotNil(err, invalid)
+	}
+}
+
+func (s *NumericTestSuite) Test_Scan() {
+	src := []byte("-1234.5000")
+
+	var r big.Rat
+	s.Nil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, src, &r))
+	s.Equal("-2469/2", r.String())
+
+	var f *big.Float
+	s.Nil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, src, &f))
+	f64, _ := f.Float64()
+	s.Equal(-1234.5, f64)
+
+	var str string
+	s.Nil(s.m.Scan(pgtype.NumericOID, pgproto.FormatBinary, []byte{0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x01, 0x13, 0x88}, &str))
+	s.Equal("1.50", str)
+
+	// Floats are rounded, their accuracy is reported by Float64
+	s.Nil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, []byte("0.1"), &f64))
+	s.Equal(0.1, f64)
+	n, err := pgtype.ParseNumeric("0.1")
+	s.Nil(err)
+	_, exact := n.Float64()
+	s.False(exact)
+	n, err = pgtype.ParseNumeric("0.5")
+	s.Nil(err)
+	_, exact = n.Float64()
+	s.True(exact)
+	s.NotNil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, []byte("1e400"), &f64))
+	var f32 float32
+	s.NotNil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, []byte("1e39"), &f32))
+	s.Nil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, []byte("-Infinity"), &f64))
+	s.True(math.IsInf(f64, -1))
+	s.Nil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, []byte("NaN"), &f64))
+	s.True(math.IsNaN(f64))
+
+	// Integers are scanned when the value is one and fits
+	var i int16
+	s.Nil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, []byte("1200.00"), &i))
+	s.Equal(int16(1200), i)
+	s.NotNil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, []byte("1200.5"), &i))
+	s.NotNil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, []byte("40000"), &i))
+	var u uint64
+	s.Nil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, []byte("18446744073709551615"), &u))
+	s.Equal(uint64(math.MaxUint64), u)
+	s.NotNil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, []byte("-1"), &u))
+	var bi big.Int
+	s.Nil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, []byte("123456789012345678901234567890"), &bi))
+	s.Equal("123456789012345678901234567890", bi.String())
+
+	s.NotNil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, []byte("NaN"), &r))
+	s.NotNil(s.m.Scan(pgtype.NumericOID, pgproto.FormatText, []byte("Infinity"), &i))
+}
+
+func (s *NumericTestSuite) Test_Encode() {
+	cases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{big.NewRat(-1, 8), "-0.125"},
+		{*big.NewRat(3, 1), "3"},
+		{new(big.Int).Lsh(big.NewInt(1), 70), "1180591620717411303424"},
+		{big.NewFloat(2.5), "2.5"},
+		{new(big.Float).SetInf(true), "-Infinity"},
+		{0.1, "0.1"},
+		{float32(0.1), "0.1"},
+		{math.NaN(), "NaN"},
+		{math.Inf(1), "Infinity"},
+		{uint64(math.MaxUint64), "18446744073709551615"},
+		{-42, "-42"},
+		{"  -12.50 ", "-12.50"},
+		{pgtype.Numeric{Int: big.NewInt(15), Scale: -2}, "1500"},
+		{pgtype.Numeric{}, "0"},
+	}
+	for _, c := range cases {
+		encoded, err := s.m.Encode(pgtype.NumericOID, pgproto.FormatText, c.value)
+		s.Nil(err, c.value)
+		s.Equal(c.expected, string(encoded), c.value)
+
+		// The binary representation holds the same value
+		binary, err := s.m.Encode(pgtype.NumericOID, pgproto.FormatBinary, c.value)
+		s.Nil(err, c.value)
+		v, err := s.m.Decode(pgtype.NumericOID, pgproto.FormatBinary, binary)
+		s.Nil(err, c.value)
+		s.Equal(c.expected, v.(pgtype.Numeric).String())
+	}
+
+	_, err := s.m.Encode(pgtype.NumericOID, pgproto.FormatText, big.NewRat(1, 3))
+	s.NotNil(err)
+	_, err = s.m.Encode(pgtype.NumericOID, pgproto.FormatText, "abc")
+	s.NotNil(err)
+}
+
+func (s *NumericTestSuite) Test_TypeModifier() {
+	precision, scale, ok := pgtype.NumericTypeModifier(pgproto.RowField{TypeOID: pgtype.NumericOID, TypeModifier: 10<<16 | 2 + 4})
+	s.True(ok)
+	s.Equal(10, precision)
+	s.Equal(2, scale)
+
+	// Negative scales are allowed since PostgreSQL 15, e.g. numeric(2,-3)
+	precision, scale, ok = pgtype.NumericTypeModifier(pgproto.RowField{TypeOID: pgtype.NumericOID, TypeModifier: 2<<16 | 0x7fd + 4})
+	s.True(ok)
+	s.Equal(2, precision)
+	s.Equal(-3, scale)
+
+	_, _, ok = pgtype.NumericTypeModifier(pgproto.RowField{TypeOID: pgtype.NumericOID, TypeModifier: -1})
+	s.False(ok)
+	_, _, ok = pgtype.NumericTypeModifier(pgproto.RowField{TypeOID: pgtype.Int4OID, TypeModifier: 10<<16 | 2 + 4})
+	s.False(ok)
+}
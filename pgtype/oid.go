@@ -0,0 +1,58 @@
+package pgtype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// OIDCodec converts oid values to and from a Go uint32
+//
+// Any Go integer is encoded when the value fits in a uint32.
+type OIDCodec struct{}
+
+func (OIDCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	oid, err := strconv.ParseUint(string(src), 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return uint32(oid), nil
+}
+
+func (c OIDCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	oid, err := c.uint32(v)
+	if err != nil {
+		return nil, err
+	}
+	return strconv.AppendUint(buf, uint64(oid), 10), nil
+}
+
+// DecodeBinary decodes a big endian unsigned 4 byte integer
+func (OIDCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	err := checkSize(src, 4)
+	if err != nil {
+		return nil, err
+	}
+	return binary.BigEndian.Uint32(src), nil
+}
+
+func (c OIDCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	oid, err := c.uint32(v)
+	if err != nil {
+		return nil, err
+	}
+	return binary.BigEndian.AppendUint32(buf, oid), nil
+}
+
+// uint32 converts the Go integer v to a uint32, failing when it does not fit
+func (OIDCodec) uint32(v interface{}) (uint32, error) {
+	oid, ok := toUint64(v)
+	if !ok && !isInteger(kindOf(v)) {
+		return 0, fmt.Errorf("cannot encode %T", v)
+	}
+	if !ok || oid > math.MaxUint32 {
+		return 0, fmt.Errorf("%v is out of range for oid", v)
+	}
+	return uint32(oid), nil
+}
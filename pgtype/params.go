@@ -0,0 +1,209 @@
+package pgtype
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
+	"time"
+
+	"github.com/c653labs/pgproto"
+)
+
+// Bind returns the Bind message binding args to the parameters of the prepared statement named
+// statement, whose types are given by description, the ParameterDescription the server returned
+// when the statement was described, or nil when they are unknown
+//
+// Each parameter is sent in the binary format when the codec of its type supports it and encodes
+// its argument, and in the text format otherwise. Arguments implementing driver.Valuer are encoded
+// as the value they return, nil and nil pointers are sent as NULL. Strings, and values implementing
+// encoding.TextMarshaler as their text, are sent as is in the text format when the codec of their
+// parameter does not encode them, the server parses them as values of the type of the parameter.
+//
+// Without a description, the types of the parameters are inferred from the Go types of args, e.g.
+// int8 for an int64, timestamptz for a time.Time or int4[] for an []int32, and every parameter is
+// sent in the text format, which the server parses as the type it inferred for the parameter.
+//
+// The portal and the formats of the results are left for the caller to set.
+func (m *TypeMap) Bind(statement string, description *pgproto.ParameterDescription, args ...interface{}) (*pgproto.Bind, error) {
+	if description != nil && len(description.OIDs) != len(args) {
+		return nil, fmt.Errorf("pgtype: expected %d parameters, got %d", len(description.OIDs), len(args))
+	}
+
+	b := &pgproto.Bind{
+		Statement:  []byte(statement),
+		Parameters: make([][]byte, len(args)),
+	}
+	formats := make([]pgproto.Format, len(args))
+	binary := false
+	for i, arg := range args {
+		v, err := parameterValue(arg)
+		if err == nil && v != nil {
+			if description != nil {
+				formats[i], b.Parameters[i], err = m.encodeParameter(description.OIDs[i], v)
+			} else {
+				b.Parameters[i], err = m.encodeTextParameter(v)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("pgtype: parameter %d: %w", i+1, err)
+		}
+		binary = binary || formats[i] == pgproto.FormatBinary
+	}
+
+	// No formats stand for every parameter in the text format
+	if binary {
+		b.ParameterFormats = formats
+	}
+	return b, nil
+}
+
+// valuerType is the type of driver.Valuer
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// parameterValue returns the value arg encodes as, the value driver.Valuer implementations return,
+// and nil for nil pointers
+func parameterValue(arg interface{}) (interface{}, error) {
+	if valuer, ok := arg.(driver.Valuer); ok {
+		// Nil pointers to values implementing driver.Valuer can not be called, like database/sql
+		// they are NULL
+		r := reflect.ValueOf(arg)
+		if r.Kind() == reflect.Ptr && r.IsNil() && r.Type().Elem().Implements(valuerType) {
+			return nil, nil
+		}
+		return valuer.Value()
+	}
+
+	v := indirect(arg)
+	if valuer, ok := v.(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	return v, nil
+}
+
+// encodeParameter encodes v, a non nil value, as a value of type oid in the binary format when its
+// codec supports it, and in the text format otherwise
+func (m *TypeMap) encodeParameter(oid int, v interface{}) (pgproto.Format, []byte, error) {
+	if t, ok := m.oids[oid]; ok {
+		if _, ok := t.Codec.(BinaryCodec); ok {
+			buf, err := m.encode(oid, pgproto.FormatBinary, v)
+			if err == nil {
+				return pgproto.FormatBinary, buf, nil
+			}
+		}
+	}
+
+	buf, err := m.encode(oid, pgproto.FormatText, v)
+	if err != nil {
+		if text, ok := parameterText(v); ok {
+			return pgproto.FormatText, text, nil
+		}
+		return 0, nil, err
+	}
+	return pgproto.FormatText, buf, nil
+}
+
+// encodeTextParameter encodes v, a non nil value, in the text format as a value of the type its Go
+// type is inferred as
+func (m *TypeMap) encodeTextParameter(v interface{}) ([]byte, error) {
+	oid, ok := m.parameterOID(reflect.TypeOf(v))
+	if !ok {
+		if text, ok := parameterText(v); ok {
+			return text, nil
+		}
+		return nil, fmt.Errorf("cannot infer the type of %T, a ParameterDescription is required", v)
+	}
+	return m.encode(oid, pgproto.FormatText, v)
+}
+
+// parameterText returns the text strings and encoding.TextMarshaler implementations are sent as
+// when no codec encodes them
+func parameterText(v interface{}) ([]byte, bool) {
+	if s, ok := v.(string); ok {
+		return []byte(s), true
+	}
+	if marshaler, ok := v.(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		return text, err == nil
+	}
+	return nil, false
+}
+
+// parameterOIDs are the OIDs of the types the values of Go types are inferred as
+var parameterOIDs = map[reflect.Type]int{
+	reflect.TypeOf(time.Time{}):        TimestamptzOID,
+	durationType:                       IntervalOID,
+	reflect.TypeOf(Interval{}):         IntervalOID,
+	reflect.TypeOf(TimeTZ{}):           TimetzOID,
+	reflect.TypeOf(UUID{}):             UUIDOID,
+	reflect.TypeOf(Numeric{}):          NumericOID,
+	bigIntType:                         NumericOID,
+	bigRatType:                         NumericOID,
+	bigFloatType:                       NumericOID,
+	reflect.TypeOf(json.RawMessage{}):  JSONOID,
+	addrType:                           InetOID,
+	reflect.TypeOf(netip.Prefix{}):     InetOID,
+	reflect.TypeOf(net.IP{}):           InetOID,
+	reflect.TypeOf(net.IPNet{}):        InetOID,
+	reflect.TypeOf(net.HardwareAddr{}): MACAddrOID,
+	reflect.TypeOf(Point{}):            PointOID,
+	reflect.TypeOf(Line{}):             LineOID,
+	reflect.TypeOf(Lseg{}):             LsegOID,
+	reflect.TypeOf(Box{}):              BoxOID,
+	reflect.TypeOf(Path{}):             PathOID,
+	reflect.TypeOf(Polygon{}):          PolygonOID,
+	reflect.TypeOf(Circle{}):           CircleOID,
+}
+
+// parameterOID returns the OID of the type the values of the Go type t are inferred as, arrays of
+// the type of their elements for slices
+func (m *TypeMap) parameterOID(t reflect.Type) (int, bool) {
+	if oid, ok := parameterOIDs[t]; ok {
+		return oid, true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return BoolOID, true
+	case reflect.Int8, reflect.Int16:
+		return Int2OID, true
+	case reflect.Int32:
+		return Int4OID, true
+	case reflect.Int, reflect.Int64, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Int8OID, true
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return NumericOID, true
+	case reflect.Float32:
+		return Float4OID, true
+	case reflect.Float64:
+		return Float8OID, true
+	case reflect.String:
+		return TextOID, true
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return ByteaOID, true
+		}
+		elem := t.Elem()
+		for elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		// Nested slices are arrays of more dimensions of the type of their innermost elements
+		for elem.Kind() == reflect.Slice && elem.Elem().Kind() != reflect.Uint8 {
+			if _, ok := parameterOIDs[elem]; ok {
+				break
+			}
+			elem = elem.Elem()
+		}
+		oid, ok := m.parameterOID(elem)
+		if !ok {
+			return 0, false
+		}
+		if t, ok := m.oids[oid]; ok && t.ArrayOID != 0 {
+			return t.ArrayOID, true
+		}
+	}
+	return 0, false
+}
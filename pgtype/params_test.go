@@ -0,0 +1,128 @@
+package pgtype_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtype"
+	"github.com/stretchr/testify/suite"
+)
+
+type ParamsTestSuite struct {
+	suite.Suite
+	m *pgtype.TypeMap
+}
+
+func TestParamsTestSuite(t *testing.T) {
+	suite.Run(t, new(ParamsTestSuite))
+}
+
+func (s *ParamsTestSuite) SetupTest() {
+	s.m = pgtype.NewTypeMap()
+}
+
+// celsius is a driver.Valuer of a temperature, NULL when unknown
+type celsius struct {
+	degrees float64
+	known   bool
+}
+
+func (c celsius) Value() (driver.Value, error) {
+	if !c.known {
+		return nil, nil
+	}
+	return c.degrees, nil
+}
+
+// version is an encoding.TextMarshaler of a major and minor version
+type version struct {
+	major, minor int
+}
+
+func (v version) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d.%d", v.major, v.minor)), nil
+}
+
+// failing is a driver.Valuer returning an error
+type failing struct{}
+
+func (failing) Value() (driver.Value, error) {
+	return nil, errors.New("no value")
+}
+
+func (s *ParamsTestSuite) Test_Description() {
+	description := &pgproto.ParameterDescription{
+		OIDs: []int{pgtype.Int4OID, pgtype.TextOID, pgtype.Float8OID, pgtype.Float8OID, pgtype.MoneyOID, pgtype.Int8OID, pgtype.DateOID},
+	}
+	var nilValuer *celsius
+	b, err := s.m.Bind("insert", description, 42, "a", celsius{degrees: 1.5, known: true}, nilValuer, "$1.50", "7", nil)
+	s.Nil(err)
+	s.Equal([]byte("insert"), b.Statement)
+	s.Equal([]pgproto.Format{
+		pgproto.FormatBinary,
+		pgproto.FormatBinary,
+		pgproto.FormatBinary,
+		pgproto.FormatText,
+		pgproto.FormatText,
+		pgproto.FormatText,
+		pgproto.FormatText,
+	}, b.ParameterFormats)
+	s.Equal([][]byte{
+		{0x00, 0x00, 0x00, 0x2a},
+		[]byte("a"),
+		{0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		nil,
+		[]byte("$1.50"),
+		[]byte("7"),
+		nil,
+	}, b.Parameters)
+
+	// Every parameter in the text format has no formats
+	b, err = s.m.Bind("", &pgproto.ParameterDescription{OIDs: []int{pgtype.MoneyOID}}, "$1.50")
+	s.Nil(err)
+	s.Nil(b.ParameterFormats)
+	s.Equal([][]byte{[]byte("$1.50")}, b.Parameters)
+}
+
+func (s *ParamsTestSuite) Test_Inferred() {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	id := pgtype.UUID{0x01}
+	b, err := s.m.Bind("", nil, int64(42), true, "a", []byte{0xde, 0xad}, at, []int32{1, 2}, [][]string{{"a"}, {"b"}}, &id, celsius{})
+	s.Nil(err)
+	s.Nil(b.ParameterFormats)
+	s.Equal([][]byte{
+		[]byte("42"),
+		[]byte("t"),
+		[]byte("a"),
+		[]byte(`\xdead`),
+		[]byte("2024-01-02 03:04:05+00"),
+		[]byte("{1,2}"),
+		[]byte("{{a},{b}}"),
+		[]byte("01000000-0000-0000-0000-000000000000"),
+		nil,
+	}, b.Parameters)
+
+	// Types which can not be inferred are sent as their text
+	b, err = s.m.Bind("", nil, version{1, 2})
+	s.Nil(err)
+	s.Equal([][]byte{[]byte("1.2")}, b.Parameters)
+}
+
+func (s *ParamsTestSuite) Test_Errors() {
+	_, err := s.m.Bind("", &pgproto.ParameterDescription{OIDs: []int{pgtype.Int4OID}})
+	s.EqualError(err, "pgtype: expected 1 parameters, got 0")
+
+	_, err = s.m.Bind("", &pgproto.ParameterDescription{OIDs: []int{pgtype.Int4OID}}, true)
+	s.NotNil(err)
+	s.Contains(err.Error(), "parameter 1: int4")
+
+	_, err = s.m.Bind("", nil, 1, failing{})
+	s.EqualError(err, "pgtype: parameter 2: no value")
+
+	_, err = s.m.Bind("", nil, struct{}{})
+	s.EqualError(err, "pgtype: parameter 1: cannot infer the type of struct {}, a ParameterDescription is required")
+}
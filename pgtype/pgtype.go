@@ -0,0 +1,520 @@
+/*
+Package pgtype converts the values of DataRow and Bind messages between their PostgreSQL wire
+representations and Go values.
+
+Values are converted by the Codec registered for their type OID in a TypeMap, which NewTypeMap
+returns with the codecs of the built-in types registered. The type OID and format of a column are
+given by its RowField in the RowDescription preceding the rows:
+
+	m := pgtype.NewTypeMap()
+	for i, field := range description.Fields {
+		var v interface{}
+		err := m.ScanField(field, row.Fields[i], &v)
+		...
+	}
+
+Scan stores a value in any Go type it can be converted to without overflowing, and NULL in pointers:
+
+	var id int64
+	var name *string
+	err := m.Scan(pgtype.Int4OID, pgproto.FormatText, []byte("42"), &id)
+	err = m.Scan(pgtype.TextOID, pgproto.FormatText, nil, &name) // name == nil
+
+Values of types without a codec are decoded as a string in the text format, and as a byte slice in
+the binary format.
+
+Bind encodes the arguments of a prepared statement, in the binary format when the codecs of the
+types of its parameters, given by the ParameterDescription of the statement, support it:
+
+	bind, err := m.Bind("statement", description, 42, "alice", time.Now())
+
+The types created in the database, e.g. enums, domains, composites and the types of extensions, have
+OIDs differing between databases. They are registered by LoadTypes from the rows of the query
+TypesQuery returns, with the codecs RegisterCodec registered by name for the types of extensions:
+
+	m.RegisterCodec("hstore", hstoreCodec{})
+	query := &pgproto.SimpleQuery{Query: []byte(pgtype.TypesQuery())}
+	...
+	err := m.LoadTypes(rows)
+
+The text representation of date and time values depends on the DateStyle, IntervalStyle and
+TimeZone parameters of the session, which the server reports in ParameterStatus messages:
+
+	case *pgproto.ParameterStatus:
+		m.SetParameter(string(msg.Name), string(msg.Value))
+*/
+package pgtype
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/c653labs/pgproto"
+)
+
+// OIDs of the built-in types
+const (
+	BoolOID    = 16
+	ByteaOID   = 17
+	CharOID    = 18
+	NameOID    = 19
+	Int8OID    = 20
+	Int2OID    = 21
+	Int4OID    = 23
+	TextOID    = 25
+	OIDOID     = 26
+	Float4OID  = 700
+	Float8OID  = 701
+	UnknownOID = 705
+	BPCharOID  = 1042
+	VarcharOID = 1043
+	NumericOID = 1700
+	UUIDOID    = 2950
+
+	JSONOID     = 114
+	JSONBOID    = 3802
+	JSONPathOID = 4072
+
+	DateOID        = 1082
+	TimeOID        = 1083
+	TimestampOID   = 1114
+	TimestamptzOID = 1184
+	IntervalOID    = 1186
+	TimetzOID      = 1266
+
+	Int4RangeOID      = 3904
+	NumRangeOID       = 3906
+	TsRangeOID        = 3908
+	TstzRangeOID      = 3910
+	DateRangeOID      = 3912
+	Int8RangeOID      = 3926
+	Int4MultirangeOID = 4451
+	NumMultirangeOID  = 4532
+	TsMultirangeOID   = 4533
+	TstzMultirangeOID = 4534
+	DateMultirangeOID = 4535
+	Int8MultirangeOID = 4536
+
+	CIDROID     = 650
+	MACAddr8OID = 774
+	MACAddrOID  = 829
+	InetOID     = 869
+
+	PointOID   = 600
+	LsegOID    = 601
+	PathOID    = 602
+	BoxOID     = 603
+	PolygonOID = 604
+	LineOID    = 628
+	CircleOID  = 718
+
+	RecordOID = 2249
+
+	Int2VectorOID    = 22
+	RegProcOID       = 24
+	TIDOID           = 27
+	XIDOID           = 28
+	CIDOID           = 29
+	OIDVectorOID     = 30
+	XMLOID           = 142
+	PgNodeTreeOID    = 194
+	MoneyOID         = 790
+	ACLItemOID       = 1033
+	BitOID           = 1560
+	VarbitOID        = 1562
+	RefcursorOID     = 1790
+	RegProcedureOID  = 2202
+	RegOperOID       = 2203
+	RegOperatorOID   = 2204
+	RegClassOID      = 2205
+	RegTypeOID       = 2206
+	CstringOID       = 2275
+	VoidOID          = 2278
+	TxidSnapshotOID  = 2970
+	PgLSNOID         = 3220
+	TSVectorOID      = 3614
+	TSQueryOID       = 3615
+	GTSVectorOID     = 3642
+	RegConfigOID     = 3734
+	RegDictionaryOID = 3769
+	RegNamespaceOID  = 4089
+	RegRoleOID       = 4096
+	RegCollationOID  = 4191
+	PgSnapshotOID    = 5038
+	XID8OID          = 5069
+
+	BoolArrayOID        = 1000
+	ByteaArrayOID       = 1001
+	CharArrayOID        = 1002
+	NameArrayOID        = 1003
+	Int2ArrayOID        = 1005
+	Int4ArrayOID        = 1007
+	TextArrayOID        = 1009
+	BPCharArrayOID      = 1014
+	VarcharArrayOID     = 1015
+	Int8ArrayOID        = 1016
+	Float4ArrayOID      = 1021
+	Float8ArrayOID      = 1022
+	OIDArrayOID         = 1028
+	TimestampArrayOID   = 1115
+	DateArrayOID        = 1182
+	TimeArrayOID        = 1183
+	TimestamptzArrayOID = 1185
+	IntervalArrayOID    = 1187
+	NumericArrayOID     = 1231
+	TimetzArrayOID      = 1270
+	UUIDArrayOID        = 2951
+	JSONArrayOID        = 199
+	JSONBArrayOID       = 3807
+	JSONPathArrayOID    = 4073
+
+	Int4RangeArrayOID      = 3905
+	NumRangeArrayOID       = 3907
+	TsRangeArrayOID        = 3909
+	TstzRangeArrayOID      = 3911
+	DateRangeArrayOID      = 3913
+	Int8RangeArrayOID      = 3927
+	Int4MultirangeArrayOID = 6150
+	NumMultirangeArrayOID  = 6151
+	TsMultirangeArrayOID   = 6152
+	TstzMultirangeArrayOID = 6153
+	DateMultirangeArrayOID = 6155
+	Int8MultirangeArrayOID = 6157
+
+	CIDRArrayOID     = 651
+	MACAddr8ArrayOID = 775
+	MACAddrArrayOID  = 1040
+	InetArrayOID     = 1041
+
+	PointArrayOID   = 1017
+	LsegArrayOID    = 1018
+	PathArrayOID    = 1019
+	BoxArrayOID     = 1020
+	PolygonArrayOID = 1027
+	LineArrayOID    = 629
+	CircleArrayOID  = 719
+
+	RecordArrayOID = 2287
+
+	XMLArrayOID           = 143
+	XID8ArrayOID          = 271
+	MoneyArrayOID         = 791
+	Int2VectorArrayOID    = 1006
+	RegProcArrayOID       = 1008
+	TIDArrayOID           = 1010
+	XIDArrayOID           = 1011
+	CIDArrayOID           = 1012
+	OIDVectorArrayOID     = 1013
+	ACLItemArrayOID       = 1034
+	CstringArrayOID       = 1263
+	BitArrayOID           = 1561
+	VarbitArrayOID        = 1563
+	RefcursorArrayOID     = 2201
+	RegProcedureArrayOID  = 2207
+	RegOperArrayOID       = 2208
+	RegOperatorArrayOID   = 2209
+	RegClassArrayOID      = 2210
+	RegTypeArrayOID       = 2211
+	TxidSnapshotArrayOID  = 2949
+	PgLSNArrayOID         = 3221
+	TSVectorArrayOID      = 3643
+	GTSVectorArrayOID     = 3644
+	TSQueryArrayOID       = 3645
+	RegConfigArrayOID     = 3735
+	RegDictionaryArrayOID = 3770
+	RegNamespaceArrayOID  = 4090
+	RegRoleArrayOID       = 4097
+	RegCollationArrayOID  = 4192
+	PgSnapshotArrayOID    = 5039
+)
+
+// Codec converts the values of a type between their wire representations and Go values
+type Codec interface {
+	// DecodeText returns the Go value of the text representation src, src is never nil
+	DecodeText(m *TypeMap, src []byte) (interface{}, error)
+
+	// EncodeText appends the text representation of v to buf, v is never nil nor a pointer
+	EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error)
+}
+
+// BinaryCodec is implemented by the codecs supporting the binary format, the representation sent
+// and received by the typsend and typreceive functions of the type
+type BinaryCodec interface {
+	Codec
+
+	// DecodeBinary returns the Go value of the binary representation src, src is never nil
+	DecodeBinary(m *TypeMap, src []byte) (interface{}, error)
+
+	// EncodeBinary appends the binary representation of v to buf, v is never nil nor a pointer
+	EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error)
+}
+
+// Type is a PostgreSQL type, its attributes in the pg_type catalog, and the codec converting its
+// values
+type Type struct {
+	Name string
+	OID  int
+
+	// Codec converts the values of the type, those of types without a codec are decoded as a string
+	// in the text format and as a byte slice in the binary format
+	Codec Codec
+
+	// ArrayOID is the OID of the array type of the type, typarray, zero when it has none
+	ArrayOID int
+
+	// Category is the typcategory of the type, e.g. 'N' for numeric types or 'S' for string types
+	Category byte
+
+	// Len is the typlen of the type, the size of its values in bytes, -1 for variable length types and
+	// -2 for null-terminated strings
+	Len int
+
+	// Delimiter is the typdelim of the type, separating the elements of its arrays in the text
+	// format, a comma when zero
+	Delimiter byte
+}
+
+// TypeMap holds the types known by OID and name, and the run-time parameters of the session
+// affecting how values are represented
+//
+// A TypeMap may be used concurrently once its types are registered. As parameters may differ
+// between sessions, each connection should use its own TypeMap.
+type TypeMap struct {
+	oids  map[int]*Type
+	names map[string]*Type
+
+	// codecs are the codecs registered by name for the types LoadTypes loads
+	codecs map[string]Codec
+
+	// mu guards the parameters, which change as the server reports them
+	mu       sync.RWMutex
+	params   map[string]string
+	location *time.Location
+}
+
+// NewTypeMap returns a TypeMap with every built-in type registered
+func NewTypeMap() *TypeMap {
+	m := &TypeMap{
+		oids:     make(map[int]*Type),
+		names:    make(map[string]*Type),
+		codecs:   make(map[string]Codec),
+		params:   make(map[string]string),
+		location: time.UTC,
+	}
+	for _, t := range builtinTypes {
+		t := t
+		m.RegisterType(&t)
+		if t.ArrayOID != 0 {
+			m.RegisterType(&Type{
+				Name:     "_" + t.Name,
+				OID:      t.ArrayOID,
+				Codec:    ArrayCodec{ElementOID: t.OID, Delimiter: t.Delimiter},
+				Category: 'A',
+				Len:      -1,
+			})
+		}
+	}
+	return m
+}
+
+// SetParameter records the value of a run-time parameter the server reported in a ParameterStatus
+// message
+//
+// DateStyle, IntervalStyle and TimeZone change the text representation of date and time values,
+// they default to "ISO, MDY", "postgres" and "UTC" until set.
+func (m *TypeMap) SetParameter(name, value string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.params[name] = value
+
+	if name == "TimeZone" {
+		// Time zones the Go time zone database does not know, e.g. POSIX specifications, are nil
+		// and values are decoded in the offset the server sent instead
+		loc, err := time.LoadLocation(value)
+		if err != nil {
+			loc = nil
+		}
+		m.location = loc
+	}
+}
+
+// Parameter returns the value of a run-time parameter set by SetParameter
+func (m *TypeMap) Parameter(name string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.params[name]
+}
+
+// RegisterType adds t to the map, replacing any type with the same OID or name
+func (m *TypeMap) RegisterType(t *Type) {
+	m.oids[t.OID] = t
+	m.names[t.Name] = t
+}
+
+// RegisterCodec sets c as the codec of the type registered as name, and of the types of that name
+// LoadTypes registers later, e.g. the types of extensions, whose OIDs differ between databases
+func (m *TypeMap) RegisterCodec(name string, c Codec) {
+	m.codecs[name] = c
+	if t, ok := m.names[name]; ok {
+		t.Codec = c
+	}
+}
+
+// TypeForOID returns the type registered for oid
+func (m *TypeMap) TypeForOID(oid int) (*Type, bool) {
+	t, ok := m.oids[oid]
+	return t, ok
+}
+
+// TypeForName returns the type registered as name
+func (m *TypeMap) TypeForName(name string) (*Type, bool) {
+	t, ok := m.names[name]
+	return t, ok
+}
+
+// Decode returns the Go value of src, a value of type oid in the given format, a nil src is NULL
+// and decoded as nil
+func (m *TypeMap) Decode(oid int, format pgproto.Format, src []byte) (interface{}, error) {
+	v, err := m.decode(oid, format, src)
+	if err != nil {
+		return nil, fmt.Errorf("pgtype: %w", err)
+	}
+	return v, nil
+}
+
+// decode implements Decode, for the codecs decoding the values of other types, e.g. array elements
+func (m *TypeMap) decode(oid int, format pgproto.Format, src []byte) (interface{}, error) {
+	if src == nil {
+		return nil, nil
+	}
+
+	t, ok := m.oids[oid]
+	if !ok || t.Codec == nil {
+		switch format {
+		case pgproto.FormatText:
+			return string(src), nil
+		case pgproto.FormatBinary:
+			return append([]byte{}, src...), nil
+		}
+		return nil, fmt.Errorf("unknown format %d", format)
+	}
+
+	var v interface{}
+	var err error
+	switch format {
+	case pgproto.FormatText:
+		v, err = t.Codec.DecodeText(m, src)
+	case pgproto.FormatBinary:
+		if c, ok := t.Codec.(BinaryCodec); ok {
+			v, err = c.DecodeBinary(m, src)
+		} else {
+			err = errNoBinary
+		}
+	default:
+		err = fmt.Errorf("unknown format %d", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", t.Name, err)
+	}
+	return v, nil
+}
+
+// Scan decodes src like Decode and stores the value in dst, which must be a non nil pointer
+//
+// Values are stored in any type they convert to without overflowing, e.g. an int4 in an int64 or a
+// uint8 when it fits, and in sql.Scanner implementations. NULL can only be stored in pointers, slices,
+// maps and interfaces, which are set to nil. The text of a value in the text format can always be
+// stored in a string, values implementing encoding.TextMarshaler are stored as their text otherwise.
+// JSON values are unmarshaled into any type but strings, byte slices and interfaces.
+// inet values of a single address are stored in a netip.Addr.
+func (m *TypeMap) Scan(oid int, format pgproto.Format, src []byte, dst interface{}) error {
+	err := m.scan(oid, format, src, dst)
+	if err != nil {
+		return fmt.Errorf("pgtype: %w", err)
+	}
+	return nil
+}
+
+func (m *TypeMap) scan(oid int, format pgproto.Format, src []byte, dst interface{}) error {
+	v, err := m.decode(oid, format, src)
+	if err != nil {
+		return err
+	}
+
+	err = assign(dst, v)
+	if errors.Is(err, errMismatch) && format == pgproto.FormatText && src != nil {
+		if d := reflect.ValueOf(dst); d.Kind() == reflect.Ptr && !d.IsNil() && d.Elem().Kind() == reflect.String {
+			d.Elem().SetString(string(src))
+			return nil
+		}
+	}
+	return err
+}
+
+// Encode returns the representation of v as a value of type oid in the given format, nil and nil
+// pointers are encoded as NULL, a nil slice
+func (m *TypeMap) Encode(oid int, format pgproto.Format, v interface{}) ([]byte, error) {
+	buf, err := m.encode(oid, format, v)
+	if err != nil {
+		return nil, fmt.Errorf("pgtype: %w", err)
+	}
+	return buf, nil
+}
+
+// encode implements Encode, for the codecs encoding the values of other types, e.g. array elements
+func (m *TypeMap) encode(oid int, format pgproto.Format, v interface{}) ([]byte, error) {
+	v = indirect(v)
+	if v == nil {
+		return nil, nil
+	}
+
+	t, ok := m.oids[oid]
+	if !ok || t.Codec == nil {
+		// Strings can be sent as values of any type in the text format, and bytes in the binary format
+		if s, ok := toString(v); ok && format == pgproto.FormatText {
+			return []byte(s), nil
+		}
+		if b, ok := v.([]byte); ok && format == pgproto.FormatBinary {
+			return append([]byte{}, b...), nil
+		}
+		if ok {
+			return nil, fmt.Errorf("cannot encode %T as %s, which has no codec", v, t.Name)
+		}
+		return nil, fmt.Errorf("unknown type OID %d", oid)
+	}
+
+	var buf []byte
+	var err error
+	switch format {
+	case pgproto.FormatText:
+		buf, err = t.Codec.EncodeText(m, make([]byte, 0, 16), v)
+	case pgproto.FormatBinary:
+		if c, ok := t.Codec.(BinaryCodec); ok {
+			buf, err = c.EncodeBinary(m, make([]byte, 0, 16), v)
+		} else {
+			err = errNoBinary
+		}
+	default:
+		err = fmt.Errorf("unknown format %d", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", t.Name, err)
+	}
+	return buf, nil
+}
+
+// errNoBinary is returned for the values of types whose codec does not support the binary format
+var errNoBinary = errors.New("binary format is not supported")
+
+// DecodeField decodes src, the value of the column described by f, like Decode
+func (m *TypeMap) DecodeField(f pgproto.RowField, src []byte) (interface{}, error) {
+	return m.Decode(f.TypeOID, f.Format, src)
+}
+
+// ScanField scans src, the value of the column described by f, into dst like Scan
+func (m *TypeMap) ScanField(f pgproto.RowField, src []byte, dst interface{}) error {
+	return m.Scan(f.TypeOID, f.Format, src, dst)
+}
@@ -0,0 +1,342 @@
+package pgtype_test
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtype"
+	"github.com/stretchr/testify/suite"
+)
+
+type PgtypeTestSuite struct {
+	suite.Suite
+	m *pgtype.TypeMap
+}
+
+func TestPgtypeTestSuite(t *testing.T) {
+	suite.Run(t, new(PgtypeTestSuite))
+}
+
+func (s *PgtypeTestSuite) SetupTest() {
+	s.m = pgtype.NewTypeMap()
+}
+
+func (s *PgtypeTestSuite) Test_DecodeText() {
+	cases := []struct {
+		oid      int
+		text     string
+		expected interface{}
+	}{
+		{pgtype.BoolOID, "t", true},
+		{pgtype.BoolOID, "f", false},
+		{pgtype.BoolOID, "yes", true},
+		{pgtype.Int2OID, "-32768", int16(math.MinInt16)},
+		{pgtype.Int4OID, "2147483647", int32(math.MaxInt32)},
+		{pgtype.Int8OID, "-9223372036854775808", int64(math.MinInt64)},
+		{pgtype.Float4OID, "1.5", float32(1.5)},
+		{pgtype.Float8OID, "-1.2345678901234567e+300", -1.2345678901234567e+300},
+		{pgtype.Float8OID, "Infinity", math.Inf(1)},
+		{pgtype.Float8OID, "-Infinity", math.Inf(-1)},
+		{pgtype.TextOID, "héllo", "héllo"},
+		{pgtype.VarcharOID, "", ""},
+		{pgtype.BPCharOID, "ab  ", "ab  "},
+		{pgtype.NameOID, "pg_class", "pg_class"},
+		{pgtype.ByteaOID, `\x00ff10`, []byte{0x00, 0xff, 0x10}},
+		{pgtype.ByteaOID, `\x`, []byte{}},
+		{pgtype.ByteaOID, `a\\b\000\377`, []byte{'a', '\\', 'b', 0x00, 0xff}},
+		{pgtype.OIDOID, "4294967295", uint32(math.MaxUint32)},
+		{pgtype.CharOID, "r", byte('r')},
+		{pgtype.CharOID, `\377`, byte(0xff)},
+		{pgtype.CharOID, "", byte(0)},
+	}
+
+	for _, c := range cases {
+		v, err := s.m.Decode(c.oid, pgproto.FormatText, []byte(c.text))
+		s.Nil(err, c.text)
+		s.Equal(c.expected, v, c.text)
+	}
+
+	v, err := s.m.Decode(pgtype.Float8OID, pgproto.FormatText, []byte("NaN"))
+	s.Nil(err)
+	s.True(math.IsNaN(v.(float64)))
+
+	// NULL is decoded as nil whatever the type
+	v, err = s.m.Decode(pgtype.Int4OID, pgproto.FormatText, nil)
+	s.Nil(err)
+	s.Nil(v)
+
+	for _, c := range []struct {
+		oid  int
+		text string
+	}{
+		{pgtype.BoolOID, "maybe"},
+		{pgtype.Int2OID, "32768"},
+		{pgtype.Int4OID, "1.5"},
+		{pgtype.Float4OID, "abc"},
+		{pgtype.ByteaOID, `\xf`},
+		{pgtype.ByteaOID, `\9`},
+		{pgtype.OIDOID, "-1"},
+	} {
+		_, err := s.m.Decode(c.oid, pgproto.FormatText, []byte(c.text))
+		s.NotNil(err, c.text)
+	}
+}
+
+func (s *PgtypeTestSuite) Test_EncodeText() {
+	type myInt int
+	type myString string
+
+	cases := []struct {
+		oid      int
+		value    interface{}
+		expected string
+	}{
+		{pgtype.BoolOID, true, "t"},
+		{pgtype.BoolOID, false, "f"},
+		{pgtype.Int2OID, uint8(255), "255"},
+		{pgtype.Int4OID, myInt(-42), "-42"},
+		{pgtype.Int8OID, uint64(math.MaxInt64), "9223372036854775807"},
+		{pgtype.Float4OID, float32(0.1), "0.1"},
+		{pgtype.Float8OID, 0.1, "0.1"},
+		{pgtype.Float8OID, 3, "3"},
+		{pgtype.Float8OID, math.Inf(-1), "-Infinity"},
+		{pgtype.Float8OID, math.NaN(), "NaN"},
+		{pgtype.TextOID, "héllo", "héllo"},
+		{pgtype.TextOID, myString("a"), "a"},
+		{pgtype.VarcharOID, []byte("b"), "b"},
+		{pgtype.TextOID, "", ""},
+		{pgtype.ByteaOID, []byte{0x00, 0xff, '\\'}, `\x00ff5c`},
+		{pgtype.OIDOID, 26, "26"},
+		{pgtype.CharOID, "r", "r"},
+		{pgtype.CharOID, byte(0xff), `\377`},
+		{pgtype.CharOID, "", ""},
+	}
+
+	for _, c := range cases {
+		encoded, err := s.m.Encode(c.oid, pgproto.FormatText, c.value)
+		s.Nil(err, c.value)
+		s.Equal(c.expected, string(encoded), c.value)
+		s.NotNil(encoded, c.value)
+
+		// Encoded values decode to the same value
+		decoded, err := s.m.Decode(c.oid, pgproto.FormatText, encoded)
+		s.Nil(err)
+		reencoded, err := s.m.Encode(c.oid, pgproto.FormatText, decoded)
+		s.Nil(err)
+		s.Equal(encoded, reencoded)
+	}
+
+	// nil and nil pointers are NULL, other pointers are encoded as the value they point to
+	var p *int
+	encoded, err := s.m.Encode(pgtype.Int4OID, pgproto.FormatText, p)
+	s.Nil(err)
+	s.Nil(encoded)
+	encoded, err = s.m.Encode(pgtype.TextOID, pgproto.FormatText, nil)
+	s.Nil(err)
+	s.Nil(encoded)
+	i := 7
+	encoded, err = s.m.Encode(pgtype.Int4OID, pgproto.FormatText, &i)
+	s.Nil(err)
+	s.Equal("7", string(encoded))
+
+	for _, c := range []struct {
+		oid   int
+		value interface{}
+	}{
+		{pgtype.BoolOID, 1},
+		{pgtype.Int2OID, 32768},
+		{pgtype.Int4OID, uint64(math.MaxUint64)},
+		{pgtype.Int8OID, "1"},
+		{pgtype.Float4OID, 1e300},
+		{pgtype.Float8OID, int64(math.MaxInt64)},
+		{pgtype.TextOID, 1},
+		{pgtype.OIDOID, -1},
+		{pgtype.CharOID, "ab"},
+		{pgtype.CharOID, 256},
+	} {
+		_, err := s.m.Encode(c.oid, pgproto.FormatText, c.value)
+		s.NotNil(err, c.value)
+	}
+}
+
+func (s *PgtypeTestSuite) Test_Scan() {
+	var i16 int16
+	s.Nil(s.m.Scan(pgtype.Int4OID, pgproto.FormatText, []byte("-12"), &i16))
+	s.Equal(int16(-12), i16)
+
+	var u8 uint8
+	s.Nil(s.m.Scan(pgtype.Int8OID, pgproto.FormatText, []byte("255"), &u8))
+	s.Equal(uint8(255), u8)
+	s.NotNil(s.m.Scan(pgtype.Int8OID, pgproto.FormatText, []byte("256"), &u8))
+	s.NotNil(s.m.Scan(pgtype.Int8OID, pgproto.FormatText, []byte("-1"), &u8))
+
+	var f float64
+	s.Nil(s.m.Scan(pgtype.Int4OID, pgproto.FormatText, []byte("3"), &f))
+	s.Equal(3.0, f)
+	s.Nil(s.m.Scan(pgtype.Float4OID, pgproto.FormatText, []byte("0.5"), &f))
+	s.Equal(0.5, f)
+
+	var b []byte
+	s.Nil(s.m.Scan(pgtype.ByteaOID, pgproto.FormatText, []byte(`\x0102`), &b))
+	s.Equal([]byte{1, 2}, b)
+	s.Nil(s.m.Scan(pgtype.TextOID, pgproto.FormatText, []byte("abc"), &b))
+	s.Equal([]byte("abc"), b)
+
+	// Named types are converted
+	type status string
+	var st status
+	s.Nil(s.m.Scan(pgtype.TextOID, pgproto.FormatText, []byte("active"), &st))
+	s.Equal(status("active"), st)
+
+	// Any value in the text format is stored as is in a string
+	var str string
+	s.Nil(s.m.Scan(pgtype.Int4OID, pgproto.FormatText, []byte("42"), &str))
+	s.Equal("42", str)
+	s.Nil(s.m.Scan(pgtype.CharOID, pgproto.FormatText, []byte("r"), &str))
+	s.Equal("r", str)
+
+	// Values of unknown types are decoded as strings
+	s.Nil(s.m.Scan(123456, pgproto.FormatText, []byte("(1,2)"), &str))
+	s.Equal("(1,2)", str)
+
+	var value interface{}
+	s.Nil(s.m.Scan(pgtype.BoolOID, pgproto.FormatText, []byte("t"), &value))
+	s.Equal(true, value)
+	s.Nil(s.m.Scan(pgtype.BoolOID, pgproto.FormatText, nil, &value))
+	s.Nil(value)
+
+	var n sql.NullInt64
+	s.Nil(s.m.Scan(pgtype.Int2OID, pgproto.FormatText, []byte("5"), &n))
+	s.Equal(sql.NullInt64{Int64: 5, Valid: true}, n)
+	s.Nil(s.m.Scan(pgtype.Int2OID, pgproto.FormatText, nil, &n))
+	s.Equal(sql.NullInt64{}, n)
+
+	s.NotNil(s.m.Scan(pgtype.BoolOID, pgproto.FormatText, []byte("t"), &i16))
+	s.NotNil(s.m.Scan(pgtype.Float8OID, pgproto.FormatText, []byte("1.5"), &i16))
+	s.NotNil(s.m.Scan(pgtype.Int4OID, pgproto.FormatText, []byte("1"), i16))
+}
+
+func (s *PgtypeTestSuite) Test_ScanNull() {
+	// NULL can only be stored in pointers
+	i := int32(1)
+	s.NotNil(s.m.Scan(pgtype.Int4OID, pgproto.FormatText, nil, &i))
+
+	p := &i
+	s.Nil(s.m.Scan(pgtype.Int4OID, pgproto.FormatText, nil, &p))
+	s.Nil(p)
+	s.Equal(int32(1), i)
+
+	s.Nil(s.m.Scan(pgtype.Int4OID, pgproto.FormatText, []byte("2"), &p))
+	s.Equal(int32(2), *p)
+
+	var name *string
+	s.Nil(s.m.Scan(pgtype.TextOID, pgproto.FormatText, []byte("pgproto"), &name))
+	s.Equal("pgproto", *name)
+
+	var pp **int64
+	s.Nil(s.m.Scan(pgtype.Int8OID, pgproto.FormatText, []byte("3"), &pp))
+	s.Equal(int64(3), **pp)
+}
+
+func (s *PgtypeTestSuite) Test_Binary() {
+	uuid := pgtype.UUID{0xa0, 0xee, 0xbc, 0x99, 0x9c, 0x0b, 0x4e, 0xf8, 0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11}
+
+	// The layouts of the typsend functions, e.g. int4send sends a big endian int32
+	cases := []struct {
+		oid      int
+		binary   []byte
+		expected interface{}
+	}{
+		{pgtype.BoolOID, []byte{0x01}, true},
+		{pgtype.BoolOID, []byte{0x00}, false},
+		{pgtype.Int2OID, []byte{0xff, 0xfe}, int16(-2)},
+		{pgtype.Int4OID, []byte{0x00, 0x00, 0x01, 0x00}, int32(256)},
+		{pgtype.Int8OID, []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, int64(math.MinInt64)},
+		{pgtype.Float4OID, []byte{0x3f, 0xc0, 0x00, 0x00}, float32(1.5)},
+		{pgtype.Float8OID, []byte{0xc0, 0x09, 0x21, 0xfb, 0x54, 0x44, 0x2d, 0x18}, -math.Pi},
+		{pgtype.Float8OID, []byte{0x7f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, math.Inf(1)},
+		{pgtype.TextOID, []byte("héllo"), "héllo"},
+		{pgtype.NameOID, []byte("pg_class"), "pg_class"},
+		{pgtype.TextOID, []byte{}, ""},
+		{pgtype.ByteaOID, []byte{0x00, 0xff, '\\'}, []byte{0x00, 0xff, '\\'}},
+		{pgtype.OIDOID, []byte{0xff, 0xff, 0xff, 0xff}, uint32(math.MaxUint32)},
+		{pgtype.CharOID, []byte{'r'}, byte('r')},
+		{pgtype.UUIDOID, uuid[:], uuid},
+	}
+
+	for _, c := range cases {
+		v, err := s.m.Decode(c.oid, pgproto.FormatBinary, c.binary)
+		s.Nil(err, c.binary)
+		s.Equal(c.expected, v, c.binary)
+
+		encoded, err := s.m.Encode(c.oid, pgproto.FormatBinary, c.expected)
+		s.Nil(err, c.expected)
+		s.Equal(c.binary, encoded, c.expected)
+	}
+
+	// The format of the RowField selects the representation
+	var i int
+	s.Nil(s.m.ScanField(pgproto.RowField{TypeOID: pgtype.Int4OID, Format: pgproto.FormatBinary}, []byte{0x00, 0x00, 0x00, 0x2a}, &i))
+	s.Equal(42, i)
+	s.Nil(s.m.ScanField(pgproto.RowField{TypeOID: pgtype.Int4OID, Format: pgproto.FormatText}, []byte("43"), &i))
+	s.Equal(43, i)
+
+	// Values of unknown types are decoded as bytes
+	v, err := s.m.Decode(123456, pgproto.FormatBinary, []byte{0x01})
+	s.Nil(err)
+	s.Equal([]byte{0x01}, v)
+
+	for _, c := range []struct {
+		oid    int
+		binary []byte
+	}{
+		{pgtype.BoolOID, []byte{}},
+		{pgtype.Int2OID, []byte{0x00, 0x00, 0x00, 0x01}},
+		{pgtype.Int8OID, []byte{0x00, 0x01}},
+		{pgtype.Float4OID, []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{pgtype.UUIDOID, uuid[:15]},
+	} {
+		_, err := s.m.Decode(c.oid, pgproto.FormatBinary, c.binary)
+		s.NotNil(err, c.binary)
+	}
+}
+
+func (s *PgtypeTestSuite) Test_UUID() {
+	expected := pgtype.UUID{0xa0, 0xee, 0xbc, 0x99, 0x9c, 0x0b, 0x4e, 0xf8, 0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11}
+
+	for _, text := range []string{
+		"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11",
+		"{a0eebc99-9c0b4ef8-bb6d6bb9-bd380a11}",
+		"a0eebc999c0b4ef8bb6d6bb9bd380a11",
+		"a0ee-bc99-9c0b-4ef8-bb6d-6bb9-bd38-0a11",
+	} {
+		u, err := pgtype.ParseUUID(text)
+		s.Nil(err, text)
+		s.Equal(expected, u, text)
+	}
+	s.Equal("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", expected.String())
+
+	for _, text := range []string{"", "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a1", "a0eebc99--9c0b-4ef8-bb6d-6bb9bd380a11", "-a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", "g0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"} {
+		_, err := pgtype.ParseUUID(text)
+		s.NotNil(err, text)
+	}
+
+	v, err := s.m.Decode(pgtype.UUIDOID, pgproto.FormatText, []byte("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"))
+	s.Nil(err)
+	s.Equal(expected, v)
+
+	encoded, err := s.m.Encode(pgtype.UUIDOID, pgproto.FormatText, "{a0eebc999c0b4ef8bb6d6bb9bd380a11}")
+	s.Nil(err)
+	s.Equal("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", string(encoded))
+
+	// UUIDs are stored as text in strings and as bytes in byte arrays
+	var str string
+	s.Nil(s.m.Scan(pgtype.UUIDOID, pgproto.FormatBinary, expected[:], &str))
+	s.Equal("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", str)
+	var b [16]byte
+	s.Nil(s.m.Scan(pgtype.UUIDOID, pgproto.FormatBinary, expected[:], &b))
+	s.Equal([16]byte(expected), b)
+}
@@ -0,0 +1,592 @@
+package pgtype
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/c653labs/pgproto"
+)
+
+// Flags of the binary representation of ranges
+const (
+	rangeEmpty     = 0x01
+	rangeLowerInc  = 0x02
+	rangeUpperInc  = 0x04
+	rangeLowerInf  = 0x08
+	rangeUpperInf  = 0x10
+	rangeLowerNull = 0x20
+	rangeUpperNull = 0x40
+)
+
+// BoundType is the type of a bound of a range
+type BoundType int8
+
+const (
+	// Unbounded is the bound of a range without a lower or upper limit, e.g. (,5]
+	Unbounded BoundType = iota
+	// Inclusive is the bound of a range including its limit, e.g. [1,
+	Inclusive
+	// Exclusive is the bound of a range excluding its limit, e.g. (1,
+	Exclusive
+)
+
+func (b BoundType) String() string {
+	switch b {
+	case Unbounded:
+		return "unbounded"
+	case Inclusive:
+		return "inclusive"
+	case Exclusive:
+		return "exclusive"
+	}
+	return fmt.Sprintf("BoundType(%d)", int8(b))
+}
+
+// Range is a range value, the values between Lower and Upper, which are ignored when their bound is
+// Unbounded. The zero Range is unbounded, it contains every value.
+//
+// Ranges are decoded as a Range[interface{}], holding the values of their element type, and are
+// scanned into a Range of any type these values are scanned into, e.g. a Range[int64] for an
+// int8range or a Range[time.Time] for a tstzrange.
+type Range[T any] struct {
+	Lower      T
+	Upper      T
+	LowerBound BoundType
+	UpperBound BoundType
+
+	// Empty is set for the empty range, which contains no value whatever the bounds
+	Empty bool
+}
+
+// rangeSetter is implemented by pointers to ranges, setting a range of any type from a decoded range
+type rangeSetter interface {
+	setRange(src Range[interface{}]) error
+}
+
+// ranger is implemented by ranges, converting a range of any type to a range of interfaces
+type ranger interface {
+	untypedRange() Range[interface{}]
+}
+
+func (r *Range[T]) setRange(src Range[interface{}]) error {
+	*r = Range[T]{LowerBound: src.LowerBound, UpperBound: src.UpperBound, Empty: src.Empty}
+	if src.Empty {
+		return nil
+	}
+	if src.LowerBound != Unbounded {
+		err := assignValue(reflect.ValueOf(&r.Lower).Elem(), src.Lower)
+		if err != nil {
+			return fmt.Errorf("lower bound: %w", err)
+		}
+	}
+	if src.UpperBound != Unbounded {
+		err := assignValue(reflect.ValueOf(&r.Upper).Elem(), src.Upper)
+		if err != nil {
+			return fmt.Errorf("upper bound: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r Range[T]) untypedRange() Range[interface{}] {
+	return Range[interface{}]{Lower: r.Lower, Upper: r.Upper, LowerBound: r.LowerBound, UpperBound: r.UpperBound, Empty: r.Empty}
+}
+
+// convert allows scanning a range into a Range of another type
+func (r Range[T]) convert(t reflect.Type) (interface{}, error) {
+	p := reflect.New(t)
+	s, ok := p.Interface().(rangeSetter)
+	if !ok {
+		return nil, nil
+	}
+	err := s.setRange(r.untypedRange())
+	if err != nil {
+		return nil, err
+	}
+	return p.Elem().Interface(), nil
+}
+
+// Multirange is a multirange value, its ranges in ascending order
+//
+// Multiranges are decoded as a Multirange[interface{}], and are scanned into a Multirange or a slice
+// of Range of any type the values of their ranges are scanned into.
+type Multirange[T any] []Range[T]
+
+// convert allows scanning a multirange into a slice of ranges of another type
+func (mr Multirange[T]) convert(t reflect.Type) (interface{}, error) {
+	if t.Kind() != reflect.Slice || !reflect.PointerTo(t.Elem()).Implements(reflect.TypeOf((*rangeSetter)(nil)).Elem()) {
+		return nil, nil
+	}
+	d := reflect.MakeSlice(t, len(mr), len(mr))
+	for i, r := range mr {
+		err := d.Index(i).Addr().Interface().(rangeSetter).setRange(r.untypedRange())
+		if err != nil {
+			return nil, fmt.Errorf("range %d: %w", i+1, err)
+		}
+	}
+	return d.Interface(), nil
+}
+
+// RangeCodec converts ranges of ElementOID values to and from a Range[interface{}], whose bounds are
+// converted by the codec of ElementOID in the TypeMap
+//
+// Ranges of any type are encoded.
+type RangeCodec struct {
+	ElementOID int
+}
+
+// DecodeText decodes a range, empty or the bounds in brackets for inclusive bounds and in parentheses
+// for exclusive bounds, e.g. [1,5) or ["2024-01-01 00:00:00+00",). Missing bounds are unbounded.
+func (c RangeCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	p := rangeParser{src: string(src)}
+	r, err := p.parseRange()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("invalid range %q: unexpected text after the range", src)
+	}
+	return c.decodeBounds(m, r)
+}
+
+// decodeBounds decodes the text of the bounds of r
+func (c RangeCodec) decodeBounds(m *TypeMap, r Range[string]) (Range[interface{}], error) {
+	decoded := Range[interface{}]{LowerBound: r.LowerBound, UpperBound: r.UpperBound, Empty: r.Empty}
+	var err error
+	if r.LowerBound != Unbounded && !r.Empty {
+		decoded.Lower, err = m.decode(c.ElementOID, pgproto.FormatText, []byte(r.Lower))
+		if err != nil {
+			return decoded, fmt.Errorf("lower bound: %w", err)
+		}
+	}
+	if r.UpperBound != Unbounded && !r.Empty {
+		decoded.Upper, err = m.decode(c.ElementOID, pgproto.FormatText, []byte(r.Upper))
+		if err != nil {
+			return decoded, fmt.Errorf("upper bound: %w", err)
+		}
+	}
+	return decoded, nil
+}
+
+func (c RangeCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	r, ok := v.(ranger)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	return c.appendText(m, buf, r.untypedRange())
+}
+
+// appendText appends the text representation of r
+func (c RangeCodec) appendText(m *TypeMap, buf []byte, r Range[interface{}]) ([]byte, error) {
+	if r.Empty {
+		return append(buf, "empty"...), nil
+	}
+
+	if r.LowerBound == Inclusive {
+		buf = append(buf, '[')
+	} else {
+		buf = append(buf, '(')
+	}
+	var err error
+	if r.LowerBound != Unbounded {
+		buf, err = c.appendBound(m, buf, r.Lower)
+		if err != nil {
+			return nil, fmt.Errorf("lower bound: %w", err)
+		}
+	}
+	buf = append(buf, ',')
+	if r.UpperBound != Unbounded {
+		buf, err = c.appendBound(m, buf, r.Upper)
+		if err != nil {
+			return nil, fmt.Errorf("upper bound: %w", err)
+		}
+	}
+	if r.UpperBound == Inclusive {
+		return append(buf, ']'), nil
+	}
+	return append(buf, ')'), nil
+}
+
+// appendBound appends the text of a bound, quoted when it is empty or contains special characters
+func (c RangeCodec) appendBound(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	text, err := m.encode(c.ElementOID, pgproto.FormatText, v)
+	if err != nil {
+		return nil, err
+	}
+	if text == nil {
+		return nil, errors.New("bounds can not be NULL, use Unbounded")
+	}
+	if len(text) > 0 && !strings.ContainsAny(string(text), "\"\\()[], \t\n\r\v\f") {
+		return append(buf, text...), nil
+	}
+
+	// Quotes and backslashes are doubled in quoted bounds
+	buf = append(buf, '"')
+	for _, b := range text {
+		if b == '"' || b == '\\' {
+			buf = append(buf, b)
+		}
+		buf = append(buf, b)
+	}
+	return append(buf, '"'), nil
+}
+
+// DecodeBinary decodes the flags byte, followed by the 32 bit length and value of each bound which is
+// not unbounded
+func (c RangeCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	if len(src) == 0 {
+		return nil, errors.New("missing range flags")
+	}
+	flags := src[0]
+	src = src[1:]
+	if flags&rangeEmpty != 0 {
+		return Range[interface{}]{Empty: true}, nil
+	}
+
+	var r Range[interface{}]
+	var err error
+	if flags&(rangeLowerInf|rangeLowerNull) == 0 {
+		r.LowerBound = boundType(flags&rangeLowerInc != 0)
+		r.Lower, src, err = c.decodeBound(m, src)
+		if err != nil {
+			return nil, fmt.Errorf("lower bound: %w", err)
+		}
+	}
+	if flags&(rangeUpperInf|rangeUpperNull) == 0 {
+		r.UpperBound = boundType(flags&rangeUpperInc != 0)
+		r.Upper, src, err = c.decodeBound(m, src)
+		if err != nil {
+			return nil, fmt.Errorf("upper bound: %w", err)
+		}
+	}
+	if len(src) > 0 {
+		return nil, fmt.Errorf("%d unexpected bytes after the bounds", len(src))
+	}
+	return r, nil
+}
+
+// decodeBound decodes the length prefixed binary value of a bound, and returns the bytes following it
+func (c RangeCodec) decodeBound(m *TypeMap, src []byte) (interface{}, []byte, error) {
+	if len(src) < 4 {
+		return nil, nil, errors.New("bound is truncated")
+	}
+	length := int32(binary.BigEndian.Uint32(src))
+	src = src[4:]
+	if length < 0 || int(length) > len(src) {
+		return nil, nil, errors.New("bound is truncated")
+	}
+	v, err := m.decode(c.ElementOID, pgproto.FormatBinary, src[:length])
+	return v, src[length:], err
+}
+
+func (c RangeCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	r, ok := v.(ranger)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	return c.appendBinary(m, buf, r.untypedRange())
+}
+
+// appendBinary appends the binary representation of r
+func (c RangeCodec) appendBinary(m *TypeMap, buf []byte, r Range[interface{}]) ([]byte, error) {
+	if r.Empty {
+		return append(buf, rangeEmpty), nil
+	}
+
+	var flags byte
+	switch r.LowerBound {
+	case Unbounded:
+		flags |= rangeLowerInf
+	case Inclusive:
+		flags |= rangeLowerInc
+	}
+	switch r.UpperBound {
+	case Unbounded:
+		flags |= rangeUpperInf
+	case Inclusive:
+		flags |= rangeUpperInc
+	}
+	buf = append(buf, flags)
+
+	for i, bound := range []BoundType{r.LowerBound, r.UpperBound} {
+		if bound == Unbounded {
+			continue
+		}
+		v, name := r.Lower, "lower bound"
+		if i == 1 {
+			v, name = r.Upper, "upper bound"
+		}
+		value, err := m.encode(c.ElementOID, pgproto.FormatBinary, v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if value == nil {
+			return nil, fmt.Errorf("%s: bounds can not be NULL, use Unbounded", name)
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(value)))
+		buf = append(buf, value...)
+	}
+	return buf, nil
+}
+
+// boundType returns the type of a bound which is not unbounded
+func boundType(inclusive bool) BoundType {
+	if inclusive {
+		return Inclusive
+	}
+	return Exclusive
+}
+
+// MultirangeCodec converts multiranges of ElementOID values to and from a Multirange[interface{}]
+//
+// Multiranges and slices of ranges of any type are encoded.
+type MultirangeCodec struct {
+	ElementOID int
+}
+
+// DecodeText decodes the ranges of the multirange in curly braces, e.g. {[1,3),[5,7)}
+func (c MultirangeCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	p := rangeParser{src: string(src)}
+	p.skipSpace()
+	if !p.consume('{') {
+		return nil, fmt.Errorf("invalid multirange %q: expected '{'", src)
+	}
+
+	mr := Multirange[interface{}]{}
+	p.skipSpace()
+	if !p.consume('}') {
+		for {
+			r, err := p.parseRange()
+			if err != nil {
+				return nil, err
+			}
+			decoded, err := RangeCodec(c).decodeBounds(m, r)
+			if err != nil {
+				return nil, fmt.Errorf("range %d: %w", len(mr)+1, err)
+			}
+			mr = append(mr, decoded)
+
+			p.skipSpace()
+			if p.consume(',') {
+				continue
+			}
+			if p.consume('}') {
+				break
+			}
+			return nil, fmt.Errorf("invalid multirange %q: unexpected character at offset %d", src, p.pos)
+		}
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("invalid multirange %q: unexpected text after the multirange", src)
+	}
+	return mr, nil
+}
+
+func (c MultirangeCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	ranges, err := c.ranges(v)
+	if err != nil {
+		return nil, err
+	}
+	buf = append(buf, '{')
+	for i, r := range ranges {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf, err = RangeCodec(c).appendText(m, buf, r)
+		if err != nil {
+			return nil, fmt.Errorf("range %d: %w", i+1, err)
+		}
+	}
+	return append(buf, '}'), nil
+}
+
+// DecodeBinary decodes the number of ranges as a 32 bit integer, followed by the 32 bit length and
+// binary representation of each range
+func (c MultirangeCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	if len(src) < 4 {
+		return nil, fmt.Errorf("expected at least 4 bytes, got %d", len(src))
+	}
+	n := binary.BigEndian.Uint32(src)
+	src = src[4:]
+	// Each range takes at least 5 bytes
+	if int64(n) > int64(len(src)/5) {
+		return nil, fmt.Errorf("invalid number of ranges %d", n)
+	}
+
+	mr := make(Multirange[interface{}], 0, n)
+	for i := 0; i < int(n); i++ {
+		if len(src) < 4 {
+			return nil, errors.New("ranges are truncated")
+		}
+		length := int32(binary.BigEndian.Uint32(src))
+		src = src[4:]
+		if length < 0 || int(length) > len(src) {
+			return nil, errors.New("ranges are truncated")
+		}
+		r, err := RangeCodec(c).DecodeBinary(m, src[:length])
+		if err != nil {
+			return nil, fmt.Errorf("range %d: %w", i+1, err)
+		}
+		mr = append(mr, r.(Range[interface{}]))
+		src = src[length:]
+	}
+	if len(src) > 0 {
+		return nil, fmt.Errorf("%d unexpected bytes after the ranges", len(src))
+	}
+	return mr, nil
+}
+
+func (c MultirangeCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	ranges, err := c.ranges(v)
+	if err != nil {
+		return nil, err
+	}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(ranges)))
+	for i, r := range ranges {
+		start := len(buf)
+		buf = append(buf, 0, 0, 0, 0)
+		buf, err = RangeCodec(c).appendBinary(m, buf, r)
+		if err != nil {
+			return nil, fmt.Errorf("range %d: %w", i+1, err)
+		}
+		binary.BigEndian.PutUint32(buf[start:], uint32(len(buf)-start-4))
+	}
+	return buf, nil
+}
+
+// ranges converts v, a slice of ranges of any type, to a slice of ranges of interfaces
+func (MultirangeCodec) ranges(v interface{}) ([]Range[interface{}], error) {
+	r := reflect.ValueOf(v)
+	if r.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	ranges := make([]Range[interface{}], r.Len())
+	for i := range ranges {
+		e, ok := elem(r.Index(i)).Interface().(ranger)
+		if !ok {
+			return nil, fmt.Errorf("cannot encode %T", v)
+		}
+		ranges[i] = e.untypedRange()
+	}
+	return ranges, nil
+}
+
+// rangeParser parses the text representation of ranges
+type rangeParser struct {
+	src string
+	pos int
+}
+
+// consume skips the next byte when it is c, and returns whether it was
+func (p *rangeParser) consume(c byte) bool {
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// skipSpace skips the whitespace allowed around ranges
+func (p *rangeParser) skipSpace() {
+	for p.pos < len(p.src) && isArraySpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// parseRange parses a range, whose bounds are the text of their values
+func (p *rangeParser) parseRange() (Range[string], error) {
+	var r Range[string]
+	p.skipSpace()
+	if len(p.src)-p.pos >= 5 && strings.EqualFold(p.src[p.pos:p.pos+5], "empty") {
+		p.pos += 5
+		return Range[string]{Empty: true}, nil
+	}
+
+	switch {
+	case p.consume('['):
+		r.LowerBound = Inclusive
+	case p.consume('('):
+		r.LowerBound = Exclusive
+	default:
+		return r, fmt.Errorf("invalid range %q: expected '[' or '('", p.src)
+	}
+	lower, ok, err := p.parseBound()
+	if err != nil {
+		return r, err
+	}
+	if !ok {
+		r.LowerBound = Unbounded
+	}
+	if !p.consume(',') {
+		return r, fmt.Errorf("invalid range %q: expected ','", p.src)
+	}
+	upper, ok, err := p.parseBound()
+	if err != nil {
+		return r, err
+	}
+	switch {
+	case p.consume(']'):
+		r.UpperBound = Inclusive
+	case p.consume(')'):
+		r.UpperBound = Exclusive
+	default:
+		return r, fmt.Errorf("invalid range %q: expected ']' or ')'", p.src)
+	}
+	if !ok {
+		r.UpperBound = Unbounded
+	}
+	r.Lower, r.Upper = lower, upper
+	return r, nil
+}
+
+// parseBound parses the text of a bound, which may be quoted and whose characters may be escaped by a
+// backslash, and returns whether it is present
+func (p *rangeParser) parseBound() (string, bool, error) {
+	var b strings.Builder
+	present := false
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch c {
+		case ',', ')', ']':
+			return b.String(), present, nil
+		case '\\':
+			if p.pos+1 >= len(p.src) {
+				return "", false, fmt.Errorf("invalid range %q: unterminated escape", p.src)
+			}
+			b.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case '"':
+			// A doubled quote is a quote within a quoted bound
+			p.pos++
+			for {
+				if p.pos >= len(p.src) {
+					return "", false, fmt.Errorf("invalid range %q: unterminated quoted bound", p.src)
+				}
+				c = p.src[p.pos]
+				p.pos++
+				if c == '\\' && p.pos < len(p.src) {
+					c = p.src[p.pos]
+					p.pos++
+				} else if c == '"' {
+					if p.pos < len(p.src) && p.src[p.pos] == '"' {
+						p.pos++
+					} else {
+						break
+					}
+				}
+				b.WriteByte(c)
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+		present = true
+	}
+	return "", false, fmt.Errorf("invalid range %q: unterminated range", p.src)
+}
@@ -0,0 +1,199 @@
+package pgtype_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtype"
+	"github.com/stretchr/testify/suite"
+)
+
+type RangeTestSuite struct {
+	suite.Suite
+	m *pgtype.TypeMap
+}
+
+func TestRangeTestSuite(t *testing.T) {
+	suite.Run(t, new(RangeTestSuite))
+}
+
+func (s *RangeTestSuite) SetupTest() {
+	s.m = pgtype.NewTypeMap()
+}
+
+func (s *RangeTestSuite) Test_Formats() {
+	cases := []struct {
+		oid    int
+		text   string
+		binary []byte
+		value  interface{}
+	}{
+		{
+			pgtype.Int4RangeOID,
+			"[1,5)",
+			[]byte{0x02, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x05},
+			pgtype.Range[interface{}]{Lower: int32(1), Upper: int32(5), LowerBound: pgtype.Inclusive, UpperBound: pgtype.Exclusive},
+		},
+		{
+			pgtype.Int8RangeOID,
+			"(,5]",
+			[]byte{0x0c, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05},
+			pgtype.Range[interface{}]{Upper: int64(5), UpperBound: pgtype.Inclusive},
+		},
+		{
+			pgtype.Int8RangeOID,
+			"(,)",
+			[]byte{0x18},
+			pgtype.Range[interface{}]{},
+		},
+		{
+			pgtype.Int4RangeOID,
+			"empty",
+			[]byte{0x01},
+			pgtype.Range[interface{}]{Empty: true},
+		},
+		{
+			pgtype.Int4MultirangeOID,
+			"{[1,3),[5,7)}",
+			[]byte{
+				0x00, 0x00, 0x00, 0x02,
+				0x00, 0x00, 0x00, 0x11, 0x02, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x03,
+				0x00, 0x00, 0x00, 0x11, 0x02, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x07,
+			},
+			pgtype.Multirange[interface{}]{
+				{Lower: int32(1), Upper: int32(3), LowerBound: pgtype.Inclusive, UpperBound: pgtype.Exclusive},
+				{Lower: int32(5), Upper: int32(7), LowerBound: pgtype.Inclusive, UpperBound: pgtype.Exclusive},
+			},
+		},
+		{
+			pgtype.Int8MultirangeOID,
+			"{}",
+			[]byte{0x00, 0x00, 0x00, 0x00},
+			pgtype.Multirange[interface{}]{},
+		},
+	}
+
+	for _, c := range cases {
+		v, err := s.m.Decode(c.oid, pgproto.FormatText, []byte(c.text))
+		s.Nil(err, c.text)
+		s.Equal(c.value, v, c.text)
+		v, err = s.m.Decode(c.oid, pgproto.FormatBinary, c.binary)
+		s.Nil(err, c.text)
+		s.Equal(c.value, v, c.text)
+
+		encoded, err := s.m.Encode(c.oid, pgproto.FormatText, c.value)
+		s.Nil(err, c.text)
+		s.Equal(c.text, string(encoded))
+		encoded, err = s.m.Encode(c.oid, pgproto.FormatBinary, c.value)
+		s.Nil(err, c.text)
+		s.Equal(c.binary, encoded, c.text)
+	}
+}
+
+func (s *RangeTestSuite) Test_Text() {
+	// Bounds with special characters are quoted, doubling quotes and backslashes
+	c := pgtype.RangeCodec{ElementOID: pgtype.TextOID}
+	r := pgtype.Range[string]{Lower: `a"b`, Upper: `c\d`, LowerBound: pgtype.Exclusive, UpperBound: pgtype.Inclusive}
+	encoded, err := c.EncodeText(s.m, nil, r)
+	s.Nil(err)
+	s.Equal(`("a""b","c\\d"]`, string(encoded))
+	v, err := c.DecodeText(s.m, encoded)
+	s.Nil(err)
+	s.Equal(r.Lower, v.(pgtype.Range[interface{}]).Lower)
+	s.Equal(r.Upper, v.(pgtype.Range[interface{}]).Upper)
+
+	encoded, err = c.EncodeText(s.m, nil, pgtype.Range[string]{Lower: "", Upper: "a b", LowerBound: pgtype.Inclusive, UpperBound: pgtype.Exclusive})
+	s.Nil(err)
+	s.Equal(`["","a b")`, string(encoded))
+
+	// Quoted empty bounds are empty values rather than unbounded, and characters may be escaped
+	v, err = c.DecodeText(s.m, []byte(`["",a\,b)`))
+	s.Nil(err)
+	s.Equal(pgtype.Range[interface{}]{Lower: "", Upper: "a,b", LowerBound: pgtype.Inclusive, UpperBound: pgtype.Exclusive}, v)
+
+	for _, invalid := range []string{"", "[1,5", "[1;5)", "1,5)", "[1,5)x", "[a,5)", `["1,5)`, "{[1,3)"} {
+		_, err = s.m.Decode(pgtype.Int4RangeOID, pgproto.FormatText, []byte(invalid))
+		s.NotNil(err, invalid)
+	}
+	for _, invalid := range []string{"", "[1,3)", "{[1,3)", "{[1,3) [5,7)}", "{[1,3),}", "{[1,3)}x"} {
+		_, err = s.m.Decode(pgtype.Int4MultirangeOID, pgproto.FormatText, []byte(invalid))
+		s.NotNil(err, invalid)
+	}
+}
+
+func (s *RangeTestSuite) Test_Scan() {
+	var r pgtype.Range[int]
+	s.Nil(s.m.Scan(pgtype.Int8RangeOID, pgproto.FormatText, []byte("[10,20)"), &r))
+	s.Equal(pgtype.Range[int]{Lower: 10, Upper: 20, LowerBound: pgtype.Inclusive, UpperBound: pgtype.Exclusive}, r)
+
+	s.Nil(s.m.Scan(pgtype.Int8RangeOID, pgproto.FormatText, []byte("empty"), &r))
+	s.Equal(pgtype.Range[int]{Empty: true}, r)
+
+	var small pgtype.Range[int8]
+	s.NotNil(s.m.Scan(pgtype.Int8RangeOID, pgproto.FormatText, []byte("[10,200)"), &small))
+
+	s.m.SetParameter("TimeZone", "UTC")
+	var period *pgtype.Range[time.Time]
+	s.Nil(s.m.Scan(pgtype.TstzRangeOID, pgproto.FormatText, []byte(`["2024-01-01 09:00:00+00","2024-01-01 17:30:00+00")`), &period))
+	s.Equal(time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC), period.Lower)
+	s.Equal(time.Date(2024, time.January, 1, 17, 30, 0, 0, time.UTC), period.Upper)
+
+	var ranges []pgtype.Range[int]
+	s.Nil(s.m.Scan(pgtype.Int4MultirangeOID, pgproto.FormatText, []byte("{[1,3), [5,)}"), &ranges))
+	s.Equal([]pgtype.Range[int]{
+		{Lower: 1, Upper: 3, LowerBound: pgtype.Inclusive, UpperBound: pgtype.Exclusive},
+		{Lower: 5, LowerBound: pgtype.Inclusive},
+	}, ranges)
+
+	var mr pgtype.Multirange[int64]
+	s.Nil(s.m.Scan(pgtype.Int4MultirangeOID, pgproto.FormatText, []byte("{[1,3)}"), &mr))
+	s.Equal(pgtype.Multirange[int64]{{Lower: 1, Upper: 3, LowerBound: pgtype.Inclusive, UpperBound: pgtype.Exclusive}}, mr)
+
+	// Arrays of ranges
+	s.Nil(s.m.Scan(pgtype.Int4RangeArrayOID, pgproto.FormatText, []byte(`{"[1,3)",empty}`), &ranges))
+	s.Equal([]pgtype.Range[int]{{Lower: 1, Upper: 3, LowerBound: pgtype.Inclusive, UpperBound: pgtype.Exclusive}, {Empty: true}}, ranges)
+}
+
+func (s *RangeTestSuite) Test_Encode() {
+	day := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		oid      int
+		value    interface{}
+		expected string
+	}{
+		{pgtype.Int4RangeOID, pgtype.Range[int]{Lower: 1, Upper: 3, LowerBound: pgtype.Inclusive, UpperBound: pgtype.Inclusive}, "[1,3]"},
+		{pgtype.DateRangeOID, &pgtype.Range[time.Time]{Lower: day, LowerBound: pgtype.Inclusive}, "[2024-03-01,)"},
+		{pgtype.TsRangeOID, pgtype.Range[time.Time]{Lower: day, Upper: day.Add(time.Hour), LowerBound: pgtype.Exclusive, UpperBound: pgtype.Exclusive}, `("2024-03-01 00:00:00","2024-03-01 01:00:00")`},
+		{pgtype.NumRangeOID, pgtype.Range[string]{Lower: "1.5", Upper: "2.50", LowerBound: pgtype.Inclusive, UpperBound: pgtype.Exclusive}, "[1.5,2.50)"},
+		{pgtype.Int8MultirangeOID, []pgtype.Range[int]{{Lower: 1, Upper: 2, LowerBound: pgtype.Inclusive, UpperBound: pgtype.Exclusive}, {Empty: true}}, "{[1,2),empty}"},
+		{pgtype.Int8MultirangeOID, pgtype.Multirange[int]{}, "{}"},
+	}
+	for _, c := range cases {
+		encoded, err := s.m.Encode(c.oid, pgproto.FormatText, c.value)
+		s.Nil(err, c.value)
+		s.Equal(c.expected, string(encoded), c.value)
+
+		// The binary representation holds the same range
+		binary, err := s.m.Encode(c.oid, pgproto.FormatBinary, c.value)
+		s.Nil(err, c.value)
+		fromBinary, err := s.m.Decode(c.oid, pgproto.FormatBinary, binary)
+		s.Nil(err, c.value)
+		fromText, err := s.m.Decode(c.oid, pgproto.FormatText, encoded)
+		s.Nil(err, c.value)
+		s.Equal(fromText, fromBinary, c.value)
+	}
+
+	for _, invalid := range []interface{}{
+		42,
+		pgtype.Range[*int]{LowerBound: pgtype.Inclusive},
+		pgtype.Range[int64]{Lower: 1 << 40, LowerBound: pgtype.Inclusive},
+	} {
+		_, err := s.m.Encode(pgtype.Int4RangeOID, pgproto.FormatText, invalid)
+		s.NotNil(err, invalid)
+		_, err = s.m.Encode(pgtype.Int4RangeOID, pgproto.FormatBinary, invalid)
+		s.NotNil(err, invalid)
+	}
+	_, err := s.m.Encode(pgtype.Int4MultirangeOID, pgproto.FormatText, []int{1})
+	s.NotNil(err)
+}
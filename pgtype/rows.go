@@ -0,0 +1,124 @@
+package pgtype
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/c653labs/pgproto"
+)
+
+// RowScanner scans the DataRows of a query into Go values, converted by a TypeMap according to the
+// RowDescription preceding the rows
+//
+// Rows are scanned into structs, whose fields are matched with the columns by the name of their pg
+// tag, e.g. `pg:"column"`, or by their own name regardless of case and underscores, e.g. UserName for
+// user_name. Fields tagged `pg:"-"` are skipped and every column must have a field. The plan matching
+// the fields of a struct type with the columns is computed once. Rows are also scanned into a
+// []interface{} of their values in order, and into a map[string]interface{} of their values by
+// column name.
+//
+// A RowScanner may be used concurrently.
+type RowScanner struct {
+	m       *TypeMap
+	columns []pgproto.RowField
+
+	// plans are the indexes of the fields of each column by struct type
+	mu    sync.RWMutex
+	plans map[reflect.Type][][]int
+}
+
+// NewRowScanner returns a RowScanner of the rows described by description
+func NewRowScanner(m *TypeMap, description *pgproto.RowDescription) *RowScanner {
+	return &RowScanner{
+		m:       m,
+		columns: description.Fields,
+		plans:   make(map[reflect.Type][][]int),
+	}
+}
+
+// Columns returns the names of the columns of the rows
+func (s *RowScanner) Columns() []string {
+	names := make([]string, len(s.columns))
+	for i, c := range s.columns {
+		names[i] = string(c.ColumnName)
+	}
+	return names
+}
+
+// Scan stores the values of row in dst, a pointer to a struct, a []interface{} or a
+// map[string]interface{}
+//
+// Values are stored in struct fields as by TypeMap.Scan, and NULL only in the fields of a type which
+// can be nil, e.g. pointers. Values are decoded as by TypeMap.Decode in slices and maps.
+func (s *RowScanner) Scan(row *pgproto.DataRow, dst interface{}) error {
+	if len(row.Fields) != len(s.columns) {
+		return fmt.Errorf("pgtype: expected %d columns, got %d", len(s.columns), len(row.Fields))
+	}
+
+	switch d := dst.(type) {
+	case *[]interface{}:
+		values := make([]interface{}, len(s.columns))
+		for i, c := range s.columns {
+			v, err := s.m.decode(c.TypeOID, c.Format, row.Fields[i])
+			if err != nil {
+				return fmt.Errorf("pgtype: column %q: %w", c.ColumnName, err)
+			}
+			values[i] = v
+		}
+		*d = values
+		return nil
+	case *map[string]interface{}:
+		values := make(map[string]interface{}, len(s.columns))
+		for i, c := range s.columns {
+			v, err := s.m.decode(c.TypeOID, c.Format, row.Fields[i])
+			if err != nil {
+				return fmt.Errorf("pgtype: column %q: %w", c.ColumnName, err)
+			}
+			values[string(c.ColumnName)] = v
+		}
+		*d = values
+		return nil
+	}
+
+	r := reflect.ValueOf(dst)
+	if r.Kind() != reflect.Ptr || r.IsNil() || r.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("pgtype: cannot scan into %T, a pointer to a struct, a []interface{} or a map[string]interface{} is required", dst)
+	}
+	plan, err := s.plan(r.Elem().Type())
+	if err != nil {
+		return fmt.Errorf("pgtype: %w", err)
+	}
+	for i, c := range s.columns {
+		field := r.Elem().FieldByIndex(plan[i])
+		err = s.m.scan(c.TypeOID, c.Format, row.Fields[i], field.Addr().Interface())
+		if err != nil {
+			return fmt.Errorf("pgtype: column %q: %w", c.ColumnName, err)
+		}
+	}
+	return nil
+}
+
+// plan returns the indexes of the fields of the struct type t holding each column
+func (s *RowScanner) plan(t reflect.Type) ([][]int, error) {
+	s.mu.RLock()
+	plan, ok := s.plans[t]
+	s.mu.RUnlock()
+	if ok {
+		return plan, nil
+	}
+
+	fields := scannedFields(t)
+	plan = make([][]int, len(s.columns))
+	for i, c := range s.columns {
+		plan[i], ok = fieldByName(fields, string(c.ColumnName))
+		if !ok {
+			return nil, fmt.Errorf("%s has no field for the column %q", t, c.ColumnName)
+		}
+	}
+
+	s.mu.Lock()
+	s.plans[t] = plan
+	s.mu.Unlock()
+	return plan, nil
+}
@@ -0,0 +1,143 @@
+package pgtype_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtype"
+	"github.com/stretchr/testify/suite"
+)
+
+type RowsTestSuite struct {
+	suite.Suite
+	s *pgtype.RowScanner
+}
+
+func TestRowsTestSuite(t *testing.T) {
+	suite.Run(t, new(RowsTestSuite))
+}
+
+func (s *RowsTestSuite) SetupTest() {
+	s.s = pgtype.NewRowScanner(pgtype.NewTypeMap(), &pgproto.RowDescription{
+		Fields: []pgproto.RowField{
+			{ColumnName: []byte("id"), TypeOID: pgtype.Int4OID, Format: pgproto.FormatBinary},
+			{ColumnName: []byte("user_name"), TypeOID: pgtype.TextOID, Format: pgproto.FormatText},
+			{ColumnName: []byte("email"), TypeOID: pgtype.TextOID, Format: pgproto.FormatText},
+			{ColumnName: []byte("tags"), TypeOID: pgtype.TextArrayOID, Format: pgproto.FormatText},
+		},
+	})
+}
+
+// row returns a DataRow of the id, user_name, email and tags columns
+func row(email []byte) *pgproto.DataRow {
+	return &pgproto.DataRow{Fields: [][]byte{{0x00, 0x00, 0x00, 0x2a}, []byte("alice"), email, []byte("{a,b}")}}
+}
+
+type user struct {
+	ID    int64
+	Name  string  `pg:"user_name"`
+	Email *string `pg:"email"`
+	Tags  []string
+	Notes string `pg:"-"`
+}
+
+func (s *RowsTestSuite) Test_Struct() {
+	s.Equal([]string{"id", "user_name", "email", "tags"}, s.s.Columns())
+
+	var u user
+	s.Nil(s.s.Scan(row([]byte("alice@example.com")), &u))
+	email := "alice@example.com"
+	s.Equal(user{ID: 42, Name: "alice", Email: &email, Tags: []string{"a", "b"}}, u)
+
+	s.Nil(s.s.Scan(row(nil), &u))
+	s.Nil(u.Email)
+
+	// Fields of embedded structs are matched as the fields of the struct
+	type base struct {
+		ID int32
+	}
+	var embedded struct {
+		base
+		UserName string
+		Email    *string
+		Tags     []string
+	}
+	s.Nil(s.s.Scan(row(nil), &embedded))
+	s.Equal(int32(42), embedded.ID)
+	s.Equal("alice", embedded.UserName)
+
+	// Plans are shared by concurrent scans
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var u user
+			s.Nil(s.s.Scan(row(nil), &u))
+			s.Equal(int64(42), u.ID)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *RowsTestSuite) Test_Errors() {
+	var u user
+	err := s.s.Scan(&pgproto.DataRow{Fields: [][]byte{nil}}, &u)
+	s.EqualError(err, "pgtype: expected 4 columns, got 1")
+
+	// NULL can not be stored in a field which can not be nil
+	var notNull struct {
+		ID       int64
+		UserName string
+		Email    string
+		Tags     []string
+	}
+	err = s.s.Scan(row(nil), &notNull)
+	s.NotNil(err)
+	s.Contains(err.Error(), `column "email"`)
+	s.Contains(err.Error(), "use a pointer")
+
+	var mismatch struct {
+		ID       bool
+		UserName string
+		Email    *string
+		Tags     []string
+	}
+	err = s.s.Scan(row(nil), &mismatch)
+	s.NotNil(err)
+	s.Contains(err.Error(), `column "id"`)
+
+	var missing struct {
+		ID   int64
+		Name string
+	}
+	err = s.s.Scan(row(nil), &missing)
+	s.NotNil(err)
+	s.Contains(err.Error(), `no field for the column "user_name"`)
+
+	for _, dst := range []interface{}{u, nil, new(int), (*user)(nil)} {
+		s.NotNil(s.s.Scan(row(nil), dst), dst)
+	}
+	s.NotNil(s.s.Scan(&pgproto.DataRow{Fields: [][]byte{{0x00}, nil, nil, nil}}, &[]interface{}{}))
+}
+
+func (s *RowsTestSuite) Test_Values() {
+	var values []interface{}
+	s.Nil(s.s.Scan(row(nil), &values))
+	s.Equal([]interface{}{
+		int32(42),
+		"alice",
+		nil,
+		pgtype.Array{Elements: []interface{}{"a", "b"}, Dimensions: []pgtype.ArrayDimension{{Length: 2, LowerBound: 1}}},
+	}, values)
+
+	var m map[string]interface{}
+	s.Nil(s.s.Scan(row([]byte("alice@example.com")), &m))
+	s.Equal(map[string]interface{}{
+		"id":        int32(42),
+		"user_name": "alice",
+		"email":     "alice@example.com",
+		"tags":      pgtype.Array{Elements: []interface{}{"a", "b"}, Dimensions: []pgtype.ArrayDimension{{Length: 2, LowerBound: 1}}},
+	}, m)
+}
@@ -0,0 +1,113 @@
+package pgtype
+
+import (
+	"fmt"
+	"math"
+)
+
+// TextCodec converts text, varchar, bpchar and name values to and from a Go string
+//
+// Strings and byte slices are encoded.
+type TextCodec struct{}
+
+func (TextCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	return string(src), nil
+}
+
+func (TextCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	s, ok := toString(v)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	return append(buf, s...), nil
+}
+
+// DecodeBinary decodes the string, the binary representation is the same as the text representation
+func (c TextCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	return c.DecodeText(m, src)
+}
+
+func (c TextCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	return c.EncodeText(m, buf, v)
+}
+
+// CharCodec converts "char" values, a single byte, to and from a Go byte
+//
+// Integers from 0 to 255 and strings of at most one byte are encoded, an empty string is the zero
+// byte.
+type CharCodec struct{}
+
+// DecodeText decodes the single byte, bytes with the high bit set are sent as an octal escape, e.g. \377
+func (CharCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	switch {
+	case len(src) == 0:
+		return byte(0), nil
+	case len(src) == 4 && src[0] == '\\' && isOctal(src[1:]):
+		return byte((src[1]-'0')<<6 | (src[2]-'0')<<3 | (src[3] - '0')), nil
+	}
+	return src[0], nil
+}
+
+func (cc CharCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	c, err := cc.byte(v)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c == 0:
+		return buf, nil
+	case c&0x80 != 0:
+		return append(buf, '\\', '0'+c>>6, '0'+c>>3&7, '0'+c&7), nil
+	}
+	return append(buf, c), nil
+}
+
+// DecodeBinary decodes the single byte
+func (CharCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	err := checkSize(src, 1)
+	if err != nil {
+		return nil, err
+	}
+	return src[0], nil
+}
+
+func (cc CharCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	c, err := cc.byte(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, c), nil
+}
+
+// byte converts an integer or a string of at most one byte to a byte
+func (CharCodec) byte(v interface{}) (byte, error) {
+	if s, ok := toString(v); ok {
+		if len(s) > 1 {
+			return 0, fmt.Errorf("%q is longer than one byte", s)
+		}
+		if len(s) == 0 {
+			return 0, nil
+		}
+		return s[0], nil
+	}
+
+	i, ok := toInt64(v)
+	if !ok && !isInteger(kindOf(v)) {
+		return 0, fmt.Errorf("cannot encode %T", v)
+	}
+	if !ok || i < 0 || i > math.MaxUint8 {
+		return 0, fmt.Errorf("%v is out of range for \"char\"", v)
+	}
+	return byte(i), nil
+}
+
+// isOctal returns whether b only holds octal digits of a byte, its first digit is at most 3
+func isOctal(b []byte) bool {
+	for i, c := range b {
+		if c < '0' || c > '7' || (i == 0 && len(b) == 3 && c > '3') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package pgtype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// TimeTZ is a timetz value, a time of day with a time zone offset
+type TimeTZ struct {
+	// Time is the time since midnight
+	Time time.Duration
+	// Offset is the time zone offset in seconds east of UTC
+	Offset int
+}
+
+// TimeCodec converts time values to and from a time.Duration since midnight, and timetz values
+// WithTimeZone to and from a TimeTZ
+//
+// The wall clock of a time.Time is encoded, along with its offset WithTimeZone.
+type TimeCodec struct {
+	WithTimeZone bool
+}
+
+// DecodeText decodes a time of day, HH:MM:SS[.ffffff], followed WithTimeZone by an offset, e.g. -08
+// or +05:30
+func (c TimeCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	clock, zone, err := parseClock(string(src))
+	if err != nil {
+		return nil, err
+	}
+	if !c.WithTimeZone {
+		if zone != "" {
+			return nil, fmt.Errorf("invalid time %q", src)
+		}
+		return clock, nil
+	}
+
+	offset, ok := parseOffset(zone)
+	if !ok {
+		return nil, fmt.Errorf("invalid time zone offset %q", zone)
+	}
+	return TimeTZ{Time: clock, Offset: offset}, nil
+}
+
+func (c TimeCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	t, err := c.timeTZ(v)
+	if err != nil {
+		return nil, err
+	}
+	buf = appendClock(buf, t.Time)
+	if c.WithTimeZone {
+		buf = appendOffset(buf, t.Offset)
+	}
+	return buf, nil
+}
+
+// DecodeBinary decodes the microseconds since midnight as an int64, followed WithTimeZone by the
+// time zone offset in seconds west of UTC as an int32
+func (c TimeCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	size := 8
+	if c.WithTimeZone {
+		size = 12
+	}
+	err := checkSize(src, size)
+	if err != nil {
+		return nil, err
+	}
+
+	clock := time.Duration(binary.BigEndian.Uint64(src)) * time.Microsecond
+	if !c.WithTimeZone {
+		return clock, nil
+	}
+	return TimeTZ{Time: clock, Offset: -int(int32(binary.BigEndian.Uint32(src[8:])))}, nil
+}
+
+func (c TimeCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	t, err := c.timeTZ(v)
+	if err != nil {
+		return nil, err
+	}
+	buf = binary.BigEndian.AppendUint64(buf, uint64(t.Time/time.Microsecond))
+	if c.WithTimeZone {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(int32(-t.Offset)))
+	}
+	return buf, nil
+}
+
+// timeTZ converts v to a time of day rounded to microseconds, along with its offset
+func (c TimeCodec) timeTZ(v interface{}) (TimeTZ, error) {
+	var t TimeTZ
+	switch v := v.(type) {
+	case time.Duration:
+		if c.WithTimeZone {
+			return t, fmt.Errorf("cannot encode %T without a time zone offset", v)
+		}
+		t.Time = v
+	case TimeTZ:
+		if !c.WithTimeZone {
+			return t, fmt.Errorf("cannot encode %T", v)
+		}
+		t = v
+	case time.Time:
+		t.Time = clockOf(v)
+		_, t.Offset = v.Zone()
+	default:
+		return t, fmt.Errorf("cannot encode %T", v)
+	}
+
+	t.Time = t.Time.Round(time.Microsecond)
+	if t.Time < 0 || t.Time > 24*time.Hour {
+		return t, fmt.Errorf("%v is out of range", t.Time)
+	}
+	return t, nil
+}
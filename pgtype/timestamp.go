@@ -0,0 +1,112 @@
+package pgtype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// TimestampCodec converts timestamp and, WithTimeZone, timestamptz values to and from a time.Time,
+// infinite timestamps are decoded as an InfinityModifier
+//
+// Timestamps are decoded in UTC and timestamptz values in the TimeZone parameter, or their offset
+// when its location is unknown. The wall clock of a time.Time is encoded as a timestamp, and the
+// instant it represents as a timestamptz, along with InfinityModifier values.
+type TimestampCodec struct {
+	WithTimeZone bool
+}
+
+// DecodeText decodes a timestamp in any DateStyle, e.g. 1997-12-17 07:37:16-08 or
+// Wed Dec 17 07:37:16 1997 PST, followed by BC before the year 1
+func (c TimestampCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	if inf := infinity(string(src)); inf != 0 {
+		return inf, nil
+	}
+	dt, err := m.parseDateTime(string(src))
+	if err != nil {
+		return nil, err
+	}
+	if !c.WithTimeZone {
+		if dt.zone != "" {
+			return nil, fmt.Errorf("invalid timestamp %q", src)
+		}
+		return dt.in(time.UTC), nil
+	}
+	return m.zonedTime(dt)
+}
+
+// EncodeText encodes the timestamp in the ISO style, which is accepted whatever the DateStyle, a
+// timestamptz is encoded in UTC
+func (c TimestampCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	var t time.Time
+	switch v := v.(type) {
+	case InfinityModifier:
+		return append(buf, v.String()...), nil
+	case time.Time:
+		t = roundMicro(v)
+	default:
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+
+	if c.WithTimeZone {
+		t = t.UTC()
+	}
+	buf = appendDate(buf, t)
+	buf = append(buf, ' ')
+	buf = appendClock(buf, clockOf(t))
+	if c.WithTimeZone {
+		buf = append(buf, "+00"...)
+	}
+	return appendBC(buf, t), nil
+}
+
+// DecodeBinary decodes the microseconds since 2000-01-01 00:00:00 as an int64, the largest and
+// smallest int64 are infinity and -infinity
+func (c TimestampCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	err := checkSize(src, 8)
+	if err != nil {
+		return nil, err
+	}
+
+	micro := int64(binary.BigEndian.Uint64(src))
+	switch micro {
+	case math.MaxInt64:
+		return Infinity, nil
+	case math.MinInt64:
+		return NegativeInfinity, nil
+	}
+	seconds, micro := floorDiv(micro, 1e6)
+	t := time.Unix(postgresEpoch+seconds, micro*1e3).UTC()
+	if c.WithTimeZone {
+		if loc := m.timeLocation(); loc != nil {
+			t = t.In(loc)
+		}
+	}
+	return t, nil
+}
+
+func (c TimestampCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	var micro int64
+	switch v := v.(type) {
+	case InfinityModifier:
+		micro = math.MaxInt64
+		if v == NegativeInfinity {
+			micro = math.MinInt64
+		}
+	case time.Time:
+		t := roundMicro(v)
+		if !c.WithTimeZone {
+			// The wall clock is encoded whatever the location
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+		}
+		seconds := t.Unix() - postgresEpoch
+		if seconds > math.MaxInt64/1000000-1 || seconds < math.MinInt64/1000000+1 {
+			return nil, fmt.Errorf("%v is out of range", v)
+		}
+		micro = seconds*1e6 + int64(t.Nanosecond()/1e3)
+	default:
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	return binary.BigEndian.AppendUint64(buf, uint64(micro)), nil
+}
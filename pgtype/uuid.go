@@ -0,0 +1,118 @@
+package pgtype
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// UUID is a uuid value
+type UUID [16]byte
+
+// ParseUUID parses the representations of a UUID PostgreSQL accepts: 32 hex digits, optionally
+// surrounded by braces and with hyphens after any group of four digits
+//
+//   a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11
+//   {a0eebc99-9c0b4ef8-bb6d6bb9-bd380a11}
+//   a0eebc999c0b4ef8bb6d6bb9bd380a11
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	digits := s
+	if strings.HasPrefix(digits, "{") && strings.HasSuffix(digits, "}") {
+		digits = digits[1 : len(digits)-1]
+	}
+
+	b := make([]byte, 0, 32)
+	for i := 0; i < len(digits); i++ {
+		if digits[i] == '-' && len(b) > 0 && len(b)%4 == 0 && i+1 < len(digits) && digits[i+1] != '-' {
+			continue
+		}
+		b = append(b, digits[i])
+	}
+	if len(b) != 32 {
+		return u, fmt.Errorf("invalid uuid %q", s)
+	}
+	_, err := hex.Decode(u[:], b)
+	if err != nil {
+		return u, fmt.Errorf("invalid uuid %q", s)
+	}
+	return u, nil
+}
+
+// String returns the canonical representation of u, e.g. a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11
+func (u UUID) String() string {
+	b := make([]byte, 36)
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b)
+}
+
+// MarshalText implements encoding.TextMarshaler, encoding u in its canonical representation
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, decoding any representation ParseUUID accepts
+func (u *UUID) UnmarshalText(text []byte) error {
+	var err error
+	*u, err = ParseUUID(string(text))
+	return err
+}
+
+// UUIDCodec converts uuid values to and from a UUID
+//
+// UUIDs, arrays of 16 bytes and strings ParseUUID accepts are encoded.
+type UUIDCodec struct{}
+
+func (UUIDCodec) DecodeText(m *TypeMap, src []byte) (interface{}, error) {
+	return ParseUUID(string(src))
+}
+
+func (c UUIDCodec) EncodeText(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	u, err := c.uuid(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, u.String()...), nil
+}
+
+// DecodeBinary decodes the 16 bytes of the UUID
+func (UUIDCodec) DecodeBinary(m *TypeMap, src []byte) (interface{}, error) {
+	err := checkSize(src, 16)
+	if err != nil {
+		return nil, err
+	}
+	var u UUID
+	copy(u[:], src)
+	return u, nil
+}
+
+func (c UUIDCodec) EncodeBinary(m *TypeMap, buf []byte, v interface{}) ([]byte, error) {
+	u, err := c.uuid(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, u[:]...), nil
+}
+
+// uuid converts v to a UUID
+func (UUIDCodec) uuid(v interface{}) (UUID, error) {
+	if s, ok := v.(string); ok {
+		return ParseUUID(s)
+	}
+	r := reflect.ValueOf(v)
+	if r.Kind() == reflect.Array && r.Type().Elem().Kind() == reflect.Uint8 && r.Len() == 16 {
+		var u UUID
+		reflect.Copy(reflect.ValueOf(u[:]), r)
+		return u, nil
+	}
+	return UUID{}, fmt.Errorf("cannot encode %T", v)
+}
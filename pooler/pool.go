@@ -0,0 +1,174 @@
+package pooler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/internal/pgconn"
+)
+
+// errPoolClosed is returned when acquiring a backend after the Pooler has been closed
+var errPoolClosed = errors.New("pooler: pool closed")
+
+type poolKey struct {
+	database string
+	user     string
+
+	// options are the other run-time parameters of the clients' StartupMessage, see optionsKey
+	options string
+}
+
+// optionsKey returns a comparable representation of options, the same for equal maps
+func optionsKey(options map[string]string) string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var key strings.Builder
+	for _, name := range names {
+		key.WriteString(name)
+		key.WriteByte(0)
+		key.WriteString(options[name])
+		key.WriteByte(0)
+	}
+	return key.String()
+}
+
+// cancelKey is the BackendKeyData given to a client
+type cancelKey struct {
+	pid int
+	key int
+}
+
+// pool holds the backend connections for a single database, user and set of run-time parameters
+type pool struct {
+	config *pgconn.Config
+
+	// slots holds one entry per backend in use, since new backends are only connected when none are
+	// idle its capacity also limits the total number of backends
+	slots chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+	idle   []*backend
+	params map[string]string
+}
+
+func newPool(size int, config *pgconn.Config) *pool {
+	return &pool{
+		config: config,
+		slots:  make(chan struct{}, size),
+	}
+}
+
+// acquire returns an idle backend or connects a new one, waiting for a free slot when the pool is full
+func (p *pool) acquire(ctx context.Context) (*backend, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.slots
+		return nil, errPoolClosed
+	}
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return c, nil
+	}
+	p.mu.Unlock()
+
+	conn, err := pgconn.Connect(ctx, p.config)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	c := &backend{Conn: conn, prepared: make(map[string]bool)}
+
+	p.mu.Lock()
+	if p.params == nil {
+		p.params = make(map[string]string, len(c.Parameters))
+		for name, value := range c.Parameters {
+			p.params[name] = value
+		}
+	}
+	p.mu.Unlock()
+	return c, nil
+}
+
+// release returns c to the pool, running query first when it is not empty,
+// backends which are not healthy or fail to reset are closed instead
+func (p *pool) release(c *backend, healthy bool, query string) {
+	defer func() { <-p.slots }()
+
+	if healthy && query != "" {
+		_, err := c.Exec(query)
+		healthy = err == nil && c.Status == pgproto.READY_IDLE
+		c.forget(query)
+	}
+
+	p.mu.Lock()
+	if !healthy || p.closed {
+		p.mu.Unlock()
+		c.Close()
+		return
+	}
+	p.idle = append(p.idle, c)
+	p.mu.Unlock()
+}
+
+// parameters returns the ParameterStatus values reported by the first backend, connecting one if needed
+func (p *pool) parameters(ctx context.Context) (map[string]string, error) {
+	p.mu.Lock()
+	params := p.params
+	p.mu.Unlock()
+	if params != nil {
+		return params, nil
+	}
+
+	c, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.release(c, true, "")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.params, nil
+}
+
+func (p *pool) stats() (idle int, active int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle), len(p.slots)
+}
+
+func (p *pool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for _, c := range p.idle {
+		c.Close()
+	}
+	p.idle = nil
+}
+
+func randomInt() int {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return int(int32(binary.BigEndian.Uint32(b)))
+}
@@ -0,0 +1,340 @@
+/*
+Package pooler implements a PostgreSQL connection pooler built on pgproto.
+
+A Pooler authenticates clients itself, using the passwords in Users, and relays their messages to
+backend connections taken from a pool per database and user. The other run-time parameters of a
+client's StartupMessage, such as application_name, client_encoding, DateStyle or options, are sent
+in the StartupMessage of the backends, which are pooled separately for every set of parameters so
+clients get the session defaults they asked for. Replication connections are refused.
+
+In SessionMode a client keeps the same backend until it disconnects. In TransactionMode the backend
+is returned to the pool as soon as a ReadyForQuery reports the server is idle and every Sync and
+query sent by the client has been answered, so session state such as SET must not be relied upon.
+
+Named prepared statements do work in TransactionMode: every Parse is recorded for the client and sent
+to backends under a name derived from the statement, so identical statements share a name. Before a
+Bind or Describe uses a statement the backend has not prepared yet, the Parse is replayed and its
+ParseComplete is not relayed. Parse and Close messages for statements the backend already knows are
+answered by the pooler itself, and like the server, a Parse reusing the name of a statement the
+client has not closed fails with a duplicate prepared statement error.
+
+A DEALLOCATE query of a statement prepared by Parse is sent with its backend name, but the SQL EXECUTE
+command can not run such statements. Statements prepared by the SQL PREPARE command are session
+state, only usable until the backend is returned to the pool.
+
+Backends are reset with ResetQuery, "DISCARD ALL" by default, when a session mode client disconnects.
+Every client receives its own BackendKeyData and CancelRequests are routed to the backend currently
+assigned to that client.
+
+	p := &pooler.Pooler{
+		Upstream: "127.0.0.1:5432",
+		Mode:     pooler.TransactionMode,
+		Users:    map[string]string{"app": "secret"},
+	}
+	log.Fatal(p.ListenAndServe(":6432"))
+*/
+package pooler
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/internal/pgconn"
+)
+
+// ErrPoolerClosed is returned by Serve and ListenAndServe after Close has been called
+var ErrPoolerClosed = errors.New("pooler: Pooler closed")
+
+// DefaultPoolSize is the number of backend connections per database and user when PoolSize is not set
+const DefaultPoolSize = 10
+
+// DefaultResetQuery is used to reset backends when ResetQuery is not set
+const DefaultResetQuery = "DISCARD ALL"
+
+// Mode decides how long a client keeps its backend connection
+type Mode int
+
+const (
+	// SessionMode assigns a backend for the whole client connection
+	SessionMode Mode = iota
+	// TransactionMode assigns a backend for a single transaction
+	TransactionMode
+)
+
+func (m Mode) String() string {
+	switch m {
+	case SessionMode:
+		return "Session"
+	case TransactionMode:
+		return "Transaction"
+	}
+	return "Unknown"
+}
+
+// Pooler accepts client connections and shares a limited number of backend connections between them
+type Pooler struct {
+	// Upstream is the address of the PostgreSQL server to connect to
+	Upstream string
+
+	// Dial is used to connect to Upstream, defaults to net.Dialer.DialContext
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// TLSConfig, when set, is used to accept SSL connections from clients
+	TLSConfig *tls.Config
+
+	// UpstreamTLSConfig, when set, is used to require SSL on backend connections
+	UpstreamTLSConfig *tls.Config
+
+	// Mode decides when a backend is returned to the pool, defaults to SessionMode
+	Mode Mode
+
+	// Users maps user names to passwords, used both to authenticate clients and to connect backends,
+	// when nil every user is trusted and backends are connected without a password
+	Users map[string]string
+
+	// AuthMethod is the method used to authenticate clients, one of Plaintext, MD5 or SASL
+	// (SCRAM-SHA-256), defaults to MD5
+	AuthMethod pgproto.AuthenticationMethod
+
+	// PoolSize is the maximum number of backend connections per database and user, defaults to DefaultPoolSize
+	PoolSize int
+
+	// ResetQuery is run on a backend before it is reused by another session, defaults to DefaultResetQuery
+	ResetQuery string
+
+	// ResetAlways will also run ResetQuery after every transaction in TransactionMode
+	ResetAlways bool
+
+	// ErrorLog is used to log session errors, defaults to the log package's standard logger
+	ErrorLog *log.Logger
+
+	mu        sync.Mutex
+	closed    bool
+	listeners map[net.Listener]struct{}
+	sessions  map[*session]struct{}
+	pools     map[poolKey]*pool
+	cancels   map[cancelKey]*session
+}
+
+// ListenAndServe will listen on the TCP address addr and serve client connections
+func (p *Pooler) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return p.Serve(l)
+}
+
+// Serve will accept client connections on l, handling each in a new goroutine, until l fails or
+// the Pooler is closed
+func (p *Pooler) Serve(l net.Listener) error {
+	if !p.trackListener(l, true) {
+		return ErrPoolerClosed
+	}
+	defer p.trackListener(l, false)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if p.isClosed() {
+				return ErrPoolerClosed
+			}
+			return err
+		}
+
+		go func() {
+			err := p.ServeConn(conn)
+			if err != nil {
+				p.logf("pooler: session from %s: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// ServeConn will serve a single client connection until it disconnects
+func (p *Pooler) ServeConn(conn net.Conn) error {
+	s := newSession(p, conn)
+	if !p.trackSession(s, true) {
+		conn.Close()
+		return ErrPoolerClosed
+	}
+	defer p.trackSession(s, false)
+	defer s.close()
+
+	return s.run()
+}
+
+// Stats returns the number of idle and in use backend connections for database and user, summed
+// over the pools of every set of run-time parameters
+func (p *Pooler) Stats(database string, user string) (idle int, active int) {
+	p.mu.Lock()
+	var pools []*pool
+	for key, pl := range p.pools {
+		if key.database == database && key.user == user {
+			pools = append(pools, pl)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, pl := range pools {
+		i, a := pl.stats()
+		idle += i
+		active += a
+	}
+	return idle, active
+}
+
+// Close will stop all listeners, terminate all client sessions and close all backend connections
+func (p *Pooler) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for l := range p.listeners {
+		l.Close()
+	}
+	for s := range p.sessions {
+		s.close()
+	}
+	for _, pl := range p.pools {
+		pl.close()
+	}
+	return nil
+}
+
+// pool returns the pool of backends for database, user and the run-time parameters options,
+// creating it if needed
+func (p *Pooler) pool(database string, user string, options map[string]string) *pool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := poolKey{database: database, user: user, options: optionsKey(options)}
+	if pl, ok := p.pools[key]; ok {
+		return pl
+	}
+
+	size := p.PoolSize
+	if size <= 0 {
+		size = DefaultPoolSize
+	}
+	pl := newPool(size, &pgconn.Config{
+		Address:   p.Upstream,
+		Dial:      p.Dial,
+		TLSConfig: p.UpstreamTLSConfig,
+		User:      user,
+		Password:  p.Users[user],
+		Database:  database,
+		Options:   options,
+	})
+	if p.pools == nil {
+		p.pools = make(map[poolKey]*pool)
+	}
+	p.pools[key] = pl
+	return pl
+}
+
+// register will assign a unique cancel key to s, returning false when the Pooler is closed
+func (p *Pooler) register(s *session) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return false
+	}
+	if p.cancels == nil {
+		p.cancels = make(map[cancelKey]*session)
+	}
+	for {
+		key := cancelKey{pid: randomInt(), key: randomInt()}
+		if _, ok := p.cancels[key]; !ok && key.pid > 0 {
+			s.key = key
+			p.cancels[key] = s
+			return true
+		}
+	}
+}
+
+// cancel will forward req to the backend assigned to the session it was issued for, if any
+func (p *Pooler) cancel(req *pgproto.CancelRequest) error {
+	p.mu.Lock()
+	s, ok := p.cancels[cancelKey{pid: req.PID, key: req.Key}]
+	p.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	b := s.currentBackend()
+	if b == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return b.Cancel(ctx)
+}
+
+func (p *Pooler) resetQuery() string {
+	if p.ResetQuery != "" {
+		return p.ResetQuery
+	}
+	return DefaultResetQuery
+}
+
+func (p *Pooler) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+func (p *Pooler) trackListener(l net.Listener, add bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !add {
+		delete(p.listeners, l)
+		return true
+	}
+	if p.closed {
+		return false
+	}
+	if p.listeners == nil {
+		p.listeners = make(map[net.Listener]struct{})
+	}
+	p.listeners[l] = struct{}{}
+	return true
+}
+
+func (p *Pooler) trackSession(s *session, add bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !add {
+		delete(p.sessions, s)
+		if p.cancels[s.key] == s {
+			delete(p.cancels, s.key)
+		}
+		return true
+	}
+	if p.closed {
+		return false
+	}
+	if p.sessions == nil {
+		p.sessions = make(map[*session]struct{})
+	}
+	p.sessions[s] = struct{}{}
+	return true
+}
+
+func (p *Pooler) logf(format string, args ...interface{}) {
+	if p.ErrorLog != nil {
+		p.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
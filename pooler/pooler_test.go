@@ -0,0 +1,437 @@
+package pooler_test
+
+import (
+	"io"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtest"
+	"github.com/c653labs/pgproto/pooler"
+	"github.com/stretchr/testify/suite"
+)
+
+var options = map[string]string{
+	"user":     "pgproto",
+	"database": "db_name",
+}
+
+var responses = pgtest.Responses{
+	"SELECT 1": {
+		Fields: []pgproto.RowField{{ColumnName: []byte("?column?"), TypeOID: 23, ColumnLength: 4}},
+		Rows:   [][][]byte{{[]byte("1")}},
+	},
+	"SELECT $1": {
+		Fields:        []pgproto.RowField{{ColumnName: []byte("?column?"), TypeOID: 25, ColumnLength: -1}},
+		Rows:          [][][]byte{{[]byte("value")}},
+		ParameterOIDs: []int{25},
+	},
+}
+
+type PoolerTestSuite struct {
+	suite.Suite
+	upstream *pgtest.Server
+	pooler   *pooler.Pooler
+	addr     string
+}
+
+func TestPoolerTestSuite(t *testing.T) {
+	suite.Run(t, new(PoolerTestSuite))
+}
+
+func (s *PoolerTestSuite) SetupTest() {
+	s.upstream = pgtest.NewUnstartedServer(pgtest.HandlerFunc(func(c *pgtest.Conn, query string, params [][]byte) (*pgtest.Result, error) {
+		if query == "SELECT pg_sleep(10)" {
+			select {
+			case <-c.Canceled():
+				return nil, pgtest.ErrQueryCanceled
+			case <-time.After(10 * time.Second):
+				return &pgtest.Result{}, nil
+			}
+		}
+		return responses.Query(c, query, params)
+	}))
+	s.upstream.Users = map[string]string{"pgproto": "secret"}
+	s.upstream.Start()
+
+	s.pooler = &pooler.Pooler{
+		Upstream: s.upstream.Addr,
+		Users:    map[string]string{"pgproto": "secret"},
+		PoolSize: 1,
+		ErrorLog: log.New(io.Discard, "", 0),
+	}
+}
+
+func (s *PoolerTestSuite) TearDownTest() {
+	s.pooler.Close()
+	s.upstream.Close()
+}
+
+func (s *PoolerTestSuite) start() {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().Nil(err)
+	s.addr = l.Addr().String()
+	go s.pooler.Serve(l)
+}
+
+func (s *PoolerTestSuite) connect() *pgtest.Client {
+	client, err := pgtest.Connect(s.addr, options, "secret")
+	s.Require().Nil(err)
+	return client
+}
+
+// query runs query and returns the status of the final ReadyForQuery
+func (s *PoolerTestSuite) query(client *pgtest.Client, query string) pgproto.ReadyStatus {
+	msgs, err := client.Query(query)
+	s.Require().Nil(err)
+	s.Require().NotEmpty(msgs)
+	return msgs[len(msgs)-1].(*pgproto.ReadyForQuery).Status
+}
+
+// waitIdle waits until every backend has been returned to the pool
+func (s *PoolerTestSuite) waitIdle(idle int) {
+	s.True(pgtest.Eventually(func() bool {
+		i, active := s.pooler.Stats("db_name", "pgproto")
+		return i == idle && active == 0
+	}, time.Second))
+}
+
+func (s *PoolerTestSuite) Test_Startup() {
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	s.Equal("UTF8", client.Parameters["client_encoding"])
+	s.NotZero(client.PID)
+
+	// The client gets its own key data rather than the backend's
+	conns := s.upstream.Conns()
+	s.Require().Len(conns, 1)
+	s.NotEqual(conns[0].Key, client.Key)
+}
+
+func (s *PoolerTestSuite) Test_StartupParameters() {
+	s.start()
+	connect := func(applicationName string) *pgtest.Client {
+		client, err := pgtest.Connect(s.addr, map[string]string{
+			"user":             "pgproto",
+			"database":         "db_name",
+			"application_name": applicationName,
+			"datestyle":        "ISO, DMY",
+		}, "secret")
+		s.Require().Nil(err)
+		return client
+	}
+
+	// Run-time parameters are sent to the backends, which are pooled separately for each set
+	first := connect("first")
+	defer first.Close()
+	second := connect("second")
+	defer second.Close()
+
+	conns := s.upstream.Conns()
+	s.Require().Len(conns, 2)
+	names := []string{}
+	for _, c := range conns {
+		s.Equal("ISO, DMY", string(c.Startup.Options["datestyle"]))
+		names = append(names, string(c.Startup.Options["application_name"]))
+	}
+	s.ElementsMatch([]string{"first", "second"}, names)
+
+	s.Equal(pgproto.READY_IDLE, s.query(first, "SELECT 1"))
+	s.Equal(pgproto.READY_IDLE, s.query(second, "SELECT 1"))
+	s.Len(s.upstream.Conns(), 2)
+	first.Close()
+	second.Close()
+	s.waitIdle(2)
+
+	// Replication connections are refused
+	client, err := pgtest.Connect(s.addr, map[string]string{"user": "pgproto", "replication": "database"}, "secret")
+	s.NotNil(err)
+	s.Nil(client)
+}
+
+func (s *PoolerTestSuite) Test_AuthenticationFailed() {
+	s.start()
+	client, err := pgtest.Connect(s.addr, options, "wrong")
+	s.NotNil(err)
+	s.Nil(client)
+
+	client, err = pgtest.Connect(s.addr, map[string]string{"user": "unknown"}, "secret")
+	s.NotNil(err)
+	s.Nil(client)
+}
+
+func (s *PoolerTestSuite) Test_SCRAM() {
+	s.upstream.Close()
+	s.upstream = pgtest.NewUnstartedServer(responses)
+	s.upstream.Users = map[string]string{"pgproto": "secret"}
+	s.upstream.AuthMethod = pgproto.AuthenticationMethodSASL
+	s.upstream.Start()
+
+	s.pooler.Upstream = s.upstream.Addr
+	s.pooler.AuthMethod = pgproto.AuthenticationMethodSASL
+	s.start()
+
+	client := s.connect()
+	defer client.Close()
+	s.Equal(pgproto.READY_IDLE, s.query(client, "SELECT 1"))
+
+	_, err := pgtest.Connect(s.addr, options, "wrong")
+	s.NotNil(err)
+}
+
+func (s *PoolerTestSuite) Test_UpstreamUnavailable() {
+	s.pooler.Upstream = "127.0.0.1:1"
+	s.start()
+
+	client, err := pgtest.Connect(s.addr, options, "secret")
+	s.NotNil(err)
+	s.Nil(client)
+}
+
+func (s *PoolerTestSuite) Test_SessionMode() {
+	s.start()
+	client := s.connect()
+
+	msgs, err := client.Query("SELECT 1")
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 4)
+	s.Equal(&pgproto.DataRow{Fields: [][]byte{[]byte("1")}}, msgs[1])
+
+	// The backend is kept for the whole session
+	_, active := s.pooler.Stats("db_name", "pgproto")
+	s.Equal(1, active)
+
+	client.Close()
+	s.waitIdle(1)
+
+	// The backend was reset and is reused by the next client
+	conns := s.upstream.Conns()
+	s.Require().Len(conns, 1)
+	s.Equal([]string{"SELECT 1", "DISCARD ALL"}, conns[0].Queries())
+
+	client = s.connect()
+	defer client.Close()
+	s.query(client, "SELECT 1")
+	s.Len(s.upstream.Conns(), 1)
+}
+
+func (s *PoolerTestSuite) Test_SessionMode_PoolExhausted() {
+	s.start()
+	first := s.connect()
+	s.query(first, "SELECT 1")
+
+	second := s.connect()
+	defer second.Close()
+	err := second.Send(&pgproto.SimpleQuery{Query: []byte("SELECT 1")})
+	s.Require().Nil(err)
+
+	// The second client waits until the first one disconnects
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		second.ReceiveUntilReady()
+	}()
+	select {
+	case <-done:
+		s.Fail("query completed while the pool was exhausted")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		s.Fail("query did not complete once a backend was released")
+	}
+}
+
+func (s *PoolerTestSuite) Test_TransactionMode() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.start()
+	first := s.connect()
+	defer first.Close()
+	second := s.connect()
+	defer second.Close()
+
+	// Both clients share the single backend between transactions
+	s.Equal(pgproto.READY_IDLE, s.query(first, "SELECT 1"))
+	s.waitIdle(1)
+	s.Equal(pgproto.READY_IDLE, s.query(second, "SELECT 1"))
+	s.waitIdle(1)
+
+	// A backend in a transaction stays assigned until the transaction ends
+	s.Equal(pgproto.READY_TRANSACTION, s.query(first, "BEGIN"))
+	_, active := s.pooler.Stats("db_name", "pgproto")
+	s.Equal(1, active)
+
+	err := second.Send(&pgproto.SimpleQuery{Query: []byte("SELECT 1")})
+	s.Require().Nil(err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		second.ReceiveUntilReady()
+	}()
+
+	s.Equal(pgproto.READY_TRANSACTION, s.query(first, "SELECT 1"))
+	select {
+	case <-done:
+		s.Fail("query completed while the backend was in a transaction")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.Equal(pgproto.READY_IDLE, s.query(first, "COMMIT"))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		s.Fail("query did not complete once the transaction ended")
+	}
+
+	// Backends are not reset between transactions
+	conns := s.upstream.Conns()
+	s.Require().Len(conns, 1)
+	s.NotContains(conns[0].Queries(), "DISCARD ALL")
+}
+
+func (s *PoolerTestSuite) Test_TransactionMode_FailedTransaction() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	s.Equal(pgproto.READY_TRANSACTION, s.query(client, "BEGIN"))
+	s.Equal(pgproto.READY_ERROR, s.query(client, "SELECT 2"))
+
+	_, active := s.pooler.Stats("db_name", "pgproto")
+	s.Equal(1, active)
+
+	s.Equal(pgproto.READY_IDLE, s.query(client, "ROLLBACK"))
+	s.waitIdle(1)
+}
+
+func (s *PoolerTestSuite) Test_TransactionMode_ResetAlways() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.pooler.ResetAlways = true
+	s.pooler.ResetQuery = "RESET ALL"
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	s.query(client, "SELECT 1")
+	s.waitIdle(1)
+
+	conns := s.upstream.Conns()
+	s.Require().Len(conns, 1)
+	s.Equal([]string{"SELECT 1", "RESET ALL"}, conns[0].Queries())
+}
+
+func (s *PoolerTestSuite) Test_TransactionMode_ExtendedQuery() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	// The backend is kept until the Sync is answered
+	err := client.Send(
+		&pgproto.Parse{Query: []byte("SELECT $1")},
+		&pgproto.Bind{Parameters: [][]byte{[]byte("value")}},
+		&pgproto.Execute{},
+	)
+	s.Require().Nil(err)
+	time.Sleep(20 * time.Millisecond)
+	_, active := s.pooler.Stats("db_name", "pgproto")
+	s.Equal(1, active)
+
+	err = client.Send(&pgproto.Sync{})
+	s.Require().Nil(err)
+	msgs, err := client.ReceiveUntilReady()
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 5)
+	s.IsType(&pgproto.ParseComplete{}, msgs[0])
+	s.IsType(&pgproto.BindComplete{}, msgs[1])
+	s.Equal(&pgproto.DataRow{Fields: [][]byte{[]byte("value")}}, msgs[2])
+	s.IsType(&pgproto.CommandCompletion{}, msgs[3])
+	s.IsType(&pgproto.ReadyForQuery{}, msgs[4])
+	s.waitIdle(1)
+}
+
+func (s *PoolerTestSuite) Test_TransactionMode_Pipeline() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	err := client.Send(
+		&pgproto.SimpleQuery{Query: []byte("SELECT 1")},
+		&pgproto.SimpleQuery{Query: []byte("SELECT 1")},
+		&pgproto.SimpleQuery{Query: []byte("SELECT 1")},
+	)
+	s.Require().Nil(err)
+	for i := 0; i < 3; i++ {
+		msgs, err := client.ReceiveUntilReady()
+		s.Require().Nil(err)
+		s.Len(msgs, 4)
+	}
+	s.waitIdle(1)
+}
+
+func (s *PoolerTestSuite) Test_DisconnectInTransaction() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.start()
+	client := s.connect()
+
+	s.Equal(pgproto.READY_TRANSACTION, s.query(client, "BEGIN"))
+	client.Close()
+
+	// A backend left in a transaction can not be reused
+	s.waitIdle(0)
+	s.True(pgtest.Eventually(func() bool {
+		return len(s.upstream.Conns()) == 0
+	}, time.Second))
+}
+
+func (s *PoolerTestSuite) Test_CancelRequest() {
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	err := client.Send(&pgproto.SimpleQuery{Query: []byte("SELECT pg_sleep(10)")})
+	s.Require().Nil(err)
+	s.True(pgtest.Eventually(func() bool {
+		conns := s.upstream.Conns()
+		return len(conns) == 1 && len(conns[0].Queries()) == 1
+	}, time.Second))
+
+	conn, err := net.Dial("tcp", s.addr)
+	s.Require().Nil(err)
+	defer conn.Close()
+	_, err = pgproto.WriteMessage(&pgproto.CancelRequest{PID: client.PID, Key: client.Key}, conn)
+	s.Require().Nil(err)
+
+	msgs, err := client.ReceiveUntilReady()
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 2)
+	s.Equal([]byte("57014"), msgs[0].(*pgproto.Error).Code)
+}
+
+func (s *PoolerTestSuite) Test_CancelRequest_UnknownKey() {
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	conn, err := net.Dial("tcp", s.addr)
+	s.Require().Nil(err)
+	defer conn.Close()
+	_, err = pgproto.WriteMessage(&pgproto.CancelRequest{PID: client.PID, Key: client.Key + 1}, conn)
+	s.Require().Nil(err)
+
+	// The pooler closes the connection without a response
+	var b [1]byte
+	_, err = conn.Read(b[:])
+	s.Equal(io.EOF, err)
+
+	s.Equal(pgproto.READY_IDLE, s.query(client, "SELECT 1"))
+}
@@ -0,0 +1,443 @@
+package pooler
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/internal/scram"
+)
+
+var (
+	// errAuthenticationFailed is returned when a client fails to authenticate
+	errAuthenticationFailed = errors.New("pooler: authentication failed")
+
+	// errReplication is returned when a client requests a replication connection
+	errReplication = errors.New("pooler: replication connections are not supported")
+)
+
+// session is a single client connection
+type session struct {
+	pooler *Pooler
+	key    cancelKey
+	pool   *pool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	conn net.Conn
+	r    *bufio.Reader
+	wmu  sync.Mutex
+	w    *bufio.Writer
+
+	// The backend currently assigned and the state of the exchange with it, the client goroutine
+	// assigns backends while the goroutine relaying backend messages releases them
+	mu      sync.Mutex
+	backend *backend
+	status  pgproto.ReadyStatus
+	pending int  // SimpleQuery and Sync messages not yet answered with ReadyForQuery
+	dirty   bool // extended query messages have been sent since the last Sync
+	closing bool
+
+	// statements is only used in TransactionMode
+	statements *statementTracker
+}
+
+func newSession(p *Pooler, conn net.Conn) *session {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &session{
+		pooler: p,
+		ctx:    ctx,
+		cancel: cancel,
+		conn:   conn,
+	}
+	if p.Mode == TransactionMode {
+		s.statements = newStatementTracker()
+	}
+	return s
+}
+
+func (s *session) run() error {
+	conn, msg, err := pgproto.AcceptSSL(s.conn, s.pooler.TLSConfig)
+	if err != nil {
+		return err
+	}
+	startup, ok := msg.(*pgproto.StartupMessage)
+	if !ok {
+		// Cancel requests are sent on their own connection and get no response
+		return s.pooler.cancel(msg.(*pgproto.CancelRequest))
+	}
+	s.r = bufio.NewReader(conn)
+	s.w = bufio.NewWriter(conn)
+
+	user := string(startup.Options["user"])
+	database := user
+	options := make(map[string]string)
+	for name, value := range startup.Options {
+		switch name {
+		case "user":
+		case "database":
+			database = string(value)
+		case "replication":
+			s.sendFatal("0A000", "replication connections are not supported by the pooler")
+			return errReplication
+		default:
+			options[name] = string(value)
+		}
+	}
+
+	if !s.authenticate(user) {
+		s.sendFatal("28P01", fmt.Sprintf("password authentication failed for user \"%s\"", user))
+		return errAuthenticationFailed
+	}
+
+	s.pool = s.pooler.pool(database, user, options)
+	params, err := s.pool.parameters(s.ctx)
+	if err != nil {
+		s.sendFatal("08006", "could not connect to upstream server")
+		return err
+	}
+	if !s.pooler.register(s) {
+		return ErrPoolerClosed
+	}
+
+	s.send(&pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodOK})
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.send(&pgproto.ParameterStatus{Name: []byte(name), Value: []byte(params[name])})
+	}
+	s.send(&pgproto.BackendKeyData{PID: s.key.pid, Key: s.key.key})
+	s.send(&pgproto.ReadyForQuery{Status: pgproto.READY_IDLE})
+	err = s.flush()
+	if err != nil {
+		return err
+	}
+
+	err = s.relayClient()
+	s.detach()
+	if err == io.EOF || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// authenticate the client using the configured method, reporting whether it may continue
+func (s *session) authenticate(user string) bool {
+	if s.pooler.Users == nil {
+		return true
+	}
+	password, ok := s.pooler.Users[user]
+
+	switch s.pooler.AuthMethod {
+	case pgproto.AuthenticationMethodSASL:
+		req := &pgproto.AuthenticationRequest{
+			Method:     pgproto.AuthenticationMethodSASL,
+			Mechanisms: [][]byte{[]byte(scram.Mechanism)},
+		}
+		s.send(req)
+		if s.flush() != nil {
+			return false
+		}
+		msg, err := pgproto.ParseAuthenticationResponse(s.r, req)
+		if err != nil {
+			return false
+		}
+		initial, isInitial := msg.(*pgproto.SASLInitialResponse)
+		if !isInitial || string(initial.Mechanism) != scram.Mechanism {
+			return false
+		}
+
+		server := scram.NewServer(password)
+		data, err := server.First(initial.Data)
+		if err != nil {
+			return false
+		}
+		req = &pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodSASLContinue, Data: data}
+		s.send(req)
+		if s.flush() != nil {
+			return false
+		}
+		msg, err = pgproto.ParseAuthenticationResponse(s.r, req)
+		if err != nil {
+			return false
+		}
+		resp, isResponse := msg.(*pgproto.SASLResponse)
+		if !isResponse {
+			return false
+		}
+		data, err = server.Final(resp.Data)
+		if err != nil || !ok {
+			return false
+		}
+		s.send(&pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodSASLFinal, Data: data})
+		return true
+	case pgproto.AuthenticationMethodPlaintext:
+		s.send(&pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodPlaintext})
+		if s.flush() != nil {
+			return false
+		}
+		msg, err := pgproto.ParsePasswordMessage(s.r)
+		if err != nil {
+			return false
+		}
+		return ok && string(msg.Password) == password
+	default:
+		salt := make([]byte, 4)
+		rand.Read(salt)
+		s.send(&pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodMD5, Salt: salt})
+		if s.flush() != nil {
+			return false
+		}
+		msg, err := pgproto.ParsePasswordMessage(s.r)
+		if err != nil {
+			return false
+		}
+		return ok && msg.PasswordValid([]byte(user), []byte(password), salt)
+	}
+}
+
+// relayClient forwards client messages to the assigned backend, assigning one when needed
+func (s *session) relayClient() error {
+	for {
+		msg, err := pgproto.ParseClientMessage(s.r)
+		if err != nil {
+			return err
+		}
+		if _, ok := msg.(*pgproto.Termination); ok {
+			return nil
+		}
+
+		b, msgs, answers, err := s.assign(msg)
+		if err != nil {
+			s.sendFatal("08006", "could not connect to upstream server")
+			return err
+		}
+
+		for _, m := range msgs {
+			err = b.Write(m)
+			if err != nil {
+				return err
+			}
+		}
+		for _, m := range answers {
+			err = s.write(m, false)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Only flush once the client has nothing more for us
+		if s.r.Buffered() == 0 {
+			err = b.Flush()
+			if err != nil {
+				return err
+			}
+			if len(answers) > 0 {
+				err = s.flush()
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// assign returns the backend msg should be sent to, acquiring one from the pool when none is assigned,
+// and records msg so the backend is only released once the exchange it starts is complete
+//
+// The messages to write to the backend are returned, which in TransactionMode may differ from msg to
+// prepare the client's named statements on the backend, along with the messages answered by the
+// pooler which are due to the client right away
+func (s *session) assign(msg pgproto.ClientMessage) (*backend, []pgproto.ClientMessage, []pgproto.ServerMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.backend == nil {
+		s.mu.Unlock()
+		b, err := s.pool.acquire(s.ctx)
+		s.mu.Lock()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		s.backend = b
+		s.status = pgproto.READY_IDLE
+		go s.relayServer(b)
+	}
+
+	switch msg.(type) {
+	case *pgproto.SimpleQuery:
+		s.pending++
+	case *pgproto.Sync:
+		s.pending++
+		s.dirty = false
+	case *pgproto.CopyData, *pgproto.CopyDone, *pgproto.CopyFail:
+		// Part of the COPY started by a query which has not completed yet
+	default:
+		s.dirty = true
+	}
+
+	if s.statements == nil {
+		return s.backend, []pgproto.ClientMessage{msg}, nil, nil
+	}
+	msgs, answers := s.statements.send(s.backend, msg)
+	return s.backend, msgs, answers, nil
+}
+
+// relayServer forwards messages from b to the client until b is released or fails
+func (s *session) relayServer(b *backend) {
+	for {
+		msg, err := b.Receive()
+		if err != nil {
+			s.mu.Lock()
+			closing := s.closing
+			if s.backend == b {
+				s.backend = nil
+			}
+			if s.statements != nil {
+				s.statements.reset()
+			}
+			s.mu.Unlock()
+
+			s.pool.release(b, false, "")
+			if !closing {
+				// The client can not continue without its backend
+				s.pooler.logf("pooler: backend connection failed: %v", err)
+				s.close()
+			}
+			return
+		}
+
+		s.mu.Lock()
+		forward, answers := true, []pgproto.ServerMessage(nil)
+		if s.statements != nil {
+			forward, answers = s.statements.receive(b, msg)
+		}
+		closing := s.closing
+		s.mu.Unlock()
+
+		ready, ok := msg.(*pgproto.ReadyForQuery)
+		if !ok {
+			if !closing {
+				if forward {
+					s.write(msg, false)
+				}
+				for _, m := range answers {
+					s.write(m, false)
+				}
+				if !b.Buffered() {
+					s.flush()
+				}
+			}
+			continue
+		}
+
+		s.mu.Lock()
+		if s.pending > 0 {
+			s.pending--
+		}
+		s.status = ready.Status
+		clean := s.pending == 0 && !s.dirty && ready.Status == pgproto.READY_IDLE
+		closing = s.closing
+		release := clean && (closing || s.pooler.Mode == TransactionMode)
+		if release {
+			s.backend = nil
+		}
+		s.mu.Unlock()
+
+		if !closing {
+			s.write(msg, false)
+			for _, m := range answers {
+				s.write(m, false)
+			}
+			s.flush()
+		}
+		if release {
+			query := ""
+			if s.pooler.Mode == SessionMode || s.pooler.ResetAlways {
+				query = s.pooler.resetQuery()
+			}
+			s.pool.release(b, true, query)
+			return
+		}
+	}
+}
+
+// detach will give up the assigned backend once the client has gone, a backend in a clean state is
+// synced one last time so it is released by its relay, any other backend is closed
+func (s *session) detach() {
+	s.mu.Lock()
+	s.closing = true
+	b := s.backend
+	clean := s.pending == 0 && !s.dirty && s.status == pgproto.READY_IDLE
+	if b != nil && clean {
+		s.pending++
+	}
+	s.mu.Unlock()
+
+	if b == nil {
+		return
+	}
+	if !clean || b.Send(&pgproto.Sync{}) != nil {
+		b.NetConn().Close()
+	}
+}
+
+// currentBackend returns the backend assigned to the session, if any
+func (s *session) currentBackend() *backend {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.backend
+}
+
+func (s *session) isClosing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+func (s *session) sendFatal(code string, message string) {
+	s.send(&pgproto.Error{
+		Severity: []byte("FATAL"),
+		Code:     []byte(code),
+		Message:  []byte(message),
+	})
+	s.flush()
+}
+
+func (s *session) send(m pgproto.ServerMessage) {
+	s.write(m, false)
+}
+
+func (s *session) write(m pgproto.ServerMessage, flush bool) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	_, err := pgproto.WriteMessage(m, s.w)
+	if err != nil || !flush {
+		return err
+	}
+	return s.w.Flush()
+}
+
+func (s *session) flush() error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	return s.w.Flush()
+}
+
+// close will terminate the client connection and abort any pending acquire
+func (s *session) close() {
+	s.cancel()
+	s.conn.Close()
+}
@@ -0,0 +1,313 @@
+package pooler
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/internal/pgconn"
+)
+
+// backend is a pooled server connection along with the named statements prepared on it
+type backend struct {
+	*pgconn.Conn
+
+	// prepared holds the backend names of the statements prepared on this connection
+	prepared map[string]bool
+}
+
+// forget will clear the prepared statements when query drops them on the server
+func (b *backend) forget(query string) {
+	if dropsStatements(query) {
+		b.prepared = make(map[string]bool)
+	}
+}
+
+// statementPrefix starts the name of every statement prepared on behalf of clients
+const statementPrefix = "pgproto_"
+
+// statementName returns the name used on backends for the statement defined by p, identical
+// statements prepared by different clients share the same backend name
+func statementName(p *pgproto.Parse) string {
+	h := sha256.New()
+	h.Write(p.Query)
+	for _, oid := range p.OIDs {
+		h.Write(binary.BigEndian.AppendUint32(nil, uint32(oid)))
+	}
+	return statementPrefix + hex.EncodeToString(h.Sum(nil)[:12])
+}
+
+// dropsStatements returns whether query deallocates every prepared statement
+func dropsStatements(query string) bool {
+	q := strings.ToUpper(strings.Join(strings.Fields(strings.TrimRight(query, "; \t\n")), " "))
+	return q == "DISCARD ALL" || q == "DEALLOCATE ALL" || q == "DEALLOCATE PREPARE ALL"
+}
+
+// deallocatedStatement returns the statement name deallocated by query when it is a DEALLOCATE
+// [PREPARE] of a single statement, unquoted names are folded to lower case like the server does
+func deallocatedStatement(query string) (string, bool) {
+	fields := strings.Fields(strings.TrimRight(query, "; \t\n"))
+	if len(fields) == 0 || !strings.EqualFold(fields[0], "DEALLOCATE") {
+		return "", false
+	}
+	fields = fields[1:]
+	if len(fields) == 2 && strings.EqualFold(fields[0], "PREPARE") {
+		fields = fields[1:]
+	}
+	if len(fields) != 1 || strings.EqualFold(fields[0], "ALL") {
+		return "", false
+	}
+
+	name := fields[0]
+	if len(name) >= 2 && name[0] == '"' && name[len(name)-1] == '"' {
+		return strings.ReplaceAll(name[1:len(name)-1], `""`, `"`), true
+	}
+	return strings.ToLower(name), true
+}
+
+// responseKind identifies the client message a response is expected for
+type responseKind int
+
+const (
+	responseParse responseKind = iota
+	responseBind
+	responseDescribe
+	responseExecute
+	responseClose
+	responseSync
+	responseQuery
+)
+
+// response is a client message awaiting its response from the backend
+type response struct {
+	kind responseKind
+
+	// statement is the backend name of the statement prepared by a Parse
+	statement string
+
+	// name is the client's name of the statement defined by a Parse, and closed the statement
+	// dropped by a Close, which are undone when the backend skips the message after an error
+	name   string
+	closed *pgproto.Parse
+
+	// swallow is set for Parse messages replayed by the pooler, the client never sees their response
+	swallow bool
+
+	// synthetic is sent to the client in place of a response for messages answered by the pooler
+	synthetic pgproto.ServerMessage
+}
+
+// completes returns whether msg is the last message sent by the backend in response to r
+func (r *response) completes(msg pgproto.ServerMessage) bool {
+	switch msg.(type) {
+	case *pgproto.ParseComplete:
+		return r.kind == responseParse
+	case *pgproto.BindComplete:
+		return r.kind == responseBind
+	case *pgproto.RowDescription, *pgproto.NoData:
+		return r.kind == responseDescribe
+	case *pgproto.CommandCompletion, *pgproto.EmptyQueryResponse, *pgproto.PortalSuspended:
+		return r.kind == responseExecute
+	case *pgproto.CloseComplete:
+		return r.kind == responseClose
+	case *pgproto.Error:
+		return r.kind != responseSync && r.kind != responseQuery
+	case *pgproto.ReadyForQuery:
+		return r.kind == responseSync || r.kind == responseQuery
+	}
+	return false
+}
+
+// statementTracker keeps the named statements a client prepared in transaction mode, so they can be
+// prepared again on whichever backend the client is assigned next
+type statementTracker struct {
+	// statements maps the client's statement names to the Parse sent to backends
+	statements map[string]*pgproto.Parse
+
+	// responses are the client messages sent to the backend, or answered by the pooler, which have not
+	// been responded to yet, in order
+	responses []*response
+
+	// skipping is set once the pooler answered a message with an error, like the server it discards
+	// every message until the next Sync
+	skipping bool
+}
+
+func newStatementTracker() *statementTracker {
+	return &statementTracker{
+		statements: make(map[string]*pgproto.Parse),
+	}
+}
+
+// send returns the messages to write to b in place of msg, along with the messages to send to the
+// client immediately when msg is answered by the pooler and nothing else is awaiting a response
+func (t *statementTracker) send(b *backend, msg pgproto.ClientMessage) ([]pgproto.ClientMessage, []pgproto.ServerMessage) {
+	if t.skipping {
+		if _, ok := msg.(*pgproto.Sync); !ok {
+			return nil, nil
+		}
+		t.skipping = false
+	}
+
+	switch m := msg.(type) {
+	case *pgproto.Parse:
+		if len(m.Name) == 0 {
+			t.expect(&response{kind: responseParse})
+			return []pgproto.ClientMessage{m}, nil
+		}
+		if _, ok := t.statements[string(m.Name)]; ok {
+			t.skipping = true
+			return nil, t.answer(&pgproto.Error{
+				Severity: []byte("ERROR"),
+				Text:     []byte("ERROR"),
+				Code:     []byte("42P05"),
+				Message:  []byte(fmt.Sprintf("prepared statement \"%s\" already exists", m.Name)),
+			})
+		}
+
+		parse := &pgproto.Parse{
+			Name:  []byte(statementName(m)),
+			Query: m.Query,
+			OIDs:  m.OIDs,
+		}
+		t.statements[string(m.Name)] = parse
+		if b.prepared[string(parse.Name)] {
+			t.expect(&response{synthetic: &pgproto.ParseComplete{}, name: string(m.Name)})
+			return nil, t.drain()
+		}
+		b.prepared[string(parse.Name)] = true
+		t.expect(&response{kind: responseParse, statement: string(parse.Name), name: string(m.Name)})
+		return []pgproto.ClientMessage{parse}, nil
+	case *pgproto.Bind:
+		msgs := t.prepare(b, m.Statement)
+		if parse, ok := t.statements[string(m.Statement)]; ok {
+			bind := *m
+			bind.Statement = parse.Name
+			m = &bind
+		}
+		t.expect(&response{kind: responseBind})
+		return append(msgs, m), nil
+	case *pgproto.Describe:
+		var msgs []pgproto.ClientMessage
+		if m.ObjectType == pgproto.ObjectTypePreparedStatement {
+			msgs = t.prepare(b, m.Name)
+			if parse, ok := t.statements[string(m.Name)]; ok {
+				m = &pgproto.Describe{ObjectType: m.ObjectType, Name: parse.Name}
+			}
+		}
+		t.expect(&response{kind: responseDescribe})
+		return append(msgs, m), nil
+	case *pgproto.Close:
+		if m.ObjectType == pgproto.ObjectTypePreparedStatement {
+			// The statement is kept on the backend for other clients, only the client's name is dropped
+			if parse, ok := t.statements[string(m.Name)]; ok {
+				delete(t.statements, string(m.Name))
+				t.expect(&response{synthetic: &pgproto.CloseComplete{}, name: string(m.Name), closed: parse})
+				return nil, t.drain()
+			}
+		}
+		t.expect(&response{kind: responseClose})
+		return []pgproto.ClientMessage{m}, nil
+	case *pgproto.Execute:
+		t.expect(&response{kind: responseExecute})
+	case *pgproto.Sync:
+		t.expect(&response{kind: responseSync})
+	case *pgproto.SimpleQuery:
+		if dropsStatements(string(m.Query)) {
+			t.statements = make(map[string]*pgproto.Parse)
+			b.forget(string(m.Query))
+		}
+		if name, ok := deallocatedStatement(string(m.Query)); ok {
+			if parse, ok := t.statements[name]; ok {
+				// The backend only knows the statement by its backend name, and must have it prepared
+				// for the DEALLOCATE to succeed
+				msgs := t.prepare(b, []byte(name))
+				delete(t.statements, name)
+				delete(b.prepared, string(parse.Name))
+				t.expect(&response{kind: responseQuery})
+				return append(msgs, &pgproto.SimpleQuery{Query: []byte("DEALLOCATE " + string(parse.Name))}), nil
+			}
+		}
+		t.expect(&response{kind: responseQuery})
+	}
+	return []pgproto.ClientMessage{msg}, nil
+}
+
+// receive handles msg from b, returning whether it should be forwarded to the client and the
+// messages to send to the client after it
+func (t *statementTracker) receive(b *backend, msg pgproto.ServerMessage) (bool, []pgproto.ServerMessage) {
+	if len(t.responses) == 0 {
+		// Asynchronous messages, e.g. notices and notifications
+		return true, nil
+	}
+
+	r := t.responses[0]
+	if !r.completes(msg) {
+		return !r.swallow, nil
+	}
+	t.responses = t.responses[1:]
+
+	if _, ok := msg.(*pgproto.Error); ok {
+		// The backend skips every message until the next Sync, so will not respond to them either
+		failed := []*response{r}
+		for len(t.responses) > 0 && t.responses[0].kind != responseSync {
+			failed = append(failed, t.responses[0])
+			t.responses = t.responses[1:]
+		}
+		for _, f := range failed {
+			if f.statement != "" {
+				delete(b.prepared, f.statement)
+			}
+			switch {
+			case f.closed != nil:
+				t.statements[f.name] = f.closed
+			case f.name != "":
+				delete(t.statements, f.name)
+			}
+		}
+		return true, nil
+	}
+	return !r.swallow, t.drain()
+}
+
+// reset forgets the responses still expected, e.g. once the backend has failed
+func (t *statementTracker) reset() {
+	t.responses = nil
+}
+
+// prepare returns the Parse to replay on b when the client statement name is not prepared on it yet
+func (t *statementTracker) prepare(b *backend, name []byte) []pgproto.ClientMessage {
+	parse, ok := t.statements[string(name)]
+	if !ok || b.prepared[string(parse.Name)] {
+		return nil
+	}
+
+	b.prepared[string(parse.Name)] = true
+	t.expect(&response{kind: responseParse, statement: string(parse.Name), swallow: true})
+	return []pgproto.ClientMessage{parse}
+}
+
+func (t *statementTracker) expect(r *response) {
+	t.responses = append(t.responses, r)
+}
+
+// answer queues msg as the response to a message handled by the pooler, returning the messages which
+// can be sent to the client right away
+func (t *statementTracker) answer(msg pgproto.ServerMessage) []pgproto.ServerMessage {
+	t.expect(&response{synthetic: msg})
+	return t.drain()
+}
+
+// drain removes the answers at the front of the queue, they are due once every message before them
+// has been responded to
+func (t *statementTracker) drain() []pgproto.ServerMessage {
+	var msgs []pgproto.ServerMessage
+	for len(t.responses) > 0 && t.responses[0].synthetic != nil {
+		msgs = append(msgs, t.responses[0].synthetic)
+		t.responses = t.responses[1:]
+	}
+	return msgs
+}
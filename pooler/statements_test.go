@@ -0,0 +1,297 @@
+package pooler_test
+
+import (
+	"strings"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtest"
+	"github.com/c653labs/pgproto/pooler"
+)
+
+func (s *PoolerTestSuite) prepare(client *pgtest.Client, name string, query string) []pgproto.ServerMessage {
+	err := client.Send(
+		&pgproto.Parse{Name: []byte(name), Query: []byte(query)},
+		&pgproto.Sync{},
+	)
+	s.Require().Nil(err)
+	msgs, err := client.ReceiveUntilReady()
+	s.Require().Nil(err)
+	return msgs
+}
+
+func (s *PoolerTestSuite) execute(client *pgtest.Client, name string, params ...[]byte) []pgproto.ServerMessage {
+	err := client.Send(
+		&pgproto.Bind{Statement: []byte(name), Parameters: params},
+		&pgproto.Execute{},
+		&pgproto.Sync{},
+	)
+	s.Require().Nil(err)
+	msgs, err := client.ReceiveUntilReady()
+	s.Require().Nil(err)
+	return msgs
+}
+
+func (s *PoolerTestSuite) Test_PreparedStatements_AcrossBackends() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.pooler.PoolSize = 2
+	s.start()
+	first := s.connect()
+	defer first.Close()
+	second := s.connect()
+	defer second.Close()
+
+	msgs := s.prepare(first, "stmt", "SELECT $1")
+	s.Require().Len(msgs, 2)
+	s.IsType(&pgproto.ParseComplete{}, msgs[0])
+	s.waitIdle(1)
+
+	// Keep the backend the statement was prepared on busy
+	s.Equal(pgproto.READY_TRANSACTION, s.query(second, "BEGIN"))
+
+	// The statement is prepared again on the new backend and its ParseComplete is not relayed
+	msgs = s.execute(first, "stmt", []byte("value"))
+	s.Require().Len(msgs, 4)
+	s.IsType(&pgproto.BindComplete{}, msgs[0])
+	s.Equal(&pgproto.DataRow{Fields: [][]byte{[]byte("value")}}, msgs[1])
+	s.IsType(&pgproto.CommandCompletion{}, msgs[2])
+	s.IsType(&pgproto.ReadyForQuery{}, msgs[3])
+	s.Len(s.upstream.Conns(), 2)
+
+	// Once prepared it is not prepared again
+	msgs = s.execute(first, "stmt", []byte("value"))
+	s.Require().Len(msgs, 4)
+	s.IsType(&pgproto.BindComplete{}, msgs[0])
+
+	s.Equal(pgproto.READY_IDLE, s.query(second, "COMMIT"))
+}
+
+func (s *PoolerTestSuite) Test_PreparedStatements_Shared() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.start()
+	first := s.connect()
+	defer first.Close()
+	second := s.connect()
+	defer second.Close()
+
+	s.prepare(first, "a", "SELECT $1")
+
+	// The same statement under another name is answered without preparing it again
+	msgs := s.prepare(second, "b", "SELECT $1")
+	s.Require().Len(msgs, 2)
+	s.IsType(&pgproto.ParseComplete{}, msgs[0])
+	s.IsType(&pgproto.ReadyForQuery{}, msgs[1])
+
+	msgs = s.execute(second, "b", []byte("value"))
+	s.Require().Len(msgs, 4)
+	s.Equal(&pgproto.DataRow{Fields: [][]byte{[]byte("value")}}, msgs[1])
+
+	msgs = s.execute(first, "a", []byte("value"))
+	s.Require().Len(msgs, 4)
+	s.Equal(&pgproto.DataRow{Fields: [][]byte{[]byte("value")}}, msgs[1])
+}
+
+func (s *PoolerTestSuite) Test_PreparedStatements_Describe() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.pooler.ResetAlways = true
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	s.prepare(client, "stmt", "SELECT $1")
+	s.waitIdle(1)
+
+	// The backend was reset, so the statement is prepared again before describing it
+	err := client.Send(
+		&pgproto.Describe{ObjectType: pgproto.ObjectTypePreparedStatement, Name: []byte("stmt")},
+		&pgproto.Sync{},
+	)
+	s.Require().Nil(err)
+	msgs, err := client.ReceiveUntilReady()
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 3)
+	s.Equal(&pgproto.ParameterDescription{OIDs: []int{25}}, msgs[0])
+	s.IsType(&pgproto.RowDescription{}, msgs[1])
+	s.IsType(&pgproto.ReadyForQuery{}, msgs[2])
+}
+
+func (s *PoolerTestSuite) Test_PreparedStatements_Pipeline() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.start()
+	first := s.connect()
+	defer first.Close()
+	second := s.connect()
+	defer second.Close()
+
+	s.prepare(first, "a", "SELECT $1")
+
+	// The answered Parse is relayed in order between the responses from the backend
+	err := second.Send(
+		&pgproto.Parse{Query: []byte("SELECT 1")},
+		&pgproto.Parse{Name: []byte("b"), Query: []byte("SELECT $1")},
+		&pgproto.Bind{Statement: []byte("b"), Parameters: [][]byte{[]byte("value")}},
+		&pgproto.Execute{},
+		&pgproto.Sync{},
+	)
+	s.Require().Nil(err)
+	msgs, err := second.ReceiveUntilReady()
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 6)
+	s.IsType(&pgproto.ParseComplete{}, msgs[0])
+	s.IsType(&pgproto.ParseComplete{}, msgs[1])
+	s.IsType(&pgproto.BindComplete{}, msgs[2])
+	s.IsType(&pgproto.DataRow{}, msgs[3])
+	s.IsType(&pgproto.CommandCompletion{}, msgs[4])
+	s.IsType(&pgproto.ReadyForQuery{}, msgs[5])
+}
+
+func (s *PoolerTestSuite) Test_PreparedStatements_Close() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	s.prepare(client, "stmt", "SELECT $1")
+
+	err := client.Send(
+		&pgproto.Close{ObjectType: pgproto.ObjectTypePreparedStatement, Name: []byte("stmt")},
+		&pgproto.Sync{},
+	)
+	s.Require().Nil(err)
+	msgs, err := client.ReceiveUntilReady()
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 2)
+	s.IsType(&pgproto.CloseComplete{}, msgs[0])
+
+	// The client can no longer use the statement
+	msgs = s.execute(client, "stmt", []byte("value"))
+	s.Require().Len(msgs, 2)
+	s.IsType(&pgproto.Error{}, msgs[0])
+}
+
+func (s *PoolerTestSuite) Test_PreparedStatements_DiscardAll() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	s.prepare(client, "stmt", "SELECT $1")
+	s.Equal(pgproto.READY_IDLE, s.query(client, "DISCARD ALL"))
+
+	msgs := s.execute(client, "stmt", []byte("value"))
+	s.Require().Len(msgs, 2)
+	s.IsType(&pgproto.Error{}, msgs[0])
+
+	// Preparing it again works since the backend forgot it as well
+	msgs = s.prepare(client, "stmt", "SELECT $1")
+	s.Require().Len(msgs, 2)
+	s.IsType(&pgproto.ParseComplete{}, msgs[0])
+	msgs = s.execute(client, "stmt", []byte("value"))
+	s.Require().Len(msgs, 4)
+}
+
+func (s *PoolerTestSuite) Test_PreparedStatements_ParseError() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	// The fake backend rejects binding an unknown statement, everything up to the Sync is skipped
+	err := client.Send(
+		&pgproto.Bind{Statement: []byte("missing")},
+		&pgproto.Parse{Name: []byte("stmt"), Query: []byte("SELECT $1")},
+		&pgproto.Sync{},
+	)
+	s.Require().Nil(err)
+	msgs, err := client.ReceiveUntilReady()
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 2)
+	s.IsType(&pgproto.Error{}, msgs[0])
+
+	// Like on the server, the skipped Parse did not define the statement, which can be prepared again
+	msgs = s.execute(client, "stmt", []byte("value"))
+	s.Require().Len(msgs, 2)
+	s.IsType(&pgproto.Error{}, msgs[0])
+	msgs = s.prepare(client, "stmt", "SELECT $1")
+	s.Require().Len(msgs, 2)
+	s.IsType(&pgproto.ParseComplete{}, msgs[0])
+	msgs = s.execute(client, "stmt", []byte("value"))
+	s.Require().Len(msgs, 4)
+	s.IsType(&pgproto.BindComplete{}, msgs[0])
+}
+
+func (s *PoolerTestSuite) Test_PreparedStatements_Duplicate() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	s.prepare(client, "stmt", "SELECT $1")
+
+	// Preparing a name the client already has fails, the messages up to the Sync are skipped
+	err := client.Send(
+		&pgproto.Parse{Name: []byte("stmt"), Query: []byte("SELECT $1")},
+		&pgproto.Bind{Statement: []byte("stmt"), Parameters: [][]byte{[]byte("value")}},
+		&pgproto.Execute{},
+		&pgproto.Sync{},
+	)
+	s.Require().Nil(err)
+	msgs, err := client.ReceiveUntilReady()
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 2)
+	s.Require().IsType(&pgproto.Error{}, msgs[0])
+	s.Equal([]byte("42P05"), msgs[0].(*pgproto.Error).Code)
+	s.IsType(&pgproto.ReadyForQuery{}, msgs[1])
+
+	// The original statement is kept
+	msgs = s.execute(client, "stmt", []byte("value"))
+	s.Require().Len(msgs, 4)
+	s.Equal(&pgproto.DataRow{Fields: [][]byte{[]byte("value")}}, msgs[1])
+
+	// Once closed the name can be prepared again
+	err = client.Send(
+		&pgproto.Close{ObjectType: pgproto.ObjectTypePreparedStatement, Name: []byte("stmt")},
+		&pgproto.Parse{Name: []byte("stmt"), Query: []byte("SELECT $1::text")},
+		&pgproto.Sync{},
+	)
+	s.Require().Nil(err)
+	msgs, err = client.ReceiveUntilReady()
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 3)
+	s.IsType(&pgproto.CloseComplete{}, msgs[0])
+	s.IsType(&pgproto.ParseComplete{}, msgs[1])
+}
+
+func (s *PoolerTestSuite) Test_PreparedStatements_Deallocate() {
+	s.pooler.Mode = pooler.TransactionMode
+	s.pooler.ResetAlways = true
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	s.prepare(client, "stmt", "SELECT $1")
+	s.waitIdle(1)
+
+	// The backend was reset, the statement is prepared again to be deallocated under its backend name
+	msgs, err := client.Query(`DEALLOCATE PREPARE "stmt";`)
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 2)
+	s.IsType(&pgproto.CommandCompletion{}, msgs[0])
+	s.IsType(&pgproto.ReadyForQuery{}, msgs[1])
+	deallocated := false
+	for _, q := range s.upstream.Conns()[0].Queries() {
+		deallocated = deallocated || strings.HasPrefix(q, "DEALLOCATE pgproto_")
+	}
+	s.True(deallocated)
+
+	// The client can no longer use the statement, and may prepare its name again
+	msgs = s.execute(client, "stmt", []byte("value"))
+	s.Require().Len(msgs, 2)
+	s.IsType(&pgproto.Error{}, msgs[0])
+	msgs = s.prepare(client, "stmt", "SELECT $1")
+	s.Require().Len(msgs, 2)
+	s.IsType(&pgproto.ParseComplete{}, msgs[0])
+
+	// Statements unknown to the pooler are deallocated by the backend
+	s.Equal(pgproto.READY_IDLE, s.query(client, "DEALLOCATE other"))
+	s.Contains(s.upstream.Conns()[0].Queries(), "DEALLOCATE other")
+}
@@ -0,0 +1,66 @@
+package pgproto
+
+import (
+	"fmt"
+	"io"
+)
+
+// PortalSuspended is a server response indicating an Execute reached its row limit before completing
+type PortalSuspended struct{}
+
+func (p *PortalSuspended) server() {}
+
+// ParsePortalSuspended will attempt to read a PortalSuspended message from the io.Reader
+func ParsePortalSuspended(r io.Reader) (*PortalSuspended, error) {
+	b := newReadBuffer(r)
+
+	// 's' [int32 - length]
+	err := b.ReadTag('s')
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := b.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+
+	if l != 4 {
+		return nil, fmt.Errorf("expected message length of 4")
+	}
+
+	return &PortalSuspended{}, nil
+}
+
+// Encode will return the byte representation of this message
+func (p *PortalSuspended) Encode() []byte {
+	// 's' [int32 - length]
+	b := newWriteBuffer()
+	b.Wrap('s')
+	return b.Bytes()
+}
+
+// AsMap method returns a common map representation of this message:
+//
+//   map[string]interface{}{
+//     "Type": "PortalSuspended",
+//     "Payload": nil,
+//   }
+func (p *PortalSuspended) AsMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Type":    "PortalSuspended",
+		"Payload": nil,
+	}
+}
+
+func (p *PortalSuspended) String() string { return messageToString(p) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (p *PortalSuspended) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("PortalSuspended", p)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (p *PortalSuspended) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "PortalSuspended", p)
+}
@@ -0,0 +1,83 @@
+package pgproto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+type PortalSuspendedTestSuite struct {
+	suite.Suite
+}
+
+func TestPortalSuspendedTestSuite(t *testing.T) {
+	suite.Run(t, new(PortalSuspendedTestSuite))
+}
+
+func (s *PortalSuspendedTestSuite) Test_ParsePortalSuspended() {
+	raw := []byte{
+		// Tag
+		's',
+		// Length
+		'\x00', '\x00', '\x00', '\x04',
+	}
+
+	suspended, err := pgproto.ParsePortalSuspended(bytes.NewReader(raw))
+	s.Nil(err)
+	s.NotNil(suspended)
+	s.Equal(raw, suspended.Encode())
+
+	m, err := pgproto.ParseServerMessage(bytes.NewReader(raw))
+	s.Nil(err)
+	s.Equal(suspended, m)
+}
+
+func (s *PortalSuspendedTestSuite) Test_ParsePortalSuspended_Empty() {
+	suspended, err := pgproto.ParsePortalSuspended(bytes.NewReader([]byte{}))
+	s.NotNil(err)
+	s.Nil(suspended)
+}
+
+func (s *PortalSuspendedTestSuite) Test_ParsePortalSuspended_Malformed() {
+	for _, raw := range [][]byte{
+		{'s', '\x00', '\x00', '\x00', '\x05', '\x00'},
+		{'s', '\xff', '\xff', '\xff', '\xff'},
+		{'s', '\x00', '\x00'},
+	} {
+		suspended, err := pgproto.ParsePortalSuspended(bytes.NewReader(raw))
+		s.NotNil(err, raw)
+		s.Nil(suspended, raw)
+	}
+}
+
+func (s *PortalSuspendedTestSuite) Test_PortalSuspendedEncode() {
+	expected := []byte{
+		// Tag
+		's',
+		// Length
+		'\x00', '\x00', '\x00', '\x04',
+	}
+
+	suspended := &pgproto.PortalSuspended{}
+	s.Equal(expected, suspended.Encode())
+}
+
+func BenchmarkPortalSuspendedParse(b *testing.B) {
+	raw := []byte{
+		// Tag
+		's',
+		// Length
+		'\x00', '\x00', '\x00', '\x04',
+	}
+
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			_, err := pgproto.ParsePortalSuspended(bytes.NewReader(raw))
+			if err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"github.com/c653labs/pgproto"
+)
+
+// Interceptor is used to observe and rewrite the messages relayed by a Proxy
+//
+// Each method receives a message before it is forwarded and returns the message to forward instead,
+// which may be the original, a modified copy or an entirely different message. Returning a nil
+// message drops it, returning an error terminates the session. Additional messages can be injected
+// in either direction with Session.SendToClient and Session.SendToServer.
+//
+// The client's StartupMessage is passed to ClientMessage before the upstream connection is opened.
+type Interceptor interface {
+	ClientMessage(s *Session, m pgproto.ClientMessage) (pgproto.ClientMessage, error)
+	ServerMessage(s *Session, m pgproto.ServerMessage) (pgproto.ServerMessage, error)
+}
+
+// FrameObserver is implemented by Interceptors which observe the raw frames relayed, e.g. to record
+// them as they were sent on the wire
+//
+// ClientFrame and ServerFrame are called with every frame forwarded, once every interceptor has run,
+// along with the message it holds. The message is nil for frames pgproto does not parse, e.g.
+// FunctionCall, which are forwarded without being passed to ClientMessage or ServerMessage. The
+// startup and cancel requests are observed as encoded after interception. Returning an error
+// terminates the session.
+type FrameObserver interface {
+	ClientFrame(s *Session, frame []byte, m pgproto.ClientMessage) error
+	ServerFrame(s *Session, frame []byte, m pgproto.ServerMessage) error
+}
+
+// InterceptorFuncs adapts ordinary functions to an Interceptor, a nil function forwards messages unchanged
+type InterceptorFuncs struct {
+	Client func(s *Session, m pgproto.ClientMessage) (pgproto.ClientMessage, error)
+	Server func(s *Session, m pgproto.ServerMessage) (pgproto.ServerMessage, error)
+}
+
+// ClientMessage calls f.Client(s, m) when it is set
+func (f InterceptorFuncs) ClientMessage(s *Session, m pgproto.ClientMessage) (pgproto.ClientMessage, error) {
+	if f.Client == nil {
+		return m, nil
+	}
+	return f.Client(s, m)
+}
+
+// ServerMessage calls f.Server(s, m) when it is set
+func (f InterceptorFuncs) ServerMessage(s *Session, m pgproto.ServerMessage) (pgproto.ServerMessage, error) {
+	if f.Server == nil {
+		return m, nil
+	}
+	return f.Server(s, m)
+}
+
+// interceptClient runs m through every interceptor in order, stopping once a message is dropped
+func (p *Proxy) interceptClient(s *Session, m pgproto.ClientMessage) (pgproto.ClientMessage, error) {
+	var err error
+	for _, i := range p.Interceptors {
+		m, err = i.ClientMessage(s, m)
+		if err != nil || m == nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// interceptServer runs m through every interceptor in order, stopping once a message is dropped
+func (p *Proxy) interceptServer(s *Session, m pgproto.ServerMessage) (pgproto.ServerMessage, error) {
+	var err error
+	for _, i := range p.Interceptors {
+		m, err = i.ServerMessage(s, m)
+		if err != nil || m == nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// observeClient passes frame, forwarded to the server, to every FrameObserver in order
+func (p *Proxy) observeClient(s *Session, frame []byte, m pgproto.ClientMessage) error {
+	for _, i := range p.Interceptors {
+		if o, ok := i.(FrameObserver); ok {
+			err := o.ClientFrame(s, frame, m)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// observeServer passes frame, forwarded to the client, to every FrameObserver in order
+func (p *Proxy) observeServer(s *Session, frame []byte, m pgproto.ServerMessage) error {
+	for _, i := range p.Interceptors {
+		if o, ok := i.(FrameObserver); ok {
+			err := o.ServerFrame(s, frame, m)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
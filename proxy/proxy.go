@@ -0,0 +1,191 @@
+/*
+Package proxy implements a protocol aware PostgreSQL proxy built on pgproto.
+
+A Proxy accepts client connections, optionally terminating SSL, opens a connection to the upstream
+server for each client and relays every message in both directions after parsing it with
+pgproto.ParseClientMessage and pgproto.ParseServerMessage. Authentication is passed through to the
+upstream server untouched. Interceptors can observe, modify, drop or inject messages.
+
+Messages are forwarded as the bytes they were read as unless an interceptor changes them, and
+messages pgproto does not parse, e.g. FunctionCall, are forwarded without being intercepted.
+Interceptors implementing FrameObserver see the raw frame of every message forwarded, including those.
+
+	p := &proxy.Proxy{
+		Upstream: "127.0.0.1:5432",
+		Interceptors: []proxy.Interceptor{
+			proxy.InterceptorFuncs{
+				Client: func(s *proxy.Session, m pgproto.ClientMessage) (pgproto.ClientMessage, error) {
+					log.Println(m)
+					return m, nil
+				},
+			},
+		},
+	}
+	log.Fatal(p.ListenAndServe(":6432"))
+*/
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrProxyClosed is returned by Serve and ListenAndServe after Close has been called
+var ErrProxyClosed = errors.New("proxy: Proxy closed")
+
+// Proxy relays PostgreSQL client connections to an upstream server
+type Proxy struct {
+	// Upstream is the address of the PostgreSQL server to connect to
+	Upstream string
+
+	// Dial is used to connect to Upstream, defaults to net.Dialer.DialContext
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// TLSConfig, when set, is used to accept SSL connections from clients
+	TLSConfig *tls.Config
+
+	// UpstreamTLSConfig, when set, is used to require SSL on the upstream connection
+	UpstreamTLSConfig *tls.Config
+
+	// Interceptors are called in order for every relayed message
+	Interceptors []Interceptor
+
+	// ErrorLog is used to log session errors, defaults to the log package's standard logger
+	ErrorLog *log.Logger
+
+	nextID    uint64
+	mu        sync.Mutex
+	closed    bool
+	listeners map[net.Listener]struct{}
+	sessions  map[*Session]struct{}
+}
+
+// ListenAndServe will listen on the TCP address addr and serve client connections
+func (p *Proxy) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return p.Serve(l)
+}
+
+// Serve will accept client connections on l, handling each in a new goroutine, until l fails or
+// the Proxy is closed
+func (p *Proxy) Serve(l net.Listener) error {
+	if !p.trackListener(l, true) {
+		return ErrProxyClosed
+	}
+	defer p.trackListener(l, false)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if p.isClosed() {
+				return ErrProxyClosed
+			}
+			return err
+		}
+
+		go func() {
+			err := p.ServeConn(conn)
+			if err != nil {
+				p.logf("proxy: session from %s: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// ServeConn will relay a single client connection until either side disconnects
+func (p *Proxy) ServeConn(conn net.Conn) error {
+	s := &Session{
+		ID:     atomic.AddUint64(&p.nextID, 1),
+		proxy:  p,
+		client: newEndpoint(conn),
+	}
+	if !p.trackSession(s, true) {
+		conn.Close()
+		return ErrProxyClosed
+	}
+	defer p.trackSession(s, false)
+	defer s.Close()
+
+	return s.run()
+}
+
+// Close will stop all listeners and terminate all active sessions
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for l := range p.listeners {
+		l.Close()
+	}
+	for s := range p.sessions {
+		s.Close()
+	}
+	return nil
+}
+
+func (p *Proxy) dial(ctx context.Context) (net.Conn, error) {
+	if p.Dial != nil {
+		return p.Dial(ctx, "tcp", p.Upstream)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", p.Upstream)
+}
+
+func (p *Proxy) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+func (p *Proxy) trackListener(l net.Listener, add bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !add {
+		delete(p.listeners, l)
+		return true
+	}
+	if p.closed {
+		return false
+	}
+	if p.listeners == nil {
+		p.listeners = make(map[net.Listener]struct{})
+	}
+	p.listeners[l] = struct{}{}
+	return true
+}
+
+func (p *Proxy) trackSession(s *Session, add bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !add {
+		delete(p.sessions, s)
+		return true
+	}
+	if p.closed {
+		return false
+	}
+	if p.sessions == nil {
+		p.sessions = make(map[*Session]struct{})
+	}
+	p.sessions[s] = struct{}{}
+	return true
+}
+
+func (p *Proxy) logf(format string, args ...interface{}) {
+	if p.ErrorLog != nil {
+		p.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,357 @@
+package proxy_test
+
+import (
+	"io"
+	"log"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtest"
+	"github.com/c653labs/pgproto/proxy"
+	"github.com/stretchr/testify/suite"
+)
+
+var options = map[string]string{
+	"user":     "pgproto",
+	"database": "db_name",
+}
+
+type ProxyTestSuite struct {
+	suite.Suite
+	upstream *pgtest.Server
+	proxy    *proxy.Proxy
+	addr     string
+}
+
+func TestProxyTestSuite(t *testing.T) {
+	suite.Run(t, new(ProxyTestSuite))
+}
+
+func (s *ProxyTestSuite) SetupTest() {
+	s.upstream = pgtest.NewUnstartedServer(pgtest.Responses{
+		"SELECT 1": {
+			Fields: []pgproto.RowField{{ColumnName: []byte("?column?"), TypeOID: 23, ColumnLength: 4}},
+			Rows:   [][][]byte{{[]byte("1")}},
+		},
+		"SELECT $1": {
+			Fields:        []pgproto.RowField{{ColumnName: []byte("?column?"), TypeOID: 25, ColumnLength: -1}},
+			Rows:          [][][]byte{{[]byte("value")}},
+			ParameterOIDs: []int{25},
+		},
+	})
+	s.upstream.Users = map[string]string{"pgproto": "secret"}
+	s.upstream.Start()
+
+	s.proxy = &proxy.Proxy{
+		Upstream: s.upstream.Addr,
+		ErrorLog: log.New(io.Discard, "", 0),
+	}
+}
+
+func (s *ProxyTestSuite) TearDownTest() {
+	s.proxy.Close()
+	s.upstream.Close()
+}
+
+func (s *ProxyTestSuite) start() {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().Nil(err)
+	s.addr = l.Addr().String()
+	go s.proxy.Serve(l)
+}
+
+func (s *ProxyTestSuite) connect() *pgtest.Client {
+	client, err := pgtest.Connect(s.addr, options, "secret")
+	s.Require().Nil(err)
+	return client
+}
+
+func (s *ProxyTestSuite) Test_Relay() {
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	s.Equal("UTF8", client.Parameters["client_encoding"])
+	s.NotZero(client.PID)
+
+	msgs, err := client.Query("SELECT 1")
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 4)
+	s.IsType(&pgproto.RowDescription{}, msgs[0])
+	s.Equal(&pgproto.DataRow{Fields: [][]byte{[]byte("1")}}, msgs[1])
+	s.Equal(&pgproto.CommandCompletion{Tag: []byte("SELECT 1")}, msgs[2])
+	s.Equal(&pgproto.ReadyForQuery{Status: pgproto.READY_IDLE}, msgs[3])
+}
+
+func (s *ProxyTestSuite) Test_Relay_AuthenticationFailed() {
+	s.start()
+	client, err := pgtest.Connect(s.addr, options, "wrong")
+	s.NotNil(err)
+	s.Nil(client)
+}
+
+func (s *ProxyTestSuite) Test_Relay_ExtendedQuery() {
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	err := client.Send(
+		&pgproto.Parse{Name: []byte("stmt"), Query: []byte("SELECT $1")},
+		&pgproto.Describe{ObjectType: pgproto.ObjectTypePreparedStatement, Name: []byte("stmt")},
+		&pgproto.Bind{Statement: []byte("stmt"), Parameters: [][]byte{[]byte("value")}},
+		&pgproto.Execute{},
+		&pgproto.Sync{},
+	)
+	s.Require().Nil(err)
+
+	msgs, err := client.ReceiveUntilReady()
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 7)
+	s.IsType(&pgproto.ParseComplete{}, msgs[0])
+	s.Equal(&pgproto.ParameterDescription{OIDs: []int{25}}, msgs[1])
+	s.IsType(&pgproto.RowDescription{}, msgs[2])
+	s.IsType(&pgproto.BindComplete{}, msgs[3])
+	s.Equal(&pgproto.DataRow{Fields: [][]byte{[]byte("value")}}, msgs[4])
+	s.IsType(&pgproto.CommandCompletion{}, msgs[5])
+	s.IsType(&pgproto.ReadyForQuery{}, msgs[6])
+}
+
+func (s *ProxyTestSuite) Test_Relay_Raw() {
+	// FunctionCall and FunctionCallResponse are not parsed by pgproto, and the unknown 'X' field of
+	// the notice is dropped when it is parsed, they must all be relayed as they were sent
+	functionCall := []byte{'F', '\x00', '\x00', '\x00', '\x0e', '\x00', '\x00', '\x04', '\xd2', '\x00', '\x00', '\x00', '\x00', '\x00', '\x00'}
+	functionCallResponse := []byte{'V', '\x00', '\x00', '\x00', '\x08', '\xff', '\xff', '\xff', '\xff'}
+	notice := append([]byte{'N', '\x00', '\x00', '\x00', '\x14'}, "SNOTICE\x00Xextra\x00\x00"...)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().Nil(err)
+	defer l.Close()
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err = pgproto.ParseClientMessage(conn); err != nil {
+			return
+		}
+		pgproto.WriteMessages([]pgproto.Message{
+			&pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodOK},
+			&pgproto.ReadyForQuery{Status: pgproto.READY_IDLE},
+		}, conn)
+
+		buf := make([]byte, len(functionCall))
+		if _, err = io.ReadFull(conn, buf); err != nil {
+			return
+		}
+		received <- buf
+		conn.Write(append(append([]byte{}, notice...), functionCallResponse...))
+		io.Copy(io.Discard, conn)
+	}()
+
+	// An interceptor observing every message must not change how they are relayed
+	s.proxy.Upstream = l.Addr().String()
+	s.proxy.Interceptors = []proxy.Interceptor{proxy.InterceptorFuncs{}}
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	_, err = client.Conn.Write(functionCall)
+	s.Require().Nil(err)
+	select {
+	case buf := <-received:
+		s.Equal(functionCall, buf)
+	case <-time.After(time.Second):
+		s.Fail("FunctionCall was not relayed")
+	}
+
+	buf := make([]byte, len(notice)+len(functionCallResponse))
+	client.Conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = io.ReadFull(client.Conn, buf)
+	s.Require().Nil(err)
+	s.Equal(append(append([]byte{}, notice...), functionCallResponse...), buf)
+}
+
+func (s *ProxyTestSuite) Test_Interceptor_ModifyInPlace() {
+	s.proxy.Interceptors = []proxy.Interceptor{
+		proxy.InterceptorFuncs{
+			Client: func(sess *proxy.Session, m pgproto.ClientMessage) (pgproto.ClientMessage, error) {
+				if q, ok := m.(*pgproto.SimpleQuery); ok && string(q.Query) == "SELECT 2" {
+					q.Query = []byte("SELECT 1")
+				}
+				return m, nil
+			},
+		},
+	}
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	msgs, err := client.Query("SELECT 2")
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 4)
+	s.Equal(&pgproto.DataRow{Fields: [][]byte{[]byte("1")}}, msgs[1])
+}
+
+func (s *ProxyTestSuite) Test_Interceptor_Modify() {
+	s.proxy.Interceptors = []proxy.Interceptor{
+		proxy.InterceptorFuncs{
+			Client: func(sess *proxy.Session, m pgproto.ClientMessage) (pgproto.ClientMessage, error) {
+				if q, ok := m.(*pgproto.SimpleQuery); ok && string(q.Query) == "SELECT 2" {
+					return &pgproto.SimpleQuery{Query: []byte("SELECT 1")}, nil
+				}
+				return m, nil
+			},
+			Server: func(sess *proxy.Session, m pgproto.ServerMessage) (pgproto.ServerMessage, error) {
+				if _, ok := m.(*pgproto.DataRow); ok {
+					return &pgproto.DataRow{Fields: [][]byte{[]byte("2")}}, nil
+				}
+				return m, nil
+			},
+		},
+	}
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	msgs, err := client.Query("SELECT 2")
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 4)
+	s.Equal(&pgproto.DataRow{Fields: [][]byte{[]byte("2")}}, msgs[1])
+}
+
+func (s *ProxyTestSuite) Test_Interceptor_DropAndInject() {
+	s.proxy.Interceptors = []proxy.Interceptor{
+		proxy.InterceptorFuncs{
+			Client: func(sess *proxy.Session, m pgproto.ClientMessage) (pgproto.ClientMessage, error) {
+				if q, ok := m.(*pgproto.SimpleQuery); ok && string(q.Query) == "DROP TABLE users" {
+					sess.SendToClient(&pgproto.Error{
+						Severity: []byte("ERROR"),
+						Code:     []byte("42501"),
+						Message:  []byte("blocked by proxy"),
+					})
+					sess.SendToClient(&pgproto.ReadyForQuery{Status: pgproto.READY_IDLE})
+					return nil, nil
+				}
+				return m, nil
+			},
+		},
+	}
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	msgs, err := client.Query("DROP TABLE users")
+	s.Require().Nil(err)
+	s.Require().Len(msgs, 2)
+	s.Equal([]byte("blocked by proxy"), msgs[0].(*pgproto.Error).Message)
+
+	// The upstream server never saw the dropped query
+	conns := s.upstream.Conns()
+	s.Require().Len(conns, 1)
+	s.Empty(conns[0].Queries())
+
+	// The session is still usable afterwards
+	msgs, err = client.Query("SELECT 1")
+	s.Require().Nil(err)
+	s.Len(msgs, 4)
+}
+
+func (s *ProxyTestSuite) Test_Interceptor_Observe() {
+	var mu sync.Mutex
+	seen := make([]string, 0)
+	s.proxy.Interceptors = []proxy.Interceptor{
+		proxy.InterceptorFuncs{
+			Client: func(sess *proxy.Session, m pgproto.ClientMessage) (pgproto.ClientMessage, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				seen = append(seen, m.AsMap()["Type"].(string))
+				return m, nil
+			},
+		},
+	}
+	s.start()
+	client := s.connect()
+	_, err := client.Query("SELECT 1")
+	s.Require().Nil(err)
+	client.Close()
+
+	s.True(pgtest.Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(seen) == 4
+	}, time.Second))
+
+	mu.Lock()
+	defer mu.Unlock()
+	s.Equal([]string{"StartupMessage", "PasswordMessage", "SimpleQuery", "Termination"}, seen)
+}
+
+func (s *ProxyTestSuite) Test_Interceptor_RewriteStartup() {
+	s.proxy.Interceptors = []proxy.Interceptor{
+		proxy.InterceptorFuncs{
+			Client: func(sess *proxy.Session, m pgproto.ClientMessage) (pgproto.ClientMessage, error) {
+				if startup, ok := m.(*pgproto.StartupMessage); ok {
+					startup.Options["database"] = []byte("rewritten")
+				}
+				return m, nil
+			},
+		},
+	}
+	s.start()
+	client := s.connect()
+	defer client.Close()
+
+	conns := s.upstream.Conns()
+	s.Require().Len(conns, 1)
+	s.Equal("rewritten", conns[0].Database())
+}
+
+func (s *ProxyTestSuite) Test_SSLTermination() {
+	serverConfig, clientConfig := pgtest.Certificates()
+	s.proxy.TLSConfig = serverConfig
+	s.start()
+
+	conn, err := net.Dial("tcp", s.addr)
+	s.Require().Nil(err)
+	tlsConn, ok, err := pgproto.RequestSSL(conn, clientConfig)
+	s.Require().Nil(err)
+	s.Require().True(ok)
+
+	client, err := pgtest.NewClient(tlsConn, options, "secret")
+	s.Require().Nil(err)
+	defer client.Close()
+
+	msgs, err := client.Query("SELECT 1")
+	s.Require().Nil(err)
+	s.Len(msgs, 4)
+}
+
+func (s *ProxyTestSuite) Test_UpstreamSSL() {
+	serverConfig, clientConfig := pgtest.Certificates()
+	s.upstream.Close()
+	s.upstream = pgtest.NewUnstartedServer(pgtest.Responses{})
+	s.upstream.TLSConfig = serverConfig
+	s.upstream.Start()
+
+	s.proxy.Upstream = s.upstream.Addr
+	s.proxy.UpstreamTLSConfig = clientConfig
+	s.start()
+
+	client := s.connect()
+	defer client.Close()
+	s.Equal("UTF8", client.Parameters["client_encoding"])
+}
+
+func (s *ProxyTestSuite) Test_UpstreamUnavailable() {
+	s.proxy.Upstream = "127.0.0.1:1"
+	s.start()
+
+	client, err := pgtest.Connect(s.addr, options, "secret")
+	s.NotNil(err)
+	s.Nil(client)
+}
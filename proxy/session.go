@@ -0,0 +1,388 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/c653labs/pgproto"
+)
+
+// maxMessageLength is the largest message length relayed, the limit PostgreSQL itself applies
+const maxMessageLength = 1<<30 - 1
+
+// errNotConnected is returned when sending to the upstream server before it has been connected
+var errNotConnected = errors.New("proxy: upstream server is not connected")
+
+// Session is a single client connection relayed to the upstream server
+type Session struct {
+	// ID uniquely identifies the session within its Proxy
+	ID uint64
+
+	// Startup is the StartupMessage sent upstream, after interception
+	Startup *pgproto.StartupMessage
+
+	proxy *Proxy
+
+	mu     sync.Mutex
+	closed bool
+	client *endpoint
+	server *endpoint
+	auth   *pgproto.AuthenticationRequest
+}
+
+// ClientAddr returns the remote address of the client connection
+func (s *Session) ClientAddr() net.Addr {
+	return s.clientEndpoint().conn.RemoteAddr()
+}
+
+// SendToClient will write m to the client connection
+func (s *Session) SendToClient(m pgproto.ServerMessage) error {
+	return s.clientEndpoint().write(m, true)
+}
+
+// SendToServer will write m to the upstream server connection
+func (s *Session) SendToServer(m pgproto.ClientMessage) error {
+	e := s.serverEndpoint()
+	if e == nil {
+		return errNotConnected
+	}
+	return e.write(m, true)
+}
+
+// Close will terminate the session, closing both the client and upstream connections
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	err := s.client.conn.Close()
+	if s.server != nil {
+		s.server.conn.Close()
+	}
+	return err
+}
+
+func (s *Session) run() error {
+	conn, first, err := pgproto.AcceptSSL(s.client.conn, s.proxy.TLSConfig)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.client = newEndpoint(conn)
+	s.mu.Unlock()
+	if startup, ok := first.(*pgproto.StartupMessage); ok {
+		s.Startup = startup
+	}
+
+	msg, err := s.proxy.interceptClient(s, first)
+	if err != nil || msg == nil {
+		return err
+	}
+	if startup, ok := msg.(*pgproto.StartupMessage); ok {
+		s.Startup = startup
+	}
+
+	err = s.proxy.observeClient(s, msg.Encode(), msg)
+	if err != nil {
+		return err
+	}
+
+	// Cancel requests are sent on their own connection and get no response
+	if cancel, ok := msg.(*pgproto.CancelRequest); ok {
+		return s.forwardCancel(cancel)
+	}
+
+	upstream, err := s.connectUpstream()
+	if err != nil {
+		s.SendToClient(&pgproto.Error{
+			Severity: []byte("FATAL"),
+			Code:     []byte("08006"),
+			Message:  []byte("could not connect to upstream server"),
+		})
+		return err
+	}
+
+	s.mu.Lock()
+	s.server = newEndpoint(upstream)
+	if s.closed {
+		upstream.Close()
+	}
+	s.mu.Unlock()
+
+	err = s.SendToServer(msg)
+	if err != nil {
+		return err
+	}
+
+	// Relay in both directions until either side stops, then tear down the other
+	errs := make(chan error, 2)
+	go func() { errs <- s.relayClient() }()
+	go func() { errs <- s.relayServer() }()
+
+	err = <-errs
+	s.Close()
+	<-errs
+
+	if err == io.EOF || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// forwardCancel will relay a cancel request to the upstream server on a new connection
+func (s *Session) forwardCancel(cancel *pgproto.CancelRequest) error {
+	upstream, err := s.connectUpstream()
+	if err != nil {
+		return err
+	}
+	defer upstream.Close()
+
+	_, err = pgproto.WriteMessage(cancel, upstream)
+	return err
+}
+
+func (s *Session) connectUpstream() (net.Conn, error) {
+	conn, err := s.proxy.dial(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if s.proxy.UpstreamTLSConfig == nil {
+		return conn, nil
+	}
+
+	tlsConn, ok, err := pgproto.RequestSSL(conn, s.proxy.UpstreamTLSConfig)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("proxy: upstream server refused SSL")
+	}
+	return tlsConn, nil
+}
+
+func (s *Session) relayClient() error {
+	client, server := s.clientEndpoint(), s.serverEndpoint()
+	for {
+		frame, err := readFrame(client.r)
+		if err != nil {
+			return err
+		}
+
+		data := frame
+		msg, err := s.parseClientMessage(frame)
+		var unknown *pgproto.UnknownMessageError
+		switch {
+		case errors.As(err, &unknown):
+			// Messages pgproto does not parse, e.g. FunctionCall, are forwarded without interception
+			msg = nil
+		case err != nil:
+			return err
+		case len(s.proxy.Interceptors) > 0:
+			before := msg.Encode()
+			out, err := s.proxy.interceptClient(s, msg)
+			if err != nil {
+				return err
+			}
+			data = intercepted(frame, msg, before, out)
+			msg = out
+		}
+
+		if data != nil {
+			err = s.proxy.observeClient(s, data, msg)
+			if err != nil {
+				return err
+			}
+			err = server.writeRaw(data, false)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Only flush once the client has nothing more for us
+		if client.r.Buffered() == 0 {
+			err = server.flush()
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, ok := msg.(*pgproto.Termination); ok {
+			return nil
+		}
+	}
+}
+
+func (s *Session) relayServer() error {
+	client, server := s.clientEndpoint(), s.serverEndpoint()
+	for {
+		frame, err := readFrame(server.r)
+		if err != nil {
+			return err
+		}
+
+		data := frame
+		msg, err := pgproto.ParseServerMessage(bytes.NewReader(frame))
+		var unknown *pgproto.UnknownMessageError
+		switch {
+		case errors.As(err, &unknown):
+			// Messages pgproto does not parse, e.g. FunctionCallResponse, are forwarded without interception
+			msg = nil
+		case err != nil:
+			return err
+		case len(s.proxy.Interceptors) > 0:
+			before := msg.Encode()
+			out, err := s.proxy.interceptServer(s, msg)
+			if err != nil {
+				return err
+			}
+			data = intercepted(frame, msg, before, out)
+			msg = out
+		}
+
+		// Keep track of the authentication request the client is answering next
+		if auth, ok := msg.(*pgproto.AuthenticationRequest); ok {
+			s.mu.Lock()
+			s.auth = auth
+			s.mu.Unlock()
+		}
+
+		if data != nil {
+			err = s.proxy.observeServer(s, data, msg)
+			if err != nil {
+				return err
+			}
+			err = client.writeRaw(data, false)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Only flush once the server has nothing more for us
+		if server.r.Buffered() == 0 {
+			err = client.flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseClientMessage parses a client message read as frame, using the last authentication request
+// sent to the client to decide how to parse password and SASL responses
+func (s *Session) parseClientMessage(frame []byte) (pgproto.ClientMessage, error) {
+	if frame[0] == 'p' {
+		s.mu.Lock()
+		auth := s.auth
+		s.mu.Unlock()
+		return pgproto.ParseAuthenticationResponse(bytes.NewReader(frame), auth)
+	}
+	return pgproto.ParseClientMessage(bytes.NewReader(frame))
+}
+
+// intercepted returns the bytes to forward for msg, read as frame and encoded as before, once the
+// interceptors returned out: frame when out is msg unchanged, so the message is relayed exactly as
+// it was received, nil when it was dropped and the encoding of out otherwise
+func intercepted(frame []byte, msg pgproto.Message, before []byte, out pgproto.Message) []byte {
+	if out == nil {
+		return nil
+	}
+	data := out.Encode()
+	if out == msg && bytes.Equal(data, before) {
+		return frame
+	}
+	return data
+}
+
+// readFrame reads the next message of r without parsing it: its tag, length and payload
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	header, err := r.Peek(5)
+	if err != nil {
+		if err == io.EOF && r.Buffered() > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	// [char - tag] [int32 - length] [payload], the length counts itself but not the tag
+	l := int(int32(binary.BigEndian.Uint32(header[1:])))
+	if l < 4 || l > maxMessageLength {
+		return nil, fmt.Errorf("proxy: invalid length %d for message '%c'", l, header[0])
+	}
+
+	frame := make([]byte, 1+l)
+	_, err = io.ReadFull(r, frame)
+	if err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+func (s *Session) clientEndpoint() *endpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client
+}
+
+func (s *Session) serverEndpoint() *endpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.server
+}
+
+// endpoint is one side of a relayed session, writes are buffered and safe for concurrent use
+type endpoint struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+func newEndpoint(conn net.Conn) *endpoint {
+	return &endpoint{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}
+}
+
+func (e *endpoint) write(m pgproto.Message, flush bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, err := pgproto.WriteMessage(m, e.w)
+	if err != nil || !flush {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func (e *endpoint) writeRaw(data []byte, flush bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, err := e.w.Write(data)
+	if err != nil || !flush {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func (e *endpoint) flush() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.w.Flush()
+}
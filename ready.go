@@ -8,17 +8,37 @@ import (
 type ReadyStatus int
 
 const (
-	READY_IDLE ReadyStatus = 73
+	READY_IDLE        ReadyStatus = 73 // 'I'
+	READY_TRANSACTION ReadyStatus = 84 // 'T'
+	READY_ERROR       ReadyStatus = 69 // 'E'
 )
 
 func (r ReadyStatus) String() string {
 	switch r {
 	case READY_IDLE:
 		return "Idle"
+	case READY_TRANSACTION:
+		return "Transaction"
+	case READY_ERROR:
+		return "Error"
 	}
 	return "Unknown"
 }
 
+// MarshalText implements encoding.TextMarshaler, encoding the status as the character sent on the wire
+func (r ReadyStatus) MarshalText() ([]byte, error) {
+	return []byte{byte(r)}, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, decoding a single status character
+func (r *ReadyStatus) UnmarshalText(text []byte) error {
+	if len(text) != 1 {
+		return fmt.Errorf("invalid ready status %q, must be a single character", text)
+	}
+	*r = ReadyStatus(text[0])
+	return nil
+}
+
 type ReadyForQuery struct {
 	Status ReadyStatus
 }
@@ -69,3 +89,13 @@ func (r *ReadyForQuery) AsMap() map[string]interface{} {
 }
 
 func (r *ReadyForQuery) String() string { return messageToString(r) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (r *ReadyForQuery) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("ReadyForQuery", r)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (r *ReadyForQuery) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "ReadyForQuery", r)
+}
@@ -0,0 +1,40 @@
+package record
+
+import (
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/proxy"
+)
+
+// Interceptor returns a proxy.Interceptor writing every message relayed to w, using the proxy
+// session ID as the recorded session
+//
+// Messages are recorded as the raw frames forwarded once every interceptor has run, see
+// proxy.FrameObserver, including the messages pgproto does not parse. Recording errors are returned
+// and end the session.
+func Interceptor(w *Writer) proxy.Interceptor {
+	return &recorder{w: w}
+}
+
+// recorder is a proxy.FrameObserver relaying messages unchanged
+type recorder struct {
+	proxy.InterceptorFuncs
+	w *Writer
+}
+
+func (r *recorder) ClientFrame(s *proxy.Session, frame []byte, m pgproto.ClientMessage) error {
+	return r.write(Frontend, s, frame)
+}
+
+func (r *recorder) ServerFrame(s *proxy.Session, frame []byte, m pgproto.ServerMessage) error {
+	return r.write(Backend, s, frame)
+}
+
+func (r *recorder) write(d Direction, s *proxy.Session, frame []byte) error {
+	err := r.w.WriteRaw(d, s.ID, time.Now(), frame)
+	if err == nil {
+		err = r.w.Flush()
+	}
+	return err
+}
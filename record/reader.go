@@ -0,0 +1,201 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/c653labs/pgproto"
+)
+
+// ErrInvalidRecording is returned when the input does not start with a recording header
+var ErrInvalidRecording = errors.New("record: not a pgproto recording")
+
+// maxMessageSize bounds the length of a recorded message, larger lengths mean the recording is corrupt
+const maxMessageSize = 1 << 30
+
+// ParseError is returned by Reader.Next along with the record when its message could not be parsed,
+// the Reader can still be used to read the following records
+type ParseError struct {
+	Record *Record
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("record: unable to parse %s message: %v", e.Record.Direction, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Reader reads the records of a recording in order
+type Reader struct {
+	// Start is the time the recording started
+	Start time.Time
+
+	r        *bufio.Reader
+	last     time.Time
+	sessions map[uint64]*sessionState
+}
+
+// sessionState holds what is needed to parse the messages of a session which depend on earlier ones
+type sessionState struct {
+	startup    bool
+	sslRequest bool
+	auth       *pgproto.AuthenticationRequest
+}
+
+// NewReader will read the recording header from r and return a Reader for its records
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, 16)
+	_, err := io.ReadFull(br, header)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, ErrInvalidRecording
+	}
+	if err != nil {
+		return nil, err
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, ErrInvalidRecording
+	}
+	if header[len(magic)] != Version {
+		return nil, fmt.Errorf("record: unsupported recording version %d", header[len(magic)])
+	}
+
+	start := time.Unix(0, int64(binary.BigEndian.Uint64(header[8:]))).UTC()
+	return &Reader{
+		Start:    start,
+		r:        br,
+		last:     start,
+		sessions: make(map[uint64]*sessionState),
+	}, nil
+}
+
+// Next returns the next record, or io.EOF once every record has been read
+//
+// When the message of a record can not be parsed the record is returned with a *ParseError
+func (r *Reader) Next() (*Record, error) {
+	d, err := r.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if Direction(d) != Frontend && Direction(d) != Backend {
+		return nil, fmt.Errorf("record: invalid direction %d", d)
+	}
+
+	session, err := r.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	delta, err := r.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	l, err := r.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if l > maxMessageSize {
+		return nil, fmt.Errorf("record: invalid message length %d", l)
+	}
+	raw := make([]byte, l)
+	_, err = io.ReadFull(r.r, raw)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	r.last = r.last.Add(time.Duration(delta))
+	rec := &Record{
+		Direction: Direction(d),
+		Session:   session,
+		Time:      r.last,
+		Raw:       raw,
+	}
+
+	rec.Message, err = r.parse(rec)
+	if err != nil {
+		return rec, &ParseError{Record: rec, Err: err}
+	}
+	return rec, nil
+}
+
+// parse the message of rec, keeping track of the session state needed to parse later messages
+func (r *Reader) parse(rec *Record) (pgproto.Message, error) {
+	state, ok := r.sessions[rec.Session]
+	if !ok {
+		state = &sessionState{}
+		r.sessions[rec.Session] = state
+	}
+	buf := bytes.NewReader(rec.Raw)
+
+	if rec.Direction == Backend {
+		if state.sslRequest {
+			state.sslRequest = false
+			resp, err := pgproto.ParseSSLResponse(buf)
+			if err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+
+		msg, err := pgproto.ParseServerMessage(buf)
+		if err != nil {
+			return nil, err
+		}
+		if auth, ok := msg.(*pgproto.AuthenticationRequest); ok {
+			state.auth = auth
+		}
+		return msg, nil
+	}
+
+	if !state.startup {
+		// Startup, SSL and cancel requests are the only messages without a type byte
+		msg, err := pgproto.ParseClientMessage(buf)
+		if err != nil {
+			return nil, err
+		}
+		if startup, ok := msg.(*pgproto.StartupMessage); ok {
+			if startup.SSLRequest || startup.GSSEncRequest {
+				state.sslRequest = true
+			} else {
+				state.startup = true
+			}
+		}
+		return msg, nil
+	}
+
+	// DEV: The parse functions return typed nil messages along with their errors, which must not end
+	//      up in Record.Message
+	var msg pgproto.ClientMessage
+	var err error
+	if len(rec.Raw) > 0 && rec.Raw[0] == 'p' {
+		msg, err = pgproto.ParseAuthenticationResponse(buf, state.auth)
+	} else {
+		msg, err = pgproto.ParseClientMessage(buf)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (r *Reader) readUvarint() (uint64, error) {
+	v, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	return v, nil
+}
+
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF, since a record was only partially read
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+/*
+Package record reads and writes recordings of PostgreSQL wire traffic.
+
+A recording is a sequence of messages, each with the direction it was sent in, the session it belongs
+to, the time it was sent and its raw bytes. Recordings are written with a Writer, usually through the
+proxy.Interceptor returned by Interceptor, and read back as pgproto messages with a Reader.
+
+	w := record.NewWriter(f)
+	p := &proxy.Proxy{
+		Upstream:     "127.0.0.1:5432",
+		Interceptors: []proxy.Interceptor{record.Interceptor(w)},
+	}
+
+File format
+
+All integers are big endian. A recording starts with a header:
+
+	[8 bytes - magic "PGPROTO" followed by the format version 0x01] [int64 - start time in nanoseconds since the Unix epoch]
+
+followed by any number of records:
+
+	[byte - direction 'F' or 'B'] [uvarint - session] [uvarint - nanoseconds since the previous record] [uvarint - length] [bytes - message]
+*/
+package record
+
+import (
+	"time"
+
+	"github.com/c653labs/pgproto"
+)
+
+// Version is the version of the file format written by Writer
+const Version = 1
+
+// magic starts every recording, the version byte follows
+const magic = "PGPROTO"
+
+// Direction is the direction a recorded message was sent in
+type Direction byte
+
+const (
+	// Frontend messages are sent by the client to the server
+	Frontend Direction = 'F'
+	// Backend messages are sent by the server to the client
+	Backend Direction = 'B'
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Frontend:
+		return "Frontend"
+	case Backend:
+		return "Backend"
+	}
+	return "Unknown"
+}
+
+// Record is a single recorded message
+type Record struct {
+	Direction Direction
+
+	// Session identifies the connection the message was sent on
+	Session uint64
+
+	Time time.Time
+
+	// Raw is the message as sent on the wire
+	Raw []byte
+
+	// Message is Raw parsed, it is nil when Raw could not be parsed
+	Message pgproto.Message
+}
@@ -0,0 +1,299 @@
+package record_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtest"
+	"github.com/c653labs/pgproto/proxy"
+	"github.com/c653labs/pgproto/record"
+	"github.com/stretchr/testify/suite"
+)
+
+type RecordTestSuite struct {
+	suite.Suite
+}
+
+func TestRecordTestSuite(t *testing.T) {
+	suite.Run(t, new(RecordTestSuite))
+}
+
+func (s *RecordTestSuite) Test_Encode() {
+	start := time.Unix(1700000000, 0)
+	buf := &bytes.Buffer{}
+	w := record.NewWriter(buf)
+	s.Nil(w.Write(record.Frontend, 1, start, &pgproto.SimpleQuery{Query: []byte("SELECT 1")}))
+	s.Nil(w.Write(record.Backend, 1, start.Add(300*time.Microsecond), &pgproto.ReadyForQuery{Status: pgproto.READY_IDLE}))
+	s.Nil(w.Flush())
+
+	expected := []byte{
+		// Magic and version
+		'P', 'G', 'P', 'R', 'O', 'T', 'O', '\x01',
+		// Start time
+		'\x17', '\x97', '\x9c', '\xfe', '\x36', '\x2a', '\x00', '\x00',
+		// Direction, session, delta, length
+		'F', '\x01', '\x00', '\x0e',
+		// Message
+		'Q', '\x00', '\x00', '\x00', '\x0d', 'S', 'E', 'L', 'E', 'C', 'T', ' ', '1', '\x00',
+		// Direction, session, delta (300000ns), length
+		'B', '\x01', '\xe0', '\xa7', '\x12', '\x06',
+		// Message
+		'Z', '\x00', '\x00', '\x00', '\x05', 'I',
+	}
+	s.Equal(expected, buf.Bytes())
+}
+
+func (s *RecordTestSuite) Test_RoundTrip() {
+	start := time.Unix(1700000000, 0)
+	salt := []byte{'\x01', '\x02', '\x03', '\x04'}
+	password := &pgproto.PasswordMessage{}
+	password.SetPassword([]byte("pgproto"), []byte("secret"), salt)
+
+	recorded := []struct {
+		direction record.Direction
+		session   uint64
+		message   pgproto.Message
+	}{
+		{record.Frontend, 1, &pgproto.StartupMessage{SSLRequest: true, Options: map[string][]byte{}}},
+		{record.Backend, 1, &pgproto.SSLResponse{Accepted: false}},
+		{record.Frontend, 1, &pgproto.StartupMessage{Options: map[string][]byte{"user": []byte("pgproto")}}},
+		{record.Frontend, 2, &pgproto.CancelRequest{PID: 1, Key: 2}},
+		{record.Backend, 1, &pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodMD5, Salt: salt}},
+		{record.Frontend, 1, password},
+		{record.Backend, 1, &pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodOK}},
+		{record.Backend, 1, &pgproto.ReadyForQuery{Status: pgproto.READY_IDLE}},
+		{record.Frontend, 1, &pgproto.SimpleQuery{Query: []byte("SELECT 1")}},
+		{record.Backend, 1, &pgproto.CommandCompletion{Tag: []byte("SELECT 1")}},
+		{record.Frontend, 1, &pgproto.Termination{}},
+	}
+
+	buf := &bytes.Buffer{}
+	w := record.NewWriter(buf)
+	for i, r := range recorded {
+		s.Nil(w.Write(r.direction, r.session, start.Add(time.Duration(i)*time.Millisecond), r.message))
+	}
+	s.Nil(w.Flush())
+
+	r, err := record.NewReader(buf)
+	s.Require().Nil(err)
+	s.True(start.Equal(r.Start))
+
+	for i, expected := range recorded {
+		rec, err := r.Next()
+		s.Require().Nil(err)
+		s.Equal(expected.direction, rec.Direction)
+		s.Equal(expected.session, rec.Session)
+		s.True(start.Add(time.Duration(i) * time.Millisecond).Equal(rec.Time))
+		s.Equal(expected.message.Encode(), rec.Raw)
+		s.Equal(expected.message, rec.Message)
+	}
+
+	rec, err := r.Next()
+	s.Equal(io.EOF, err)
+	s.Nil(rec)
+}
+
+func (s *RecordTestSuite) Test_ParseError() {
+	buf := &bytes.Buffer{}
+	w := record.NewWriter(buf)
+	s.Nil(w.WriteRaw(record.Backend, 1, time.Now(), []byte{'?', '\x00', '\x00', '\x00', '\x04'}))
+	s.Nil(w.WriteRaw(record.Backend, 1, time.Now(), []byte{'Z', '\x00', '\x00', '\x00', '\x04'}))
+	s.Nil(w.WriteRaw(record.Frontend, 1, time.Now(), []byte{'\x00', '\x00', '\x00', '\x08', '\x00', '\x03', '\x00', '\x00'}))
+	s.Nil(w.Write(record.Backend, 1, time.Now(), &pgproto.ReadyForQuery{Status: pgproto.READY_IDLE}))
+	s.Nil(w.Flush())
+
+	r, err := record.NewReader(buf)
+	s.Require().Nil(err)
+
+	// Messages which could not be parsed leave an untyped nil Message
+	for i := 0; i < 3; i++ {
+		rec, err := r.Next()
+		s.IsType(&record.ParseError{}, err)
+		s.True(rec.Message == nil, "%#v", rec.Message)
+	}
+
+	// The following records can still be read
+	rec, err := r.Next()
+	s.Nil(err)
+	s.Equal(&pgproto.ReadyForQuery{Status: pgproto.READY_IDLE}, rec.Message)
+}
+
+func (s *RecordTestSuite) Test_InvalidRecording() {
+	_, err := record.NewReader(bytes.NewReader([]byte("SELECT 1")))
+	s.Equal(record.ErrInvalidRecording, err)
+
+	_, err = record.NewReader(bytes.NewReader([]byte("PGPROTO\x02\x00\x00\x00\x00\x00\x00\x00\x00")))
+	s.NotNil(err)
+}
+
+func (s *RecordTestSuite) Test_Truncated() {
+	buf := &bytes.Buffer{}
+	w := record.NewWriter(buf)
+	s.Nil(w.Write(record.Frontend, 1, time.Now(), &pgproto.SimpleQuery{Query: []byte("SELECT 1")}))
+	s.Nil(w.Flush())
+
+	r, err := record.NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-2]))
+	s.Require().Nil(err)
+	_, err = r.Next()
+	s.Equal(io.ErrUnexpectedEOF, err)
+}
+
+func (s *RecordTestSuite) Test_InvalidLength() {
+	buf := bytes.NewBufferString("PGPROTO\x01\x00\x00\x00\x00\x00\x00\x00\x00")
+	// Direction, session, delta and a length of 2^40
+	buf.Write([]byte{'F', '\x01', '\x00', '\x80', '\x80', '\x80', '\x80', '\x80', '\x20'})
+
+	r, err := record.NewReader(buf)
+	s.Require().Nil(err)
+	rec, err := r.Next()
+	s.EqualError(err, "record: invalid message length 1099511627776")
+	s.Nil(rec)
+}
+
+func (s *RecordTestSuite) Test_Interceptor() {
+	upstream := pgtest.NewServer(pgtest.Responses{
+		"SELECT 1": {
+			Fields: []pgproto.RowField{{ColumnName: []byte("?column?"), TypeOID: 23, ColumnLength: 4}},
+			Rows:   [][][]byte{{[]byte("1")}},
+		},
+	})
+	defer upstream.Close()
+
+	buf := &lockedBuffer{}
+	p := &proxy.Proxy{
+		Upstream:     upstream.Addr,
+		Interceptors: []proxy.Interceptor{record.Interceptor(record.NewWriter(buf))},
+		ErrorLog:     log.New(io.Discard, "", 0),
+	}
+	defer p.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().Nil(err)
+	go p.Serve(l)
+
+	client, err := pgtest.Connect(l.Addr().String(), map[string]string{"user": "pgproto"}, "")
+	s.Require().Nil(err)
+	_, err = client.Query("SELECT 1")
+	s.Require().Nil(err)
+	client.Close()
+
+	// The Termination is recorded once the proxy has read it
+	var types []string
+	s.True(pgtest.Eventually(func() bool {
+		r, err := record.NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			return false
+		}
+		types = make([]string, 0)
+		for {
+			rec, err := r.Next()
+			if err != nil || rec.Session != 1 {
+				break
+			}
+			types = append(types, rec.Direction.String()+":"+rec.Message.AsMap()["Type"].(string))
+		}
+		return len(types) > 0 && types[len(types)-1] == "Frontend:Termination"
+	}, time.Second))
+
+	s.Equal("Frontend:StartupMessage", types[0])
+	s.Contains(types, "Frontend:SimpleQuery")
+	s.Contains(types, "Backend:DataRow")
+	s.Contains(types, "Backend:ReadyForQuery")
+}
+
+func (s *RecordTestSuite) Test_Interceptor_Raw() {
+	// FunctionCall is not parsed by pgproto and the unknown 'X' field of the notice is dropped when
+	// it is parsed, both must be recorded as they were sent
+	functionCall := []byte{'F', '\x00', '\x00', '\x00', '\x0e', '\x00', '\x00', '\x04', '\xd2', '\x00', '\x00', '\x00', '\x00', '\x00', '\x00'}
+	notice := append([]byte{'N', '\x00', '\x00', '\x00', '\x14'}, "SNOTICE\x00Xextra\x00\x00"...)
+
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().Nil(err)
+	defer upstream.Close()
+	go func() {
+		conn, err := upstream.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err = pgproto.ParseClientMessage(conn); err != nil {
+			return
+		}
+		pgproto.WriteMessages([]pgproto.Message{
+			&pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodOK},
+			&pgproto.ReadyForQuery{Status: pgproto.READY_IDLE},
+		}, conn)
+		if _, err = io.ReadFull(conn, make([]byte, len(functionCall))); err != nil {
+			return
+		}
+		conn.Write(notice)
+		io.Copy(io.Discard, conn)
+	}()
+
+	buf := &lockedBuffer{}
+	p := &proxy.Proxy{
+		Upstream:     upstream.Addr().String(),
+		Interceptors: []proxy.Interceptor{record.Interceptor(record.NewWriter(buf))},
+		ErrorLog:     log.New(io.Discard, "", 0),
+	}
+	defer p.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().Nil(err)
+	go p.Serve(l)
+
+	client, err := pgtest.Connect(l.Addr().String(), map[string]string{"user": "pgproto"}, "")
+	s.Require().Nil(err)
+	defer client.Close()
+	_, err = client.Conn.Write(functionCall)
+	s.Require().Nil(err)
+	client.Conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = io.ReadFull(client.Conn, make([]byte, len(notice)))
+	s.Require().Nil(err)
+
+	r, err := record.NewReader(bytes.NewReader(buf.Bytes()))
+	s.Require().Nil(err)
+	var records []*record.Record
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		var perr *record.ParseError
+		if err != nil && !errors.As(err, &perr) {
+			s.Require().Nil(err)
+		}
+		records = append(records, rec)
+	}
+
+	s.Require().Len(records, 5)
+	s.Equal(record.Frontend, records[3].Direction)
+	s.Equal(functionCall, records[3].Raw)
+	s.Nil(records[3].Message)
+	s.Equal(record.Backend, records[4].Direction)
+	s.Equal(notice, records[4].Raw)
+	s.NotEqual(notice, records[4].Message.Encode())
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
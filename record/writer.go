@@ -0,0 +1,89 @@
+package record
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/c653labs/pgproto"
+)
+
+// Writer writes a recording, it is safe for concurrent use
+type Writer struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	started bool
+	last    time.Time
+	err     error
+}
+
+// NewWriter returns a Writer writing a recording to w, the header is written with the first record
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Write will record m as sent in direction d on session at t
+func (w *Writer) Write(d Direction, session uint64, t time.Time, m pgproto.Message) error {
+	return w.WriteRaw(d, session, t, m.Encode())
+}
+
+// WriteRaw will record the raw message bytes as sent in direction d on session at t
+//
+// Records must be written in chronological order, earlier times are recorded as the time of the previous record
+func (w *Writer) WriteRaw(d Direction, session uint64, t time.Time, raw []byte) error {
+	if d != Frontend && d != Backend {
+		return fmt.Errorf("record: invalid direction %d", d)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+
+	if !w.started {
+		w.started = true
+		w.last = t
+
+		header := make([]byte, 0, 16)
+		header = append(header, magic...)
+		header = append(header, Version)
+		header = binary.BigEndian.AppendUint64(header, uint64(t.UnixNano()))
+		w.write(header)
+	}
+
+	delta := t.Sub(w.last)
+	if delta < 0 {
+		delta = 0
+	}
+	w.last = w.last.Add(delta)
+
+	buf := make([]byte, 0, 1+3*binary.MaxVarintLen64)
+	buf = append(buf, byte(d))
+	buf = binary.AppendUvarint(buf, session)
+	buf = binary.AppendUvarint(buf, uint64(delta))
+	buf = binary.AppendUvarint(buf, uint64(len(raw)))
+	w.write(buf)
+	w.write(raw)
+	return w.err
+}
+
+// Flush will write any buffered records to the underlying io.Writer
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.w.Flush()
+	return w.err
+}
+
+func (w *Writer) write(b []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(b)
+	}
+}
@@ -0,0 +1,62 @@
+package pgproto
+
+// RedactionPolicy returns true for the fields of a message whose values must not be output
+//
+// Fields are named as in the message struct, map entries are named by field and key separated by
+// a dot, e.g. "Options.password" for the password option of a StartupMessage.
+type RedactionPolicy func(m Message, field string) bool
+
+// Redaction is the policy applied by the String, AsMap and MarshalJSON methods of every message, and
+// by Formatters without a policy of their own
+//
+// It defaults to RedactSecrets, set it to RedactNothing to output credentials, e.g. when debugging
+// authentication or to record sessions which can be replayed. It should be set before messages
+// are output.
+var Redaction RedactionPolicy = RedactSecrets
+
+// redactedText replaces the values of redacted fields in String and AsMap output
+const redactedText = "<redacted>"
+
+// RedactSecrets redacts the fields which may hold credentials or allow recovering them:
+//
+//   PasswordMessage.Password
+//   SASLInitialResponse.Data, SASLResponse.Data
+//   AuthenticationRequest.Salt, AuthenticationRequest.Data
+//   StartupMessage.Options.password, sent by some clients
+func RedactSecrets(m Message, field string) bool {
+	switch m.(type) {
+	case *PasswordMessage:
+		return field == "Password"
+	case *SASLInitialResponse, *SASLResponse:
+		return field == "Data"
+	case *AuthenticationRequest:
+		return field == "Salt" || field == "Data"
+	case *StartupMessage:
+		return field == "Options.password"
+	}
+	return false
+}
+
+// RedactNothing does not redact any field
+func RedactNothing(m Message, field string) bool { return false }
+
+// redacted returns whether the field of m must be redacted from its output by the Redaction policy
+func redacted(m Message, field string) bool {
+	return Redaction != nil && Redaction(m, field)
+}
+
+// redactBytes returns the value of a byte field for AsMap, replaced when it must be redacted
+func redactBytes(m Message, field string, value []byte) interface{} {
+	if value != nil && redacted(m, field) {
+		return redactedText
+	}
+	return value
+}
+
+// redactString returns the value of a text field for AsMap, replaced when it must be redacted
+func redactString(m Message, field string, value []byte) string {
+	if value != nil && redacted(m, field) {
+		return redactedText
+	}
+	return string(value)
+}
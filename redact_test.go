@@ -0,0 +1,62 @@
+package pgproto_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+type RedactTestSuite struct {
+	suite.Suite
+}
+
+func TestRedactTestSuite(t *testing.T) {
+	suite.Run(t, new(RedactTestSuite))
+}
+
+func (s *RedactTestSuite) Test_RedactSecrets() {
+	password := &pgproto.PasswordMessage{Password: []byte("secret")}
+	s.Equal(`PasswordMessage<Password=<redacted>>`, password.String())
+	s.Equal("<redacted>", password.AsMap()["Payload"].(map[string]interface{})["Password"])
+
+	encoded, err := json.Marshal(password)
+	s.Nil(err)
+	s.Equal(`{"type":"PasswordMessage","payload":{"Password":{"redacted":true}}}`, string(encoded))
+
+	// Redacted values are decoded as nil
+	decoded, err := pgproto.DecodeJSONMessage(encoded)
+	s.Nil(err)
+	s.Equal(&pgproto.PasswordMessage{}, decoded)
+
+	sasl := &pgproto.SASLResponse{Data: []byte("c=biws,r=abc,p=proof")}
+	s.Equal(`SASLResponse<Data=<redacted>>`, sasl.String())
+	s.Equal("<redacted>", sasl.AsMap()["Payload"].(map[string]interface{})["Data"])
+
+	// Only the password option of a startup message is redacted
+	startup := &pgproto.StartupMessage{Options: map[string][]byte{"user": []byte("pgproto"), "password": []byte("secret")}}
+	s.Equal(`StartupMessage<SSLRequest=false, GSSEncRequest=false, Options={password=<redacted>, user="pgproto"}>`, startup.String())
+	s.Equal(map[string]string{"user": "pgproto", "password": "<redacted>"}, startup.AsMap()["Payload"].(map[string]interface{})["Options"])
+
+	encoded, err = json.Marshal(startup)
+	s.Nil(err)
+	s.Equal(`{"type":"StartupMessage","payload":{"SSLRequest":false,"GSSEncRequest":false,"Options":{"password":{"redacted":true},"user":"pgproto"}}}`, string(encoded))
+
+	// Missing values are not redacted
+	auth := &pgproto.AuthenticationRequest{Method: pgproto.AuthenticationMethodOK}
+	s.Equal(`AuthenticationRequest<Method=OK, Salt=nil, Mechanisms=[], Data=nil>`, auth.String())
+}
+
+func (s *RedactTestSuite) Test_RedactNothing() {
+	pgproto.Redaction = pgproto.RedactNothing
+	defer func() { pgproto.Redaction = pgproto.RedactSecrets }()
+
+	password := &pgproto.PasswordMessage{Password: []byte("secret")}
+	s.Equal(`PasswordMessage<Password="secret">`, password.String())
+	s.Equal("secret", password.AsMap()["Payload"].(map[string]interface{})["Password"])
+
+	encoded, err := json.Marshal(password)
+	s.Nil(err)
+	s.Equal(`{"type":"PasswordMessage","payload":{"Password":"secret"}}`, string(encoded))
+}
@@ -16,7 +16,7 @@ type RowField struct {
 
 func (f RowField) AsMap() map[string]interface{} {
 	return map[string]interface{}{
-		"ColumnName":   f.ColumnName,
+		"ColumnName":   string(f.ColumnName),
 		"TableOID":     f.TableOID,
 		"ColumnIndex":  f.ColumnIndex,
 		"TypeOID":      f.TypeOID,
@@ -48,7 +48,8 @@ func ParseRowDescription(r io.Reader) (*RowDescription, error) {
 	}
 
 	// Field count - int16
-	c, err := b.ReadInt16()
+	// Each field takes at least 19 bytes, its name's null terminator and the fixed size attributes
+	c, err := b.ReadCount(19)
 	if err != nil {
 		return nil, err
 	}
@@ -149,3 +150,13 @@ func (r *RowDescription) AsMap() map[string]interface{} {
 }
 
 func (r *RowDescription) String() string { return messageToString(r) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (r *RowDescription) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("RowDescription", r)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (r *RowDescription) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "RowDescription", r)
+}
@@ -0,0 +1,178 @@
+package pgproto
+
+import (
+	"io"
+)
+
+// SASLInitialResponse is the first client message of a SASL authentication exchange,
+// selecting the mechanism to use. A nil Data is sent as a length of -1
+type SASLInitialResponse struct {
+	Mechanism []byte
+	Data      []byte
+}
+
+func (s *SASLInitialResponse) client() {}
+
+// ParseSASLInitialResponse will attempt to read a SASLInitialResponse message from the io.Reader
+func ParseSASLInitialResponse(r io.Reader) (*SASLInitialResponse, error) {
+	b := newReadBuffer(r)
+
+	// 'p' [int32 - length] [string - mechanism] \0 [int32 - data length] [bytes - data]
+	err := b.ReadTag('p')
+	if err != nil {
+		return nil, err
+	}
+
+	b, err = b.ReadLength()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SASLInitialResponse{}
+	s.Mechanism, err = b.ReadString(stripNull)
+	if err != nil {
+		return nil, err
+	}
+
+	// [int32 - length] [bytes - data], a length of -1 means there is no initial response
+	s.Data, err = b.ReadValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Encode will return the byte representation of this message
+func (s *SASLInitialResponse) Encode() []byte {
+	w := newWriteBuffer()
+	w.WriteString(s.Mechanism, writeNull)
+	if s.Data == nil {
+		w.WriteInt(-1)
+	} else {
+		w.WriteInt(len(s.Data))
+		w.WriteBytes(s.Data)
+	}
+	w.Wrap('p')
+	return w.Bytes()
+}
+
+// AsMap method returns a common map representation of this message:
+//
+//   map[string]interface{}{
+//     "Type": "SASLInitialResponse",
+//     "Payload": map[string]interface{}{
+//       "Mechanism": <SASLInitialResponse.Mechanism>,
+//       "Data": <SASLInitialResponse.Data>,
+//     },
+//   }
+func (s *SASLInitialResponse) AsMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Type": "SASLInitialResponse",
+		"Payload": map[string]interface{}{
+			"Mechanism": string(s.Mechanism),
+			"Data":      redactBytes(s, "Data", s.Data),
+		},
+	}
+}
+
+func (s *SASLInitialResponse) String() string { return messageToString(s) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (s *SASLInitialResponse) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("SASLInitialResponse", s)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (s *SASLInitialResponse) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "SASLInitialResponse", s)
+}
+
+// SASLResponse is a client message continuing a SASL authentication exchange
+type SASLResponse struct {
+	Data []byte
+}
+
+func (s *SASLResponse) client() {}
+
+// ParseSASLResponse will attempt to read a SASLResponse message from the io.Reader
+func ParseSASLResponse(r io.Reader) (*SASLResponse, error) {
+	b := newReadBuffer(r)
+
+	// 'p' [int32 - length] [bytes - data]
+	err := b.ReadTag('p')
+	if err != nil {
+		return nil, err
+	}
+
+	b, err = b.ReadLength()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SASLResponse{
+		Data: []byte{},
+	}
+	if b != nil {
+		s.Data, err = b.ReadRemaining()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Encode will return the byte representation of this message
+func (s *SASLResponse) Encode() []byte {
+	w := newWriteBuffer()
+	w.WriteBytes(s.Data)
+	w.Wrap('p')
+	return w.Bytes()
+}
+
+// AsMap method returns a common map representation of this message:
+//
+//   map[string]interface{}{
+//     "Type": "SASLResponse",
+//     "Payload": map[string]interface{}{
+//       "Data": <SASLResponse.Data>,
+//     },
+//   }
+func (s *SASLResponse) AsMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Type": "SASLResponse",
+		"Payload": map[string]interface{}{
+			"Data": redactBytes(s, "Data", s.Data),
+		},
+	}
+}
+
+func (s *SASLResponse) String() string { return messageToString(s) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (s *SASLResponse) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("SASLResponse", s)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (s *SASLResponse) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "SASLResponse", s)
+}
+
+// ParseAuthenticationResponse will read the client's 'p' message sent in response to req
+//
+// PasswordMessage, SASLInitialResponse and SASLResponse all share the 'p' tag and can only be told
+// apart by the AuthenticationRequest the server sent last, which is why ParseClientMessage always
+// returns a PasswordMessage for them
+func ParseAuthenticationResponse(r io.Reader, req *AuthenticationRequest) (ClientMessage, error) {
+	if req != nil {
+		switch req.Method {
+		case AuthenticationMethodSASL:
+			return ParseSASLInitialResponse(r)
+		case AuthenticationMethodSASLContinue:
+			return ParseSASLResponse(r)
+		}
+	}
+	return ParsePasswordMessage(r)
+}
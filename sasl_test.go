@@ -0,0 +1,141 @@
+package pgproto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/stretchr/testify/suite"
+)
+
+type SASLTestSuite struct {
+	suite.Suite
+}
+
+func TestSASLTestSuite(t *testing.T) {
+	suite.Run(t, new(SASLTestSuite))
+}
+
+func (s *SASLTestSuite) Test_ParseAuthenticationRequest_SASL() {
+	raw := []byte{
+		// Tag
+		'R',
+		// Length
+		'\x00', '\x00', '\x00', '\x17',
+		// Method
+		'\x00', '\x00', '\x00', '\x0a',
+		// "SCRAM-SHA-256" \0
+		'S', 'C', 'R', 'A', 'M', '-', 'S', 'H', 'A', '-', '2', '5', '6', '\x00',
+		// ending
+		'\x00',
+	}
+
+	auth, err := pgproto.ParseAuthenticationRequest(bytes.NewReader(raw))
+	s.Nil(err)
+	s.NotNil(auth)
+	s.Equal(pgproto.AuthenticationMethodSASL, auth.Method)
+	s.Equal([][]byte{[]byte("SCRAM-SHA-256")}, auth.Mechanisms)
+	s.Equal(raw, auth.Encode())
+}
+
+func (s *SASLTestSuite) Test_ParseAuthenticationRequest_SASLContinue() {
+	raw := []byte{
+		// Tag
+		'R',
+		// Length
+		'\x00', '\x00', '\x00', '\x0b',
+		// Method
+		'\x00', '\x00', '\x00', '\x0b',
+		// Data
+		'r', '=', 'a',
+	}
+
+	auth, err := pgproto.ParseAuthenticationRequest(bytes.NewReader(raw))
+	s.Nil(err)
+	s.NotNil(auth)
+	s.Equal(pgproto.AuthenticationMethodSASLContinue, auth.Method)
+	s.Equal([]byte("r=a"), auth.Data)
+	s.Equal(raw, auth.Encode())
+}
+
+func (s *SASLTestSuite) Test_ParseSASLInitialResponse() {
+	raw := []byte{
+		// Tag
+		'p',
+		// Length
+		'\x00', '\x00', '\x00', '\x19',
+		// "SCRAM-SHA-256" \0
+		'S', 'C', 'R', 'A', 'M', '-', 'S', 'H', 'A', '-', '2', '5', '6', '\x00',
+		// Data length
+		'\x00', '\x00', '\x00', '\x03',
+		// Data
+		'n', ',', ',',
+	}
+
+	m, err := pgproto.ParseAuthenticationResponse(bytes.NewReader(raw), &pgproto.AuthenticationRequest{
+		Method: pgproto.AuthenticationMethodSASL,
+	})
+	s.Nil(err)
+	resp, ok := m.(*pgproto.SASLInitialResponse)
+	s.True(ok)
+	s.Equal([]byte("SCRAM-SHA-256"), resp.Mechanism)
+	s.Equal([]byte("n,,"), resp.Data)
+	s.Equal(raw, resp.Encode())
+}
+
+func (s *SASLTestSuite) Test_ParseSASLInitialResponse_Malformed() {
+	for _, length := range [][]byte{
+		// Negative data length other than -1
+		{'\xff', '\xff', '\xff', '\xfe'},
+		// Data length past the end of the message
+		{'\x00', '\x00', '\x00', '\x04'},
+	} {
+		raw := []byte{'p', '\x00', '\x00', '\x00', '\x0f', 'P', 'L', 'A', 'I', 'N', '\x00'}
+		raw = append(raw, length...)
+		raw = append(raw, 'a', 'b')
+
+		s.NotPanics(func() {
+			resp, err := pgproto.ParseSASLInitialResponse(bytes.NewReader(raw))
+			s.NotNil(err)
+			s.Nil(resp)
+		})
+	}
+}
+
+func (s *SASLTestSuite) Test_ParseSASLResponse() {
+	raw := []byte{
+		// Tag
+		'p',
+		// Length
+		'\x00', '\x00', '\x00', '\x07',
+		// Data
+		'c', '=', 'b',
+	}
+
+	m, err := pgproto.ParseAuthenticationResponse(bytes.NewReader(raw), &pgproto.AuthenticationRequest{
+		Method: pgproto.AuthenticationMethodSASLContinue,
+	})
+	s.Nil(err)
+	resp, ok := m.(*pgproto.SASLResponse)
+	s.True(ok)
+	s.Equal([]byte("c=b"), resp.Data)
+	s.Equal(raw, resp.Encode())
+}
+
+func (s *SASLTestSuite) Test_ParseAuthenticationResponse_Password() {
+	raw := []byte{
+		// Tag
+		'p',
+		// Length
+		'\x00', '\x00', '\x00', '\x09',
+		// "pass" \0
+		'p', 'a', 's', 's', '\x00',
+	}
+
+	m, err := pgproto.ParseAuthenticationResponse(bytes.NewReader(raw), nil)
+	s.Nil(err)
+	resp, ok := m.(*pgproto.PasswordMessage)
+	s.True(ok)
+	s.Equal([]byte("pass"), resp.Password)
+	s.Equal(raw, resp.Encode())
+}
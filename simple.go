@@ -50,3 +50,13 @@ func (q *SimpleQuery) AsMap() map[string]interface{} {
 }
 
 func (q *SimpleQuery) String() string { return messageToString(q) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (q *SimpleQuery) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("SimpleQuery", q)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (q *SimpleQuery) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "SimpleQuery", q)
+}
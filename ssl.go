@@ -0,0 +1,254 @@
+package pgproto
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// ALPNProtocol is the TLS application protocol name used by PostgreSQL
+const ALPNProtocol = "postgresql"
+
+// tlsHandshakeRecord is the first byte of a TLS ClientHello, used to detect direct TLS connections
+const tlsHandshakeRecord = '\x16'
+
+// ErrDirectSSLNotSupported is returned by AcceptSSL when a client starts a direct TLS handshake
+// but no tls.Config was provided
+var ErrDirectSSLNotSupported = errors.New("received direct SSL connection request without SSL configured")
+
+// SSLResponse is the single byte server response to an SSLRequest or GSSEncRequest,
+// 'S' when the server is willing to perform the TLS handshake or 'N' otherwise
+type SSLResponse struct {
+	Accepted bool
+}
+
+func (s *SSLResponse) server() {}
+
+// ParseSSLResponse will attempt to read an SSLResponse from the io.Reader
+//
+// This message cannot be detected by ParseServerMessage since it does not have a tag or length,
+// it must be read explicitly after sending an SSLRequest
+func ParseSSLResponse(r io.Reader) (*SSLResponse, error) {
+	b := newReadBuffer(r)
+
+	// 'S' | 'N'
+	c, err := b.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch c {
+	case 'S':
+		return &SSLResponse{Accepted: true}, nil
+	case 'N':
+		return &SSLResponse{Accepted: false}, nil
+	case 'E':
+		// Servers older than 7.3 respond with an error message instead
+		return nil, fmt.Errorf("server does not support SSL negotiation")
+	}
+	return nil, fmt.Errorf("invalid SSL response '%c', must be 'S' or 'N'", c)
+}
+
+// Encode will return the byte representation of this message
+func (s *SSLResponse) Encode() []byte {
+	if s.Accepted {
+		return []byte{'S'}
+	}
+	return []byte{'N'}
+}
+
+// AsMap method returns a common map representation of this message:
+//
+//   map[string]interface{}{
+//     "Type": "SSLResponse",
+//     "Payload": map[string]interface{}{
+//       "Accepted": <SSLResponse.Accepted>,
+//     },
+//   }
+func (s *SSLResponse) AsMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Type": "SSLResponse",
+		"Payload": map[string]interface{}{
+			"Accepted": s.Accepted,
+		},
+	}
+}
+
+func (s *SSLResponse) String() string { return messageToString(s) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (s *SSLResponse) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("SSLResponse", s)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (s *SSLResponse) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "SSLResponse", s)
+}
+
+// RequestSSL will perform the client side of the SSLRequest exchange on conn
+//
+// When the server accepts the request the TLS handshake is performed using config and the upgraded
+// connection is returned with a value of true. When the server refuses the original connection is
+// returned with a value of false, the caller can decide whether to continue unencrypted.
+func RequestSSL(conn net.Conn, config *tls.Config) (net.Conn, bool, error) {
+	req := &StartupMessage{SSLRequest: true}
+	_, err := WriteMessage(req, conn)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resp, err := ParseSSLResponse(conn)
+	if err != nil {
+		return nil, false, err
+	}
+	if !resp.Accepted {
+		return conn, false, nil
+	}
+
+	tlsConn := tls.Client(conn, withALPN(config))
+	err = tlsConn.Handshake()
+	if err != nil {
+		return nil, false, err
+	}
+	return tlsConn, true, nil
+}
+
+// AcceptSSL will perform the server side of the SSL negotiation on a newly accepted conn and
+// return the connection to use going forward along with the client's first message, which is
+// either a *StartupMessage or a *CancelRequest
+//
+// SSLRequests are answered with 'S' and upgraded using config when it is not nil, otherwise they are
+// answered with 'N'. GSSEncRequests are always refused. Clients connecting with direct TLS
+// (PostgreSQL 17 sslnegotiation=direct) are detected from the first byte and must negotiate the
+// "postgresql" ALPN protocol. Like PostgreSQL, each request is accepted at most once before the
+// startup message, a repeated request is an error.
+func AcceptSSL(conn net.Conn, config *tls.Config) (net.Conn, ClientMessage, error) {
+	br := bufio.NewReader(conn)
+	first, err := br.Peek(1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Direct TLS connection, there is no SSLRequest, the client starts the handshake right away
+	if first[0] == tlsHandshakeRecord {
+		if config == nil {
+			return nil, nil, ErrDirectSSLNotSupported
+		}
+
+		tlsConn := tls.Server(&bufferedConn{Conn: conn, r: br}, withALPN(config))
+		err = tlsConn.Handshake()
+		if err != nil {
+			return nil, nil, err
+		}
+		if tlsConn.ConnectionState().NegotiatedProtocol != ALPNProtocol {
+			return nil, nil, fmt.Errorf("direct SSL connection requires ALPN protocol %q", ALPNProtocol)
+		}
+		return acceptEncryptedStartup(tlsConn)
+	}
+
+	var sslDone, gssDone bool
+	for {
+		msg, err := readStartup(br)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		startup, ok := msg.(*StartupMessage)
+		switch {
+		case !ok:
+			// Cancel request
+			return &bufferedConn{Conn: conn, r: br}, msg, nil
+		case startup.GSSEncRequest && gssDone:
+			return nil, nil, fmt.Errorf("received a second GSSAPI encryption request")
+		case startup.SSLRequest && sslDone:
+			return nil, nil, fmt.Errorf("received a second SSL request")
+		case startup.GSSEncRequest:
+			// We do not support GSSAPI encryption, the client may try SSL or continue unencrypted next
+			gssDone = true
+			_, err = WriteMessage(&SSLResponse{Accepted: false}, conn)
+			if err != nil {
+				return nil, nil, err
+			}
+		case startup.SSLRequest && config == nil:
+			sslDone = true
+			_, err = WriteMessage(&SSLResponse{Accepted: false}, conn)
+			if err != nil {
+				return nil, nil, err
+			}
+		case startup.SSLRequest:
+			// The client must wait for our response before starting the handshake,
+			// anything already buffered was sent unencrypted and cannot be trusted
+			if br.Buffered() > 0 {
+				return nil, nil, fmt.Errorf("received unencrypted data after SSL request")
+			}
+
+			_, err = WriteMessage(&SSLResponse{Accepted: true}, conn)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			tlsConn := tls.Server(conn, withALPN(config))
+			err = tlsConn.Handshake()
+			if err != nil {
+				return nil, nil, err
+			}
+			return acceptEncryptedStartup(tlsConn)
+		default:
+			return &bufferedConn{Conn: conn, r: br}, startup, nil
+		}
+	}
+}
+
+// acceptEncryptedStartup reads the first message sent after the TLS handshake has completed
+func acceptEncryptedStartup(conn net.Conn) (net.Conn, ClientMessage, error) {
+	msg, err := readStartup(conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if startup, ok := msg.(*StartupMessage); ok && (startup.SSLRequest || startup.GSSEncRequest) {
+		return nil, nil, fmt.Errorf("received encryption request on an encrypted connection")
+	}
+	return conn, msg, nil
+}
+
+// readStartup reads a message which must either be a StartupMessage or a CancelRequest
+func readStartup(r io.Reader) (ClientMessage, error) {
+	msg, err := ParseClientMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch msg.(type) {
+	case *StartupMessage, *CancelRequest:
+		return msg, nil
+	}
+	return nil, fmt.Errorf("expected startup message, received %s", msg.AsMap()["Type"])
+}
+
+// withALPN returns a copy of config which advertises the PostgreSQL ALPN protocol
+func withALPN(config *tls.Config) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	}
+	for _, p := range config.NextProtos {
+		if p == ALPNProtocol {
+			return config
+		}
+	}
+
+	config = config.Clone()
+	config.NextProtos = append(config.NextProtos, ALPNProtocol)
+	return config
+}
+
+// bufferedConn is a net.Conn which first reads any data already buffered while inspecting the connection
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) { return c.r.Read(p) }
@@ -0,0 +1,274 @@
+package pgproto_test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtest"
+	"github.com/stretchr/testify/suite"
+)
+
+type SSLTestSuite struct {
+	suite.Suite
+	serverConfig *tls.Config
+	clientConfig *tls.Config
+}
+
+func TestSSLTestSuite(t *testing.T) {
+	suite.Run(t, new(SSLTestSuite))
+}
+
+func (s *SSLTestSuite) SetupSuite() {
+	s.serverConfig, s.clientConfig = pgtest.Certificates()
+}
+
+// serve will accept a single connection and run AcceptSSL on it
+func (s *SSLTestSuite) serve(config *tls.Config) (string, chan *pgproto.StartupMessage, chan error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().Nil(err)
+
+	startups := make(chan *pgproto.StartupMessage, 1)
+	errs := make(chan error, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer conn.Close()
+
+		c, msg, err := pgproto.AcceptSSL(conn, config)
+		if err != nil {
+			errs <- err
+			return
+		}
+		startup, ok := msg.(*pgproto.StartupMessage)
+		if !ok {
+			errs <- fmt.Errorf("expected startup message, got %s", msg)
+			return
+		}
+		// Confirm the connection is usable by replying with a message
+		_, err = pgproto.WriteMessage(&pgproto.ReadyForQuery{Status: pgproto.READY_IDLE}, c)
+		if err != nil {
+			errs <- err
+			return
+		}
+		startups <- startup
+	}()
+	return l.Addr().String(), startups, errs
+}
+
+func (s *SSLTestSuite) startup() *pgproto.StartupMessage {
+	return &pgproto.StartupMessage{
+		Options: map[string][]byte{
+			"user":     []byte("pgproto"),
+			"database": []byte("db_name"),
+		},
+	}
+}
+
+func (s *SSLTestSuite) finish(conn net.Conn, startups chan *pgproto.StartupMessage, errs chan error) {
+	_, err := pgproto.WriteMessage(s.startup(), conn)
+	s.Require().Nil(err)
+
+	msg, err := pgproto.ParseServerMessage(conn)
+	s.Require().Nil(err)
+	s.IsType(&pgproto.ReadyForQuery{}, msg)
+
+	select {
+	case startup := <-startups:
+		s.Equal([]byte("pgproto"), startup.Options["user"])
+		s.Equal([]byte("db_name"), startup.Options["database"])
+	case err := <-errs:
+		s.Fail(err.Error())
+	}
+}
+
+func (s *SSLTestSuite) Test_SSLRequestEncode() {
+	expected := []byte{
+		// Length
+		'\x00', '\x00', '\x00', '\x08',
+		// SSL request code
+		'\x04', '\xd2', '\x16', '\x2f',
+	}
+	startup := &pgproto.StartupMessage{SSLRequest: true}
+	s.Equal(expected, startup.Encode())
+
+	parsed, err := pgproto.ParseStartupMessage(bytes.NewReader(expected))
+	s.Nil(err)
+	s.True(parsed.SSLRequest)
+}
+
+func (s *SSLTestSuite) Test_GSSEncRequestEncode() {
+	expected := []byte{
+		// Length
+		'\x00', '\x00', '\x00', '\x08',
+		// GSSAPI encryption request code
+		'\x04', '\xd2', '\x16', '\x30',
+	}
+	startup := &pgproto.StartupMessage{GSSEncRequest: true}
+	s.Equal(expected, startup.Encode())
+
+	parsed, err := pgproto.ParseStartupMessage(bytes.NewReader(expected))
+	s.Nil(err)
+	s.True(parsed.GSSEncRequest)
+}
+
+func (s *SSLTestSuite) Test_ParseSSLResponse() {
+	resp, err := pgproto.ParseSSLResponse(bytes.NewReader([]byte{'S'}))
+	s.Nil(err)
+	s.True(resp.Accepted)
+	s.Equal([]byte{'S'}, resp.Encode())
+
+	resp, err = pgproto.ParseSSLResponse(bytes.NewReader([]byte{'N'}))
+	s.Nil(err)
+	s.False(resp.Accepted)
+	s.Equal([]byte{'N'}, resp.Encode())
+
+	resp, err = pgproto.ParseSSLResponse(bytes.NewReader([]byte{'X'}))
+	s.NotNil(err)
+	s.Nil(resp)
+}
+
+func (s *SSLTestSuite) Test_SSLRequest_Accepted() {
+	addr, startups, errs := s.serve(s.serverConfig)
+	conn, err := net.Dial("tcp", addr)
+	s.Require().Nil(err)
+	defer conn.Close()
+
+	c, ok, err := pgproto.RequestSSL(conn, s.clientConfig)
+	s.Require().Nil(err)
+	s.True(ok)
+
+	tlsConn, isTLS := c.(*tls.Conn)
+	s.Require().True(isTLS)
+	s.Equal(pgproto.ALPNProtocol, tlsConn.ConnectionState().NegotiatedProtocol)
+	s.finish(c, startups, errs)
+}
+
+func (s *SSLTestSuite) Test_SSLRequest_Refused() {
+	addr, startups, errs := s.serve(nil)
+	conn, err := net.Dial("tcp", addr)
+	s.Require().Nil(err)
+	defer conn.Close()
+
+	c, ok, err := pgproto.RequestSSL(conn, s.clientConfig)
+	s.Require().Nil(err)
+	s.False(ok)
+	s.Equal(conn, c)
+	s.finish(c, startups, errs)
+}
+
+func (s *SSLTestSuite) Test_NoSSLRequest() {
+	addr, startups, errs := s.serve(s.serverConfig)
+	conn, err := net.Dial("tcp", addr)
+	s.Require().Nil(err)
+	defer conn.Close()
+
+	s.finish(conn, startups, errs)
+}
+
+func (s *SSLTestSuite) Test_GSSEncRequest_Refused() {
+	addr, startups, errs := s.serve(s.serverConfig)
+	conn, err := net.Dial("tcp", addr)
+	s.Require().Nil(err)
+	defer conn.Close()
+
+	_, err = pgproto.WriteMessage(&pgproto.StartupMessage{GSSEncRequest: true}, conn)
+	s.Require().Nil(err)
+	resp, err := pgproto.ParseSSLResponse(conn)
+	s.Require().Nil(err)
+	s.False(resp.Accepted)
+
+	// Clients fall back to SSL after GSSAPI encryption was refused
+	c, ok, err := pgproto.RequestSSL(conn, s.clientConfig)
+	s.Require().Nil(err)
+	s.True(ok)
+	s.finish(c, startups, errs)
+}
+
+func (s *SSLTestSuite) Test_RepeatedRequest() {
+	for _, request := range []*pgproto.StartupMessage{{SSLRequest: true}, {GSSEncRequest: true}} {
+		addr, _, errs := s.serve(nil)
+		conn, err := net.Dial("tcp", addr)
+		s.Require().Nil(err)
+
+		// Each request is refused once, then the connection is closed
+		_, err = pgproto.WriteMessage(request, conn)
+		s.Require().Nil(err)
+		resp, err := pgproto.ParseSSLResponse(conn)
+		s.Require().Nil(err)
+		s.False(resp.Accepted)
+
+		_, err = pgproto.WriteMessage(request, conn)
+		s.Require().Nil(err)
+		s.NotNil(<-errs)
+		conn.Close()
+	}
+}
+
+func (s *SSLTestSuite) Test_CancelRequest() {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().Nil(err)
+	defer l.Close()
+
+	msgs := make(chan pgproto.ClientMessage, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, msg, _ := pgproto.AcceptSSL(conn, s.serverConfig)
+		msgs <- msg
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	s.Require().Nil(err)
+	defer conn.Close()
+
+	_, err = pgproto.WriteMessage(&pgproto.CancelRequest{PID: 1234, Key: 5678}, conn)
+	s.Require().Nil(err)
+	s.Equal(&pgproto.CancelRequest{PID: 1234, Key: 5678}, <-msgs)
+}
+
+func (s *SSLTestSuite) Test_DirectSSL() {
+	addr, startups, errs := s.serve(s.serverConfig)
+	config := s.clientConfig.Clone()
+	config.NextProtos = []string{pgproto.ALPNProtocol}
+
+	conn, err := tls.Dial("tcp", addr, config)
+	s.Require().Nil(err)
+	defer conn.Close()
+
+	s.finish(conn, startups, errs)
+}
+
+func (s *SSLTestSuite) Test_DirectSSL_MissingALPN() {
+	addr, _, errs := s.serve(s.serverConfig)
+
+	conn, err := tls.Dial("tcp", addr, s.clientConfig)
+	if err == nil {
+		defer conn.Close()
+	}
+
+	s.NotNil(<-errs)
+}
+
+func (s *SSLTestSuite) Test_DirectSSL_NotConfigured() {
+	addr, _, errs := s.serve(nil)
+	config := s.clientConfig.Clone()
+	config.NextProtos = []string{pgproto.ALPNProtocol}
+
+	conn, err := tls.Dial("tcp", addr, config)
+	if err == nil {
+		defer conn.Close()
+	}
+
+	s.Equal(pgproto.ErrDirectSSLNotSupported, <-errs)
+}
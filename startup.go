@@ -8,12 +8,14 @@ import (
 )
 
 const (
-	sslRequestVersion = 80877103
+	sslRequestVersion    = 80877103
+	gssEncRequestVersion = 80877104
 )
 
 type StartupMessage struct {
-	SSLRequest bool
-	Options    map[string][]byte
+	SSLRequest    bool
+	GSSEncRequest bool
+	Options       map[string][]byte
 }
 
 func (s *StartupMessage) client() {}
@@ -38,11 +40,15 @@ func ParseStartupMessage(r io.Reader) (*StartupMessage, error) {
 		return nil, err
 	}
 
-	// Protocol version should either be protocol version 3.0 or an SSL request version
+	// Protocol version should either be protocol version 3.0 or an SSL/GSSAPI encryption request version
 	if p == sslRequestVersion {
 		s.SSLRequest = true
 		// Exit early, we don't have any options
 		return s, nil
+	} else if p == gssEncRequestVersion {
+		s.GSSEncRequest = true
+		// Exit early, we don't have any options
+		return s, nil
 	} else if p != ProtocolVersion {
 		return nil, fmt.Errorf("unsupported protocol version")
 	}
@@ -56,7 +62,10 @@ func ParseStartupMessage(r io.Reader) (*StartupMessage, error) {
 			return nil, err
 		}
 
-		// This message ends in a single null terminator
+		// This message ends in a single null terminator, an empty key means it is missing
+		if len(key) == 0 {
+			return nil, fmt.Errorf("missing null terminator in startup message")
+		}
 		if bytes.Equal(key, []byte{'\x00'}) {
 			break
 		}
@@ -76,6 +85,18 @@ func ParseStartupMessage(r io.Reader) (*StartupMessage, error) {
 
 func (s *StartupMessage) Encode() []byte {
 	w := newWriteBuffer()
+
+	// Encryption requests are only [int32 - length] [int32 - request code]
+	if s.SSLRequest || s.GSSEncRequest {
+		if s.SSLRequest {
+			w.WriteInt(sslRequestVersion)
+		} else {
+			w.WriteInt(gssEncRequestVersion)
+		}
+		w.PrependLength()
+		return w.Bytes()
+	}
+
 	w.WriteInt(ProtocolVersion)
 
 	// Encode the options in sorted order
@@ -97,14 +118,29 @@ func (s *StartupMessage) Encode() []byte {
 }
 
 func (s *StartupMessage) AsMap() map[string]interface{} {
+	options := make(map[string]string, len(s.Options))
+	for k, v := range s.Options {
+		options[k] = redactString(s, "Options."+k, v)
+	}
 	return map[string]interface{}{
 		"Type": "StartupMessage",
 		"Payload": map[string]interface{}{
-			"SSLRequest": s.SSLRequest,
-			"Protocol":   ProtocolVersion,
-			"Options":    s.Options,
+			"SSLRequest":    s.SSLRequest,
+			"GSSEncRequest": s.GSSEncRequest,
+			"Protocol":      ProtocolVersion,
+			"Options":       options,
 		},
 	}
 }
 
 func (s *StartupMessage) String() string { return messageToString(s) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (s *StartupMessage) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("StartupMessage", s)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (s *StartupMessage) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "StartupMessage", s)
+}
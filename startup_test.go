@@ -72,6 +72,30 @@ func (s *StartupMessageTestSuite) Test_ParseStartupMessage_InvalidProtocolVersio
 	s.Nil(startup)
 }
 
+func (s *StartupMessageTestSuite) Test_ParseStartupMessage_Malformed() {
+	for name, raw := range map[string][]byte{
+		// The options are not ended by a null terminator
+		"missing terminator": {
+			'\x00', '\x00', '\x00', '\x11',
+			'\x00', '\x03', '\x00', '\x00',
+			'u', 's', 'e', 'r', '\x00', 'b', 'o', 'b', '\x00',
+		},
+		"no options": {
+			'\x00', '\x00', '\x00', '\x08',
+			'\x00', '\x03', '\x00', '\x00',
+		},
+		// Startup messages are limited to 10000 bytes
+		"too long": {
+			'\x00', '\x00', '\x27', '\x11',
+			'\x00', '\x03', '\x00', '\x00',
+		},
+	} {
+		startup, err := pgproto.ParseClientMessage(bytes.NewReader(raw))
+		s.NotNil(err, name)
+		s.Nil(startup, name)
+	}
+}
+
 func (s *StartupMessageTestSuite) Test_StartupMessageEncode() {
 	expected := []byte{
 		// Length
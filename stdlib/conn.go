@@ -0,0 +1,318 @@
+package stdlib
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/internal/pgconn"
+	"github.com/c653labs/pgproto/pgtype"
+)
+
+// ErrFailedTransaction is returned by Commit when a query of the transaction failed, the transaction
+// is rolled back
+var ErrFailedTransaction = errors.New("stdlib: transaction failed and was rolled back")
+
+// errBusy is returned when a query is sent before the rows of the previous query are closed
+var errBusy = errors.New("stdlib: connection busy, the rows of the previous query are not closed")
+
+// cancelTimeout bounds the time spent sending a CancelRequest
+const cancelTimeout = 10 * time.Second
+
+// Conn is a connection to a server implementing the database/sql/driver interfaces, it is used by a
+// single goroutine at a time
+type Conn struct {
+	conn *pgconn.Conn
+	m    *pgtype.TypeMap
+
+	// rows are the response being read, the connection can not send a query until it is complete
+	rows *Rows
+
+	// bad is set once the connection is broken, database/sql then discards it
+	bad bool
+
+	// statements counts the statements prepared, naming them
+	statements int
+}
+
+// TypeMap returns the TypeMap converting the values of the connection, e.g. to register types with
+// sql.Conn.Raw
+func (c *Conn) TypeMap() *pgtype.TypeMap { return c.m }
+
+// send writes msgs to the server, a request ended by a Sync or a simple query, and returns the Rows
+// reading the response, the query is canceled when ctx is done before the response is complete
+func (c *Conn) send(ctx context.Context, msgs ...pgproto.ClientMessage) (*Rows, error) {
+	if c.bad {
+		return nil, driver.ErrBadConn
+	}
+	if c.rows != nil {
+		return nil, errBusy
+	}
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.conn.Send(msgs...)
+	if err != nil {
+		c.bad = true
+		return nil, err
+	}
+	c.rows = &Rows{c: c, ctx: ctx, stop: c.watch(ctx)}
+	return c.rows, nil
+}
+
+// watch cancels the query running on the connection once ctx is done, until the returned function
+// is called, which reports whether the query was canceled
+func (c *Conn) watch(ctx context.Context) func() bool {
+	if ctx.Done() == nil {
+		return func() bool { return false }
+	}
+
+	canceled := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(canceled)
+		ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+		defer cancel()
+		c.conn.Cancel(ctx)
+	})
+	return func() bool {
+		if stop() {
+			return false
+		}
+		// Wait for the CancelRequest so it can not cancel a later query
+		<-canceled
+		return true
+	}
+}
+
+// receive reads the next message from the server, keeping the parameters of the TypeMap up to date
+func (c *Conn) receive() (pgproto.ServerMessage, error) {
+	msg, err := c.conn.Receive()
+	if err != nil {
+		c.bad = true
+		c.conn.NetConn().Close()
+		return nil, err
+	}
+
+	switch m := msg.(type) {
+	case *pgproto.ParameterStatus:
+		c.m.SetParameter(string(m.Name), string(m.Value))
+	case *pgproto.CopyInResponse:
+		// The server waits for the data of COPY FROM STDIN, which database/sql can not provide
+		err = c.conn.Send(&pgproto.CopyFail{Message: []byte("COPY FROM STDIN is not supported by database/sql")})
+		if err != nil {
+			c.bad = true
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+// Prepare returns a prepared statement of query
+func (c *Conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext returns a prepared statement of query, described to learn the types of its
+// parameters and columns
+func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	c.statements++
+	name := fmt.Sprintf("stdlib_%d", c.statements)
+	r, err := c.send(ctx,
+		&pgproto.Parse{Name: []byte(name), Query: []byte(query)},
+		&pgproto.Describe{ObjectType: pgproto.ObjectTypePreparedStatement, Name: []byte(name)},
+		&pgproto.Sync{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stmt{c: c, name: name}
+	for msg := r.next(); msg != nil; msg = r.next() {
+		switch m := msg.(type) {
+		case *pgproto.ParameterDescription:
+			s.description = m
+		case *pgproto.RowDescription:
+			s.fields = m.Fields
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return s, nil
+}
+
+// ExecContext runs query with args, queries without arguments use the simple query protocol and may
+// hold several statements
+func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if len(args) == 0 {
+		r, err := c.send(ctx, &pgproto.SimpleQuery{Query: []byte(query)})
+		if err != nil {
+			return nil, err
+		}
+		return r.result()
+	}
+
+	bind, err := c.m.Bind("", nil, values(args)...)
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.send(ctx, &pgproto.Parse{Query: []byte(query)}, bind, &pgproto.Execute{}, &pgproto.Sync{})
+	if err != nil {
+		return nil, err
+	}
+	return r.result()
+}
+
+// QueryContext runs query with args as the unnamed statement, arguments are sent in the text format
+// as the types the server infers for the parameters
+func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	bind, err := c.m.Bind("", nil, values(args)...)
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.send(ctx,
+		&pgproto.Parse{Query: []byte(query)},
+		bind,
+		&pgproto.Describe{ObjectType: pgproto.ObjectTypePortal},
+		&pgproto.Execute{},
+		&pgproto.Sync{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return r.describe()
+}
+
+// CheckNamedValue accepts every argument TypeMap.Bind encodes as is, named arguments are not
+// supported
+func (c *Conn) CheckNamedValue(v *driver.NamedValue) error {
+	if v.Name != "" {
+		return fmt.Errorf("stdlib: named argument %s is not supported, use $%d", v.Name, v.Ordinal)
+	}
+	return nil
+}
+
+// values returns the values of args, in order
+func values(args []driver.NamedValue) []interface{} {
+	v := make([]interface{}, len(args))
+	for i, arg := range args {
+		v[i] = arg.Value
+	}
+	return v
+}
+
+// Begin starts a transaction
+func (c *Conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx starts a transaction of the isolation level and access mode of opts
+func (c *Conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.conn.Status != pgproto.READY_IDLE {
+		return nil, errors.New("stdlib: a transaction is already in progress")
+	}
+
+	query := "BEGIN"
+	switch sql.IsolationLevel(opts.Isolation) {
+	case sql.LevelDefault:
+	case sql.LevelReadUncommitted:
+		query += " ISOLATION LEVEL READ UNCOMMITTED"
+	case sql.LevelReadCommitted:
+		query += " ISOLATION LEVEL READ COMMITTED"
+	case sql.LevelRepeatableRead:
+		query += " ISOLATION LEVEL REPEATABLE READ"
+	case sql.LevelSerializable:
+		query += " ISOLATION LEVEL SERIALIZABLE"
+	default:
+		return nil, fmt.Errorf("stdlib: unsupported isolation level %s", sql.IsolationLevel(opts.Isolation))
+	}
+	if opts.ReadOnly {
+		query += " READ ONLY"
+	}
+
+	_, err := c.ExecContext(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{c: c}, nil
+}
+
+// Ping sends an empty query, checking the server answers
+func (c *Conn) Ping(ctx context.Context) error {
+	_, err := c.ExecContext(ctx, "", nil)
+	return err
+}
+
+// ResetSession reports the connection as bad when it is broken or a transaction database/sql does
+// not know of is in progress, e.g. one started by running BEGIN
+func (c *Conn) ResetSession(ctx context.Context) error {
+	if c.bad || c.conn.Status != pgproto.READY_IDLE {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// IsValid reports whether the connection can be reused
+func (c *Conn) IsValid() bool {
+	return !c.bad
+}
+
+// Close sends a Termination message and closes the connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// loadTypes registers the types created in the database in the TypeMap
+func (c *Conn) loadTypes(ctx context.Context) error {
+	r, err := c.send(ctx, &pgproto.SimpleQuery{Query: []byte(pgtype.TypesQuery())})
+	if err != nil {
+		return err
+	}
+
+	var rows []*pgproto.DataRow
+	for msg := r.next(); msg != nil; msg = r.next() {
+		if row, ok := msg.(*pgproto.DataRow); ok {
+			rows = append(rows, row)
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	return c.m.LoadTypes(rows)
+}
+
+// tx is a transaction, whose status is tracked from the ReadyForQuery messages of the connection
+type tx struct {
+	c *Conn
+}
+
+// Commit commits the transaction, or rolls it back when a query failed
+func (t *tx) Commit() error {
+	if t.c.conn.Status == pgproto.READY_ERROR {
+		err := t.Rollback()
+		if err != nil {
+			return err
+		}
+		return ErrFailedTransaction
+	}
+	_, err := t.c.ExecContext(context.Background(), "COMMIT", nil)
+	return err
+}
+
+// Rollback rolls the transaction back
+func (t *tx) Rollback() error {
+	_, err := t.c.ExecContext(context.Background(), "ROLLBACK", nil)
+	return err
+}
+
+// isCanceled returns whether err is the error of a query canceled by a CancelRequest
+func isCanceled(err error) bool {
+	var e *pgproto.Error
+	return errors.As(err, &e) && string(e.Code) == "57014"
+}
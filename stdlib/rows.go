@@ -0,0 +1,294 @@
+package stdlib
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/c653labs/pgproto"
+	"github.com/c653labs/pgproto/pgtype"
+)
+
+// Rows reads the response to a query up to its ReadyForQuery, decoding its DataRows by the TypeMap
+// of the connection, it implements driver.Rows
+type Rows struct {
+	c    *Conn
+	ctx  context.Context
+	stop func() bool
+
+	fields []pgproto.RowField
+	tag    []byte
+
+	// err is the first error of the response
+	err  error
+	done bool
+}
+
+// next returns the next message of the response, recording its errors and command tag, nil once
+// the response is complete
+func (r *Rows) next() pgproto.ServerMessage {
+	if r.done {
+		return nil
+	}
+
+	msg, err := r.c.receive()
+	if err != nil {
+		r.fail(err)
+		r.finish()
+		return nil
+	}
+	switch m := msg.(type) {
+	case *pgproto.Error:
+		r.fail(m)
+	case *pgproto.CommandCompletion:
+		r.tag = m.Tag
+	case *pgproto.ReadyForQuery:
+		r.finish()
+	}
+	return msg
+}
+
+// fail records err unless the response already failed
+func (r *Rows) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// finish ends the response, a query canceled by the context of the response fails with its error
+func (r *Rows) finish() {
+	r.done = true
+	r.c.rows = nil
+	if r.stop() && isCanceled(r.err) {
+		r.err = r.ctx.Err()
+	}
+}
+
+// describe reads the response up to the description of the rows
+func (r *Rows) describe() (*Rows, error) {
+	for msg := r.next(); msg != nil; msg = r.next() {
+		switch m := msg.(type) {
+		case *pgproto.RowDescription:
+			r.fields = m.Fields
+			return r, nil
+		case *pgproto.NoData:
+			return r, nil
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r, nil
+}
+
+// result reads the whole response, discarding its rows
+func (r *Rows) result() (driver.Result, error) {
+	for r.next() != nil {
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return result{tag: r.tag}, nil
+}
+
+// Columns returns the names of the columns
+func (r *Rows) Columns() []string {
+	names := make([]string, len(r.fields))
+	for i, f := range r.fields {
+		names[i] = string(f.ColumnName)
+	}
+	return names
+}
+
+// ColumnTypeDatabaseTypeName returns the upper case name of the type of the column index, e.g. INT4,
+// or its OID when the type is unknown
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	oid := r.fields[index].TypeOID
+	if t, ok := r.c.m.TypeForOID(oid); ok {
+		return strings.ToUpper(t.Name)
+	}
+	return strconv.Itoa(oid)
+}
+
+// Next stores the values of the next row in dest, it returns io.EOF once every row has been read
+func (r *Rows) Next(dest []driver.Value) error {
+	for msg := r.next(); msg != nil; msg = r.next() {
+		row, ok := msg.(*pgproto.DataRow)
+		if !ok {
+			continue
+		}
+		if len(row.Fields) != len(r.fields) {
+			r.fail(errors.New("stdlib: the row does not match the description of the rows"))
+			continue
+		}
+		for i, f := range r.fields {
+			v, err := r.value(f, row.Fields[i])
+			if err != nil {
+				r.fail(err)
+				break
+			}
+			dest[i] = v
+		}
+		if r.err == nil {
+			return nil
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	return io.EOF
+}
+
+// value decodes src, the value of the column f, as one of the types database/sql expects, values of
+// other types are returned as their text representation
+func (r *Rows) value(f pgproto.RowField, src []byte) (driver.Value, error) {
+	v, err := r.c.m.DecodeField(f, src)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case nil, bool, int64, float64, string, []byte, time.Time:
+		return v, nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case float32:
+		return float64(v), nil
+	}
+	if f.Format == pgproto.FormatText {
+		return string(src), nil
+	}
+	text, err := r.c.m.Encode(f.TypeOID, pgproto.FormatText, v)
+	if err != nil {
+		return nil, err
+	}
+	return string(text), nil
+}
+
+// Close reads the rest of the response, discarding its rows, and returns its error, e.g. an error
+// sent after the rows the caller read
+func (r *Rows) Close() error {
+	for r.next() != nil {
+	}
+	return r.err
+}
+
+// Stmt is a prepared statement, described when prepared, it implements driver.Stmt
+type Stmt struct {
+	c           *Conn
+	name        string
+	description *pgproto.ParameterDescription
+	fields      []pgproto.RowField
+}
+
+// NumInput returns the number of parameters of the statement
+func (s *Stmt) NumInput() int {
+	if s.description == nil {
+		return -1
+	}
+	return len(s.description.OIDs)
+}
+
+// Exec runs the statement with args
+func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+
+// ExecContext runs the statement with args
+func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	bind, err := s.bind(args)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.c.send(ctx, bind, &pgproto.Execute{}, &pgproto.Sync{})
+	if err != nil {
+		return nil, err
+	}
+	return r.result()
+}
+
+// Query runs the statement with args
+func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+
+// QueryContext runs the statement with args, its columns are sent in the binary format when the codec
+// of their type supports it
+func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	bind, err := s.bind(args)
+	if err != nil {
+		return nil, err
+	}
+	bind.ResultFormats = make([]pgproto.Format, len(s.fields))
+	for i, f := range s.fields {
+		if t, ok := s.c.m.TypeForOID(f.TypeOID); ok {
+			if _, ok := t.Codec.(pgtype.BinaryCodec); ok {
+				bind.ResultFormats[i] = pgproto.FormatBinary
+			}
+		}
+	}
+
+	// Describing the portal returns the formats the columns are sent in
+	r, err := s.c.send(ctx, bind, &pgproto.Describe{ObjectType: pgproto.ObjectTypePortal}, &pgproto.Execute{}, &pgproto.Sync{})
+	if err != nil {
+		return nil, err
+	}
+	return r.describe()
+}
+
+// bind returns the Bind message of args to the parameters of the statement
+func (s *Stmt) bind(args []driver.NamedValue) (*pgproto.Bind, error) {
+	return s.c.m.Bind(s.name, s.description, values(args)...)
+}
+
+// Close closes the statement on the server
+func (s *Stmt) Close() error {
+	if s.c.bad {
+		return nil
+	}
+	r, err := s.c.send(context.Background(),
+		&pgproto.Close{ObjectType: pgproto.ObjectTypePreparedStatement, Name: []byte(s.name)},
+		&pgproto.Sync{},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = r.result()
+	return err
+}
+
+// named returns args as the values of positional parameters
+func named(args []driver.Value) []driver.NamedValue {
+	n := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		n[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return n
+}
+
+// result is the result of a command, the number of rows of its command tag
+type result struct {
+	tag []byte
+}
+
+// LastInsertId is not supported, RETURNING returns the values of inserted rows
+func (result) LastInsertId() (int64, error) {
+	return 0, errors.New("stdlib: LastInsertId is not supported, use RETURNING")
+}
+
+// RowsAffected returns the number of rows the command processed, e.g. 3 for "UPDATE 3"
+func (r result) RowsAffected() (int64, error) {
+	fields := strings.Fields(string(r.tag))
+	if len(fields) < 2 {
+		return 0, nil
+	}
+	return strconv.ParseInt(fields[len(fields)-1], 10, 64)
+}
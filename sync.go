@@ -43,3 +43,13 @@ func (s *Sync) AsMap() map[string]interface{} {
 }
 
 func (s *Sync) String() string { return messageToString(s) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (s *Sync) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("Sync", s)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (s *Sync) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "Sync", s)
+}
@@ -46,3 +46,13 @@ func (t *Termination) AsMap() map[string]interface{} {
 }
 
 func (t *Termination) String() string { return messageToString(t) }
+
+// MarshalJSON implements json.Marshaler, see DecodeJSONMessage for the schema
+func (t *Termination) MarshalJSON() ([]byte, error) {
+	return marshalJSONMessage("Termination", t)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see DecodeJSONMessage for the schema
+func (t *Termination) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONMessage(data, "Termination", t)
+}
@@ -1,5 +1,7 @@
 package pgproto
 
+import "fmt"
+
 // ObjectType represents an object type
 type ObjectType byte
 
@@ -20,3 +22,17 @@ func (o ObjectType) String() string {
 	}
 	return "Uknown"
 }
+
+// MarshalText implements encoding.TextMarshaler, encoding the object type as the character sent on the wire
+func (o ObjectType) MarshalText() ([]byte, error) {
+	return []byte{byte(o)}, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, decoding a single object type character
+func (o *ObjectType) UnmarshalText(text []byte) error {
+	if len(text) != 1 {
+		return fmt.Errorf("invalid object type %q, must be a single character", text)
+	}
+	*o = ObjectType(text[0])
+	return nil
+}
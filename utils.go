@@ -4,7 +4,6 @@ import (
 	"crypto/md5"
 	"encoding/binary"
 	"encoding/hex"
-	"fmt"
 	"io"
 )
 
@@ -52,23 +51,7 @@ func WriteMessages(msgs []Message, w io.Writer) (int64, error) {
 	return int64(n), err
 }
 
+// messageToString formats m with the DefaultFormatter
 func messageToString(m Message) string {
-	data := m.AsMap()
-
-	t, _ := data["Type"]
-	p, _ := data["Payload"]
-
-	str := fmt.Sprintf("%s<", t)
-	if p, ok := p.(map[string]interface{}); ok {
-		first := true
-		for k, v := range p {
-			if !first {
-				str += ", "
-			}
-			str += fmt.Sprintf("%s=%#v", k, v)
-			first = false
-		}
-	}
-
-	return str + ">"
+	return DefaultFormatter.Format(m)
 }